@@ -26,11 +26,18 @@ import (
 	"github.com/tapglue/snaas/platform/metrics"
 	"github.com/tapglue/snaas/platform/redis"
 	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/campaign"
 	"github.com/tapglue/snaas/service/connection"
 	"github.com/tapglue/snaas/service/device"
+	"github.com/tapglue/snaas/service/event"
 	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/platform"
+	"github.com/tapglue/snaas/service/reaction"
+	"github.com/tapglue/snaas/service/receipt"
 	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/segment"
 	"github.com/tapglue/snaas/service/user"
+	"github.com/tapglue/snaas/service/webhook"
 )
 
 const (
@@ -206,6 +213,16 @@ func main() {
 	)(apps)
 	apps = app.LogServiceMiddleware(logger, storeService)(apps)
 
+	var campaigns campaign.Service
+	campaigns = campaign.PostgresService(pgClient)
+	campaigns = campaign.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(campaigns)
+
 	var connections connection.Service
 	connections = connection.PostgresService(pgClient)
 	connections = connection.InstrumentServiceMiddleware(
@@ -228,6 +245,17 @@ func main() {
 	)(devices)
 	devices = device.LogServiceMiddleware(logger, storeService)(devices)
 
+	var events event.Service
+	events = event.PostgresService(pgClient)
+	events = event.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(events)
+	events = event.LogServiceMiddleware(logger, storeService)(events)
+
 	var objects object.Service
 	objects = object.PostgresService(pgClient)
 	objects = object.InstrumentServiceMiddleware(
@@ -240,6 +268,30 @@ func main() {
 	objects = object.LogServiceMiddleware(logger, storeService)(objects)
 	objects = object.CacheServiceMiddleware(objectCountsCache)(objects)
 
+	var reactions reaction.Service
+	reactions = reaction.PostgresService(pgClient)
+	reactions = reaction.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(reactions)
+	reactions = reaction.LogServiceMiddleware(logger, storeService)(reactions)
+
+	var receipts receipt.Service
+	receipts = receipt.PostgresService(pgClient)
+	receipts = receipt.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(receipts)
+
+	var platforms platform.Service
+	platforms = platform.PostgresService(pgClient)
+
 	var rules rule.Service
 	rules = rule.PostgresService(pgClient)
 	rules = rule.InstrumentServiceMiddleware(
@@ -250,6 +302,16 @@ func main() {
 		serviceOpLatency,
 	)(rules)
 
+	var segments segment.Service
+	segments = segment.PostgresService(pgClient)
+	segments = segment.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(segments)
+
 	var users user.Service
 	users = user.PostgresService(pgClient)
 	users = user.InstrumentMiddleware(
@@ -261,6 +323,26 @@ func main() {
 	)(users)
 	users = user.LogMiddleware(logger, storeService)(users)
 
+	var webhooks webhook.Service
+	webhooks = webhook.PostgresService(pgClient)
+	webhooks = webhook.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(webhooks)
+
+	var deliveries webhook.DeliveryService
+	deliveries = webhook.PostgresDeliveryService(pgClient)
+	deliveries = webhook.InstrumentDeliveryServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(deliveries)
+
 	// Setup middlewares.
 	var (
 		withConstraints = handler.Chain(
@@ -317,6 +399,34 @@ func main() {
 		),
 	)
 
+	router.Methods("PUT").Path("/api/apps/{appID:[0-9]+}/campaigns/{campaignID:[0-9]+}/cancel").Name("campaignCancel").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.CampaignCancel(core.CampaignCancel(apps, campaigns)),
+		),
+	)
+
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/campaigns/{campaignID:[0-9]+}").Name("campaignRetrieve").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.CampaignRetrieve(core.CampaignFetch(apps, campaigns)),
+		),
+	)
+
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/campaigns").Name("campaignList").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.CampaignList(core.CampaignList(apps, campaigns)),
+		),
+	)
+
+	router.Methods("POST").Path("/api/apps/{appID:[0-9]+}/campaigns").Name("campaignCreate").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.CampaignCreate(core.CampaignCreate(apps, campaigns)),
+		),
+	)
+
 	router.Methods("PUT").Path("/api/apps/{appID:[0-9]+}/rules/{ruleID:[0-9]+}/activate").Name("ruleDeactivate").HandlerFunc(
 		handler.Wrap(
 			withConstraints,
@@ -324,6 +434,13 @@ func main() {
 		),
 	)
 
+	router.Methods("PUT").Path("/api/apps/{appID:[0-9]+}/rules/{ruleID:[0-9]+}").Name("ruleUpdate").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.RuleUpdate(core.RuleUpdate(apps, rules)),
+		),
+	)
+
 	router.Methods("PUT").Path("/api/apps/{appID:[0-9]+}/rules/{ruleID:[0-9]+}/deactivate").Name("ruleDeactivate").HandlerFunc(
 		handler.Wrap(
 			withConstraints,
@@ -345,6 +462,13 @@ func main() {
 		),
 	)
 
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/rules/{ruleID:[0-9]+}/variants").Name("ruleVariants").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.ReceiptVariants(core.ReceiptVariants(apps, receipts)),
+		),
+	)
+
 	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/rules").Name("ruleList").HandlerFunc(
 		handler.Wrap(
 			withConstraints,
@@ -352,6 +476,93 @@ func main() {
 		),
 	)
 
+	router.Methods("POST").Path("/api/apps/{appID:[0-9]+}/rules").Name("ruleCreate").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.RuleCreate(core.RuleCreate(apps, rules)),
+		),
+	)
+
+	router.Methods("POST").Path("/api/apps/{appID:[0-9]+}/segments/preview").Name("segmentPreview").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.SegmentPreview(core.SegmentPreview(apps, segments)),
+		),
+	)
+
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/segments/{segmentID:[0-9]+}/members").Name("segmentMembers").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.SegmentMembers(core.SegmentMembers(apps, segments, users)),
+		),
+	)
+
+	router.Methods("PUT").Path("/api/apps/{appID:[0-9]+}/segments/{segmentID:[0-9]+}/refresh").Name("segmentRefresh").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.SegmentRefresh(core.SegmentRefresh(apps, segments)),
+		),
+	)
+
+	router.Methods("PUT").Path("/api/apps/{appID:[0-9]+}/segments/{segmentID:[0-9]+}").Name("segmentUpdate").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.SegmentUpdate(core.SegmentUpdate(apps, segments)),
+		),
+	)
+
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/segments/{segmentID:[0-9]+}").Name("segmentRetrieve").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.SegmentRetrieve(core.SegmentFetch(apps, segments)),
+		),
+	)
+
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/segments").Name("segmentList").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.SegmentList(core.SegmentList(apps, segments)),
+		),
+	)
+
+	router.Methods("POST").Path("/api/apps/{appID:[0-9]+}/segments").Name("segmentCreate").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.SegmentCreate(core.SegmentCreate(apps, segments)),
+		),
+	)
+
+	router.Methods("POST").Path("/api/apps/{appID:[0-9]+}/platforms/webpush").Name("platformCreateWebPush").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.PlatformCreateWebPush(core.PlatformCreateWebPush(apps, platforms)),
+		),
+	)
+
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/receipts").Name("receiptList").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.ReceiptList(core.ReceiptList(apps, receipts)),
+		),
+	)
+
+	router.Methods("POST").Path("/api/apps/{appID:[0-9]+}/rules/preview").Name("rulePreview").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.RulePreview(
+				core.RulePreview(
+					apps,
+					connections,
+					events,
+					objects,
+					reactions,
+					rules,
+					users,
+				),
+			),
+		),
+	)
+
 	router.Methods("POST").Path("/api/me").Name("memberRetrieveMe").HandlerFunc(
 		handler.Wrap(
 			withConstraints,
@@ -391,6 +602,50 @@ func main() {
 		),
 	)
 
+	router.Methods("DELETE").Path("/api/apps/{appID:[0-9]+}/webhooks/{webhookID:[0-9]+}").Name("webhookDelete").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.WebhookDelete(core.WebhookDelete(apps, webhooks)),
+		),
+	)
+
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/webhooks/{webhookID:[0-9]+}/deliveries").Name("webhookDeliveryList").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.WebhookDeliveryList(
+				core.WebhookDeliveryList(apps, webhooks, deliveries),
+			),
+		),
+	)
+
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/webhooks/{webhookID:[0-9]+}").Name("webhookRetrieve").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.WebhookRetrieve(core.WebhookFetch(apps, webhooks)),
+		),
+	)
+
+	router.Methods("PUT").Path("/api/apps/{appID:[0-9]+}/webhooks/{webhookID:[0-9]+}").Name("webhookUpdate").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.WebhookUpdate(core.WebhookUpdate(apps, webhooks)),
+		),
+	)
+
+	router.Methods("GET").Path("/api/apps/{appID:[0-9]+}/webhooks").Name("webhookList").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.WebhookList(core.WebhookList(apps, webhooks)),
+		),
+	)
+
+	router.Methods("POST").Path("/api/apps/{appID:[0-9]+}/webhooks").Name("webhookCreate").HandlerFunc(
+		handler.Wrap(
+			withConstraints,
+			handler.WebhookCreate(core.WebhookCreate(apps, webhooks)),
+		),
+	)
+
 	router.Methods("GET").PathPrefix("/fonts").Name("fonts").Handler(
 		http.FileServer(FS(*staticLocal)),
 	)
@@ -23,14 +23,17 @@ import (
 	"github.com/tapglue/snaas/platform/limiter"
 	"github.com/tapglue/snaas/platform/metrics"
 	"github.com/tapglue/snaas/platform/redis"
+	"github.com/tapglue/snaas/platform/source"
 	"github.com/tapglue/snaas/service/app"
 	"github.com/tapglue/snaas/service/connection"
 	"github.com/tapglue/snaas/service/counter"
 	"github.com/tapglue/snaas/service/device"
 	"github.com/tapglue/snaas/service/event"
 	"github.com/tapglue/snaas/service/invite"
+	"github.com/tapglue/snaas/service/notification"
 	"github.com/tapglue/snaas/service/object"
 	"github.com/tapglue/snaas/service/reaction"
+	"github.com/tapglue/snaas/service/receipt"
 	"github.com/tapglue/snaas/service/session"
 	"github.com/tapglue/snaas/service/user"
 )
@@ -57,8 +60,15 @@ const (
 
 // Supported source types.
 const (
-	sourceNop = "nop"
-	sourceSQS = "sqs"
+	sourceNop      = "nop"
+	sourcePostgres = "postgres"
+	sourceRedis    = "redis"
+	sourceSQS      = "sqs"
+)
+
+// Consumer group which shares the state changes of Redis sources.
+const (
+	sourceGroup = "sims"
 )
 
 // Prefixes.
@@ -70,6 +80,7 @@ const (
 const (
 	defaultReadTimeout  = 2 * time.Second
 	defaultWriteTimeout = 3 * time.Second
+	relayBackoff        = time.Second
 )
 
 // Buildtime vars.
@@ -87,7 +98,8 @@ func main() {
 		listenAddr    = flag.String("listen.addr", ":8083", "HTTP bind address for main API")
 		postgresURL   = flag.String("postgres.url", "", "Postgres URL to connect to")
 		redisAddr     = flag.String("redis.addr", ":6379", "Redis address to connect to")
-		source        = flag.String("source", sourceNop, "Source type used for state change propagations")
+		sourceType    = flag.String("source", sourceNop, "Source type used for state change propagations")
+		sourceRelay   = flag.String("source.relay", "", "Source type the postgres outbox is relayed to")
 		telemetryAddr = flag.String("telemetry.addr", ":9000", "HTTP bind address where prometheus telemetry is exposed")
 	)
 	flag.Parse()
@@ -234,89 +246,76 @@ func main() {
 	)(reactionCountsCache)
 
 	// Setup sources.
+	if *sourceRelay != "" &&
+		(*sourceType != sourcePostgres || *sourceRelay != sourceSQS) {
+		logger.Log(
+			"err", fmt.Sprintf("Relay from '%s' to '%s' not supported", *sourceType, *sourceRelay),
+			"lifecycle", "abort",
+		)
+		os.Exit(1)
+	}
+
+	// The postgres outbox is written by the services in the transaction of
+	// the change itself and replaces the sourcing middlewares.
 	var (
-		conSource      connection.Source
-		eventSource    event.Source
-		objectSource   object.Source
-		reactionSource reaction.Source
-	)
-
-	switch *source {
-	case sourceNop:
-		conSource = connection.NopSource()
-		eventSource = event.NopSource()
-		objectSource = object.NopSource()
-	case sourceSQS:
-		conSource, err = connection.SQSSource(sqsAPI)
-		if err != nil {
-			logger.Log("err", err, "lifecycle", "abort")
-			os.Exit(1)
-		}
+		outbox  = *sourceType == sourcePostgres
+		sources = map[string]source.Source{}
+	)
 
-		eventSource, err = event.SQSSource(sqsAPI)
-		if err != nil {
-			logger.Log("err", err, "lifecycle", "abort")
-			os.Exit(1)
+	for _, e := range []source.Entity{
+		connection.Entity,
+		event.Entity,
+		object.Entity,
+		reaction.Entity,
+		user.Entity,
+	} {
+		var src source.Source
+
+		switch *sourceType {
+		case sourceNop:
+			src = source.NopSource()
+		case sourcePostgres:
+			src = source.PostgresSource(pgClient, e)
+		case sourceRedis:
+			src, err = source.RedisSource(redisPool, e, sourceGroup, hostname)
+		case sourceSQS:
+			src, err = source.SQSSource(sqsAPI, e)
+		default:
+			err = fmt.Errorf("Source type '%s' not supported", *sourceType)
 		}
-
-		objectSource, err = object.SQSSource(sqsAPI)
 		if err != nil {
 			logger.Log("err", err, "lifecycle", "abort")
 			os.Exit(1)
 		}
 
-		reactionSource, err = reaction.SQSSource(sqsAPI)
-		if err != nil {
-			logger.Log("err", err, "lifecycle", "abort")
-			os.Exit(1)
+		src = source.InstrumentMiddleware(
+			component,
+			e.Name,
+			*sourceType,
+			sourceErrCount,
+			sourceOpCount,
+			sourceOpLatency,
+			sourceQueueLatency,
+		)(src)
+		src = source.LogMiddleware(e, *sourceType, logger)(src)
+
+		// Relay outbox state changes.
+		if *sourceRelay != "" {
+			dst, err := source.SQSSource(sqsAPI, e)
+			if err != nil {
+				logger.Log("err", err, "lifecycle", "abort")
+				os.Exit(1)
+			}
+
+			go func(name string, src, dst source.Source) {
+				source.Relay(src, dst, relayBackoff, func(err error) {
+					logger.Log("entity", name, "err", err, "sub", "relay")
+				})
+			}(e.Name, src, dst)
 		}
-	default:
-		logger.Log(
-			"err", fmt.Sprintf("Source type '%s' not supported", *source),
-			"lifecycle", "abort",
-		)
-		os.Exit(1)
-	}
 
-	conSource = connection.InstrumentSourceMiddleware(
-		component,
-		*source,
-		sourceErrCount,
-		sourceOpCount,
-		sourceOpLatency,
-		sourceQueueLatency,
-	)(conSource)
-	conSource = connection.LogSourceMiddleware(*source, logger)(conSource)
-
-	eventSource = event.InstrumentSourceMiddleware(
-		component,
-		*source,
-		sourceErrCount,
-		sourceOpCount,
-		sourceOpLatency,
-		sourceQueueLatency,
-	)(eventSource)
-	eventSource = event.LogSourceMiddleware(*source, logger)(eventSource)
-
-	objectSource = object.InstrumentSourceMiddleware(
-		component,
-		*source,
-		sourceErrCount,
-		sourceOpCount,
-		sourceOpLatency,
-		sourceQueueLatency,
-	)(objectSource)
-	objectSource = object.LogSourceMiddleware(*source, logger)(objectSource)
-
-	reactionSource = reaction.InstrumentSourceMiddleware(
-		component,
-		*source,
-		sourceErrCount,
-		sourceOpCount,
-		sourceOpLatency,
-		sourceQueueLatency,
-	)(reactionSource)
-	reactionSource = reaction.LogSourceMiddleware(*source, logger)(reactionSource)
+		sources[e.Name] = src
+	}
 
 	// Setup services.
 	var apps app.Service
@@ -331,7 +330,11 @@ func main() {
 	apps = app.LogServiceMiddleware(logger, storeService)(apps)
 
 	var connections connection.Service
-	connections = connection.PostgresService(pgClient)
+	if outbox {
+		connections = connection.PostgresOutboxService(pgClient)
+	} else {
+		connections = connection.PostgresService(pgClient)
+	}
 	connections = connection.InstrumentServiceMiddleware(
 		component,
 		storeService,
@@ -341,7 +344,9 @@ func main() {
 	)(connections)
 	connections = connection.LogServiceMiddleware(logger, storeService)(connections)
 	// Combine connection service and source.
-	connections = connection.SourcingServiceMiddleware(conSource)(connections)
+	if !outbox {
+		connections = connection.SourcingServiceMiddleware(sources[connection.Entity.Name])(connections)
+	}
 
 	var counters counter.Service
 	counters = counter.PostgresService(pgClient)
@@ -358,7 +363,11 @@ func main() {
 	devices = device.LogServiceMiddleware(logger, storeService)(devices)
 
 	var events event.Service
-	events = event.PostgresService(pgClient)
+	if outbox {
+		events = event.PostgresOutboxService(pgClient)
+	} else {
+		events = event.PostgresService(pgClient)
+	}
 	events = event.InstrumentServiceMiddleware(
 		component,
 		storeService,
@@ -368,7 +377,9 @@ func main() {
 	)(events)
 	events = event.LogServiceMiddleware(logger, storeService)(events)
 	// Combine event service and source.
-	events = event.SourcingServiceMiddleware(eventSource)(events)
+	if !outbox {
+		events = event.SourcingServiceMiddleware(sources[event.Entity.Name])(events)
+	}
 	// Wrap service with caching.
 	// TODO: Reenable with proper write-through updates.
 	// events = event.CacheServiceMiddleware(eventCountsCache)(events)
@@ -384,8 +395,32 @@ func main() {
 	)(invites)
 	invites = invite.LogServiceMiddleware(logger, storeService)(invites)
 
+	var notifications notification.Service
+	notifications = notification.PostgresService(pgClient)
+	notifications = notification.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(notifications)
+
+	var notificationSettings notification.SettingsService
+	notificationSettings = notification.PostgresSettingsService(pgClient)
+	notificationSettings = notification.InstrumentSettingsServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(notificationSettings)
+
 	var objects object.Service
-	objects = object.PostgresService(pgClient)
+	if outbox {
+		objects = object.PostgresOutboxService(pgClient)
+	} else {
+		objects = object.PostgresService(pgClient)
+	}
 	objects = object.InstrumentServiceMiddleware(
 		component,
 		storeService,
@@ -395,12 +430,18 @@ func main() {
 	)(objects)
 	objects = object.LogServiceMiddleware(logger, storeService)(objects)
 	// Combine object service and source.
-	objects = object.SourcingServiceMiddleware(objectSource)(objects)
+	if !outbox {
+		objects = object.SourcingServiceMiddleware(sources[object.Entity.Name])(objects)
+	}
 	// Wrap service with caching
 	// objects = object.CacheServiceMiddleware(objectCountsCache)(objects)
 
 	var reactions reaction.Service
-	reactions = reaction.PostgresService(pgClient)
+	if outbox {
+		reactions = reaction.PostgresOutboxService(pgClient)
+	} else {
+		reactions = reaction.PostgresService(pgClient)
+	}
 	reactions = reaction.InstrumentServiceMiddleware(
 		component,
 		storeService,
@@ -410,10 +451,22 @@ func main() {
 	)(reactions)
 	reactions = reaction.LogServiceMiddleware(logger, storeService)(reactions)
 	// Combine reaction service and source.
-	reactions = reaction.SourcingServiceMiddleware(reactionSource)(reactions)
+	if !outbox {
+		reactions = reaction.SourcingServiceMiddleware(sources[reaction.Entity.Name])(reactions)
+	}
 	// Wrap service with caching
 	// reactions = reaction.CacheServiceMiddleware(reactionCountsCache)(reactions)
 
+	var receipts receipt.Service
+	receipts = receipt.PostgresService(pgClient)
+	receipts = receipt.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(receipts)
+
 	var sessions session.Service
 	sessions = session.PostgresService(pgClient)
 	sessions = session.InstrumentMiddleware(
@@ -426,7 +479,11 @@ func main() {
 	sessions = session.LogMiddleware(logger, storeService)(sessions)
 
 	var users user.Service
-	users = user.PostgresService(pgClient)
+	if outbox {
+		users = user.PostgresOutboxService(pgClient)
+	} else {
+		users = user.PostgresService(pgClient)
+	}
 	users = user.InstrumentMiddleware(
 		component,
 		storeService,
@@ -435,6 +492,10 @@ func main() {
 		serviceOpLatency,
 	)(users)
 	users = user.LogMiddleware(logger, storeService)(users)
+	// Combine user service and source.
+	if !outbox {
+		users = user.SourcingServiceMiddleware(sources[user.Entity.Name])(users)
+	}
 
 	// Setup middlewares.
 	var (
@@ -631,6 +692,106 @@ func main() {
 		),
 	)
 
+	// Notification routes.
+	current.Methods("GET").Path("/me/notifications").Name("notificationList").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationList(
+				core.NotificationList(notifications, users),
+			),
+		),
+	)
+
+	current.Methods("GET").Path("/me/notifications/count").Name("notificationCountUnread").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationCountUnread(
+				core.NotificationCountUnread(notifications, users),
+			),
+		),
+	)
+
+	current.Methods("PUT").Path("/me/notifications/read").Name("notificationReadAll").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationReadAll(
+				core.NotificationReadAll(users),
+			),
+		),
+	)
+
+	current.Methods("PUT").Path("/me/notifications/{notificationID:[0-9]+}/read").Name("notificationRead").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationRead(
+				core.NotificationRead(notifications),
+			),
+		),
+	)
+
+	current.Methods("POST").Path("/me/notifications/opens").Name("notificationOpen").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.ReceiptOpen(
+				core.ReceiptOpen(receipts),
+			),
+		),
+	)
+
+	current.Methods("GET").Path("/me/notifications/settings").Name("notificationSettingsRetrieve").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationSettingsRetrieve(
+				core.NotificationSettingsFetch(notificationSettings),
+			),
+		),
+	)
+
+	current.Methods("PUT").Path("/me/notifications/settings").Name("notificationSettingsUpdate").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationSettingsUpdate(
+				core.NotificationSettingsUpdate(notificationSettings),
+			),
+		),
+	)
+
+	current.Methods("PUT").Path("/me/notifications/settings/mutes/posts/{postID:[0-9]+}").Name("notificationMutePost").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationMutePost(
+				core.NotificationMutePost(notificationSettings),
+			),
+		),
+	)
+
+	current.Methods("DELETE").Path("/me/notifications/settings/mutes/posts/{postID:[0-9]+}").Name("notificationUnmutePost").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationUnmutePost(
+				core.NotificationMutePost(notificationSettings),
+			),
+		),
+	)
+
+	current.Methods("PUT").Path("/me/notifications/settings/mutes/users/{userID:[0-9]+}").Name("notificationMuteUser").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationMuteUser(
+				core.NotificationMuteUser(notificationSettings),
+			),
+		),
+	)
+
+	current.Methods("DELETE").Path("/me/notifications/settings/mutes/users/{userID:[0-9]+}").Name("notificationUnmuteUser").HandlerFunc(
+		handler.Wrap(
+			withUser,
+			handler.NotificationUnmuteUser(
+				core.NotificationMuteUser(notificationSettings),
+			),
+		),
+	)
+
 	// Invite routes.
 	current.Methods("POST").Path(`/me/invites`).Name("deviceCreate").HandlerFunc(
 		handler.Wrap(
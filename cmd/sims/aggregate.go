@@ -0,0 +1,48 @@
+package main
+
+import (
+	"time"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+// aggregateBatch is the maximum number of buffered messages looked at per
+// round.
+const aggregateBatch = 50
+
+// aggregateLock names the advisory lock which elects the instance flushing
+// aggregation windows.
+const aggregateLock = "sims.aggregate"
+
+// dispatchAggregates continuously flushes closed aggregation windows to send
+// and waits for the given interval whenever there was nothing due. Only the
+// instance holding the lock flushes, so a window is never delivered twice,
+// others stand by to take over.
+func dispatchAggregates(
+	lock *pg.Lock,
+	flush core.MessageFlushFunc,
+	send core.MessageDeliverFunc,
+	interval time.Duration,
+) error {
+	for {
+		leader, err := lock.Acquire()
+		if err != nil {
+			return err
+		}
+
+		if !leader {
+			time.Sleep(interval)
+			continue
+		}
+
+		n, err := flush(time.Now().UTC(), aggregateBatch, send)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			time.Sleep(interval)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"time"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+// campaignLock names the advisory lock which elects the instance sending
+// campaigns.
+const campaignLock = "sims.campaign"
+
+// dispatchCampaigns hands up to rate recipients of due campaigns to deliver in
+// every interval, which bounds the pace campaigns fan out at. Only the instance
+// holding the lock sends, others stand by to take over.
+func dispatchCampaigns(
+	lock *pg.Lock,
+	dispatch core.CampaignDispatchFunc,
+	deliver core.MessageDeliverFunc,
+	rate int,
+	interval time.Duration,
+) error {
+	for {
+		time.Sleep(interval)
+
+		leader, err := lock.Acquire()
+		if err != nil {
+			return err
+		}
+
+		if !leader {
+			continue
+		}
+
+		if _, err := dispatch(rate, deliver); err != nil {
+			return err
+		}
+	}
+}
@@ -1,26 +1,137 @@
 package main
 
 import (
+	"strconv"
+
 	"golang.org/x/text/language"
 
 	"github.com/tapglue/snaas/core"
 	serr "github.com/tapglue/snaas/error"
+	"github.com/tapglue/snaas/platform/email"
+	"github.com/tapglue/snaas/platform/push"
 	"github.com/tapglue/snaas/platform/sns"
 	"github.com/tapglue/snaas/service/app"
 	"github.com/tapglue/snaas/service/device"
+	"github.com/tapglue/snaas/service/platform"
+	"github.com/tapglue/snaas/service/receipt"
+	"github.com/tapglue/snaas/service/rule"
 )
 
 var languageDefault = language.English
 
 type channelFunc func(*app.App, *core.Message) error
 
+func channelEmail(
+	deviceListUser core.DeviceListUserFunc,
+	userFetch core.UserFetchFunc,
+	sender email.Sender,
+) channelFunc {
+	return func(currentApp *app.App, msg *core.Message) error {
+		if !msg.Notifies(rule.ChannelEmail) {
+			return nil
+		}
+
+		u, err := userFetch(currentApp, msg.Recipient)
+		if err != nil {
+			if core.IsNotFound(err) {
+				return nil
+			}
+
+			return err
+		}
+		if u.Email == "" {
+			return nil
+		}
+
+		// Users carry no language, the one of their devices is the best guess.
+		ds, err := deviceListUser(currentApp, u.ID)
+		if err != nil {
+			return err
+		}
+
+		lang := ""
+
+		if len(ds) > 0 {
+			lang = ds[0].Language
+		}
+
+		var (
+			body    = localise(lang, msg.Bodies)
+			subject = localise(lang, msg.Subjects)
+		)
+
+		if body == "" {
+			body = localise(lang, msg.Messages)
+		}
+
+		if subject == "" {
+			subject = localise(lang, msg.Messages)
+		}
+
+		err = sender.Send(&email.Email{
+			Body:    body,
+			Subject: subject,
+			To:      u.Email,
+		})
+		if err != nil {
+			if email.IsDeliveryFailure(err) || email.IsInvalidEmail(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		return nil
+	}
+}
+
+// channelInbox persists every message, independent of the channels it is
+// delivered on, so users can catch up in-app.
+func channelInbox(create core.NotificationCreateFunc) channelFunc {
+	return func(currentApp *app.App, msg *core.Message) error {
+		return create(currentApp, msg)
+	}
+}
+
+// channelPreferences guards a channel with the notification settings of the
+// recipient and drops every message they opted out of.
+func channelPreferences(
+	allowed core.NotificationAllowedFunc,
+) func(channelFunc) channelFunc {
+	return func(next channelFunc) channelFunc {
+		return func(currentApp *app.App, msg *core.Message) error {
+			ok, err := allowed(currentApp, msg)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				return nil
+			}
+
+			return next(currentApp, msg)
+		}
+	}
+}
+
+// channelPush delivers the message to all devices of the recipient through the
+// provider of their platform. SNS endpoints are synced before, direct providers
+// address the device by its token and disable it once the token is rejected.
+// Every attempt is recorded with its outcome in a receipt.
 func channelPush(
+	countUnread core.NotificationCountUnreadFunc,
+	deviceDisable core.DeviceDisableTokenFunc,
 	deviceListUser core.DeviceListUserFunc,
 	deviceSync core.DeviceSyncEndpointFunc,
 	fetchActive core.PlatformFetchActiveFunc,
-	push sns.PushFunc,
+	providers providerFunc,
+	recordReceipt core.ReceiptRecordFunc,
 ) channelFunc {
 	return func(currentApp *app.App, msg *core.Message) error {
+		if !msg.Notifies(rule.ChannelPush) {
+			return nil
+		}
+
 		ds, err := deviceListUser(currentApp, msg.Recipient)
 		if err != nil {
 			return err
@@ -29,6 +140,11 @@ func channelPush(
 			return nil
 		}
 
+		badge, err := pushBadge(countUnread, currentApp, msg)
+		if err != nil {
+			return err
+		}
+
 		for _, d := range ds {
 			p, err := fetchActive(currentApp, d.Platform)
 			if err != nil {
@@ -39,22 +155,82 @@ func channelPush(
 				return err
 			}
 
-			d, err = deviceSync(currentApp, p.ARN, d)
+			provider, err := providers(p)
 			if err != nil {
-				if serr.IsDeviceDisabled(err) {
+				// Misconfigured platforms don't get better on retry.
+				if push.IsInvalidCredentials(err) {
 					continue
 				}
 
 				return err
 			}
 
-			err = push(d.Platform, d.EndpointARN, p.Scheme, msg.URN, localiseMessage(d, msg.Messages))
+			t := push.Target{
+				Platform: d.Platform,
+				Scheme:   p.Scheme,
+				Token:    d.Token,
+			}
+
+			if d.Keys != nil {
+				t.Auth = d.Keys.Auth
+				t.P256DH = d.Keys.P256DH
+			}
+
+			if !p.Direct() {
+				d, err = deviceSync(currentApp, p.ARN, d)
+				if err != nil {
+					if serr.IsDeviceDisabled(err) {
+						continue
+					}
+
+					return err
+				}
+
+				t.EndpointARN = d.EndpointARN
+			}
+
+			id, err := provider.Push(t, pushPayload(d, msg, badge))
+
+			r := &receipt.Receipt{
+				DeviceID:  d.ID,
+				MessageID: id,
+				Platform:  d.Platform,
+				Provider:  platform.ProviderSNS,
+				Recipient: msg.Recipient,
+				RuleID:    msg.RuleID,
+				Status:    receipt.StatusSent,
+				VariantID: msg.VariantID,
+			}
+
+			if p.Direct() {
+				r.Provider = p.Provider
+			}
+
 			if err != nil {
-				if sns.IsDeliveryFailure(err) {
-					return nil
+				r.Error = err.Error()
+
+				switch {
+				case push.IsDeliveryFailure(err),
+					push.IsInvalidCredentials(err):
+					r.Status = receipt.StatusFailed
+				case push.IsInvalidToken(err):
+					r.Status = receipt.StatusUnregistered
+				default:
+					r.Status = receipt.StatusErrored
 				}
+			}
+
+			if err := recordReceipt(currentApp, r); err != nil {
+				return err
+			}
 
+			switch r.Status {
+			case receipt.StatusErrored:
 				return err
+			case receipt.StatusUnregistered:
+				if err := deviceDisable(currentApp, d.Token); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -63,7 +239,74 @@ func channelPush(
 }
 
 func localiseMessage(d *device.Device, msgs map[string]string) string {
-	t, err := language.Parse(d.Language)
+	return localise(d.Language, msgs)
+}
+
+// pushBadge determines the badge the pushes of the Message carry, if any.
+func pushBadge(
+	countUnread core.NotificationCountUnreadFunc,
+	currentApp *app.App,
+	msg *core.Message,
+) (*int, error) {
+	if msg.Push == nil || msg.Push.Badge == "" {
+		return nil, nil
+	}
+
+	if msg.Push.Badge == rule.BadgeUnread {
+		c, err := countUnread(currentApp, msg.Recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		badge := int(c)
+
+		return &badge, nil
+	}
+
+	// Templates which don't render an integer leave the badge untouched.
+	badge, err := strconv.Atoi(msg.Push.Badge)
+	if err != nil {
+		return nil, nil
+	}
+
+	return &badge, nil
+}
+
+// pushPayload localises the Message for the device. Messages of a template
+// variant carry rule and variant in their data for clients to report opens.
+func pushPayload(d *device.Device, msg *core.Message, badge *int) *sns.Payload {
+	p := &sns.Payload{
+		Badge:   badge,
+		Message: localiseMessage(d, msg.Messages),
+		URN:     msg.URN,
+	}
+
+	if msg.Push != nil {
+		p.CollapseKey = msg.Push.CollapseKey
+		p.Data = msg.Push.Data
+		p.Sound = msg.Push.Sound
+		p.ThreadID = msg.Push.ThreadID
+		p.Title = localiseMessage(d, msg.Push.Titles)
+	}
+
+	if msg.VariantID != "" {
+		data := map[string]string{}
+
+		for k, v := range p.Data {
+			data[k] = v
+		}
+
+		data[core.PushKeyRuleID] = strconv.FormatUint(msg.RuleID, 10)
+		data[core.PushKeyVariantID] = msg.VariantID
+
+		p.Data = data
+	}
+
+	return p
+}
+
+func localise(lang string, msgs map[string]string) string {
+	t, err := language.Parse(lang)
 	if err == nil {
 		b, _ := t.Base()
 
@@ -7,68 +7,124 @@ import (
 	"github.com/aws/aws-sdk-go/service/sqs"
 
 	"github.com/tapglue/snaas/core"
-	serr "github.com/tapglue/snaas/error"
+	"github.com/tapglue/snaas/platform/pg"
 	"github.com/tapglue/snaas/platform/sns"
 	"github.com/tapglue/snaas/platform/source"
 	platformSQS "github.com/tapglue/snaas/platform/sqs"
 	"github.com/tapglue/snaas/service/app"
-	"github.com/tapglue/snaas/service/connection"
-	"github.com/tapglue/snaas/service/event"
-	"github.com/tapglue/snaas/service/object"
-	"github.com/tapglue/snaas/service/reaction"
+	"github.com/tapglue/snaas/service/deadletter"
 	"github.com/tapglue/snaas/service/rule"
 )
 
 type ackFunc func() error
 
+// route binds the state changes of an entity to the rules and pipeline which
+// evaluate them and to the dead-letter type they are parked under.
+type route struct {
+	change   func(*source.Change) interface{}
+	entity   source.Entity
+	letter   deadletter.Type
+	pipeline func(*app.App, interface{}, ...*rule.Rule) (core.Messages, error)
+	rule     rule.Type
+	source   source.Source
+}
+
+// batch are the messages of a state change, changeID identifies it across
+// redeliveries.
 type batch struct {
 	ackFunc  ackFunc
 	app      *app.App
+	changeID string
 	messages core.Messages
 }
 
-func consumeConnection(
+func consume(
 	appFetch core.AppFetchFunc,
-	conSource connection.Source,
+	r route,
 	batchc chan<- batch,
-	pipeline core.PipelineConnectionFunc,
+	enqueue core.WebhookEnqueueFunc,
+	park parkFunc,
+	retry retryPolicy,
 	rules core.RuleListActiveFunc,
+	workers int,
 ) error {
-	for {
-		change, err := conSource.Consume()
+	return source.Work(workers, func() error {
+		c, err := r.source.Consume()
 		if err != nil {
-			if connection.IsEmptySource(err) {
-				continue
+			if e, ok := err.(*source.DecodeError); ok {
+				return parkUndecodable(r, park, e)
 			}
-			return err
-		}
 
-		currentApp, err := appForNamespace(appFetch, change.Namespace)
-		if err != nil {
 			return err
 		}
 
-		rs, err := rules(currentApp, rule.TypeConnection)
-		if err != nil {
-			return err
-		}
+		var (
+			change = r.change(c)
 
-		ms, err := pipeline(currentApp, change, rs...)
-		if err != nil {
-			return err
-		}
+			currentApp *app.App
+			ms         core.Messages
+		)
 
-		if len(ms) == 0 {
-			err := conSource.Ack(change.AckID)
+		attempts, err := retry.do(func() error {
+			a, err := appForNamespace(appFetch, c.Namespace)
+			if err != nil {
+				return err
+			}
+
+			rs, err := rules(a, r.rule)
 			if err != nil {
 				return err
 			}
 
-			continue
+			ms, err = r.pipeline(a, change, rs...)
+			if err != nil {
+				return err
+			}
+
+			currentApp = a
+
+			return nil
+		})
+		if err == nil {
+			// Retried on its own, failing pipelines must not enqueue the
+			// deliveries of an earlier attempt again.
+			attempts, err = retry.do(func() error {
+				return enqueue(currentApp, change)
+			})
 		}
+		if err != nil {
+			err := park(r.letter, c.Namespace, c.Old, c.New, attempts, err)
+			if err != nil {
+				return err
+			}
+
+			return r.source.Ack(c.AckID)
+		}
+
+		if len(ms) == 0 {
+			return r.source.Ack(c.AckID)
+		}
+
+		batchc <- batchMessages(currentApp, r.source, c.AckID, c.ID, ms)
+
+		return nil
+	})
+}
 
-		batchc <- batchMessages(currentApp, conSource, change.AckID, ms)
+// parkUndecodable sets a message aside which can't be decoded, redelivering it
+// would only fail again. The raw body is kept as string so it can be inspected
+// with the dlq command.
+func parkUndecodable(r route, park parkFunc, e *source.DecodeError) error {
+	ns := e.Namespace
+	if ns == "" {
+		ns = pg.MetaNamespace
 	}
+
+	if err := park(r.letter, ns, nil, string(e.Body), 1, e); err != nil {
+		return err
+	}
+
+	return r.source.Ack(e.AckID)
 }
 
 func consumeEndpointChange(
@@ -125,142 +181,10 @@ func consumeEndpointChange(
 	}
 }
 
-func consumeEvent(
-	appFetch core.AppFetchFunc,
-	eventSource event.Source,
-	batchc chan<- batch,
-	pipeline core.PipelineEventFunc,
-	rules core.RuleListActiveFunc,
-) error {
-	for {
-		change, err := eventSource.Consume()
-		if err != nil {
-			if event.IsEmptySource(err) {
-				continue
-			}
-			return err
-		}
-
-		currentApp, err := appForNamespace(appFetch, change.Namespace)
-		if err != nil {
-			return err
-		}
-
-		rs, err := rules(currentApp, rule.TypeEvent)
-		if err != nil {
-			return err
-		}
-
-		ms, err := pipeline(currentApp, change, rs...)
-		if err != nil {
-			return err
-		}
-
-		if len(ms) == 0 {
-			err = eventSource.Ack(change.AckID)
-			if err != nil {
-				return err
-			}
-
-			continue
-		}
-
-		batchc <- batchMessages(currentApp, eventSource, change.AckID, ms)
-	}
-}
-
-func consumeReaction(
-	appFetch core.AppFetchFunc,
-	reactionSource reaction.Source,
-	batchc chan<- batch,
-	pipeline core.PipelineReactionFunc,
-	rules core.RuleListActiveFunc,
-) error {
-	for {
-		change, err := reactionSource.Consume()
-		if err != nil {
-			if serr.IsEmptySource(err) {
-				continue
-			}
-			return err
-		}
-
-		currentApp, err := appForNamespace(appFetch, change.Namespace)
-		if err != nil {
-			return err
-		}
-
-		rs, err := rules(currentApp, rule.TypeReaction)
-		if err != nil {
-			return err
-		}
-
-		ms, err := pipeline(currentApp, change, rs...)
-		if err != nil {
-			return err
-		}
-
-		if len(ms) == 0 {
-			err = reactionSource.Ack(change.AckID)
-			if err != nil {
-				return err
-			}
-
-			continue
-		}
-
-		batchc <- batchMessages(currentApp, reactionSource, change.AckID, ms)
-	}
-}
-
-func consumeObject(
-	appFetch core.AppFetchFunc,
-	objectSource object.Source,
-	batchc chan<- batch,
-	pipeline core.PipelineObjectFunc,
-	rules core.RuleListActiveFunc,
-) error {
-	for {
-		change, err := objectSource.Consume()
-		if err != nil {
-			if object.IsEmptySource(err) {
-				continue
-			}
-			return err
-		}
-
-		currentApp, err := appForNamespace(appFetch, change.Namespace)
-		if err != nil {
-			return err
-		}
-
-		rs, err := rules(currentApp, rule.TypeObject)
-		if err != nil {
-			return err
-		}
-
-		ms, err := pipeline(currentApp, change, rs...)
-		if err != nil {
-			return err
-		}
-
-		if len(ms) == 0 {
-			err := objectSource.Ack(change.AckID)
-			if err != nil {
-				return err
-			}
-
-			continue
-		}
-
-		batchc <- batchMessages(currentApp, objectSource, change.AckID, ms)
-	}
-}
-
 func batchMessages(
 	currentApp *app.App,
 	acker source.Acker,
-	ackID string,
+	ackID, changeID string,
 	ms core.Messages,
 ) batch {
 	return batch{
@@ -278,6 +202,7 @@ func batchMessages(
 			}
 		}(false, ackID),
 		app:      currentApp,
+		changeID: changeID,
 		messages: ms,
 	}
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/source"
+	"github.com/tapglue/snaas/service/deadletter"
+)
+
+// Dead-letter command and its actions.
+const (
+	cmdDLQ = "dlq"
+
+	dlqList   = "list"
+	dlqPurge  = "purge"
+	dlqReplay = "replay"
+)
+
+// runDLQ executes one of the dead-letter actions against the letters matching
+// the given flags:
+//
+//	list    writes every letter as JSON line to out
+//	replay  propagates the parked state change again and removes the letter
+//	purge   removes the letter without further processing
+func runDLQ(
+	args []string,
+	letters deadletter.Service,
+	routes []route,
+	out io.Writer,
+) error {
+	if len(args) == 0 {
+		return fmt.Errorf("dlq: action missing, use one of list|replay|purge")
+	}
+
+	var (
+		action = args[0]
+		fs     = flag.NewFlagSet(fmt.Sprintf("%s %s", cmdDLQ, action), flag.ContinueOnError)
+
+		id        = fs.Uint64("id", 0, "Dead letter to operate on")
+		limit     = fs.Int("limit", 0, "Maximum number of dead letters to operate on")
+		namespace = fs.String("namespace", "", "App namespace to narrow down to")
+		t         = fs.String("type", "", "State change type to narrow down to")
+	)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	opts := deadletter.QueryOptions{
+		Limit: *limit,
+	}
+
+	if *id != 0 {
+		opts.IDs = []uint64{*id}
+	}
+
+	if *namespace != "" {
+		opts.Namespaces = []string{*namespace}
+	}
+
+	if *t != "" {
+		opts.Types = []deadletter.Type{deadletter.Type(*t)}
+	}
+
+	ls, err := letters.Query(pg.MetaNamespace, opts)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case dlqList:
+		enc := json.NewEncoder(out)
+
+		for _, l := range ls {
+			if err := enc.Encode(l); err != nil {
+				return err
+			}
+		}
+	case dlqPurge:
+		for _, l := range ls {
+			if err := letters.Delete(pg.MetaNamespace, l.ID); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(out, "purged %d\n", len(ls))
+	case dlqReplay:
+		for _, l := range ls {
+			if err := replay(routes, l); err != nil {
+				return fmt.Errorf("replay %d: %s", l.ID, err)
+			}
+
+			if err := letters.Delete(pg.MetaNamespace, l.ID); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(out, "replayed %d\n", len(ls))
+	default:
+		return fmt.Errorf("dlq: action '%s' not supported", action)
+	}
+
+	return nil
+}
+
+// replay propagates a parked state change again through the source of the
+// entity it belongs to.
+func replay(routes []route, l *deadletter.Letter) error {
+	for _, r := range routes {
+		if r.letter != l.Type {
+			continue
+		}
+
+		old, err := unmarshalPayload(r.entity.Payload, l.Old)
+		if err != nil {
+			return err
+		}
+
+		new, err := unmarshalPayload(r.entity.Payload, l.New)
+		if err != nil {
+			return err
+		}
+
+		_, err = r.source.Propagate(l.Namespace, old, new)
+		return err
+	}
+
+	return fmt.Errorf("type '%s' not supported", l.Type)
+}
+
+func unmarshalPayload(
+	payload source.PayloadFunc,
+	raw json.RawMessage,
+) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	p := payload()
+
+	if err := json.Unmarshal(raw, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -14,12 +14,16 @@ type endpointChange struct {
 	EventType      string `json:"EventType"`
 	FailureMessage string `json:"FailureMessage"`
 	FailureType    string `json:"FailureType"`
+	MessageID      string `json:"MessageId"`
 	Resource       string `json:"Resource"`
 	Service        string `json:"Service"`
 }
 
+// endpointUpdate disables the device of endpoints SNS failed to deliver to and
+// marks the receipt of the undelivered message as failed.
 func endpointUpdate(
 	disableDevice core.DeviceDisableFunc,
+	failReceipt core.ReceiptFailFunc,
 	currentApp *app.App,
 	c endpointChange,
 ) (err error) {
@@ -37,5 +41,9 @@ func endpointUpdate(
 		return nil
 	}
 
+	if err := failReceipt(currentApp, c.MessageID, c.FailureType); err != nil {
+		return err
+	}
+
 	return disableDevice(currentApp, c.EndpointArn)
 }
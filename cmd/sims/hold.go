@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+// holdBatch is the maximum number of held messages released per round.
+const holdBatch = 50
+
+// holdLock names the advisory lock which elects the instance releasing held
+// messages.
+const holdLock = "sims.hold"
+
+// dispatchHolds continuously releases held messages whose quiet hours ended to
+// send and waits for the given interval whenever there was nothing due. Only
+// the instance holding the lock releases, so a held message is never sent
+// twice, others stand by to take over.
+func dispatchHolds(
+	lock *pg.Lock,
+	release core.MessageReleaseFunc,
+	send core.MessageDeliverFunc,
+	interval time.Duration,
+) error {
+	for {
+		leader, err := lock.Acquire()
+		if err != nil {
+			return err
+		}
+
+		if !leader {
+			time.Sleep(interval)
+			continue
+		}
+
+		n, err := release(holdBatch, send)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			time.Sleep(interval)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/app"
+)
+
+// Hygiene command and the advisory lock which elects the instance running it
+// on schedule.
+const (
+	cmdHygiene  = "hygiene"
+	hygieneLock = "sims.hygiene"
+)
+
+// reportFunc is called for every app the hygiene changed devices of.
+type reportFunc func(*app.App, *core.DeviceHygieneReport) error
+
+// skipFunc is called for every app the hygiene failed for.
+type skipFunc func(*app.App, error)
+
+// dispatchHygiene cleans up the devices of all apps in the given interval. Only
+// the instance holding the lock runs it, others stand by to take over.
+func dispatchHygiene(
+	lock *pg.Lock,
+	appList core.AppListFunc,
+	hygiene core.DeviceHygieneFunc,
+	retention, interval time.Duration,
+	logger log.Logger,
+) error {
+	for {
+		time.Sleep(interval)
+
+		leader, err := lock.Acquire()
+		if err != nil {
+			return err
+		}
+
+		if !leader {
+			continue
+		}
+
+		err = runHygiene(
+			appList,
+			hygiene,
+			retention,
+			func(a *app.App, r *core.DeviceHygieneReport) error {
+				return logger.Log(
+					"app", a.ID,
+					"disabled", r.Disabled,
+					"pruned", r.Pruned,
+					"reassigned", r.Reassigned,
+					"resynced", r.Resynced,
+				)
+			},
+			func(a *app.App, err error) {
+				_ = logger.Log("app", a.ID, "err", err)
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// runHygiene cleans up the devices of all apps once, devices disabled for
+// longer than retention are removed. Apps the hygiene fails for are skipped so
+// one broken app doesn't keep the others from being cleaned up.
+func runHygiene(
+	appList core.AppListFunc,
+	hygiene core.DeviceHygieneFunc,
+	retention time.Duration,
+	report reportFunc,
+	skip skipFunc,
+) error {
+	as, err := appList()
+	if err != nil {
+		return err
+	}
+
+	pruneBefore := time.Now().UTC().Add(-retention)
+
+	for _, a := range as {
+		r, err := hygiene(a, pruneBefore)
+		if err != nil {
+			skip(a, err)
+			continue
+		}
+
+		if *r == (core.DeviceHygieneReport{}) {
+			continue
+		}
+
+		if err := report(a, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSkip writes a line for every app the hygiene failed for.
+func writeSkip(out io.Writer) skipFunc {
+	return func(a *app.App, err error) {
+		_, _ = fmt.Fprintf(out, "app %d: %s\n", a.ID, err)
+	}
+}
+
+// writeReport writes a line for every app the hygiene changed devices of.
+func writeReport(out io.Writer) reportFunc {
+	return func(a *app.App, r *core.DeviceHygieneReport) error {
+		_, err := fmt.Fprintf(
+			out,
+			"app %d: disabled %d pruned %d reassigned %d resynced %d\n",
+			a.ID,
+			r.Disabled,
+			r.Pruned,
+			r.Reassigned,
+			r.Resynced,
+		)
+
+		return err
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/push"
+	"github.com/tapglue/snaas/service/platform"
+)
+
+type providerFunc func(*platform.Platform) (push.Provider, error)
+
+type cachedProvider struct {
+	provider  push.Provider
+	updatedAt time.Time
+}
+
+// pushProviders returns the Provider pushes for a platform are delivered
+// through. Direct providers are kept per platform to reuse their auth tokens
+// and are rebuilt once the platform changes.
+func pushProviders(
+	snsProvider push.Provider,
+	apnsClient, client *http.Client,
+) providerFunc {
+	var (
+		mu        sync.Mutex
+		providers = map[uint64]cachedProvider{}
+	)
+
+	return func(p *platform.Platform) (push.Provider, error) {
+		if !p.Direct() {
+			return snsProvider, nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if c, ok := providers[p.ID]; ok && c.updatedAt.Equal(p.UpdatedAt) {
+			return c.provider, nil
+		}
+
+		var (
+			provider push.Provider
+			err      error
+		)
+
+		switch p.Provider {
+		case platform.ProviderAPNS:
+			host := push.APNSHostProduction
+
+			if p.Ecosystem == platform.IOSSandbox {
+				host = push.APNSHostSandbox
+			}
+
+			provider, err = push.APNS(apnsClient, host, push.APNSCredentials{
+				KeyID:      p.Credentials.KeyID,
+				PrivateKey: []byte(p.Credentials.PrivateKey),
+				TeamID:     p.Credentials.TeamID,
+				Topic:      p.Credentials.Topic,
+			})
+		case platform.ProviderFCM:
+			provider, err = push.FCM(
+				client,
+				push.FCMEndpoint,
+				[]byte(p.Credentials.ServiceAccount),
+			)
+		case platform.ProviderWebPush:
+			provider, err = push.WebPush(client, push.VAPIDCredentials{
+				PrivateKey: p.Credentials.VAPIDPrivateKey,
+				Subject:    p.Credentials.VAPIDSubject,
+			})
+		default:
+			err = fmt.Errorf("provider '%s' not supported", p.Provider)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		providers[p.ID] = cachedProvider{
+			provider:  provider,
+			updatedAt: p.UpdatedAt,
+		}
+
+		return provider, nil
+	}
+}
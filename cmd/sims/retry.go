@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/deadletter"
+)
+
+// parkFunc stores a state change which exhausted its retry budget.
+type parkFunc func(
+	t deadletter.Type,
+	ns string,
+	old, new interface{},
+	attempts int,
+	reason error,
+) error
+
+// retryPolicy describes how often and how patient an operation is retried.
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// do runs fn until it succeeds or the attempts are used up, doubling the
+// backoff after every failure. It returns the number of attempts made.
+func (p retryPolicy) do(fn func() error) (int, error) {
+	var (
+		attempts = p.attempts
+		err      error
+	)
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for i := 1; i <= attempts; i++ {
+		err = fn()
+		if err == nil {
+			return i, nil
+		}
+
+		if i < attempts {
+			time.Sleep(p.backoff * time.Duration(1<<uint(i-1)))
+		}
+	}
+
+	return attempts, err
+}
+
+func park(letters deadletter.Service, logger log.Logger) parkFunc {
+	return func(
+		t deadletter.Type,
+		ns string,
+		old, new interface{},
+		attempts int,
+		reason error,
+	) error {
+		o, err := marshalChange(old)
+		if err != nil {
+			return err
+		}
+
+		n, err := marshalChange(new)
+		if err != nil {
+			return err
+		}
+
+		l, err := letters.Put(pg.MetaNamespace, &deadletter.Letter{
+			Attempts:  attempts,
+			Namespace: ns,
+			New:       n,
+			Old:       o,
+			Reason:    reason.Error(),
+			Type:      t,
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.Log(
+			"attempts", attempts,
+			"deadletter", l.ID,
+			"namespace", ns,
+			"reason", reason,
+			"type", t,
+		)
+
+		return nil
+	}
+}
+
+func marshalChange(v interface{}) (json.RawMessage, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(raw) == "null" {
+		return nil, nil
+	}
+
+	return raw, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/cron"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+// scheduleLock names the advisory lock which elects the instance evaluating
+// scheduled rules.
+const scheduleLock = "sims.schedule"
+
+// dispatchSchedules evaluates the scheduled rules of all apps at the start of
+// every minute and hands the resulting messages on to batchc. Only the
+// instance holding the lock evaluates, others stand by to take over. Runs
+// missed during downtime or a slow evaluation are skipped, not caught up.
+func dispatchSchedules(
+	lock *pg.Lock,
+	appList core.AppListFunc,
+	ruleList core.RuleListScheduledFunc,
+	evaluate core.RuleScheduleFunc,
+	batchc chan<- batch,
+) error {
+	for {
+		tick := time.Now().UTC().Truncate(time.Minute).Add(time.Minute)
+
+		time.Sleep(tick.Sub(time.Now()))
+
+		leader, err := lock.Acquire()
+		if err != nil {
+			return err
+		}
+
+		if !leader {
+			continue
+		}
+
+		err = runSchedules(appList, ruleList, evaluate, batchc, tick)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// runSchedules evaluates the scheduled rules firing at now, each covering the
+// time since its previous run.
+func runSchedules(
+	appList core.AppListFunc,
+	ruleList core.RuleListScheduledFunc,
+	evaluate core.RuleScheduleFunc,
+	batchc chan<- batch,
+	now time.Time,
+) error {
+	as, err := appList()
+	if err != nil {
+		return err
+	}
+
+	for _, a := range as {
+		if !a.Enabled {
+			continue
+		}
+
+		rs, err := ruleList(a)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range rs {
+			s, err := cron.Parse(r.Schedule.Cron)
+			if err != nil {
+				return err
+			}
+
+			if !s.Match(now) {
+				continue
+			}
+
+			ms, err := evaluate(a, r, s.Prev(now), now)
+			if err != nil {
+				return err
+			}
+
+			if len(ms) == 0 {
+				continue
+			}
+
+			batchc <- batch{
+				ackFunc:  func() error { return nil },
+				app:      a,
+				changeID: fmt.Sprintf("schedule:%d:%d", r.ID, now.Unix()),
+				messages: ms,
+			}
+		}
+	}
+
+	return nil
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"net"
 	"net/http"
+	"net/smtp"
 	"os"
 	"time"
 
@@ -12,33 +15,68 @@ import (
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/go-kit/kit/log"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 	"github.com/jmoiron/sqlx"
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/http2"
 
 	"github.com/tapglue/snaas/core"
 	pErr "github.com/tapglue/snaas/error"
+	"github.com/tapglue/snaas/platform/dedupe"
+	"github.com/tapglue/snaas/platform/email"
+	"github.com/tapglue/snaas/platform/limiter"
 	"github.com/tapglue/snaas/platform/metrics"
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/push"
+	"github.com/tapglue/snaas/platform/redis"
 	platformSNS "github.com/tapglue/snaas/platform/sns"
+	"github.com/tapglue/snaas/platform/source"
 	platformSQS "github.com/tapglue/snaas/platform/sqs"
+	"github.com/tapglue/snaas/service/aggregate"
 	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/campaign"
 	"github.com/tapglue/snaas/service/connection"
+	"github.com/tapglue/snaas/service/deadletter"
 	"github.com/tapglue/snaas/service/device"
 	"github.com/tapglue/snaas/service/event"
+	"github.com/tapglue/snaas/service/hold"
+	"github.com/tapglue/snaas/service/notification"
 	"github.com/tapglue/snaas/service/object"
 	"github.com/tapglue/snaas/service/platform"
 	"github.com/tapglue/snaas/service/reaction"
+	"github.com/tapglue/snaas/service/receipt"
 	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/segment"
 	"github.com/tapglue/snaas/service/user"
+	"github.com/tapglue/snaas/service/webhook"
 )
 
 // Logging and telemetry identifiers.
 const (
-	component        = "sims"
-	namespaceService = "service"
-	namespaceSource  = "source"
-	sourceService    = "sqs"
-	storeService     = "postgres"
-	subsystemQueue   = "queue"
+	component         = "sims"
+	namespaceChannel  = "channel"
+	namespaceService  = "service"
+	namespaceSource   = "source"
+	storeService      = "postgres"
+	subsystemQueue    = "queue"
+	subsystemSuppress = "suppress"
+)
+
+// Supported source types.
+const (
+	sourcePostgres = "postgres"
+	sourceRedis    = "redis"
+	sourceSQS      = "sqs"
+)
+
+// Consumer group which shares the state changes of Redis sources.
+const (
+	sourceGroup = "sims"
+)
+
+// Pause between polls when no aggregation window closed.
+const (
+	aggregateTick = time.Second
 )
 
 // Queue names.
@@ -58,8 +96,30 @@ func main() {
 		awsID         = flag.String("aws.id", "", "Identifier for AWS requests")
 		awsRegion     = flag.String("aws.region", "us-east-1", "AWS region to operate in")
 		awsSecret     = flag.String("aws.secret", "", "Identification secret for AWS requests")
+		campaignIntvl = flag.Duration("campaign.interval", time.Second, "Pause between campaign batches")
+		campaignRate  = flag.Int("campaign.rate", 100, "Recipients of campaigns messaged per interval")
+		dedupeWindow  = flag.Duration("dedupe.window", 0, "Window in which identical messages to a recipient are dropped, disabled if zero")
+		emailAddr     = flag.String("email.addr", "", "SMTP server address, the email channel is disabled if empty")
+		emailFrom     = flag.String("email.from", "noreply@tapglue.com", "Sender address of notification emails")
+		emailPassword = flag.String("email.password", "", "Password for SMTP authentication")
+		emailUser     = flag.String("email.user", "", "Username for SMTP authentication, no authentication if empty")
+		holdInterval  = flag.Duration("hold.interval", 10*time.Second, "Pause between polls when no held messages are due")
+		hygieneIntvl  = flag.Duration("hygiene.interval", 0, "Interval of the device hygiene, disabled if zero")
+		hygieneRetain = flag.Duration("hygiene.retention", 30*24*time.Hour, "Duration disabled devices are kept before the hygiene removes them")
+		hookAttempts  = flag.Int("webhook.attempts", 8, "Attempts per webhook delivery before it is marked failed")
+		hookBackoff   = flag.Duration("webhook.backoff", 10*time.Second, "Initial backoff between delivery attempts, doubled on every retry")
+		hookInterval  = flag.Duration("webhook.interval", time.Second, "Pause between polls when no deliveries are due")
+		hookTimeout   = flag.Duration("webhook.timeout", 5*time.Second, "Timeout for a single webhook request")
 		postgresURL   = flag.String("postgres.url", "", "Postgres URL to connect to")
+		pushTimeout   = flag.Duration("push.timeout", 10*time.Second, "Timeout for a single request to a direct push provider")
+		redisAddr     = flag.String("redis.addr", ":6379", "Redis address to connect to")
+		retryAttempts = flag.Int("retry.attempts", 5, "Attempts per state change before it is parked")
+		retryBackoff  = flag.Duration("retry.backoff", time.Second, "Initial backoff between attempts, doubled on every retry")
+		sourceType    = flag.String("source", sourceSQS, "Source type used for state change consumption")
+		sourceWorkers = flag.Int("source.workers", 1, "Concurrent consumers per source, ordering is only kept for one")
 		telemetryAddr = flag.String("telemetry.addr", ":9001", "Address to expose telemetry on")
+		throttleLimit = flag.Int64("throttle.limit", 0, "Pushes and emails per recipient and rule within the throttle window, disabled if zero")
+		throttleWin   = flag.Duration("throttle.window", time.Hour, "Window the throttle limit applies to")
 	)
 	flag.Parse()
 
@@ -77,23 +137,25 @@ func main() {
 
 	logger = log.With(logger, "host", hostname)
 
-	// Setup instrumentation.
-	go func(addr string) {
-		logger.Log(
-			"duration", time.Now().Sub(begin).Nanoseconds(),
-			"lifecycle", "start",
-			"listen", addr,
-			"sub", "telemetry",
-		)
+	// Setup instrumentation, not needed when running a command.
+	if flag.Arg(0) != cmdDLQ && flag.Arg(0) != cmdHygiene {
+		go func(addr string) {
+			logger.Log(
+				"duration", time.Now().Sub(begin).Nanoseconds(),
+				"lifecycle", "start",
+				"listen", addr,
+				"sub", "telemetry",
+			)
 
-		http.Handle("/metrics", prometheus.Handler())
+			http.Handle("/metrics", prometheus.Handler())
 
-		err := http.ListenAndServe(addr, nil)
-		if err != nil {
-			logger.Log("err", err, "lifecycle", "abort", "sub", "telemetry")
-			os.Exit(1)
-		}
-	}(*telemetryAddr)
+			err := http.ListenAndServe(addr, nil)
+			if err != nil {
+				logger.Log("err", err, "lifecycle", "abort", "sub", "telemetry")
+				os.Exit(1)
+			}
+		}(*telemetryAddr)
+	}
 
 	serviceErrCount, serviceOpCount, serviceOpLatency := metrics.KeyMetrics(
 		namespaceService,
@@ -129,6 +191,16 @@ func main() {
 	)
 	prometheus.MustRegister(sourceQueueLatency)
 
+	suppressCount := kitprometheus.NewCounterFrom(prometheus.CounterOpts{
+		Namespace: namespaceChannel,
+		Subsystem: subsystemSuppress,
+		Name:      "count",
+		Help:      "Number of messages suppressed before delivery",
+	}, []string{
+		metrics.FieldComponent,
+		metrics.FieldReason,
+	})
+
 	// Setup clients.
 	var (
 		aSession = awsSession.New(&aws.Config{
@@ -145,6 +217,8 @@ func main() {
 		os.Exit(1)
 	}
 
+	redisPool := redis.Pool(*redisAddr, "")
+
 	// Setup services.
 	var apps app.Service
 	apps = app.PostgresService(pgClient)
@@ -157,6 +231,16 @@ func main() {
 	)(apps)
 	apps = app.LogServiceMiddleware(logger, storeService)(apps)
 
+	var campaigns campaign.Service
+	campaigns = campaign.PostgresService(pgClient)
+	campaigns = campaign.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(campaigns)
+
 	var connections connection.Service
 	connections = connection.PostgresService(pgClient)
 	connections = connection.InstrumentServiceMiddleware(
@@ -168,6 +252,46 @@ func main() {
 	)(connections)
 	connections = connection.LogServiceMiddleware(logger, storeService)(connections)
 
+	var letters deadletter.Service
+	letters = deadletter.PostgresService(pgClient)
+	letters = deadletter.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(letters)
+
+	var aggregates aggregate.Service
+	aggregates = aggregate.PostgresService(pgClient)
+	aggregates = aggregate.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(aggregates)
+
+	var holds hold.Service
+	holds = hold.PostgresService(pgClient)
+	holds = hold.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(holds)
+
+	var receipts receipt.Service
+	receipts = receipt.PostgresService(pgClient)
+	receipts = receipt.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(receipts)
+
 	var devices device.Service
 	devices = device.PostgresService(pgClient)
 	devices = device.InstrumentServiceMiddleware(
@@ -179,6 +303,26 @@ func main() {
 	)(devices)
 	devices = device.LogServiceMiddleware(logger, storeService)(devices)
 
+	var notifications notification.Service
+	notifications = notification.PostgresService(pgClient)
+	notifications = notification.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(notifications)
+
+	var notificationSettings notification.SettingsService
+	notificationSettings = notification.PostgresSettingsService(pgClient)
+	notificationSettings = notification.InstrumentSettingsServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(notificationSettings)
+
 	var objects object.Service
 	objects = object.PostgresService(pgClient)
 	objects = object.InstrumentServiceMiddleware(
@@ -206,6 +350,16 @@ func main() {
 	)(rules)
 	// TODO: Implement logging middleware.
 
+	var segments segment.Service
+	segments = segment.PostgresService(pgClient)
+	segments = segment.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(segments)
+
 	var users user.Service
 	users = user.PostgresService(pgClient)
 	users = user.InstrumentMiddleware(
@@ -217,66 +371,206 @@ func main() {
 	)(users)
 	users = user.LogMiddleware(logger, storeService)(users)
 
+	var webhooks webhook.Service
+	webhooks = webhook.PostgresService(pgClient)
+	webhooks = webhook.InstrumentServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(webhooks)
+
+	var deliveries webhook.DeliveryService
+	deliveries = webhook.PostgresDeliveryService(pgClient)
+	deliveries = webhook.InstrumentDeliveryServiceMiddleware(
+		component,
+		storeService,
+		serviceErrCount,
+		serviceOpCount,
+		serviceOpLatency,
+	)(deliveries)
+
 	// Setup sources.
-	conSource, err := connection.SQSSource(sqsAPI)
-	if err != nil {
-		logger.Log("err", err, "lifecycle", "abort")
-		os.Exit(1)
+	sources := map[string]source.Source{}
+
+	for _, e := range []source.Entity{
+		connection.Entity,
+		event.Entity,
+		object.Entity,
+		reaction.Entity,
+		user.Entity,
+	} {
+		var src source.Source
+
+		switch *sourceType {
+		case sourcePostgres:
+			src = source.PostgresSource(pgClient, e)
+		case sourceRedis:
+			src, err = source.RedisSource(redisPool, e, sourceGroup, hostname)
+		case sourceSQS:
+			src, err = source.SQSSource(sqsAPI, e)
+		default:
+			err = fmt.Errorf("Source type '%s' not supported", *sourceType)
+		}
+		if err != nil {
+			logger.Log("err", err, "lifecycle", "abort")
+			os.Exit(1)
+		}
+
+		src = source.InstrumentMiddleware(
+			component,
+			e.Name,
+			*sourceType,
+			sourceErrCount,
+			sourceOpCount,
+			sourceOpLatency,
+			sourceQueueLatency,
+		)(src)
+		src = source.LogMiddleware(e, *sourceType, logger)(src)
+
+		sources[e.Name] = src
 	}
-	conSource = connection.InstrumentSourceMiddleware(
-		component,
-		sourceService,
-		sourceErrCount,
-		sourceOpCount,
-		sourceOpLatency,
-		sourceQueueLatency,
-	)(conSource)
-	conSource = connection.LogSourceMiddleware(sourceService, logger)(conSource)
-
-	eventSource, err := event.SQSSource(sqsAPI)
-	if err != nil {
-		logger.Log("err", err, "lifecycle", "abort")
-		os.Exit(1)
+
+	var (
+		pipelineConnection = core.PipelineConnection(users)
+		pipelineEvent      = core.PipelineEvent(objects, users)
+		pipelineObject     = core.PipelineObject(connections, objects, users)
+		pipelineReaction   = core.PipelineReaction(objects, users)
+		pipelineUser       = core.PipelineUser(connections, users)
+	)
+
+	routes := []route{
+		{
+			change: func(c *source.Change) interface{} {
+				return connection.ChangeFrom(c)
+			},
+			entity: connection.Entity,
+			letter: deadletter.TypeConnection,
+			pipeline: func(
+				a *app.App,
+				c interface{},
+				rs ...*rule.Rule,
+			) (core.Messages, error) {
+				return pipelineConnection(a, c.(*connection.StateChange), rs...)
+			},
+			rule: rule.TypeConnection,
+		},
+		{
+			change: func(c *source.Change) interface{} {
+				return event.ChangeFrom(c)
+			},
+			entity: event.Entity,
+			letter: deadletter.TypeEvent,
+			pipeline: func(
+				a *app.App,
+				c interface{},
+				rs ...*rule.Rule,
+			) (core.Messages, error) {
+				return pipelineEvent(a, c.(*event.StateChange), rs...)
+			},
+			rule: rule.TypeEvent,
+		},
+		{
+			change: func(c *source.Change) interface{} {
+				return object.ChangeFrom(c)
+			},
+			entity: object.Entity,
+			letter: deadletter.TypeObject,
+			pipeline: func(
+				a *app.App,
+				c interface{},
+				rs ...*rule.Rule,
+			) (core.Messages, error) {
+				return pipelineObject(a, c.(*object.StateChange), rs...)
+			},
+			rule: rule.TypeObject,
+		},
+		{
+			change: func(c *source.Change) interface{} {
+				return reaction.ChangeFrom(c)
+			},
+			entity: reaction.Entity,
+			letter: deadletter.TypeReaction,
+			pipeline: func(
+				a *app.App,
+				c interface{},
+				rs ...*rule.Rule,
+			) (core.Messages, error) {
+				return pipelineReaction(a, c.(*reaction.StateChange), rs...)
+			},
+			rule: rule.TypeReaction,
+		},
+		{
+			change: func(c *source.Change) interface{} {
+				return user.ChangeFrom(c)
+			},
+			entity: user.Entity,
+			letter: deadletter.TypeUser,
+			pipeline: func(
+				a *app.App,
+				c interface{},
+				rs ...*rule.Rule,
+			) (core.Messages, error) {
+				return pipelineUser(a, c.(*user.StateChange), rs...)
+			},
+			rule: rule.TypeUser,
+		},
 	}
-	eventSource = event.InstrumentSourceMiddleware(
-		component,
-		sourceService,
-		sourceErrCount,
-		sourceOpCount,
-		sourceOpLatency,
-		sourceQueueLatency,
-	)(eventSource)
-	eventSource = event.LogSourceMiddleware(sourceService, logger)(eventSource)
-
-	objectSource, err := object.SQSSource(sqsAPI)
-	if err != nil {
-		logger.Log("err", err, "lifecycle", "abort")
-		os.Exit(1)
+
+	for i, r := range routes {
+		routes[i].source = sources[r.entity.Name]
 	}
-	objectSource = object.InstrumentSourceMiddleware(
-		component,
-		sourceService,
-		sourceErrCount,
-		sourceOpCount,
-		sourceOpLatency,
-		sourceQueueLatency,
-	)(objectSource)
-	objectSource = object.LogSourceMiddleware(sourceService, logger)(objectSource)
-
-	reactionSource, err := reaction.SQSSource(sqsAPI)
-	if err != nil {
-		logger.Log("err", err, "lifecycle", "abort")
-		os.Exit(1)
+
+	// Operate on dead letters instead of consuming.
+	if flag.Arg(0) == cmdDLQ {
+		err := runDLQ(
+			flag.Args()[1:],
+			letters,
+			routes,
+			os.Stdout,
+		)
+		if err != nil {
+			logger.Log("err", err, "lifecycle", "abort", "sub", cmdDLQ)
+			os.Exit(1)
+		}
+
+		return
 	}
-	reactionSource = reaction.InstrumentSourceMiddleware(
-		component,
-		sourceService,
-		sourceErrCount,
-		sourceOpCount,
-		sourceOpLatency,
-		sourceQueueLatency,
-	)(reactionSource)
-	reactionSource = reaction.LogSourceMiddleware(sourceService, logger)(reactionSource)
+
+	hygiene := core.DeviceHygiene(
+		devices,
+		platforms,
+		platformSNS.EndpointCreate(snsAPI),
+		platformSNS.EndpointRetrieve(snsAPI),
+		platformSNS.EndpointUpdate(snsAPI),
+	)
+
+	// Clean up devices once instead of consuming.
+	if flag.Arg(0) == cmdHygiene {
+		err := runHygiene(
+			core.AppList(apps),
+			hygiene,
+			*hygieneRetain,
+			writeReport(os.Stdout),
+			writeSkip(os.Stderr),
+		)
+		if err != nil {
+			logger.Log("err", err, "lifecycle", "abort", "sub", cmdHygiene)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	var (
+		enqueue    = core.WebhookEnqueue(webhooks, deliveries)
+		parkLetter = park(letters, log.With(logger, "sub", cmdDLQ))
+		retry      = retryPolicy{
+			attempts: *retryAttempts,
+			backoff:  *retryBackoff,
+		}
+	)
 
 	logger.Log(
 		"duration", time.Now().Sub(begin).Nanoseconds(),
@@ -323,7 +617,12 @@ func main() {
 				os.Exit(1)
 			}
 
-			err = endpointUpdate(core.DeviceDisable(devices), a, c)
+			err = endpointUpdate(
+				core.DeviceDisable(devices),
+				core.ReceiptFail(receipts),
+				a,
+				c,
+			)
 			if err != nil {
 				logger.Log("err", err, "lifecycle", "abort")
 				os.Exit(1)
@@ -334,86 +633,286 @@ func main() {
 	// Consume entity state changes.
 	batchc := make(chan batch)
 
+	for _, r := range routes {
+		go func(r route) {
+			err := consume(
+				core.AppFetch(apps),
+				r,
+				batchc,
+				enqueue,
+				parkLetter,
+				retry,
+				core.RuleListActive(rules),
+				*sourceWorkers,
+			)
+			if err != nil {
+				logger.Log("err", err, "lifecycle", "abort", "source", r.entity.Name)
+				os.Exit(1)
+			}
+		}(r)
+	}
+
+	// Evaluate scheduled rules on a single instance.
 	go func() {
-		err := consumeConnection(
-			core.AppFetch(apps),
-			conSource,
+		err := dispatchSchedules(
+			pg.NewLock(pgClient.DB, scheduleLock),
+			core.AppList(apps),
+			core.RuleListScheduled(rules),
+			core.RuleSchedule(connections, objects, users),
 			batchc,
-			core.PipelineConnection(users),
-			core.RuleListActive(rules),
 		)
 		if err != nil {
-			logger.Log("err", err, "lifecycle", "abort")
+			logger.Log("err", err, "lifecycle", "abort", "sub", "schedule")
 			os.Exit(1)
 		}
 	}()
 
+	// Clean up devices periodically on a single instance.
+	if *hygieneIntvl > 0 {
+		go func() {
+			err := dispatchHygiene(
+				pg.NewLock(pgClient.DB, hygieneLock),
+				core.AppList(apps),
+				hygiene,
+				*hygieneRetain,
+				*hygieneIntvl,
+				log.With(logger, "sub", cmdHygiene),
+			)
+			if err != nil {
+				logger.Log("err", err, "lifecycle", "abort", "sub", cmdHygiene)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Deliver state changes to app webhooks on a single instance.
 	go func() {
-		err := consumeEvent(
-			core.AppFetch(apps),
-			eventSource,
-			batchc,
-			core.PipelineEvent(objects, users),
-			core.RuleListActive(rules),
+		err := dispatchWebhooks(
+			pg.NewLock(pgClient.DB, webhookLock),
+			core.WebhookDeliver(
+				apps,
+				webhooks,
+				deliveries,
+				webhook.Post(webhook.Client(*hookTimeout)),
+				*hookAttempts,
+				*hookBackoff,
+			),
+			*hookInterval,
 		)
 		if err != nil {
+			logger.Log("err", err, "lifecycle", "abort", "sub", "webhook")
+			os.Exit(1)
+		}
+	}()
+
+	// Distribute messages to channels.
+	pushChannel := channelPush(
+		core.NotificationCountUnread(notifications, users),
+		core.DeviceDisableToken(devices),
+		core.DeviceListUser(devices),
+		core.DeviceSyncEndpoint(
+			devices,
+			platformSNS.EndpointCreate(snsAPI),
+			platformSNS.EndpointRetrieve(snsAPI),
+			platformSNS.EndpointUpdate(snsAPI),
+		),
+		core.PlatformFetchActive(platforms),
+		pushProviders(
+			push.SNS(snsAPI),
+			&http.Client{
+				Timeout:   *pushTimeout,
+				Transport: &http2.Transport{},
+			},
+			&http.Client{Timeout: *pushTimeout},
+		),
+		core.ReceiptRecord(receipts),
+	)
+
+	// Cap the outbound channels per recipient and rule, the inbox is exempt.
+	throttle := func(channel rule.Channel, next channelFunc) channelFunc {
+		return next
+	}
+
+	if *throttleLimit > 0 {
+		l := limiter.Redis(redisPool, "sims.throttle")
+
+		throttle = func(channel rule.Channel, next channelFunc) channelFunc {
+			return channelThrottle(
+				l,
+				channel,
+				*throttleLimit,
+				*throttleWin,
+				suppressCount,
+			)(next)
+		}
+	}
+
+	cs := []channelFunc{
+		channelInbox(core.NotificationCreate(notifications)),
+		throttle(rule.ChannelPush, pushChannel),
+	}
+
+	if *emailAddr != "" {
+		var auth smtp.Auth
+
+		if *emailUser != "" {
+			host, _, err := net.SplitHostPort(*emailAddr)
+			if err != nil {
+				logger.Log("err", err, "lifecycle", "abort")
+				os.Exit(1)
+			}
+
+			auth = smtp.PlainAuth("", *emailUser, *emailPassword, host)
+		}
+
+		cs = append(cs, throttle(rule.ChannelEmail, channelEmail(
+			core.DeviceListUser(devices),
+			core.UserFetch(users),
+			email.SMTPSender(*emailAddr, *emailFrom, auth),
+		)))
+	}
+
+	// Every channel honours the notification settings of the recipient.
+	withPreferences := channelPreferences(
+		core.NotificationAllowed(notificationSettings),
+	)
+
+	for i, channel := range cs {
+		cs[i] = withPreferences(channel)
+	}
+
+	send := func(currentApp *app.App, msg *core.Message) error {
+		for _, channel := range cs {
+			if err := channel(currentApp, msg); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	// Hold back non-urgent messages during the quiet hours of the recipient.
+	holdMessage := core.MessageHold(devices, holds, users)
+
+	// Drop identical messages to the same recipient within the window.
+	var (
+		isDuplicate duplicateFunc = func(*app.App, *core.Message) (bool, error) {
+			return false, nil
+		}
+		forgetMessage forgetFunc = func(*app.App, *core.Message) error {
+			return nil
+		}
+	)
+
+	if *dedupeWindow > 0 {
+		deduper := dedupe.Redis(redisPool, "sims.dedupe")
+
+		isDuplicate = duplicate(deduper, *dedupeWindow, suppressCount)
+		forgetMessage = forget(deduper)
+	}
+
+	deliver := func(currentApp *app.App, msg *core.Message) {
+		dup, err := isDuplicate(currentApp, msg)
+		if err != nil {
+			logger.Log("err", err, "lifecycle", "abort")
+			os.Exit(1)
+		}
+
+		if dup {
+			return
+		}
+
+		// The message is marked as seen from here on, which is undone if it
+		// is neither held nor sent.
+		abort := func(err error) {
+			if err := forgetMessage(currentApp, msg); err != nil {
+				logger.Log("err", err, "sub", "dedupe")
+			}
+
 			logger.Log("err", err, "lifecycle", "abort")
 			os.Exit(1)
 		}
+
+		held, err := holdMessage(currentApp, msg, time.Now())
+		if err != nil {
+			abort(err)
+		}
+
+		if held {
+			return
+		}
+
+		if err := send(currentApp, msg); err != nil {
+			abort(err)
+		}
+	}
+
+	// Release held messages once the quiet hours of their recipient ended on a
+	// single instance.
+	go func() {
+		err := dispatchHolds(
+			pg.NewLock(pgClient.DB, holdLock),
+			core.MessageRelease(apps, holds),
+			send,
+			*holdInterval,
+		)
+		if err != nil {
+			logger.Log("err", err, "lifecycle", "abort", "sub", "hold")
+			os.Exit(1)
+		}
 	}()
 
+	// Fan out campaigns at a bounded pace on a single instance.
 	go func() {
-		err := consumeObject(
-			core.AppFetch(apps),
-			objectSource,
-			batchc,
-			core.PipelineObject(connections, objects, users),
-			core.RuleListActive(rules),
+		err := dispatchCampaigns(
+			pg.NewLock(pgClient.DB, campaignLock),
+			core.CampaignDispatch(apps, campaigns, devices, segments, users),
+			func(currentApp *app.App, msg *core.Message) error {
+				deliver(currentApp, msg)
+				return nil
+			},
+			*campaignRate,
+			*campaignIntvl,
 		)
 		if err != nil {
-			logger.Log("err", err, "lifecycle", "abort")
+			logger.Log("err", err, "lifecycle", "abort", "sub", "campaign")
 			os.Exit(1)
 		}
 	}()
 
+	// Collapse messages of recipients configured for aggregation once their
+	// window closed on a single instance.
 	go func() {
-		err := consumeReaction(
-			core.AppFetch(apps),
-			reactionSource,
-			batchc,
-			core.PipelineReaction(objects, users),
-			core.RuleListActive(rules),
+		err := dispatchAggregates(
+			pg.NewLock(pgClient.DB, aggregateLock),
+			core.MessageFlush(apps, aggregates),
+			func(currentApp *app.App, msg *core.Message) error {
+				deliver(currentApp, msg)
+				return nil
+			},
+			aggregateTick,
 		)
 		if err != nil {
-			logger.Log("err", err, "lifecycle", "abort")
+			logger.Log("err", err, "lifecycle", "abort", "sub", "aggregate")
 			os.Exit(1)
 		}
 	}()
 
-	// Distribute messages to channels.
-	cs := []channelFunc{
-		channelPush(
-			core.DeviceListUser(devices),
-			core.DeviceSyncEndpoint(
-				devices,
-				platformSNS.EndpointCreate(snsAPI),
-				platformSNS.EndpointRetrieve(snsAPI),
-				platformSNS.EndpointUpdate(snsAPI),
-			),
-			core.PlatformFetchActive(platforms),
-			platformSNS.Push(snsAPI),
-		),
-	}
+	aggregateMessage := core.MessageAggregate(aggregates)
 
 	for batch := range batchc {
 		for _, msg := range batch.messages {
-			for _, channel := range cs {
-				err := channel(batch.app, msg)
-				if err != nil {
-					logger.Log("err", err, "lifecycle", "abort")
-					os.Exit(1)
-				}
+			buffered, err := aggregateMessage(batch.app, msg, batch.changeID, time.Now())
+			if err != nil {
+				logger.Log("err", err, "lifecycle", "abort", "sub", "aggregate")
+				os.Exit(1)
 			}
+
+			if buffered {
+				continue
+			}
+
+			deliver(batch.app, msg)
 		}
 
 		err = batch.ackFunc()
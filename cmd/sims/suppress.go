@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	kitmetrics "github.com/go-kit/kit/metrics"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/dedupe"
+	"github.com/tapglue/snaas/platform/limiter"
+	"github.com/tapglue/snaas/platform/metrics"
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/rule"
+)
+
+// Reasons for suppressed messages.
+const (
+	reasonDuplicate = "duplicate"
+	reasonThrottled = "throttled"
+)
+
+type duplicateFunc func(*app.App, *core.Message) (bool, error)
+
+type forgetFunc func(*app.App, *core.Message) error
+
+// channelThrottle caps the messages handed to the channel per recipient and
+// rule to limit within window, everything above is dropped. Every channel is
+// capped on its own.
+func channelThrottle(
+	l limiter.Limiter,
+	channel rule.Channel,
+	limit int64,
+	window time.Duration,
+	suppressCount kitmetrics.Counter,
+) func(channelFunc) channelFunc {
+	return func(next channelFunc) channelFunc {
+		return func(currentApp *app.App, msg *core.Message) error {
+			quota, _, err := l.Request(&limiter.Limitee{
+				Hash: fmt.Sprintf(
+					"%d:%d:%d:%s",
+					currentApp.ID,
+					msg.Recipient,
+					msg.RuleID,
+					channel,
+				),
+				Limit:      limit,
+				WindowSize: window,
+			})
+			if err != nil {
+				return err
+			}
+
+			if quota < 0 {
+				suppressCount.With(
+					metrics.FieldComponent, component,
+					metrics.FieldReason, reasonThrottled,
+				).Add(1)
+
+				return nil
+			}
+
+			return next(currentApp, msg)
+		}
+	}
+}
+
+// duplicate reports if an identical message for the same recipient and URN was
+// already seen within window.
+func duplicate(
+	d dedupe.Deduper,
+	window time.Duration,
+	suppressCount kitmetrics.Counter,
+) duplicateFunc {
+	return func(currentApp *app.App, msg *core.Message) (bool, error) {
+		seen, err := d.Seen(dedupeKey(currentApp, msg), window)
+		if err != nil {
+			return false, err
+		}
+
+		if seen {
+			suppressCount.With(
+				metrics.FieldComponent, component,
+				metrics.FieldReason, reasonDuplicate,
+			).Add(1)
+		}
+
+		return seen, nil
+	}
+}
+
+// forget removes the message from the seen ones, which has to happen when it
+// could not be delivered so its redelivery is not dropped as duplicate.
+func forget(d dedupe.Deduper) forgetFunc {
+	return func(currentApp *app.App, msg *core.Message) error {
+		return d.Forget(dedupeKey(currentApp, msg))
+	}
+}
+
+// dedupeKey identifies a message by its recipient, URN and rendered text.
+func dedupeKey(currentApp *app.App, msg *core.Message) string {
+	langs := []string{}
+
+	for lang := range msg.Messages {
+		langs = append(langs, lang)
+	}
+
+	sort.Strings(langs)
+
+	h := sha1.New()
+
+	fmt.Fprintf(h, "%d:%d:%s", currentApp.ID, msg.Recipient, msg.URN)
+
+	for _, lang := range langs {
+		io.WriteString(h, "\x00")
+		io.WriteString(h, lang)
+		io.WriteString(h, "\x00")
+		io.WriteString(h, msg.Messages[lang])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
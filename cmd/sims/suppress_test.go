@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/discard"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/limiter"
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/rule"
+)
+
+func TestChannelThrottle(t *testing.T) {
+	var (
+		currentApp = &app.App{ID: 1}
+		l          = &testLimiter{requests: map[string]int64{}}
+		sent       = map[rule.Channel]int{}
+	)
+
+	channel := func(c rule.Channel) channelFunc {
+		return channelThrottle(l, c, 1, time.Hour, discard.NewCounter())(
+			func(*app.App, *core.Message) error {
+				sent[c]++
+				return nil
+			},
+		)
+	}
+
+	var (
+		email = channel(rule.ChannelEmail)
+		push  = channel(rule.ChannelPush)
+	)
+
+	cases := []struct {
+		channel channelFunc
+		msg     *core.Message
+	}{
+		{email, &core.Message{Recipient: 1, RuleID: 1}},
+		{email, &core.Message{Recipient: 1, RuleID: 1}}, // Throttled
+		{email, &core.Message{Recipient: 2, RuleID: 1}}, // Other recipient
+		{email, &core.Message{Recipient: 1, RuleID: 2}}, // Other rule
+		{push, &core.Message{Recipient: 1, RuleID: 1}},  // Other channel
+		{push, &core.Message{Recipient: 1, RuleID: 1}},  // Throttled
+	}
+
+	for _, c := range cases {
+		if err := c.channel(currentApp, c.msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if have, want := sent[rule.ChannelEmail], 3; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := sent[rule.ChannelPush], 1; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+type testLimiter struct {
+	requests map[string]int64
+}
+
+func (l *testLimiter) Request(e *limiter.Limitee) (int64, time.Time, error) {
+	l.requests[e.Hash]++
+
+	return e.Limit - l.requests[e.Hash], time.Now().Add(e.WindowSize), nil
+}
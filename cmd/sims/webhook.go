@@ -0,0 +1,46 @@
+package main
+
+import (
+	"time"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+// webhookBatch is the maximum number of deliveries attempted per round.
+const webhookBatch = 50
+
+// webhookLock names the advisory lock which elects the instance delivering
+// webhooks.
+const webhookLock = "sims.webhook"
+
+// dispatchWebhooks continuously hands due deliveries to their webhooks and
+// waits for the given interval whenever there was nothing to deliver. Only the
+// instance holding the lock delivers, so a due delivery is never picked up
+// twice, others stand by to take over.
+func dispatchWebhooks(
+	lock *pg.Lock,
+	deliver core.WebhookDeliverFunc,
+	interval time.Duration,
+) error {
+	for {
+		leader, err := lock.Acquire()
+		if err != nil {
+			return err
+		}
+
+		if !leader {
+			time.Sleep(interval)
+			continue
+		}
+
+		n, err := deliver(webhookBatch)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			time.Sleep(interval)
+		}
+	}
+}
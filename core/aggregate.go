@@ -0,0 +1,369 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/aggregate"
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/user"
+)
+
+const defaultAggregateActors = 3
+
+// MessageAggregateFunc buffers the Message if its recipient is configured for
+// aggregation and reports if it did so. Messages which are not buffered have to
+// be delivered right away.
+type MessageAggregateFunc func(
+	currentApp *app.App,
+	msg *Message,
+	changeID string,
+	now time.Time,
+) (bool, error)
+
+// MessageAggregate buffers Messages of recipients which are configured for
+// aggregation until the window opened by the first Message for the same
+// recipient, rule and URN closes. Buffered Messages are stored, so the state
+// change they originate from can be acked right away. A state change which is
+// redelivered with the same changeID is only buffered once.
+func MessageAggregate(aggregates aggregate.Service) MessageAggregateFunc {
+	return func(
+		currentApp *app.App,
+		msg *Message,
+		changeID string,
+		now time.Time,
+	) (bool, error) {
+		if msg.aggregate == nil {
+			return false, nil
+		}
+
+		key := aggregateKey(msg)
+
+		es, err := aggregates.Query(pg.MetaNamespace, aggregate.QueryOptions{
+			AppIDs: []uint64{
+				currentApp.ID,
+			},
+			Keys: []string{
+				key,
+			},
+			Limit: 1,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		dueAt := now.Add(time.Duration(msg.aggregate.Window) * time.Second)
+
+		if len(es) > 0 {
+			dueAt = es[0].DueAt
+		}
+
+		raw, err := marshalAggregate(msg)
+		if err != nil {
+			return false, err
+		}
+
+		_, err = aggregates.Put(pg.MetaNamespace, &aggregate.Entry{
+			AppID:    currentApp.ID,
+			ChangeID: changeID,
+			DueAt:    dueAt,
+			Key:      key,
+			Message:  raw,
+		})
+		if err != nil && !aggregate.IsExists(err) {
+			return false, err
+		}
+
+		return true, nil
+	}
+}
+
+// MessageFlushFunc delivers the collapsed Messages of aggregation windows which
+// closed before now and returns how many were flushed.
+type MessageFlushFunc func(
+	now time.Time,
+	limit int,
+	deliver MessageDeliverFunc,
+) (int, error)
+
+// MessageFlush collapses the buffered Messages of every window which closed
+// before now into one and delivers it. Buffered Messages are only removed
+// after delivery, so they survive restarts.
+func MessageFlush(
+	apps app.Service,
+	aggregates aggregate.Service,
+) MessageFlushFunc {
+	return func(
+		now time.Time,
+		limit int,
+		deliver MessageDeliverFunc,
+	) (int, error) {
+		due, err := aggregates.Query(pg.MetaNamespace, aggregate.QueryOptions{
+			Due:   now,
+			Limit: limit,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		flushed := map[string]struct{}{}
+
+		for _, d := range due {
+			window := fmt.Sprintf("%d:%s", d.AppID, d.Key)
+
+			if _, ok := flushed[window]; ok {
+				continue
+			}
+
+			flushed[window] = struct{}{}
+
+			es, err := aggregates.Query(pg.MetaNamespace, aggregate.QueryOptions{
+				AppIDs: []uint64{
+					d.AppID,
+				},
+				Keys: []string{
+					d.Key,
+				},
+			})
+			if err != nil {
+				return 0, err
+			}
+
+			currentApp, err := AppFetch(apps)(d.AppID)
+			if err != nil && !IsNotFound(err) {
+				return 0, err
+			}
+
+			if currentApp != nil {
+				ms := Messages{}
+
+				for _, e := range es {
+					msg, err := unmarshalAggregate(e.Message)
+					if err != nil {
+						return 0, err
+					}
+
+					ms = append(ms, msg)
+				}
+
+				msg, err := collapseMessages(ms)
+				if err != nil {
+					return 0, err
+				}
+
+				if err := deliver(currentApp, msg); err != nil {
+					return 0, err
+				}
+			}
+
+			for _, e := range es {
+				if err := aggregates.Delete(pg.MetaNamespace, e.ID); err != nil {
+					return 0, err
+				}
+			}
+		}
+
+		return len(flushed), nil
+	}
+}
+
+// aggregateMessage is the stored form of a buffered Message, next to the
+// Message it keeps what the aggregate templates are rendered with.
+type aggregateMessage struct {
+	Actor     *user.User      `json:"actor"`
+	Aggregate *rule.Aggregate `json:"aggregate"`
+	Context   json.RawMessage `json:"context"`
+	Message   *Message        `json:"message"`
+}
+
+// contextAggregate is exposed to the templates of an aggregated Message.
+type contextAggregate struct {
+	// Actors are the first distinct users which caused a Message.
+	Actors []*user.User
+	// Context is the context of the latest Message.
+	Context interface{}
+	// Count is the number of collapsed Messages.
+	Count int
+	// Others is the number of distinct actors not part of Actors.
+	Others int
+}
+
+// aggregateKey groups the Messages collapsed into one.
+func aggregateKey(msg *Message) string {
+	return fmt.Sprintf("%d:%d:%s", msg.Recipient, msg.RuleID, msg.URN)
+}
+
+func collapseMessages(ms Messages) (*Message, error) {
+	var (
+		first = ms[0]
+		last  = ms[len(ms)-1]
+		limit = first.aggregate.Actors
+	)
+
+	if len(ms) == 1 {
+		return first, nil
+	}
+
+	if limit <= 0 {
+		limit = defaultAggregateActors
+	}
+
+	var (
+		actors = []*user.User{}
+		seen   = map[uint64]struct{}{}
+	)
+
+	for _, msg := range ms {
+		if msg.actor == nil {
+			continue
+		}
+
+		if _, ok := seen[msg.actor.ID]; ok {
+			continue
+		}
+
+		seen[msg.actor.ID] = struct{}{}
+		actors = append(actors, msg.actor)
+	}
+
+	context := &contextAggregate{
+		Context: last.context,
+		Count:   len(ms),
+	}
+
+	if len(actors) > limit {
+		context.Actors = actors[:limit]
+		context.Others = len(actors) - limit
+	} else {
+		context.Actors = actors
+	}
+
+	msgs, err := compileTemplates(context, first.aggregate.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields which differ between the collapsed Messages are taken from the
+	// latest, a variant is picked per rule and recipient and the same for all.
+	return &Message{
+		ActorID:   last.ActorID,
+		Bodies:    last.Bodies,
+		Channels:  first.Channels,
+		Messages:  msgs,
+		ObjectID:  first.ObjectID,
+		Push:      last.Push,
+		Recipient: first.Recipient,
+		RuleID:    first.RuleID,
+		RuleType:  first.RuleType,
+		Subjects:  last.Subjects,
+		URN:       first.URN,
+		Urgent:    first.Urgent,
+		VariantID: first.VariantID,
+	}, nil
+}
+
+// aggregateContext returns the zero value of the context the pipeline of the
+// rule type renders Messages with.
+func aggregateContext(t rule.Type) (interface{}, error) {
+	switch t {
+	case rule.TypeConnection:
+		return &contextConnection{}, nil
+	case rule.TypeEvent:
+		return &contextEvent{}, nil
+	case rule.TypeObject:
+		return &contextObject{}, nil
+	case rule.TypeReaction:
+		return &contextReaction{}, nil
+	case rule.TypeUser:
+		return &contextUser{}, nil
+	}
+
+	return nil, fmt.Errorf("aggregate: rule type %d not supported", t)
+}
+
+func marshalAggregate(msg *Message) ([]byte, error) {
+	context, err := json.Marshal(msg.context)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&aggregateMessage{
+		Actor:     msg.actor,
+		Aggregate: msg.aggregate,
+		Context:   context,
+		Message:   msg,
+	})
+}
+
+func unmarshalAggregate(raw []byte) (*Message, error) {
+	a := &aggregateMessage{}
+
+	if err := json.Unmarshal(raw, a); err != nil {
+		return nil, err
+	}
+
+	context, err := aggregateContext(a.Message.RuleType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(a.Context, context); err != nil {
+		return nil, err
+	}
+
+	msg := a.Message
+	msg.actor = a.Actor
+	msg.aggregate = a.Aggregate
+	msg.context = context
+
+	return msg, nil
+}
+
+// contextObjectID determines the object thread the Message is about.
+func contextObjectID(context interface{}) uint64 {
+	switch c := context.(type) {
+	case *contextEvent:
+		if c.Parent != nil {
+			return c.Parent.ID
+		}
+	case *contextObject:
+		if c.Parent != nil {
+			return c.Parent.ID
+		}
+
+		if c.Object != nil {
+			return c.Object.ID
+		}
+	case *contextReaction:
+		if c.Parent != nil {
+			return c.Parent.ID
+		}
+	}
+
+	return 0
+}
+
+// contextActor determines the user which caused the Message for target.
+func contextActor(context interface{}, target *user.User) *user.User {
+	switch c := context.(type) {
+	case *contextConnection:
+		if c.From != nil && c.From.ID == target.ID {
+			return c.To
+		}
+
+		return c.From
+	case *contextEvent:
+		return c.Owner
+	case *contextObject:
+		return c.Owner
+	case *contextReaction:
+		return c.Owner
+	case *contextUser:
+		return c.User
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tapglue/snaas/service/aggregate"
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/reaction"
+	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/user"
+)
+
+func TestMessageAggregate(t *testing.T) {
+	var (
+		aggregates  = aggregate.MemService()
+		currentApp  = testApp()
+		apps        = &testApps{apps: app.List{currentApp}}
+		buffer      = MessageAggregate(aggregates)
+		flush       = MessageFlush(apps, aggregates)
+		now         = time.Now().UTC()
+		owner       = &user.User{ID: 1, Username: "owner"}
+		post        = &object.Object{ID: 123, OwnerID: owner.ID}
+		currentRule = &rule.Rule{
+			ID:   42,
+			Type: rule.TypeReaction,
+		}
+		recipient = rule.Recipient{
+			Aggregate: &rule.Aggregate{
+				Actors: 1,
+				Templates: map[string]string{
+					"en": "{{(index .Actors 0).Username}} and {{.Others}} others reacted to your post ({{.Count}})",
+				},
+				Window: 60,
+			},
+			Templates: map[string]string{
+				"en": "{{.Owner.Username}} reacted to your post",
+			},
+			URN: "tapglue/posts/{{.Parent.ID}}",
+		}
+	)
+
+	var (
+		anna = &user.User{ID: 2, Username: "anna"}
+		bob  = &user.User{ID: 3, Username: "bob"}
+		carl = &user.User{ID: 4, Username: "carl"}
+	)
+
+	for i, actor := range []*user.User{anna, bob, anna, carl} {
+		msg, err := compileMessage(&contextReaction{
+			Owner:       actor,
+			Parent:      post,
+			ParentOwner: owner,
+			Reaction:    &reaction.Reaction{ObjectID: post.ID, OwnerID: actor.ID},
+		}, currentRule, recipient, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buffered, err := buffer(currentApp, msg, fmt.Sprintf("change-%d", i), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !buffered {
+			t.Fatal("expected message to be buffered")
+		}
+	}
+
+	delivered := Messages{}
+	deliver := func(a *app.App, msg *Message) error {
+		if have, want := a.ID, currentApp.ID; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+
+		delivered = append(delivered, msg)
+
+		return nil
+	}
+
+	n, err := flush(now.Add(30*time.Second), 10, deliver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := n, 0; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	n, err = flush(now.Add(time.Minute), 10, deliver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := n, 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := len(delivered), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	want := &Message{
+		ActorID: carl.ID,
+		Messages: map[string]string{
+			"en": "anna and 2 others reacted to your post (4)",
+		},
+		ObjectID:  post.ID,
+		Recipient: owner.ID,
+		RuleID:    currentRule.ID,
+		RuleType:  currentRule.Type,
+		URN:       "tapglue/posts/123",
+	}
+
+	if have := delivered[0]; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	n, err = flush(now.Add(2*time.Minute), 10, deliver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := n, 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestMessageAggregateRedelivery(t *testing.T) {
+	var (
+		aggregates = aggregate.MemService()
+		currentApp = testApp()
+		apps       = &testApps{apps: app.List{currentApp}}
+		buffer     = MessageAggregate(aggregates)
+		flush      = MessageFlush(apps, aggregates)
+		now        = time.Now().UTC()
+		owner      = &user.User{ID: 1}
+		recipient  = rule.Recipient{
+			Aggregate: &rule.Aggregate{
+				Templates: map[string]string{
+					"en": "{{.Count}} new followers",
+				},
+				Window: 60,
+			},
+			Templates: map[string]string{
+				"en": "{{.From.Username}} started following you",
+			},
+		}
+	)
+
+	cases := []struct {
+		actor    *user.User
+		changeID string
+		offset   time.Duration
+	}{
+		{&user.User{ID: 2, Username: "anna"}, "change-1", 0},
+		{&user.User{ID: 3, Username: "bob"}, "change-2", 10 * time.Second},
+		{&user.User{ID: 2, Username: "anna"}, "change-1", 40 * time.Second}, // Redelivered before the window closed
+	}
+
+	for _, c := range cases {
+		msg, err := compileMessage(&contextConnection{
+			From: c.actor,
+			To:   owner,
+		}, &rule.Rule{ID: 1, Type: rule.TypeConnection}, recipient, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buffered, err := buffer(currentApp, msg, c.changeID, now.Add(c.offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !buffered {
+			t.Fatal("expected message to be buffered")
+		}
+	}
+
+	delivered := Messages{}
+
+	n, err := flush(now.Add(time.Minute), 10, func(a *app.App, msg *Message) error {
+		delivered = append(delivered, msg)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := n, 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := delivered[0].Messages["en"], "2 new followers"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestMessageAggregateSingle(t *testing.T) {
+	var (
+		aggregates = aggregate.MemService()
+		currentApp = testApp()
+		apps       = &testApps{apps: app.List{currentApp}}
+		buffer     = MessageAggregate(aggregates)
+		flush      = MessageFlush(apps, aggregates)
+		now        = time.Now().UTC()
+		owner      = &user.User{ID: 1}
+		recipient  = rule.Recipient{
+			Aggregate: &rule.Aggregate{
+				Templates: map[string]string{
+					"en": "{{.Count}} new followers",
+				},
+				Window: 60,
+			},
+			Templates: map[string]string{
+				"en": "{{.From.Username}} started following you",
+			},
+		}
+	)
+
+	msg, err := compileMessage(&contextConnection{
+		From: &user.User{ID: 2, Username: "anna"},
+		To:   owner,
+	}, &rule.Rule{ID: 1, Type: rule.TypeConnection}, recipient, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := msg.actor.Username, "anna"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	_, err = buffer(currentApp, msg, "change-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivered := Messages{}
+
+	_, err = flush(now.Add(time.Minute), 10, func(a *app.App, msg *Message) error {
+		delivered = append(delivered, msg)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(delivered), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := delivered[0].Messages["en"], "anna started following you"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	buffered, err := buffer(currentApp, &Message{}, "change-2", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buffered {
+		t.Error("expected message without aggregation to pass through")
+	}
+}
+
+type testApps struct {
+	app.Service
+
+	apps app.List
+}
+
+func (s *testApps) Query(ns string, opts app.QueryOptions) (app.List, error) {
+	as := app.List{}
+
+	for _, a := range s.apps {
+		for _, id := range opts.IDs {
+			if a.ID == id {
+				as = append(as, a)
+			}
+		}
+	}
+
+	return as, nil
+}
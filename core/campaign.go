@@ -0,0 +1,541 @@
+package core
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/campaign"
+	"github.com/tapglue/snaas/service/device"
+	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/segment"
+	"github.com/tapglue/snaas/service/user"
+)
+
+// campaignPage is the number of devices looked at per query when collecting
+// the recipients of a Campaign.
+const campaignPage = 500
+
+// categoryCampaign is the notification category of Campaign Messages.
+const categoryCampaign = "campaign"
+
+// CampaignCancelFunc stops the Campaign from sending to further recipients.
+type CampaignCancelFunc func(appID, id uint64) (*campaign.Campaign, error)
+
+// CampaignCancel stops the Campaign from sending to further recipients. Only
+// scheduled Campaigns or those in the middle of sending can be canceled, the
+// recipients of the batch in flight are still messaged.
+func CampaignCancel(
+	apps app.Service,
+	campaigns campaign.Service,
+) CampaignCancelFunc {
+	return func(appID, id uint64) (*campaign.Campaign, error) {
+		c, err := CampaignFetch(apps, campaigns)(appID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.Final() {
+			return nil, wrapError(ErrInvalidEntity, "campaign is %s", c.State)
+		}
+
+		c.State = campaign.StateCanceled
+
+		c, err = campaigns.Put(pg.MetaNamespace, c)
+		if err != nil {
+			if campaign.IsFinal(err) {
+				return nil, wrapError(ErrInvalidEntity, "%s", err)
+			}
+
+			return nil, err
+		}
+
+		return c, nil
+	}
+}
+
+// CampaignCreateFunc schedules a new Campaign for the App.
+type CampaignCreateFunc func(
+	appID uint64,
+	c *campaign.Campaign,
+) (*campaign.Campaign, error)
+
+// CampaignCreate schedules a new Campaign for the App, without a send time it
+// goes out right away.
+func CampaignCreate(
+	apps app.Service,
+	campaigns campaign.Service,
+) CampaignCreateFunc {
+	return func(appID uint64, c *campaign.Campaign) (*campaign.Campaign, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		c.AppID = currentApp.ID
+		c.ID = 0
+		c.Progress = campaign.Progress{}
+		c.State = campaign.StateScheduled
+
+		if c.SendAt.IsZero() {
+			c.SendAt = time.Now().UTC()
+		}
+
+		if err := c.Validate(); err != nil {
+			return nil, wrapError(ErrInvalidEntity, "%s", err)
+		}
+
+		return campaigns.Put(pg.MetaNamespace, c)
+	}
+}
+
+// CampaignDispatchFunc hands the next recipients of due Campaigns to deliver
+// and returns how many were handed on.
+type CampaignDispatchFunc func(limit int, deliver MessageDeliverFunc) (int, error)
+
+// CampaignDispatch hands the next recipients of due Campaigns to deliver, up
+// to limit in total. Recipients are users with an active device matching the
+// Audience, processed in order of their id. Audiences with a segment are
+// drawn from its members, refreshed when the Campaign starts sending. Every
+// batch is recorded in the Progress before it is delivered, so a Campaign
+// canceled in the meantime stops and a restart never messages a recipient
+// twice.
+func CampaignDispatch(
+	apps app.Service,
+	campaigns campaign.Service,
+	devices device.Service,
+	segments segment.Service,
+	users user.Service,
+) CampaignDispatchFunc {
+	return func(limit int, deliver MessageDeliverFunc) (int, error) {
+		cs, err := campaigns.Query(pg.MetaNamespace, campaign.QueryOptions{
+			Due: time.Now().UTC(),
+			States: []campaign.State{
+				campaign.StateScheduled,
+				campaign.StateSending,
+			},
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		sent := 0
+
+		for _, c := range cs {
+			if sent >= limit {
+				break
+			}
+
+			n, err := campaignSend(
+				apps,
+				campaigns,
+				devices,
+				segments,
+				users,
+				c,
+				limit-sent,
+				deliver,
+			)
+			if err != nil {
+				return sent, err
+			}
+
+			sent += n
+		}
+
+		return sent, nil
+	}
+}
+
+// CampaignFetchFunc returns the Campaign for the given id.
+type CampaignFetchFunc func(appID, id uint64) (*campaign.Campaign, error)
+
+// CampaignFetch returns the Campaign for the given id.
+func CampaignFetch(
+	apps app.Service,
+	campaigns campaign.Service,
+) CampaignFetchFunc {
+	return func(appID, id uint64) (*campaign.Campaign, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		cs, err := campaigns.Query(pg.MetaNamespace, campaign.QueryOptions{
+			AppIDs: []uint64{
+				currentApp.ID,
+			},
+			IDs: []uint64{
+				id,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(cs) != 1 {
+			return nil, wrapError(ErrNotFound, "campaign (%d) not found", id)
+		}
+
+		return cs[0], nil
+	}
+}
+
+// CampaignListFunc returns the Campaigns of the App narrowed down by opts.
+type CampaignListFunc func(
+	appID uint64,
+	opts campaign.QueryOptions,
+) (campaign.List, error)
+
+// CampaignList returns the Campaigns of the App narrowed down by opts, the app
+// constraint can't be overridden.
+func CampaignList(
+	apps app.Service,
+	campaigns campaign.Service,
+) CampaignListFunc {
+	return func(appID uint64, opts campaign.QueryOptions) (campaign.List, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.AppIDs = []uint64{
+			currentApp.ID,
+		}
+
+		return campaigns.Query(pg.MetaNamespace, opts)
+	}
+}
+
+// campaignSend delivers the next batch of up to limit recipients of the
+// Campaign and returns its size. The audience is counted once when sending
+// starts.
+func campaignSend(
+	apps app.Service,
+	campaigns campaign.Service,
+	devices device.Service,
+	segments segment.Service,
+	users user.Service,
+	c *campaign.Campaign,
+	limit int,
+	deliver MessageDeliverFunc,
+) (int, error) {
+	currentApp, err := AppFetch(apps)(c.AppID)
+	if err != nil {
+		if !IsNotFound(err) {
+			return 0, err
+		}
+
+		c.State = campaign.StateCanceled
+
+		_, err := campaigns.Put(pg.MetaNamespace, c)
+		if err != nil && !campaign.IsFinal(err) {
+			return 0, err
+		}
+
+		return 0, nil
+	}
+
+	if c.State == campaign.StateScheduled {
+		if err := campaignSegmentRefresh(segments, currentApp, c.Audience); err != nil {
+			return 0, err
+		}
+
+		ids, _, err := campaignRecipients(
+			devices,
+			segments,
+			currentApp,
+			c.Audience,
+			0,
+			math.MaxInt32,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		c.Progress.Total = len(ids)
+		c.State = campaign.StateSending
+	}
+
+	ids, done, err := campaignRecipients(
+		devices,
+		segments,
+		currentApp,
+		c.Audience,
+		c.Progress.Cursor,
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	us, err := user.ListFromIDs(users, currentApp.Namespace(), ids...)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(ids) > 0 {
+		c.Progress.Cursor = ids[len(ids)-1]
+	}
+
+	c.Progress.Sent += len(us)
+
+	if done {
+		c.State = campaign.StateDone
+	}
+
+	// Claim the batch before delivery, a Campaign which became final since it
+	// was read is left alone.
+	_, err = campaigns.Put(pg.MetaNamespace, c)
+	if err != nil {
+		if campaign.IsFinal(err) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	for _, u := range us {
+		msg, err := campaignMessage(c, u)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := deliver(currentApp, msg); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), nil
+}
+
+// campaignMessage renders the templates of the Campaign for the recipient.
+func campaignMessage(c *campaign.Campaign, u *user.User) (*Message, error) {
+	context := &contextUser{User: u}
+
+	urn, err := compileTemplate(context, c.URN)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := compileTemplates(context, c.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := compilePush(context, c.Push)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		CampaignID: c.ID,
+		Channels: rule.Channels{
+			rule.ChannelPush,
+		},
+		Messages:  msgs,
+		Push:      p,
+		Recipient: u.ID,
+		URN:       urn,
+	}, nil
+}
+
+// campaignRecipients returns up to limit ids of users in the Audience, in
+// ascending order and after the given one. Devices are paged by user and a
+// full page is trimmed to its last complete user, so every user is judged by
+// all of their devices. done reports if the Audience is exhausted.
+func campaignRecipients(
+	devices device.Service,
+	segments segment.Service,
+	currentApp *app.App,
+	a campaign.Audience,
+	after uint64,
+	limit int,
+) ([]uint64, bool, error) {
+	if a.SegmentID != 0 {
+		return campaignSegmentRecipients(
+			devices,
+			segments,
+			currentApp,
+			a,
+			after,
+			limit,
+		)
+	}
+
+	ids := []uint64{}
+
+	for {
+		ds, err := devices.Query(currentApp.Namespace(), device.QueryOptions{
+			Deleted:     &defaultDeleted,
+			Disabled:    &defaultDeleted,
+			Limit:       campaignPage,
+			Platforms:   a.Platforms,
+			UserIDAfter: after,
+			UserIDs:     a.UserIDs,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		full := len(ds) == campaignPage
+
+		if full && ds[0].UserID != ds[len(ds)-1].UserID {
+			last := ds[len(ds)-1].UserID
+
+			for len(ds) > 0 && ds[len(ds)-1].UserID == last {
+				ds = ds[:len(ds)-1]
+			}
+		}
+
+		for _, d := range ds {
+			after = d.UserID
+
+			if len(ids) > 0 && ids[len(ids)-1] == d.UserID {
+				continue
+			}
+
+			if !audienceLanguage(a.Languages, d.Language) {
+				continue
+			}
+
+			ids = append(ids, d.UserID)
+
+			if len(ids) == limit {
+				return ids, false, nil
+			}
+		}
+
+		if !full {
+			return ids, true, nil
+		}
+	}
+}
+
+// campaignSegmentRefresh brings the members of the segment targeted by the
+// Audience up to date, so a Campaign reaches the segment as of its start.
+func campaignSegmentRefresh(
+	segments segment.Service,
+	currentApp *app.App,
+	a campaign.Audience,
+) error {
+	if a.SegmentID == 0 {
+		return nil
+	}
+
+	ss, err := segments.Query(currentApp.Namespace(), segment.QueryOptions{
+		IDs: []uint64{
+			a.SegmentID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(ss) != 1 {
+		return nil
+	}
+
+	_, err = segments.Refresh(currentApp.Namespace(), ss[0])
+
+	return err
+}
+
+// campaignSegmentRecipients returns up to limit ids of segment members in the
+// Audience, in ascending order and after the given one. Members are paged and
+// only kept if they have a device matching the Audience.
+func campaignSegmentRecipients(
+	devices device.Service,
+	segments segment.Service,
+	currentApp *app.App,
+	a campaign.Audience,
+	after uint64,
+	limit int,
+) ([]uint64, bool, error) {
+	ids := []uint64{}
+
+	for {
+		members, err := segments.Members(
+			currentApp.Namespace(),
+			a.SegmentID,
+			segment.MemberOptions{
+				After: after,
+				Limit: campaignPage,
+			},
+		)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(members) == 0 {
+			return ids, true, nil
+		}
+
+		ds, err := devices.Query(currentApp.Namespace(), device.QueryOptions{
+			Deleted:   &defaultDeleted,
+			Disabled:  &defaultDeleted,
+			Platforms: a.Platforms,
+			UserIDs:   members,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		matched := map[uint64]struct{}{}
+
+		for _, d := range ds {
+			if audienceLanguage(a.Languages, d.Language) {
+				matched[d.UserID] = struct{}{}
+			}
+		}
+
+		for _, id := range members {
+			after = id
+
+			if _, ok := matched[id]; !ok {
+				continue
+			}
+
+			ids = append(ids, id)
+
+			if len(ids) == limit {
+				return ids, false, nil
+			}
+		}
+
+		if len(members) < campaignPage {
+			return ids, true, nil
+		}
+	}
+}
+
+// audienceLanguage reports if the device language lang has one of the base
+// languages of langs, without langs every language matches.
+func audienceLanguage(langs []string, lang string) bool {
+	if len(langs) == 0 {
+		return true
+	}
+
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return false
+	}
+
+	base, _ := tag.Base()
+
+	for _, l := range langs {
+		t, err := language.Parse(l)
+		if err != nil {
+			continue
+		}
+
+		if b, _ := t.Base(); b == base {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/tapglue/snaas/service/campaign"
+	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/user"
+)
+
+func TestAudienceLanguage(t *testing.T) {
+	cases := []struct {
+		langs []string
+		lang  string
+		want  bool
+	}{
+		{nil, "de-AT", true},
+		{[]string{"de"}, "de-AT", true},
+		{[]string{"de-DE"}, "de-AT", true},
+		{[]string{"en", "fr"}, "de", false},
+		{[]string{"de"}, "invalid_language", false},
+	}
+
+	for _, c := range cases {
+		if have, want := audienceLanguage(c.langs, c.lang), c.want; have != want {
+			t.Errorf("%v %s: have %v, want %v", c.langs, c.lang, have, want)
+		}
+	}
+}
+
+func TestCampaignMessage(t *testing.T) {
+	c := &campaign.Campaign{
+		ID: 123,
+		Templates: rule.Templates{
+			"en": "Hello {{.User.Username}}",
+		},
+		URN: "users/{{.User.ID}}",
+	}
+
+	msg, err := campaignMessage(c, &user.User{ID: 321, Username: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := msg.Messages["en"], "Hello alice"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := msg.URN, "users/321"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := msg.Category(), categoryCampaign; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := msg.Push, (*MessagePush)(nil); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
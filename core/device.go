@@ -1,12 +1,19 @@
 package core
 
 import (
+	"time"
+
 	serr "github.com/tapglue/snaas/error"
+	"github.com/tapglue/snaas/platform/quiet"
 	"github.com/tapglue/snaas/platform/sns"
 	"github.com/tapglue/snaas/service/app"
 	"github.com/tapglue/snaas/service/device"
+	"github.com/tapglue/snaas/service/platform"
 )
 
+// hygienePage is the number of devices cleaned up per query.
+const hygienePage = 500
+
 var defaultDeleted = false
 
 // DeviceDeleteFunc removes the device of a user.
@@ -74,6 +81,249 @@ func DeviceDisable(devices device.Service) DeviceDisableFunc {
 	}
 }
 
+// DeviceDisableTokenFunc disables all devices with the given token, used when
+// a direct push provider reports the token as no longer valid.
+type DeviceDisableTokenFunc func(currentApp *app.App, token string) error
+
+// DeviceDisableToken disables all devices with the given token, used when a
+// direct push provider reports the token as no longer valid.
+func DeviceDisableToken(devices device.Service) DeviceDisableTokenFunc {
+	return func(currentApp *app.App, token string) error {
+		ds, err := devices.Query(currentApp.Namespace(), device.QueryOptions{
+			Deleted:  &defaultDeleted,
+			Disabled: &defaultDeleted,
+			Tokens: []string{
+				token,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, d := range ds {
+			d.Disabled = true
+
+			if _, err := devices.Put(currentApp.Namespace(), d); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// DeviceHygieneReport sums up the changes a hygiene run made to the devices of
+// an app.
+type DeviceHygieneReport struct {
+	// Devices whose SNS endpoint was found disabled.
+	Disabled int
+	// Devices removed after being disabled for longer than the retention.
+	Pruned int
+	// Devices which lost their token to a more recent owner.
+	Reassigned int
+	// Devices whose SNS endpoint was recreated or got its token updated.
+	Resynced int
+}
+
+// DeviceHygieneFunc cleans up the devices of the app, devices disabled before
+// pruneBefore are removed.
+type DeviceHygieneFunc func(
+	currentApp *app.App,
+	pruneBefore time.Time,
+) (*DeviceHygieneReport, error)
+
+// DeviceHygiene cleans up the devices of the app. A token registered for more
+// than one device stays with the most recently updated one while the others
+// are deleted. SNS endpoints which drifted from their device are synced again
+// and devices disabled before pruneBefore are removed for good. Devices are
+// looked at page by page.
+func DeviceHygiene(
+	devices device.Service,
+	platforms platform.Service,
+	endpointCreate sns.EndpointCreateFunc,
+	endpointRetrieve sns.EndpointRetrieveFunc,
+	endpointUpdate sns.EndpointUpdateFunc,
+) DeviceHygieneFunc {
+	return func(
+		currentApp *app.App,
+		pruneBefore time.Time,
+	) (*DeviceHygieneReport, error) {
+		var (
+			disabled = true
+			report   = &DeviceHygieneReport{}
+			sync     = DeviceSyncEndpoint(
+				devices,
+				func(platformARN, token string) (*sns.Endpoint, error) {
+					e, err := endpointCreate(platformARN, token)
+					if err == nil {
+						report.Resynced++
+					}
+
+					return e, err
+				},
+				endpointRetrieve,
+				func(arn, token string) (*sns.Endpoint, error) {
+					e, err := endpointUpdate(arn, token)
+					if err == nil {
+						report.Resynced++
+					}
+
+					return e, err
+				},
+			)
+		)
+
+		var (
+			after uint64
+			ps    = map[sns.Platform]*platform.Platform{}
+		)
+
+		for {
+			ds, err := devices.Query(currentApp.Namespace(), device.QueryOptions{
+				Deleted:     &defaultDeleted,
+				Limit:       hygienePage,
+				UserIDAfter: after,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			if len(ds) == 0 {
+				break
+			}
+
+			// Devices of the last user are looked at with the next page in case
+			// the limit cut them off.
+			if len(ds) == hygienePage && ds[0].UserID != ds[len(ds)-1].UserID {
+				last := ds[len(ds)-1].UserID
+
+				for ds[len(ds)-1].UserID == last {
+					ds = ds[:len(ds)-1]
+				}
+			}
+
+			after = ds[len(ds)-1].UserID
+
+			latest, err := deviceLatest(devices, currentApp, ds)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, d := range ds {
+				if latest[d.Token] != d.ID {
+					d.Deleted = true
+
+					if _, err := devices.Put(currentApp.Namespace(), d); err != nil {
+						return nil, err
+					}
+
+					report.Reassigned++
+
+					continue
+				}
+
+				// Endpoints are only created on first delivery.
+				if d.Disabled || d.EndpointARN == "" {
+					continue
+				}
+
+				p, ok := ps[d.Platform]
+				if !ok {
+					p, err = PlatformFetchActive(platforms)(currentApp, d.Platform)
+					if err != nil && !serr.IsNotFound(err) {
+						return nil, err
+					}
+
+					ps[d.Platform] = p
+				}
+
+				if p == nil || p.Direct() {
+					continue
+				}
+
+				_, err := sync(currentApp, p.ARN, d)
+				if err != nil {
+					if serr.IsDeviceDisabled(err) {
+						report.Disabled++
+						continue
+					}
+
+					return nil, err
+				}
+			}
+
+			if len(ds) < hygienePage {
+				break
+			}
+		}
+
+		// Pruned devices are gone for good, every query returns the next page.
+		for {
+			ds, err := devices.Query(currentApp.Namespace(), device.QueryOptions{
+				Disabled:      &disabled,
+				Limit:         hygienePage,
+				UpdatedBefore: pruneBefore,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, d := range ds {
+				if err := devices.Delete(currentApp.Namespace(), d.ID); err != nil {
+					return nil, err
+				}
+
+				report.Pruned++
+			}
+
+			if len(ds) < hygienePage {
+				break
+			}
+		}
+
+		return report, nil
+	}
+}
+
+// deviceLatest returns the id of the most recently updated device for every
+// token of the given devices, owners of a token can be on any page.
+func deviceLatest(
+	devices device.Service,
+	currentApp *app.App,
+	ds device.List,
+) (map[string]uint64, error) {
+	tokens := []string{}
+
+	for _, d := range ds {
+		tokens = append(tokens, d.Token)
+	}
+
+	owners, err := devices.Query(currentApp.Namespace(), device.QueryOptions{
+		Deleted: &defaultDeleted,
+		Tokens:  tokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ids    = map[string]uint64{}
+		latest = map[string]*device.Device{}
+	)
+
+	for _, d := range owners {
+		l, ok := latest[d.Token]
+		if !ok ||
+			d.UpdatedAt.After(l.UpdatedAt) ||
+			(d.UpdatedAt.Equal(l.UpdatedAt) && d.ID > l.ID) {
+			latest[d.Token] = d
+			ids[d.Token] = d.ID
+		}
+	}
+
+	return ids, nil
+}
+
 // DeviceListUserFunc returns all devices for origin.
 type DeviceListUserFunc func(
 	currentApp *app.App,
@@ -90,6 +340,7 @@ func DeviceListUser(devices device.Service) DeviceListUserFunc {
 				device.PlatformIOSSandbox,
 				device.PlatformIOS,
 				device.PlatformAndroid,
+				device.PlatformWeb,
 			},
 			UserIDs: []uint64{
 				origin,
@@ -178,14 +429,19 @@ func DeviceSyncEndpoint(
 	}
 }
 
-// DeviceUpdateFunc stores the device data and updates the endpoint.
+// DeviceUpdateFunc stores the device data and updates the endpoint. Keys are
+// only given for web devices, quietHours only for devices with their own
+// window.
 type DeviceUpdateFunc func(
 	currentApp *app.App,
 	origin Origin,
 	deviceID string,
 	platform sns.Platform,
 	token string,
+	keys *device.Keys,
 	language string,
+	timezone string,
+	quietHours *quiet.Hours,
 ) error
 
 // DeviceUpdate stores the device info in the given device service.
@@ -196,7 +452,10 @@ func DeviceUpdate(devices device.Service) DeviceUpdateFunc {
 		deviceID string,
 		platform sns.Platform,
 		token string,
+		keys *device.Keys,
 		language string,
+		timezone string,
+		quietHours *quiet.Hours,
 	) error {
 		// Get user devices.
 		ds, err := devices.Query(currentApp.Namespace(), device.QueryOptions{
@@ -231,8 +490,11 @@ func DeviceUpdate(devices device.Service) DeviceUpdateFunc {
 		for _, dev := range ds {
 			if dev.DeviceID == deviceID &&
 				dev.Token == token &&
+				keysEqual(dev.Keys, keys) &&
 				dev.Language == language &&
 				dev.Platform == platform &&
+				quietHoursEqual(dev.QuietHours, quietHours) &&
+				dev.Timezone == timezone &&
 				dev.UserID == origin.UserID {
 				return nil
 			}
@@ -243,12 +505,15 @@ func DeviceUpdate(devices device.Service) DeviceUpdateFunc {
 		}
 
 		d := &device.Device{
-			DeviceID: deviceID,
-			Disabled: false,
-			Language: language,
-			Platform: platform,
-			Token:    token,
-			UserID:   origin.UserID,
+			DeviceID:   deviceID,
+			Disabled:   false,
+			Keys:       keys,
+			Language:   language,
+			Platform:   platform,
+			QuietHours: quietHours,
+			Timezone:   timezone,
+			Token:      token,
+			UserID:     origin.UserID,
 		}
 
 		if old != nil {
@@ -266,3 +531,19 @@ func DeviceUpdate(devices device.Service) DeviceUpdateFunc {
 		return err
 	}
 }
+
+func keysEqual(a, b *device.Keys) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func quietHoursEqual(a, b *quiet.Hours) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/quiet"
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/device"
+	"github.com/tapglue/snaas/service/hold"
+	"github.com/tapglue/snaas/service/user"
+)
+
+// MessageDeliverFunc hands the Message to the channels of its recipient.
+type MessageDeliverFunc func(currentApp *app.App, msg *Message) error
+
+// MessageHoldFunc keeps the Message back while its recipient is in their quiet
+// hours and reports if it did so.
+type MessageHoldFunc func(
+	currentApp *app.App,
+	msg *Message,
+	now time.Time,
+) (bool, error)
+
+// MessageHold keeps non-urgent Messages back while their recipient is in their
+// quiet hours. Every device of the recipient is quiet during its own window or
+// otherwise the one of the user, evaluated in the timezone of the device,
+// falling back to the one of the user and finally UTC. Messages are held while
+// all devices are quiet and released once the first of them wakes up.
+// Recipients without devices are quiet during the window of the user.
+func MessageHold(
+	devices device.Service,
+	holds hold.Service,
+	users user.Service,
+) MessageHoldFunc {
+	return func(
+		currentApp *app.App,
+		msg *Message,
+		now time.Time,
+	) (bool, error) {
+		if msg.Urgent {
+			return false, nil
+		}
+
+		us, err := users.Query(currentApp.Namespace(), user.QueryOptions{
+			IDs: []uint64{
+				msg.Recipient,
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+
+		if len(us) != 1 {
+			return false, nil
+		}
+
+		ds, err := devices.Query(currentApp.Namespace(), device.QueryOptions{
+			Deleted: &defaultDeleted,
+			UserIDs: []uint64{
+				msg.Recipient,
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+
+		until := quietUntil(us[0], ds, now)
+		if until.IsZero() {
+			return false, nil
+		}
+
+		raw, err := json.Marshal(msg)
+		if err != nil {
+			return false, err
+		}
+
+		_, err = holds.Put(pg.MetaNamespace, &hold.Hold{
+			AppID:     currentApp.ID,
+			Message:   raw,
+			Recipient: msg.Recipient,
+			ReleaseAt: until,
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+}
+
+// MessageReleaseFunc delivers held Messages whose quiet hours ended and returns
+// how many were released.
+type MessageReleaseFunc func(limit int, deliver MessageDeliverFunc) (int, error)
+
+// MessageRelease delivers held Messages whose quiet hours ended. A hold is only
+// removed after its Message was delivered, so Messages survive restarts.
+func MessageRelease(
+	apps app.Service,
+	holds hold.Service,
+) MessageReleaseFunc {
+	return func(limit int, deliver MessageDeliverFunc) (int, error) {
+		hs, err := holds.Query(pg.MetaNamespace, hold.QueryOptions{
+			Due:   time.Now().UTC(),
+			Limit: limit,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		for _, h := range hs {
+			currentApp, err := AppFetch(apps)(h.AppID)
+			if err != nil && !IsNotFound(err) {
+				return 0, err
+			}
+
+			if currentApp != nil {
+				msg := &Message{}
+
+				if err := json.Unmarshal(h.Message, msg); err != nil {
+					return 0, err
+				}
+
+				if err := deliver(currentApp, msg); err != nil {
+					return 0, err
+				}
+			}
+
+			if err := holds.Delete(pg.MetaNamespace, h.ID); err != nil {
+				return 0, err
+			}
+		}
+
+		return len(hs), nil
+	}
+}
+
+// quietUntil returns when the first device of u leaves its quiet hours, or the
+// zero time if any of them is not quiet at now.
+func quietUntil(u *user.User, ds device.List, now time.Time) time.Time {
+	if len(ds) == 0 {
+		return hoursUntil(u.QuietHours, u.Timezone, now)
+	}
+
+	var until time.Time
+
+	for _, d := range ds {
+		var (
+			hours    = d.QuietHours
+			timezone = d.Timezone
+		)
+
+		if hours == nil {
+			hours = u.QuietHours
+		}
+
+		if timezone == "" {
+			timezone = u.Timezone
+		}
+
+		t := hoursUntil(hours, timezone, now)
+		if t.IsZero() {
+			return time.Time{}
+		}
+
+		if until.IsZero() || t.Before(until) {
+			until = t
+		}
+	}
+
+	return until
+}
+
+func hoursUntil(h *quiet.Hours, timezone string, now time.Time) time.Time {
+	if h == nil {
+		return time.Time{}
+	}
+
+	loc, err := quiet.Location(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	return h.Until(now, loc)
+}
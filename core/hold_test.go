@@ -0,0 +1,143 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/quiet"
+	"github.com/tapglue/snaas/service/device"
+	"github.com/tapglue/snaas/service/hold"
+	"github.com/tapglue/snaas/service/user"
+)
+
+func TestMessageHold(t *testing.T) {
+	var (
+		currentApp = testApp()
+		holds      = hold.MemService()
+		users      = user.MemService()
+		now        = time.Now().UTC()
+		fn         = MessageHold(&testDevices{}, holds, users)
+	)
+
+	sleeper := testUser()
+	sleeper.QuietHours = &quiet.Hours{
+		End:   now.Add(time.Hour).Format("15:04"),
+		Start: now.Add(-time.Hour).Format("15:04"),
+	}
+	sleeper.Timezone = "UTC"
+
+	sleeper, err := users.Put(currentApp.Namespace(), sleeper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awake := testUser()
+	awake.Timezone = "UTC"
+
+	awake, err = users.Put(currentApp.Namespace(), awake)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		msg  *Message
+		want bool
+	}{
+		{&Message{Recipient: sleeper.ID, URN: "tapglue/posts/1"}, true},
+		{&Message{Recipient: sleeper.ID, Urgent: true}, false},
+		{&Message{Recipient: awake.ID}, false},
+	}
+
+	for _, c := range cases {
+		held, err := fn(currentApp, c.msg, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := held, c.want; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	hs, err := holds.Query(pg.MetaNamespace, hold.QueryOptions{
+		AppIDs: []uint64{
+			currentApp.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(hs), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := hs[0].Recipient, sleeper.ID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if !hs[0].ReleaseAt.After(now) {
+		t.Errorf("have %v, want after %v", hs[0].ReleaseAt, now)
+	}
+}
+
+func TestQuietUntil(t *testing.T) {
+	var (
+		now    = time.Date(2017, 3, 1, 23, 0, 0, 0, time.UTC)
+		night  = &quiet.Hours{End: "07:00", Start: "22:00"}
+		late   = &quiet.Hours{End: "09:00", Start: "22:00"}
+		day    = &quiet.Hours{End: "18:00", Start: "09:00"}
+		sleepy = &user.User{QuietHours: night, Timezone: "UTC"}
+		awake  = &user.User{Timezone: "UTC"}
+		wakeUp = time.Date(2017, 3, 2, 7, 0, 0, 0, time.UTC)
+	)
+
+	cases := []struct {
+		user    *user.User
+		devices device.List
+		want    time.Time
+	}{
+		{sleepy, nil, wakeUp},                                      // User window
+		{awake, nil, time.Time{}},                                  // No window
+		{sleepy, device.List{{}}, wakeUp},                          // Device falls back to user window
+		{awake, device.List{{QuietHours: night}}, wakeUp},          // Device window
+		{sleepy, device.List{{QuietHours: day}}, time.Time{}},      // Device window overrides user
+		{sleepy, device.List{{}, {QuietHours: late}}, wakeUp},      // First device to wake up
+		{awake, device.List{{QuietHours: night}, {}}, time.Time{}}, // One device awake
+		{
+			awake,
+			device.List{{QuietHours: night, Timezone: "America/New_York"}},
+			time.Time{},
+		}, // Device timezone
+	}
+
+	for _, c := range cases {
+		if have, want := quietUntil(c.user, c.devices, now), c.want; !have.Equal(want) {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+type testDevices struct {
+	device.Service
+
+	devices device.List
+}
+
+func (s *testDevices) Query(
+	ns string,
+	opts device.QueryOptions,
+) (device.List, error) {
+	ds := device.List{}
+
+	for _, d := range s.devices {
+		for _, id := range opts.UserIDs {
+			if d.UserID == id {
+				ds = append(ds, d)
+			}
+		}
+	}
+
+	return ds, nil
+}
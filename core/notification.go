@@ -0,0 +1,346 @@
+package core
+
+import (
+	"time"
+
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/notification"
+	"github.com/tapglue/snaas/service/user"
+)
+
+// NotificationCountUnreadFunc returns the number of unread Notifications of
+// origin.
+type NotificationCountUnreadFunc func(
+	currentApp *app.App,
+	origin uint64,
+) (uint, error)
+
+// NotificationCountUnread returns the number of unread Notifications of
+// origin.
+func NotificationCountUnread(
+	notifications notification.Service,
+	users user.Service,
+) NotificationCountUnreadFunc {
+	return func(currentApp *app.App, origin uint64) (uint, error) {
+		lastRead, err := userLastRead(users, currentApp, origin)
+		if err != nil {
+			return 0, err
+		}
+
+		unread := false
+
+		return notifications.Count(currentApp.Namespace(), notification.QueryOptions{
+			After: lastRead,
+			Read:  &unread,
+			UserIDs: []uint64{
+				origin,
+			},
+		})
+	}
+}
+
+// NotificationCreateFunc persists the Message in the inbox of its recipient.
+type NotificationCreateFunc func(currentApp *app.App, msg *Message) error
+
+// NotificationCreate persists the Message in the inbox of its recipient.
+func NotificationCreate(
+	notifications notification.Service,
+) NotificationCreateFunc {
+	return func(currentApp *app.App, msg *Message) error {
+		if len(msg.Messages) == 0 {
+			return nil
+		}
+
+		_, err := notifications.Put(currentApp.Namespace(), &notification.Notification{
+			Messages: msg.Messages,
+			URN:      msg.URN,
+			UserID:   msg.Recipient,
+		})
+
+		return err
+	}
+}
+
+// NotificationListFunc returns the Notifications of origin.
+type NotificationListFunc func(
+	currentApp *app.App,
+	origin uint64,
+	opts notification.QueryOptions,
+) (notification.List, error)
+
+// NotificationList returns the Notifications of origin, with everything before
+// the last read watermark flagged as read.
+func NotificationList(
+	notifications notification.Service,
+	users user.Service,
+) NotificationListFunc {
+	return func(
+		currentApp *app.App,
+		origin uint64,
+		opts notification.QueryOptions,
+	) (notification.List, error) {
+		lastRead, err := userLastRead(users, currentApp, origin)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.UserIDs = []uint64{
+			origin,
+		}
+
+		ns, err := notifications.Query(currentApp.Namespace(), opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range ns {
+			if !n.CreatedAt.After(lastRead) {
+				n.Read = true
+			}
+		}
+
+		return ns, nil
+	}
+}
+
+// NotificationReadFunc marks a single Notification of origin as read.
+type NotificationReadFunc func(
+	currentApp *app.App,
+	origin, id uint64,
+) (*notification.Notification, error)
+
+// NotificationRead marks a single Notification of origin as read.
+func NotificationRead(notifications notification.Service) NotificationReadFunc {
+	return func(
+		currentApp *app.App,
+		origin, id uint64,
+	) (*notification.Notification, error) {
+		ns, err := notifications.Query(currentApp.Namespace(), notification.QueryOptions{
+			IDs: []uint64{
+				id,
+			},
+			UserIDs: []uint64{
+				origin,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ns) != 1 {
+			return nil, wrapError(ErrNotFound, "notification (%d) not found", id)
+		}
+
+		n := ns[0]
+
+		if n.Read {
+			return n, nil
+		}
+
+		n.Read = true
+
+		return notifications.Put(currentApp.Namespace(), n)
+	}
+}
+
+// NotificationReadAllFunc marks all current Notifications of origin as read.
+type NotificationReadAllFunc func(currentApp *app.App, origin uint64) error
+
+// NotificationReadAll marks all current Notifications of origin as read by
+// moving the last read watermark of the user forward.
+func NotificationReadAll(users user.Service) NotificationReadAllFunc {
+	return func(currentApp *app.App, origin uint64) error {
+		return users.PutLastRead(
+			currentApp.Namespace(),
+			origin,
+			time.Now().UTC(),
+		)
+	}
+}
+
+// NotificationAllowedFunc reports if the recipient of the Message accepts it
+// under their notification settings.
+type NotificationAllowedFunc func(currentApp *app.App, msg *Message) (bool, error)
+
+// NotificationAllowed reports if the recipient of the Message accepts it under
+// their notification settings.
+func NotificationAllowed(
+	settings notification.SettingsService,
+) NotificationAllowedFunc {
+	return func(currentApp *app.App, msg *Message) (bool, error) {
+		s, err := settingsFetch(settings, currentApp, msg.Recipient)
+		if err != nil {
+			return false, err
+		}
+
+		return s.Allows(
+			msg.Category(),
+			msg.RuleID,
+			msg.ObjectID,
+			msg.ActorID,
+			time.Now(),
+		), nil
+	}
+}
+
+// NotificationMuteFunc adds or removes id from the mutes of origin.
+type NotificationMuteFunc func(
+	currentApp *app.App,
+	origin, id uint64,
+	mute bool,
+) (*notification.Settings, error)
+
+// NotificationMutePost stops or resumes notifications about the post thread
+// with the given id for origin.
+func NotificationMutePost(
+	settings notification.SettingsService,
+) NotificationMuteFunc {
+	return func(
+		currentApp *app.App,
+		origin, postID uint64,
+		mute bool,
+	) (*notification.Settings, error) {
+		s, err := settingsFetch(settings, currentApp, origin)
+		if err != nil {
+			return nil, err
+		}
+
+		s.MutedPosts = toggleID(s.MutedPosts, postID, mute)
+
+		return settings.Put(currentApp.Namespace(), s)
+	}
+}
+
+// NotificationMuteUser stops or resumes notifications caused by the user with
+// the given id for origin.
+func NotificationMuteUser(
+	settings notification.SettingsService,
+) NotificationMuteFunc {
+	return func(
+		currentApp *app.App,
+		origin, userID uint64,
+		mute bool,
+	) (*notification.Settings, error) {
+		s, err := settingsFetch(settings, currentApp, origin)
+		if err != nil {
+			return nil, err
+		}
+
+		s.MutedUsers = toggleID(s.MutedUsers, userID, mute)
+
+		return settings.Put(currentApp.Namespace(), s)
+	}
+}
+
+// NotificationSettingsFetchFunc returns the notification settings of origin.
+type NotificationSettingsFetchFunc func(
+	currentApp *app.App,
+	origin uint64,
+) (*notification.Settings, error)
+
+// NotificationSettingsFetch returns the notification settings of origin, which
+// default to everything enabled if origin never changed them.
+func NotificationSettingsFetch(
+	settings notification.SettingsService,
+) NotificationSettingsFetchFunc {
+	return func(
+		currentApp *app.App,
+		origin uint64,
+	) (*notification.Settings, error) {
+		return settingsFetch(settings, currentApp, origin)
+	}
+}
+
+// NotificationSettingsUpdateFunc replaces the notification settings of origin.
+type NotificationSettingsUpdateFunc func(
+	currentApp *app.App,
+	origin uint64,
+	new *notification.Settings,
+) (*notification.Settings, error)
+
+// NotificationSettingsUpdate replaces the notification settings of origin.
+func NotificationSettingsUpdate(
+	settings notification.SettingsService,
+) NotificationSettingsUpdateFunc {
+	return func(
+		currentApp *app.App,
+		origin uint64,
+		new *notification.Settings,
+	) (*notification.Settings, error) {
+		old, err := settingsFetch(settings, currentApp, origin)
+		if err != nil {
+			return nil, err
+		}
+
+		new.CreatedAt = old.CreatedAt
+		new.UserID = origin
+
+		return settings.Put(currentApp.Namespace(), new)
+	}
+}
+
+// userLastRead returns the last read watermark of the user, the zero time if
+// the user is unknown.
+func userLastRead(
+	users user.Service,
+	currentApp *app.App,
+	origin uint64,
+) (time.Time, error) {
+	us, err := users.Query(currentApp.Namespace(), user.QueryOptions{
+		IDs: []uint64{
+			origin,
+		},
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(us) == 0 {
+		return time.Time{}, nil
+	}
+
+	return us[0].LastRead, nil
+}
+
+func settingsFetch(
+	settings notification.SettingsService,
+	currentApp *app.App,
+	origin uint64,
+) (*notification.Settings, error) {
+	ss, err := settings.Query(currentApp.Namespace(), notification.SettingsQueryOptions{
+		UserIDs: []uint64{
+			origin,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ss) == 0 {
+		return &notification.Settings{
+			Categories: map[string]bool{},
+			MutedPosts: []uint64{},
+			MutedUsers: []uint64{},
+			Rules:      map[uint64]bool{},
+			UserID:     origin,
+		}, nil
+	}
+
+	return ss[0], nil
+}
+
+func toggleID(ids []uint64, id uint64, add bool) []uint64 {
+	rest := []uint64{}
+
+	for _, i := range ids {
+		if i != id {
+			rest = append(rest, i)
+		}
+	}
+
+	if add {
+		rest = append(rest, id)
+	}
+
+	return rest
+}
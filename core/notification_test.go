@@ -0,0 +1,176 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tapglue/snaas/service/notification"
+	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/user"
+)
+
+func TestNotificationReadState(t *testing.T) {
+	var (
+		currentApp    = testApp()
+		notifications = notification.MemService()
+		users         = user.MemService()
+		countUnread   = NotificationCountUnread(notifications, users)
+		create        = NotificationCreate(notifications)
+		list          = NotificationList(notifications, users)
+	)
+
+	u, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origin := u.ID
+
+	for i := 0; i < 3; i++ {
+		err := create(currentApp, &Message{
+			Messages: map[string]string{
+				"en": "alice liked your post",
+			},
+			Recipient: origin,
+			URN:       "tapglue/posts/1",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ns, err := list(currentApp, origin, notification.QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ns), 3; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	_, err = NotificationRead(notifications)(currentApp, origin, ns[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NotificationRead(notifications)(currentApp, origin+1, ns[1].ID)
+	if have, want := IsNotFound(err), true; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	count, err := countUnread(currentApp, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := count, uint(2); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	err = NotificationReadAll(users)(currentApp, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The watermark is the one feeds, rules and segments read.
+	us, err := users.Query(currentApp.Namespace(), user.QueryOptions{
+		IDs: []uint64{
+			origin,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if us[0].LastRead.IsZero() {
+		t.Errorf("have %v, want last read set", us[0].LastRead)
+	}
+
+	count, err = countUnread(currentApp, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := count, uint(0); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	ns, err = list(currentApp, origin, notification.QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range ns {
+		if !n.Read {
+			t.Errorf("have %v, want %v", n.Read, true)
+		}
+	}
+}
+
+func TestNotificationAllowed(t *testing.T) {
+	var (
+		currentApp = testApp()
+		settings   = notification.MemSettingsService()
+		allowed    = NotificationAllowed(settings)
+		origin     = uint64(1)
+		msg        = &Message{
+			ActorID:   3,
+			ObjectID:  2,
+			Recipient: origin,
+			RuleID:    4,
+			RuleType:  rule.TypeReaction,
+		}
+	)
+
+	ok, err := allowed(currentApp, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := ok, true; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	_, err = NotificationMuteUser(settings)(currentApp, origin, msg.ActorID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = allowed(currentApp, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := ok, false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	s, err := NotificationMuteUser(settings)(currentApp, origin, msg.ActorID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(s.MutedUsers), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	_, err = NotificationSettingsUpdate(settings)(currentApp, origin, &notification.Settings{
+		Categories: map[string]bool{
+			rule.TypeReaction.String(): false,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err = allowed(currentApp, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := ok, false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
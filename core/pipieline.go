@@ -17,20 +17,74 @@ import (
 )
 
 const (
+	queryCondFollowers    = "followers"
+	queryCondFriends      = "friends"
 	queryCondOwnerFriends = "ownerFriends"
 	queryCondObjectOwner  = "objectOwner"
 	queryCondOwner        = "owner"
 	queryCondParentOwner  = "parentOwner"
+	queryCondSelf         = "self"
 	queryCondUserFrom     = "userFrom"
 	queryCondUserTo       = "userTo"
 )
 
 // Message is the envelope which holds the templated message produced by a
-// Pipeline together with the recipient and the URN to deliver with it.
+// Pipeline together with the recipient and the URN to deliver with it. The
+// actor, the object thread and the rule it originates from are kept to honour
+// the preferences of the recipient. Urgent Messages bypass quiet hours.
+// Messages of a campaign carry its id instead of a rule. VariantID names the
+// template variant the recipient was assigned to, if the rule has any.
 type Message struct {
-	Messages  map[string]string
-	Recipient uint64
-	URN       string
+	ActorID    uint64
+	Bodies     map[string]string
+	CampaignID uint64
+	Channels   rule.Channels
+	Messages   map[string]string
+	ObjectID   uint64
+	Push       *MessagePush
+	Recipient  uint64
+	RuleID     uint64
+	RuleType   rule.Type
+	Subjects   map[string]string
+	URN        string
+	Urgent     bool
+	VariantID  string
+
+	// Only set when the Message is subject to aggregation.
+	actor     *user.User
+	aggregate *rule.Aggregate
+	context   interface{}
+}
+
+// MessagePush holds the rendered push specifics of a Message, Titles are keyed
+// by language like Messages. Badge is either rule.BadgeUnread or an integer.
+type MessagePush struct {
+	Badge       string
+	CollapseKey string
+	Data        map[string]string
+	Sound       string
+	ThreadID    string
+	Titles      map[string]string
+}
+
+// Category is the notification category recipients can opt out of the
+// Message by.
+func (m *Message) Category() string {
+	if m.CampaignID != 0 {
+		return categoryCampaign
+	}
+
+	return m.RuleType.String()
+}
+
+// Notifies reports if the Message is meant to be delivered over c, Messages
+// without explicit channels are only pushed.
+func (m *Message) Notifies(c rule.Channel) bool {
+	if len(m.Channels) == 0 {
+		return c == rule.ChannelPush
+	}
+
+	return m.Channels.Has(c)
 }
 
 // Messages is a Message collection
@@ -92,7 +146,7 @@ func PipelineConnection(users user.Service) PipelineConnectionFunc {
 				}
 
 				for _, c := range cs {
-					msg, err := compileMessage(context, recipient, c)
+					msg, err := compileMessage(context, currentRule, recipient, c)
 					if err != nil {
 						return nil, err
 					}
@@ -108,6 +162,7 @@ func PipelineConnection(users user.Service) PipelineConnectionFunc {
 
 func compileMessage(
 	context interface{},
+	currentRule *rule.Rule,
 	recipient rule.Recipient,
 	target *user.User,
 ) (*Message, error) {
@@ -116,18 +171,68 @@ func compileMessage(
 		return nil, err
 	}
 
-	msgs := map[string]string{}
+	variantID := ""
 
-	for lang, tmpl := range recipient.Templates {
-		msg, err := compileTemplate(context, tmpl)
+	if v := recipient.Variants.Pick(currentRule.ID, target.ID); v != nil {
+		recipient.Templates = v.Templates
+		variantID = v.ID
+
+		if len(v.Titles) > 0 {
+			recipient.Push.Titles = v.Titles
+		}
+	}
+
+	msgs, err := compileTemplates(context, recipient.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := contextActor(context, target)
+
+	msg := &Message{
+		Channels:  recipient.Channels,
+		Messages:  msgs,
+		ObjectID:  contextObjectID(context),
+		Recipient: target.ID,
+		RuleID:    currentRule.ID,
+		RuleType:  currentRule.Type,
+		URN:       urn,
+		Urgent:    currentRule.Urgent,
+		VariantID: variantID,
+	}
+
+	if actor != nil {
+		msg.ActorID = actor.ID
+	}
+
+	if msg.Notifies(rule.ChannelPush) {
+		msg.Push, err = compilePush(context, recipient.Push)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		msgs[lang] = msg
+	if recipient.Aggregate != nil {
+		msg.actor = actor
+		msg.aggregate = recipient.Aggregate
+		msg.context = context
+	}
+
+	if !msg.Notifies(rule.ChannelEmail) {
+		return msg, nil
 	}
 
-	return &Message{Messages: msgs, Recipient: target.ID, URN: urn}, nil
+	msg.Bodies, err = compileTemplates(context, recipient.Email.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Subjects, err = compileTemplates(context, recipient.Email.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
 }
 
 // PipelineEventFunc constructs a Pipeline that by applying the provided rules
@@ -195,7 +300,7 @@ func PipelineEvent(
 				}
 
 				for _, r := range rs {
-					msg, err := compileMessage(context, recipient, r)
+					msg, err := compileMessage(context, currentRule, recipient, r)
 					if err != nil {
 						return nil, err
 					}
@@ -289,7 +394,7 @@ func PipelineObject(
 				}
 
 				for _, r := range rs {
-					msg, err := compileMessage(context, recipient, r)
+					msg, err := compileMessage(context, currentRule, recipient, r)
 					if err != nil {
 						return nil, err
 					}
@@ -368,7 +473,65 @@ func PipelineReaction(
 				}
 
 				for _, r := range rs {
-					msg, err := compileMessage(context, recipient, r)
+					msg, err := compileMessage(context, currentRule, recipient, r)
+					if err != nil {
+						return nil, err
+					}
+
+					ms = append(ms, msg)
+				}
+			}
+		}
+
+		return ms, nil
+	}
+}
+
+// PipelineUserFunc constructs a Pipeline that by applying the provided rules
+// outputs Messages.
+type PipelineUserFunc func(
+	*app.App,
+	*user.StateChange,
+	...*rule.Rule,
+) (Messages, error)
+
+// PipelineUser constructs a Pipeline that by applying the provided rules
+// outputs Messages.
+func PipelineUser(
+	connections connection.Service,
+	users user.Service,
+) PipelineUserFunc {
+	return func(
+		currentApp *app.App,
+		change *user.StateChange,
+		rules ...*rule.Rule,
+	) (Messages, error) {
+		ms := Messages{}
+
+		if change.New == nil {
+			return Messages{}, nil
+		}
+
+		context := &contextUser{
+			User: change.New,
+		}
+
+		for _, currentRule := range rules {
+			if !currentRule.Criteria.Match(change) {
+				continue
+			}
+
+			for _, recipient := range currentRule.Recipients {
+				rs, err := recipientsUser(
+					connections,
+					users,
+				)(currentApp, context, recipient.Query)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, r := range rs {
+					msg, err := compileMessage(context, currentRule, recipient, r)
 					if err != nil {
 						return nil, err
 					}
@@ -410,6 +573,10 @@ type contextReaction struct {
 	Reaction    *reaction.Reaction
 }
 
+type contextUser struct {
+	User *user.User
+}
+
 func compileTemplate(context interface{}, t string) (string, error) {
 	tmpl, err := template.New("message").Parse(t)
 	if err != nil {
@@ -426,6 +593,72 @@ func compileTemplate(context interface{}, t string) (string, error) {
 	return buf.String(), nil
 }
 
+// compilePush renders the push templates, without any of them configured there
+// is nothing to add to the Message.
+func compilePush(
+	context interface{},
+	t rule.PushTemplates,
+) (*MessagePush, error) {
+	if t.Badge == "" && t.CollapseKey == "" && len(t.Data) == 0 &&
+		t.Sound == "" && t.ThreadID == "" && len(t.Titles) == 0 {
+		return nil, nil
+	}
+
+	p := &MessagePush{
+		Badge: t.Badge,
+		Sound: t.Sound,
+	}
+
+	var err error
+
+	if t.Badge != rule.BadgeUnread {
+		p.Badge, err = compileTemplate(context, t.Badge)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.CollapseKey, err = compileTemplate(context, t.CollapseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Data, err = compileTemplates(context, rule.Templates(t.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	p.ThreadID, err = compileTemplate(context, t.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Titles, err = compileTemplates(context, t.Titles)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func compileTemplates(
+	context interface{},
+	ts rule.Templates,
+) (map[string]string, error) {
+	msgs := map[string]string{}
+
+	for lang, tmpl := range ts {
+		msg, err := compileTemplate(context, tmpl)
+		if err != nil {
+			return nil, err
+		}
+
+		msgs[lang] = msg
+	}
+
+	return msgs, nil
+}
+
 func filterIDs(ids []uint64, fs ...uint64) []uint64 {
 	var (
 		is   = []uint64{}
@@ -639,3 +872,45 @@ func recipientsReaction() recipientsReactionFunc {
 		return us, nil
 	}
 }
+
+type recipientsUserFunc func(
+	*app.App,
+	*contextUser,
+	rule.Query,
+) (user.List, error)
+
+func recipientsUser(
+	connections connection.Service,
+	users user.Service,
+) recipientsUserFunc {
+	return func(
+		currentApp *app.App,
+		context *contextUser,
+		q rule.Query,
+	) (user.List, error) {
+		ids := []uint64{}
+
+		for condType := range q {
+			switch condType {
+			case queryCondFollowers:
+				followerIDs, err := ConnectionFollowerIDs(connections)(currentApp, context.User.ID)
+				if err != nil {
+					return nil, err
+				}
+
+				ids = append(ids, followerIDs...)
+			case queryCondFriends:
+				friendIDs, err := ConnectionFriendIDs(connections)(currentApp, context.User.ID)
+				if err != nil {
+					return nil, err
+				}
+
+				ids = append(ids, friendIDs...)
+			case queryCondSelf:
+				ids = append(ids, context.User.ID)
+			}
+		}
+
+		return user.ListFromIDs(users, currentApp.Namespace(), ids...)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
+	"strconv"
 	"testing"
 
 	"golang.org/x/text/language"
@@ -99,6 +100,7 @@ func TestPipelineConnectionCondFrom(t *testing.T) {
 
 	want := Messages{
 		{
+			ActorID: target.ID,
 			Messages: map[string]string{
 				language.English.String(): fmt.Sprintf("%s accepted your friend request", target.Username),
 			},
@@ -117,6 +119,106 @@ func TestPipelineConnectionCondFrom(t *testing.T) {
 	}
 }
 
+func TestPipelineConnectionEmail(t *testing.T) {
+	var (
+		currentApp  = testApp()
+		connections = connection.MemService()
+		users       = user.MemService()
+	)
+
+	target, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origin, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	new, err := connections.Put(currentApp.Namespace(), &connection.Connection{
+		Enabled: true,
+		FromID:  origin.ID,
+		State:   connection.StateConfirmed,
+		ToID:    target.ID,
+		Type:    connection.TypeFollow,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		enabled        = true
+		ruleConnection = &rule.Rule{
+			Criteria: &rule.CriteriaConnection{
+				New: &connection.QueryOptions{
+					Enabled: &enabled,
+					Types: []connection.Type{
+						connection.TypeFollow,
+					},
+				},
+			},
+			Recipients: rule.Recipients{
+				{
+					Channels: rule.Channels{
+						rule.ChannelEmail,
+						rule.ChannelPush,
+					},
+					Email: rule.EmailTemplates{
+						Body: map[string]string{
+							"en": "Say hello to {{.From.Username}}.",
+						},
+						Subject: map[string]string{
+							"en": "{{.From.Username}} follows you",
+						},
+					},
+					Query: map[string]string{
+						"userTo": "",
+					},
+					Templates: map[string]string{
+						"en": "{{.From.Username}} started following you",
+					},
+					URN: "tapglue/users/{{.From.ID}}",
+				},
+			},
+		}
+	)
+
+	want := Messages{
+		{
+			ActorID: origin.ID,
+			Bodies: map[string]string{
+				language.English.String(): fmt.Sprintf("Say hello to %s.", origin.Username),
+			},
+			Channels: rule.Channels{
+				rule.ChannelEmail,
+				rule.ChannelPush,
+			},
+			Messages: map[string]string{
+				language.English.String(): fmt.Sprintf("%s started following you", origin.Username),
+			},
+			Recipient: target.ID,
+			Subjects: map[string]string{
+				language.English.String(): fmt.Sprintf("%s follows you", origin.Username),
+			},
+			URN: fmt.Sprintf("tapglue/users/%d", origin.ID),
+		},
+	}
+
+	have, err := PipelineConnection(users)(currentApp, &connection.StateChange{New: new}, ruleConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	if !have[0].Notifies(rule.ChannelEmail) {
+		t.Errorf("have %v, want %v", false, true)
+	}
+}
+
 func TestPipelineConnectionCondTo(t *testing.T) {
 	var (
 		currentApp  = testApp()
@@ -179,6 +281,7 @@ func TestPipelineConnectionCondTo(t *testing.T) {
 
 	want := Messages{
 		{
+			ActorID: origin.ID,
 			Messages: map[string]string{
 				language.English.String(): fmt.Sprintf("%s sent you a friend request", origin.Username),
 			},
@@ -261,9 +364,11 @@ func TestPipelineReactionCondParentOwner(t *testing.T) {
 
 	want := Messages{
 		{
+			ActorID: liker.ID,
 			Messages: map[string]string{
 				language.English.String(): fmt.Sprintf("%s liked your post", liker.Username),
 			},
+			ObjectID:  post.ID,
 			Recipient: postOwner.ID,
 			URN:       fmt.Sprintf("tapglue/users/%d", liker.ID),
 		},
@@ -346,9 +451,11 @@ func TestPipelineEventCondParentOwner(t *testing.T) {
 
 	want := Messages{
 		{
+			ActorID: liker.ID,
 			Messages: map[string]string{
 				language.English.String(): fmt.Sprintf("%s liked your post", liker.Username),
 			},
+			ObjectID:  post.ID,
 			Recipient: postOwner.ID,
 			URN:       fmt.Sprintf("tapglue/users/%d", liker.ID),
 		},
@@ -450,6 +557,8 @@ func TestPipelineObjectCondFriends(t *testing.T) {
 
 	want := Messages{
 		{
+			ActorID:   postOwner.ID,
+			ObjectID:  post.ID,
 			Recipient: friend2.ID,
 			Messages: map[string]string{
 				language.English.String(): fmt.Sprintf("%s just added a review", postOwner.Username),
@@ -457,6 +566,8 @@ func TestPipelineObjectCondFriends(t *testing.T) {
 			URN: fmt.Sprintf("tapglue/posts/%d", post.ID),
 		},
 		{
+			ActorID:   postOwner.ID,
+			ObjectID:  post.ID,
 			Recipient: friend1.ID,
 			Messages: map[string]string{
 				language.English.String(): fmt.Sprintf("%s just added a review", postOwner.Username),
@@ -558,6 +669,8 @@ func TestPipelineObjectCondObjectOwner(t *testing.T) {
 
 	want := Messages{
 		{
+			ActorID:   commenter3.ID,
+			ObjectID:  post.ID,
 			Recipient: commenter2.ID,
 			Messages: map[string]string{
 				language.English.String(): fmt.Sprintf("%s also commented on %ss post", commenter3.Username, postOwner.Username),
@@ -565,6 +678,8 @@ func TestPipelineObjectCondObjectOwner(t *testing.T) {
 			URN: fmt.Sprintf("tapglue/posts/%d/comments/%d", post.ID, comment3.ID),
 		},
 		{
+			ActorID:   commenter3.ID,
+			ObjectID:  post.ID,
 			Recipient: commenter1.ID,
 			Messages: map[string]string{
 				language.English.String(): fmt.Sprintf("%s also commented on %ss post", commenter3.Username, postOwner.Username),
@@ -642,6 +757,8 @@ func TestPipelineObjectCondOwner(t *testing.T) {
 
 	want := Messages{
 		{
+			ActorID:   commenter.ID,
+			ObjectID:  post.ID,
 			Recipient: postOwner.ID,
 			Messages: map[string]string{
 				language.English.String(): fmt.Sprintf("%s commented on your post", commenter.Username),
@@ -664,6 +781,294 @@ func TestPipelineObjectCondOwner(t *testing.T) {
 	}
 }
 
+func TestPipelineUserCondFollowers(t *testing.T) {
+	var (
+		currentApp  = testApp()
+		connections = connection.MemService()
+		users       = user.MemService()
+	)
+
+	// Create verified user.
+	verified, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create follower.
+	follower, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = connections.Put(currentApp.Namespace(), &connection.Connection{
+		Enabled: true,
+		FromID:  follower.ID,
+		State:   connection.StateConfirmed,
+		ToID:    verified.ID,
+		Type:    connection.TypeFollow,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := *verified
+	verified.Private = &user.Private{Verified: true}
+
+	ruleUserVerified := &rule.Rule{
+		Criteria: &rule.CriteriaUser{
+			Expression: "changed(private.verified) && new.private.verified",
+		},
+		Name: "Verified",
+		Recipients: rule.Recipients{
+			{
+				Query: map[string]string{
+					"followers": "",
+				},
+				Templates: map[string]string{
+					"en": "{{.User.Username}} is now verified",
+				},
+				URN: "tapglue/users/{{.User.ID}}",
+			},
+		},
+		Type: rule.TypeUser,
+	}
+
+	// Compiles the criteria expression.
+	if err := ruleUserVerified.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Messages{
+		{
+			ActorID:   verified.ID,
+			Recipient: follower.ID,
+			Messages: map[string]string{
+				language.English.String(): fmt.Sprintf("%s is now verified", verified.Username),
+			},
+			RuleType: rule.TypeUser,
+			URN:      fmt.Sprintf("tapglue/users/%d", verified.ID),
+		},
+	}
+
+	have, err := PipelineUser(
+		connections,
+		users,
+	)(currentApp, &user.StateChange{New: verified, Old: &old}, ruleUserVerified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+
+	// Unrelated update of a verified user.
+	have, err = PipelineUser(
+		connections,
+		users,
+	)(currentApp, &user.StateChange{New: verified, Old: verified}, ruleUserVerified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(have), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestPipelineUserCondSelf(t *testing.T) {
+	var (
+		currentApp  = testApp()
+		connections = connection.MemService()
+		users       = user.MemService()
+	)
+
+	// Create new user.
+	signup, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ruleUserSignup := &rule.Rule{
+		Criteria: &rule.CriteriaUser{
+			New: &user.QueryOptions{
+				Enabled: &defaultEnabled,
+			},
+		},
+		Recipients: rule.Recipients{
+			{
+				Query: map[string]string{
+					"self": "",
+				},
+				Templates: map[string]string{
+					"en": "Welcome {{.User.Username}}",
+				},
+				URN: "tapglue/users/{{.User.ID}}",
+			},
+		},
+	}
+
+	want := Messages{
+		{
+			ActorID:   signup.ID,
+			Recipient: signup.ID,
+			Messages: map[string]string{
+				language.English.String(): fmt.Sprintf("Welcome %s", signup.Username),
+			},
+			URN: fmt.Sprintf("tapglue/users/%d", signup.ID),
+		},
+	}
+
+	have, err := PipelineUser(
+		connections,
+		users,
+	)(currentApp, &user.StateChange{New: signup}, ruleUserSignup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestPipelinePush(t *testing.T) {
+	var (
+		currentApp  = testApp()
+		connections = connection.MemService()
+		users       = user.MemService()
+	)
+
+	signup, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ruleUserSignup := &rule.Rule{
+		Criteria: &rule.CriteriaUser{},
+		Recipients: rule.Recipients{
+			{
+				Push: rule.PushTemplates{
+					Badge:       rule.BadgeUnread,
+					CollapseKey: "welcome-{{.User.ID}}",
+					Data: map[string]string{
+						"user_id": "{{.User.ID}}",
+					},
+					Sound: "default",
+					Titles: rule.Templates{
+						"en": "Hi {{.User.Username}}",
+					},
+				},
+				Query: map[string]string{
+					"self": "",
+				},
+				Templates: map[string]string{
+					"en": "Welcome",
+				},
+			},
+		},
+	}
+
+	ms, err := PipelineUser(
+		connections,
+		users,
+	)(currentApp, &user.StateChange{New: signup}, ruleUserSignup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ms), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	want := &MessagePush{
+		Badge:       rule.BadgeUnread,
+		CollapseKey: fmt.Sprintf("welcome-%d", signup.ID),
+		Data: map[string]string{
+			"user_id": strconv.FormatUint(signup.ID, 10),
+		},
+		Sound: "default",
+		Titles: map[string]string{
+			language.English.String(): fmt.Sprintf("Hi %s", signup.Username),
+		},
+	}
+
+	if have := ms[0].Push; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestPipelineVariant(t *testing.T) {
+	var (
+		currentApp  = testApp()
+		connections = connection.MemService()
+		users       = user.MemService()
+	)
+
+	signup, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ruleUserSignup := &rule.Rule{
+		Criteria: &rule.CriteriaUser{},
+		ID:       123,
+		Recipients: rule.Recipients{
+			{
+				Query: map[string]string{
+					"self": "",
+				},
+				Variants: rule.Variants{
+					{
+						ID: "short",
+						Templates: rule.Templates{
+							"en": "Welcome",
+						},
+						Weight: 1,
+					},
+					{
+						ID: "long",
+						Templates: rule.Templates{
+							"en": "Welcome {{.User.Username}}",
+						},
+						Titles: rule.Templates{
+							"en": "Hi",
+						},
+						Weight: 1,
+					},
+				},
+			},
+		},
+	}
+
+	ms, err := PipelineUser(
+		connections,
+		users,
+	)(currentApp, &user.StateChange{New: signup}, ruleUserSignup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ms), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	v := ruleUserSignup.Recipients[0].Variants.Pick(ruleUserSignup.ID, signup.ID)
+
+	if have, want := ms[0].VariantID, v.ID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	want, err := compileTemplates(&contextUser{User: signup}, v.Templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have := ms[0].Messages; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
 func testApp() *app.App {
 	return &app.App{
 		ID: uint64(rand.Int63()),
@@ -3,6 +3,7 @@ package core
 import (
 	pErr "github.com/tapglue/snaas/error"
 	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/push"
 	"github.com/tapglue/snaas/platform/sns"
 	"github.com/tapglue/snaas/service/app"
 	"github.com/tapglue/snaas/service/platform"
@@ -12,7 +13,8 @@ var (
 	defaultActive = true
 )
 
-// PlatformCreateFunc stores the provided platform.
+// PlatformCreateFunc stores the provided platform, cert and key are only used
+// for platforms served by SNS.
 type PlatformCreateFunc func(
 	currentApp *app.App,
 	p *platform.Platform,
@@ -31,6 +33,20 @@ func PlatformCreate(
 		p *platform.Platform,
 		cert, key string,
 	) (*platform.Platform, error) {
+		p.AppID = currentApp.ID
+
+		// Direct providers authenticate with the stored credentials, there is
+		// no platform application to set up.
+		if p.Direct() {
+			p.ARN = ""
+
+			if err := platformPrepareWebPush(p); err != nil {
+				return nil, err
+			}
+
+			return platforms.Put(pg.MetaNamespace, p)
+		}
+
 		arn := ""
 
 		switch p.Ecosystem {
@@ -54,13 +70,63 @@ func PlatformCreate(
 			}
 		}
 
-		p.AppID = currentApp.ID
 		p.ARN = arn
 
 		return platforms.Put(pg.MetaNamespace, p)
 	}
 }
 
+// PlatformCreateWebPushFunc stores a Web Push platform for the app.
+type PlatformCreateWebPushFunc func(
+	appID uint64,
+	p *platform.Platform,
+) (*platform.Platform, error)
+
+// PlatformCreateWebPush stores a Web Push platform for the app. Unless given,
+// a VAPID key pair is generated, the public key is what web clients subscribe
+// with. The platform is activated if the app has no active one for web yet.
+func PlatformCreateWebPush(
+	apps app.Service,
+	platforms platform.Service,
+) PlatformCreateWebPushFunc {
+	return func(
+		appID uint64,
+		p *platform.Platform,
+	) (*platform.Platform, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		ps, err := platforms.Query(pg.MetaNamespace, platform.QueryOptions{
+			Active: &defaultActive,
+			AppIDs: []uint64{
+				currentApp.ID,
+			},
+			Deleted: &defaultDeleted,
+			Ecosystems: []sns.Platform{
+				platform.Web,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		p.Active = len(ps) == 0
+		p.AppID = currentApp.ID
+		p.ARN = ""
+		p.Ecosystem = platform.Web
+		p.ID = 0
+		p.Provider = platform.ProviderWebPush
+
+		if err := platformPrepareWebPush(p); err != nil {
+			return nil, err
+		}
+
+		return platforms.Put(pg.MetaNamespace, p)
+	}
+}
+
 // PlatformFetchActiveFunc returns the active platform for the current app and the
 // given ecosystem.
 type PlatformFetchActiveFunc func(*app.App, sns.Platform) (*platform.Platform, error)
@@ -125,3 +191,32 @@ func PlatformFetchByARN(platforms platform.Service) PlatformFetchByARNFunc {
 		return ps[0], nil
 	}
 }
+
+// platformPrepareWebPush generates the VAPID key pair for Web Push platforms
+// without one, a given private key has its public key derived.
+func platformPrepareWebPush(p *platform.Platform) error {
+	if p.Provider != platform.ProviderWebPush {
+		return nil
+	}
+
+	if p.Credentials.VAPIDPrivateKey == "" {
+		public, private, err := push.GenerateVAPIDKeys()
+		if err != nil {
+			return err
+		}
+
+		p.Credentials.VAPIDPrivateKey = private
+		p.Credentials.VAPIDPublicKey = public
+
+		return nil
+	}
+
+	public, err := push.VAPIDPublicKey(p.Credentials.VAPIDPrivateKey)
+	if err != nil {
+		return pErr.Wrap(pErr.ErrInvalidPlatform, "%s", err)
+	}
+
+	p.Credentials.VAPIDPublicKey = public
+
+	return nil
+}
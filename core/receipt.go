@@ -0,0 +1,179 @@
+package core
+
+import (
+	"time"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/receipt"
+)
+
+// Keys of the push data which let clients report opens of template variants,
+// only set for Messages with a VariantID.
+const (
+	PushKeyRuleID    = "tg_rule_id"
+	PushKeyVariantID = "tg_variant_id"
+)
+
+// ReceiptFailFunc marks the delivery of the provider message as failed.
+type ReceiptFailFunc func(currentApp *app.App, messageID, reason string) error
+
+// ReceiptFail marks the delivery of the provider message as failed. Providers
+// like SNS accept messages first and report failures later on, those are
+// correlated through the message id. Unknown messages are ignored as they
+// might have been sent before receipts were recorded.
+func ReceiptFail(receipts receipt.Service) ReceiptFailFunc {
+	return func(currentApp *app.App, messageID, reason string) error {
+		if messageID == "" {
+			return nil
+		}
+
+		rs, err := receipts.Query(pg.MetaNamespace, receipt.QueryOptions{
+			AppIDs: []uint64{
+				currentApp.ID,
+			},
+			MessageIDs: []string{
+				messageID,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, r := range rs {
+			r.Error = reason
+			r.Status = receipt.StatusFailed
+
+			if _, err := receipts.Put(pg.MetaNamespace, r); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// ReceiptListFunc returns the delivery log of the app narrowed down by opts.
+type ReceiptListFunc func(
+	appID uint64,
+	opts receipt.QueryOptions,
+) (receipt.List, error)
+
+// ReceiptList returns the delivery log of the app narrowed down by opts, the
+// app constraint can't be overridden.
+func ReceiptList(apps app.Service, receipts receipt.Service) ReceiptListFunc {
+	return func(appID uint64, opts receipt.QueryOptions) (receipt.List, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.AppIDs = []uint64{
+			currentApp.ID,
+		}
+
+		return receipts.Query(pg.MetaNamespace, opts)
+	}
+}
+
+// ReceiptOpenFunc marks the latest delivery of the rule variant to the user as
+// opened.
+type ReceiptOpenFunc func(
+	currentApp *app.App,
+	userID, ruleID uint64,
+	variantID string,
+) error
+
+// ReceiptOpen marks the latest delivery of the rule variant to the user as
+// opened. Reports without a matching delivery are ignored, as are repeated
+// ones, so clients can report every open of the push. Open rates count
+// recipients, one opened delivery marks the user no matter on which device.
+func ReceiptOpen(receipts receipt.Service) ReceiptOpenFunc {
+	return func(
+		currentApp *app.App,
+		userID, ruleID uint64,
+		variantID string,
+	) error {
+		if ruleID == 0 || variantID == "" {
+			return wrapError(ErrInvalidEntity, "rule and variant required")
+		}
+
+		rs, err := receipts.Query(pg.MetaNamespace, receipt.QueryOptions{
+			AppIDs: []uint64{
+				currentApp.ID,
+			},
+			Limit: 1,
+			Recipients: []uint64{
+				userID,
+			},
+			RuleIDs: []uint64{
+				ruleID,
+			},
+			Statuses: []receipt.Status{
+				receipt.StatusSent,
+			},
+			VariantIDs: []string{
+				variantID,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(rs) == 0 || !rs[0].OpenedAt.IsZero() {
+			return nil
+		}
+
+		rs[0].OpenedAt = time.Now()
+
+		_, err = receipts.Put(pg.MetaNamespace, rs[0])
+
+		return err
+	}
+}
+
+// ReceiptRecordFunc records the outcome of a push delivery attempt.
+type ReceiptRecordFunc func(currentApp *app.App, r *receipt.Receipt) error
+
+// ReceiptRecord records the outcome of a push delivery attempt.
+func ReceiptRecord(receipts receipt.Service) ReceiptRecordFunc {
+	return func(currentApp *app.App, r *receipt.Receipt) error {
+		r.AppID = currentApp.ID
+
+		_, err := receipts.Put(pg.MetaNamespace, r)
+
+		return err
+	}
+}
+
+// ReceiptVariantsFunc returns the open rates of the template variants of the
+// rule.
+type ReceiptVariantsFunc func(
+	appID, ruleID uint64,
+) (receipt.VariantCounts, error)
+
+// ReceiptVariants returns the number of sent and opened deliveries for every
+// template variant of the rule.
+func ReceiptVariants(
+	apps app.Service,
+	receipts receipt.Service,
+) ReceiptVariantsFunc {
+	return func(appID, ruleID uint64) (receipt.VariantCounts, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		return receipts.CountVariants(pg.MetaNamespace, receipt.QueryOptions{
+			AppIDs: []uint64{
+				currentApp.ID,
+			},
+			RuleIDs: []uint64{
+				ruleID,
+			},
+			Statuses: []receipt.Status{
+				receipt.StatusSent,
+			},
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/receipt"
+)
+
+func TestReceiptFail(t *testing.T) {
+	var (
+		currentApp = testApp()
+		otherApp   = testApp()
+		receipts   = receipt.MemService()
+		record     = ReceiptRecord(receipts)
+		fn         = ReceiptFail(receipts)
+	)
+
+	for _, a := range []uint64{currentApp.ID, otherApp.ID} {
+		_, err := receipts.Put(pg.MetaNamespace, &receipt.Receipt{
+			AppID:     a,
+			DeviceID:  1,
+			MessageID: "message-1",
+			Provider:  "sns",
+			Recipient: 2,
+			Status:    receipt.StatusSent,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := record(currentApp, &receipt.Receipt{
+		DeviceID:  1,
+		MessageID: "message-2",
+		Provider:  "sns",
+		Recipient: 2,
+		Status:    receipt.StatusSent,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fn(currentApp, "message-1", "EndpointDisabled"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unknown messages are ignored.
+	if err := fn(currentApp, "message-3", "EndpointDisabled"); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := receipts.Query(pg.MetaNamespace, receipt.QueryOptions{
+		Statuses: []receipt.Status{
+			receipt.StatusFailed,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(rs), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := rs[0].AppID, currentApp.ID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := rs[0].Error, "EndpointDisabled"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestReceiptOpen(t *testing.T) {
+	var (
+		currentApp = testApp()
+		receipts   = receipt.MemService()
+		fn         = ReceiptOpen(receipts)
+	)
+
+	// The user got variant a on two devices.
+	for _, r := range []struct {
+		deviceID  uint64
+		variantID string
+	}{
+		{1, "a"},
+		{5, "a"},
+		{1, "b"},
+	} {
+		_, err := receipts.Put(pg.MetaNamespace, &receipt.Receipt{
+			AppID:     currentApp.ID,
+			DeviceID:  r.deviceID,
+			Provider:  "sns",
+			Recipient: 2,
+			RuleID:    3,
+			Status:    receipt.StatusSent,
+			VariantID: r.variantID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Repeated opens are only counted once.
+	for i := 0; i < 2; i++ {
+		if err := fn(currentApp, 2, 3, "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Opens without a matching delivery are ignored.
+	if err := fn(currentApp, 4, 3, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fn(currentApp, 2, 3, ""); !IsInvalidEntity(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidEntity)
+	}
+
+	cs, err := receipts.CountVariants(pg.MetaNamespace, receipt.QueryOptions{
+		AppIDs: []uint64{
+			currentApp.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := receipt.VariantCounts{
+		{Opened: 1, Sent: 1, VariantID: "a"},
+		{Opened: 0, Sent: 1, VariantID: "b"},
+	}
+
+	if have := cs; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+}
@@ -1,10 +1,44 @@
 package core
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	serr "github.com/tapglue/snaas/error"
 	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/connection"
+	"github.com/tapglue/snaas/service/event"
+	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/reaction"
 	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/user"
+)
+
+// schedulePage is the number of users looked at per query when evaluating a
+// scheduled user rule.
+const schedulePage = 500
+
+var (
+	defaultScheduled   = true
+	defaultUnscheduled = false
 )
 
+// ruleQueryConds are the recipient query conditions supported per rule type.
+var ruleQueryConds = map[rule.Type][]string{
+	rule.TypeConnection: {queryCondUserFrom, queryCondUserTo},
+	rule.TypeEvent:      {queryCondParentOwner},
+	rule.TypeObject: {
+		queryCondObjectOwner,
+		queryCondOwner,
+		queryCondOwnerFriends,
+		queryCondParentOwner,
+	},
+	rule.TypeReaction: {queryCondParentOwner},
+	rule.TypeUser:     {queryCondFollowers, queryCondFriends, queryCondSelf},
+}
+
 // RuleActivateFunc puts the rule in an active state.
 type RuleActivateFunc func(appID, id uint64) error
 
@@ -34,6 +68,30 @@ func RuleActivate(
 	}
 }
 
+// RuleCreateFunc stores a new rule after validating it against the context it
+// is rendered with.
+type RuleCreateFunc func(appID uint64, r *rule.Rule) (*rule.Rule, error)
+
+// RuleCreate stores a new rule after validating it against the context it is
+// rendered with.
+func RuleCreate(apps app.Service, rules rule.Service) RuleCreateFunc {
+	return func(appID uint64, r *rule.Rule) (*rule.Rule, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ruleValidate(r); err != nil {
+			return nil, err
+		}
+
+		r.Deleted = false
+		r.ID = 0
+
+		return rules.Put(currentApp.Namespace(), r)
+	}
+}
+
 // RuleDeactivateFunc puts the rule in an inactive state.
 type RuleDeactivateFunc func(appID, id uint64) error
 
@@ -139,18 +197,617 @@ func RuleList(apps app.Service, rules rule.Service) RuleListFunc {
 	}
 }
 
-// RuleListActiveFunc returns all active rules for the current App.
+// RuleDryRun is the outcome of a dry-run of a rule against a state change.
+type RuleDryRun struct {
+	Matched    bool
+	Messages   Messages
+	Recipients user.List
+}
+
+// RulePreviewInput selects the rule and the state change for a dry-run. Either
+// the RuleID of a stored rule or an unsaved Rule has to be given, as well as
+// either the EntityID of a stored entity or a JSON encoded sample New and
+// optional Old version of the entity.
+type RulePreviewInput struct {
+	EntityID uint64
+	New      json.RawMessage
+	Old      json.RawMessage
+	Rule     *rule.Rule
+	RuleID   uint64
+}
+
+// RulePreviewFunc runs the pipeline matching the rule type without delivering
+// any Messages.
+type RulePreviewFunc func(appID uint64, input RulePreviewInput) (*RuleDryRun, error)
+
+// RulePreview runs the pipeline matching the rule type without delivering any
+// Messages.
+func RulePreview(
+	apps app.Service,
+	connections connection.Service,
+	events event.Service,
+	objects object.Service,
+	reactions reaction.Service,
+	rules rule.Service,
+	users user.Service,
+) RulePreviewFunc {
+	return func(appID uint64, input RulePreviewInput) (*RuleDryRun, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		r := input.Rule
+
+		if input.RuleID != 0 {
+			r, err = RuleFetch(apps, rules)(appID, input.RuleID)
+			if err != nil {
+				return nil, err
+			}
+		} else if r == nil {
+			return nil, wrapError(ErrInvalidEntity, "rule or rule id missing")
+		} else if err := ruleValidate(r); err != nil {
+			return nil, err
+		}
+
+		if input.EntityID == 0 && len(input.New) == 0 {
+			return nil, wrapError(ErrInvalidEntity, "entity id or sample missing")
+		}
+
+		return rulePreview(
+			connections,
+			events,
+			objects,
+			reactions,
+			users,
+		)(currentApp, r, input)
+	}
+}
+
+// RuleUpdateFunc replaces the rule with the given id after validating the new
+// version against the context it is rendered with.
+type RuleUpdateFunc func(appID, id uint64, r *rule.Rule) (*rule.Rule, error)
+
+// RuleUpdate replaces the rule with the given id after validating the new
+// version against the context it is rendered with.
+func RuleUpdate(apps app.Service, rules rule.Service) RuleUpdateFunc {
+	return func(appID, id uint64, r *rule.Rule) (*rule.Rule, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		old, err := RuleFetch(apps, rules)(appID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ruleValidate(r); err != nil {
+			return nil, err
+		}
+
+		r.CreatedAt = old.CreatedAt
+		r.Deleted = old.Deleted
+		r.ID = old.ID
+
+		return rules.Put(currentApp.Namespace(), r)
+	}
+}
+
+// RuleListActiveFunc returns all active rules for the current App which are
+// triggered by state changes.
 type RuleListActiveFunc func(*app.App, rule.Type) (rule.List, error)
 
-// RuleListActive returns all active rules for the current App.
+// RuleListActive returns all active rules for the current App which are
+// triggered by state changes.
 func RuleListActive(rules rule.Service) RuleListActiveFunc {
 	return func(currentApp *app.App, ruleType rule.Type) (rule.List, error) {
 		return rules.Query(currentApp.Namespace(), rule.QueryOptions{
-			Active:  &defaultActive,
-			Deleted: &defaultDeleted,
+			Active:    &defaultActive,
+			Deleted:   &defaultDeleted,
+			Scheduled: &defaultUnscheduled,
 			Types: []rule.Type{
 				ruleType,
 			},
 		})
 	}
 }
+
+// RuleListScheduledFunc returns all active scheduled rules for the current App.
+type RuleListScheduledFunc func(*app.App) (rule.List, error)
+
+// RuleListScheduled returns all active scheduled rules for the current App.
+func RuleListScheduled(rules rule.Service) RuleListScheduledFunc {
+	return func(currentApp *app.App) (rule.List, error) {
+		return rules.Query(currentApp.Namespace(), rule.QueryOptions{
+			Active:    &defaultActive,
+			Deleted:   &defaultDeleted,
+			Scheduled: &defaultScheduled,
+		})
+	}
+}
+
+// RuleScheduleFunc evaluates a scheduled rule for its run at to, covering the
+// time since its previous run at from.
+type RuleScheduleFunc func(
+	currentApp *app.App,
+	r *rule.Rule,
+	from, to time.Time,
+) (Messages, error)
+
+// RuleSchedule evaluates a scheduled rule for its run at to, covering the time
+// since its previous run at from. The entities the schedule considers are fed
+// as creations through the pipeline of the rule type.
+func RuleSchedule(
+	connections connection.Service,
+	objects object.Service,
+	users user.Service,
+) RuleScheduleFunc {
+	var (
+		pipelineObject = PipelineObject(connections, objects, users)
+		pipelineUser   = PipelineUser(connections, users)
+	)
+
+	return func(
+		currentApp *app.App,
+		r *rule.Rule,
+		from, to time.Time,
+	) (Messages, error) {
+		if r.Schedule == nil {
+			return nil, wrapError(ErrInvalidEntity, "rule %d is not scheduled", r.ID)
+		}
+
+		var (
+			inactivity = time.Duration(r.Schedule.Inactivity) * time.Second
+			ms         = Messages{}
+			ns         = currentApp.Namespace()
+		)
+
+		switch r.Type {
+		case rule.TypeObject:
+			opts := object.QueryOptions{
+				After:  from.Add(-inactivity),
+				Before: to.Add(-inactivity),
+			}
+
+			if c, ok := r.Criteria.(*rule.CriteriaObject); ok && c.New != nil {
+				opts.Owned = c.New.Owned
+				opts.Types = c.New.Types
+			}
+
+			os, err := objects.Query(ns, opts)
+			if err != nil {
+				return nil, err
+			}
+
+			if inactivity > 0 {
+				os, err = objectsUncommented(objects, ns, os)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			for _, o := range os {
+				out, err := pipelineObject(currentApp, &object.StateChange{New: o}, r)
+				if err != nil {
+					return nil, err
+				}
+
+				ms = append(ms, out...)
+			}
+		case rule.TypeUser:
+			opts := user.QueryOptions{
+				Deleted: &defaultDeleted,
+				Enabled: &defaultEnabled,
+				Limit:   schedulePage,
+			}
+
+			if inactivity > 0 {
+				opts.LastReadAfter = from.Add(-inactivity)
+				opts.LastReadBefore = to.Add(-inactivity)
+			}
+
+			for {
+				us, err := users.Query(ns, opts)
+				if err != nil {
+					return nil, err
+				}
+
+				for _, u := range us {
+					out, err := pipelineUser(currentApp, &user.StateChange{New: u}, r)
+					if err != nil {
+						return nil, err
+					}
+
+					ms = append(ms, out...)
+				}
+
+				if len(us) < schedulePage {
+					break
+				}
+
+				opts.After = us[len(us)-1].ID
+			}
+		default:
+			return nil, wrapError(
+				ErrInvalidEntity,
+				"schedules are not supported for %s rules",
+				r.Type,
+			)
+		}
+
+		return ms, nil
+	}
+}
+
+// objectsUncommented filters os down to the objects without any comments.
+func objectsUncommented(
+	objects object.Service,
+	ns string,
+	os object.List,
+) (object.List, error) {
+	if len(os) == 0 {
+		return os, nil
+	}
+
+	ids := []uint64{}
+
+	for _, o := range os {
+		ids = append(ids, o.ID)
+	}
+
+	counts, err := objects.CountMulti(ns, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := object.List{}
+
+	for _, o := range os {
+		if counts[o.ID].Comments == 0 {
+			fs = append(fs, o)
+		}
+	}
+
+	return fs, nil
+}
+
+// ruleContext returns a populated sample of the context the templates of a
+// rule with the given type are rendered with.
+func ruleContext(t rule.Type) interface{} {
+	switch t {
+	case rule.TypeConnection:
+		return &contextConnection{
+			Conenction: &connection.Connection{},
+			From:       &user.User{},
+			To:         &user.User{},
+		}
+	case rule.TypeEvent:
+		return &contextEvent{
+			Event:       &event.Event{},
+			Owner:       &user.User{},
+			Parent:      &object.Object{},
+			ParentOwner: &user.User{},
+		}
+	case rule.TypeObject:
+		return &contextObject{
+			Attachments: map[string]object.Contents{},
+			Object:      &object.Object{},
+			Owner:       &user.User{},
+			Parent:      &object.Object{},
+			ParentOwner: &user.User{},
+		}
+	case rule.TypeReaction:
+		return &contextReaction{
+			Owner:       &user.User{},
+			Parent:      &object.Object{},
+			ParentOwner: &user.User{},
+			Reaction:    &reaction.Reaction{},
+		}
+	case rule.TypeUser:
+		return &contextUser{
+			User: &user.User{},
+		}
+	}
+
+	return nil
+}
+
+type rulePreviewFunc func(
+	*app.App,
+	*rule.Rule,
+	RulePreviewInput,
+) (*RuleDryRun, error)
+
+// rulePreview constructs the state change for the rule type from the input
+// and feeds it through the same pipeline sims uses.
+func rulePreview(
+	connections connection.Service,
+	events event.Service,
+	objects object.Service,
+	reactions reaction.Service,
+	users user.Service,
+) rulePreviewFunc {
+	return func(
+		currentApp *app.App,
+		r *rule.Rule,
+		input RulePreviewInput,
+	) (*RuleDryRun, error) {
+		var (
+			ns = currentApp.Namespace()
+
+			change interface{}
+			ms     Messages
+		)
+
+		switch r.Type {
+		case rule.TypeConnection:
+			if input.EntityID != 0 {
+				return nil, wrapError(
+					ErrInvalidEntity,
+					"connections can only be previewed with a sample",
+				)
+			}
+
+			c := &connection.StateChange{}
+
+			if err := decodeSample(input, &c.Old, &c.New); err != nil {
+				return nil, err
+			}
+
+			change = c
+
+			res, err := PipelineConnection(users)(currentApp, c, r)
+			if err != nil {
+				return nil, err
+			}
+
+			ms = res
+		case rule.TypeEvent:
+			c := &event.StateChange{}
+
+			if input.EntityID != 0 {
+				es, err := events.Query(ns, event.QueryOptions{
+					IDs: []uint64{input.EntityID},
+				})
+				if err != nil {
+					return nil, err
+				}
+
+				if len(es) != 1 {
+					return nil, wrapError(ErrNotFound, "event (%d) not found", input.EntityID)
+				}
+
+				c.New = es[0]
+			} else if err := decodeSample(input, &c.Old, &c.New); err != nil {
+				return nil, err
+			}
+
+			change = c
+
+			res, err := PipelineEvent(objects, users)(currentApp, c, r)
+			if err != nil {
+				return nil, err
+			}
+
+			ms = res
+		case rule.TypeObject:
+			c := &object.StateChange{}
+
+			if input.EntityID != 0 {
+				o, err := objectFetch(objects)(currentApp, input.EntityID)
+				if err != nil {
+					if serr.IsNotFound(err) {
+						return nil, wrapError(ErrNotFound, "object (%d) not found", input.EntityID)
+					}
+
+					return nil, err
+				}
+
+				c.New = o
+			} else if err := decodeSample(input, &c.Old, &c.New); err != nil {
+				return nil, err
+			}
+
+			change = c
+
+			res, err := PipelineObject(connections, objects, users)(currentApp, c, r)
+			if err != nil {
+				return nil, err
+			}
+
+			ms = res
+		case rule.TypeReaction:
+			c := &reaction.StateChange{}
+
+			if input.EntityID != 0 {
+				rs, err := reactions.Query(ns, reaction.QueryOptions{
+					IDs: []uint64{input.EntityID},
+				})
+				if err != nil {
+					return nil, err
+				}
+
+				if len(rs) != 1 {
+					return nil, wrapError(ErrNotFound, "reaction (%d) not found", input.EntityID)
+				}
+
+				c.New = rs[0]
+			} else if err := decodeSample(input, &c.Old, &c.New); err != nil {
+				return nil, err
+			}
+
+			change = c
+
+			res, err := PipelineReaction(objects, users)(currentApp, c, r)
+			if err != nil {
+				return nil, err
+			}
+
+			ms = res
+		case rule.TypeUser:
+			c := &user.StateChange{}
+
+			if input.EntityID != 0 {
+				u, err := UserFetch(users)(currentApp, input.EntityID)
+				if err != nil {
+					return nil, err
+				}
+
+				c.New = u
+			} else if err := decodeSample(input, &c.Old, &c.New); err != nil {
+				return nil, err
+			}
+
+			change = c
+
+			res, err := PipelineUser(connections, users)(currentApp, c, r)
+			if err != nil {
+				return nil, err
+			}
+
+			ms = res
+		default:
+			return nil, wrapError(ErrInvalidEntity, "unsupported rule type %s", r.Type)
+		}
+
+		ids := []uint64{}
+
+		for _, msg := range ms {
+			ids = append(ids, msg.Recipient)
+		}
+
+		us, err := user.ListFromIDs(users, ns, ids...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &RuleDryRun{
+			Matched:    r.Criteria.Match(change),
+			Messages:   ms,
+			Recipients: us,
+		}, nil
+	}
+}
+
+// decodeSample unmarshals the sample entities of the input into old and new,
+// old is left untouched if no sample for it is given.
+func decodeSample(input RulePreviewInput, old, new interface{}) error {
+	if bytes.Equal(bytes.TrimSpace(input.New), []byte("null")) {
+		return wrapError(ErrInvalidEntity, "sample new can't be null")
+	}
+
+	if err := json.Unmarshal(input.New, new); err != nil {
+		return wrapError(ErrInvalidEntity, "sample new: %s", err)
+	}
+
+	if len(input.Old) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(input.Old, old); err != nil {
+		return wrapError(ErrInvalidEntity, "sample old: %s", err)
+	}
+
+	return nil
+}
+
+// ruleValidate checks the rule for semantic correctness. Beyond the checks of
+// the rule itself all recipient query conditions need to be supported for the
+// rule type and every template needs to render against its context.
+func ruleValidate(r *rule.Rule) error {
+	if err := r.Validate(); err != nil {
+		return wrapError(ErrInvalidEntity, "%s", err)
+	}
+
+	context := ruleContext(r.Type)
+
+	for i, recipient := range r.Recipients {
+		if len(recipient.Query) == 0 {
+			return wrapError(ErrInvalidEntity, "recipient %d: query missing", i)
+		}
+
+		for cond, condTemplate := range recipient.Query {
+			if !containsString(ruleQueryConds[r.Type], cond) {
+				return wrapError(
+					ErrInvalidEntity,
+					"recipient %d: unsupported query condition '%s' for %s rules",
+					i,
+					cond,
+					r.Type,
+				)
+			}
+
+			if cond != queryCondObjectOwner {
+				continue
+			}
+
+			_, err := queryOptsFromTemplate(context.(*contextObject), condTemplate)
+			if err != nil {
+				return wrapError(
+					ErrInvalidEntity,
+					"recipient %d: query condition '%s': %s",
+					i,
+					cond,
+					err,
+				)
+			}
+		}
+
+		if _, err := compileTemplate(context, recipient.URN); err != nil {
+			return wrapError(ErrInvalidEntity, "recipient %d: urn: %s", i, err)
+		}
+
+		ts := map[string]rule.Templates{
+			"templates":     recipient.Templates,
+			"email.body":    recipient.Email.Body,
+			"email.subject": recipient.Email.Subject,
+		}
+
+		for _, v := range recipient.Variants {
+			ts[fmt.Sprintf("variants.%s.templates", v.ID)] = v.Templates
+			ts[fmt.Sprintf("variants.%s.titles", v.ID)] = v.Titles
+		}
+
+		for name, templates := range ts {
+			if _, err := compileTemplates(context, templates); err != nil {
+				return wrapError(ErrInvalidEntity, "recipient %d: %s: %s", i, name, err)
+			}
+		}
+
+		if _, err := compilePush(context, recipient.Push); err != nil {
+			return wrapError(ErrInvalidEntity, "recipient %d: push: %s", i, err)
+		}
+
+		if recipient.Aggregate == nil {
+			continue
+		}
+
+		_, err := compileTemplates(&contextAggregate{
+			Actors:  []*user.User{{}},
+			Context: context,
+			Count:   2,
+			Others:  1,
+		}, recipient.Aggregate.Templates)
+		if err != nil {
+			return wrapError(
+				ErrInvalidEntity,
+				"recipient %d: aggregate.templates: %s",
+				i,
+				err,
+			)
+		}
+	}
+
+	return nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, str := range ss {
+		if str == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,393 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tapglue/snaas/service/connection"
+	"github.com/tapglue/snaas/service/event"
+	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/reaction"
+	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/user"
+)
+
+func TestRulePreview(t *testing.T) {
+	var (
+		currentApp  = testApp()
+		connections = connection.MemService()
+		events      = event.MemService()
+		objects     = object.MemService()
+		reactions   = reaction.MemService()
+		users       = user.MemService()
+		preview     = rulePreview(connections, events, objects, reactions, users)
+	)
+
+	postOwner, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post, err := objects.Put(currentApp.Namespace(), testPost(postOwner.ID).Object)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commenter, err := users.Put(currentApp.Namespace(), testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &rule.Rule{
+		Criteria: &rule.CriteriaObject{
+			Expression: "new.type == 'tg_comment'",
+		},
+		Name: "Comment",
+		Recipients: rule.Recipients{
+			{
+				Query: rule.Query{
+					queryCondParentOwner: "",
+				},
+				Templates: rule.Templates{
+					"en": "{{.Owner.Username}} commented on your post",
+				},
+				URN: "tapglue/posts/{{.Parent.ID}}",
+			},
+		},
+		Type: rule.TypeObject,
+	}
+
+	sample := fmt.Sprintf(
+		`{"object_id": %d, "owner_id": %d, "type": "tg_comment", "visibility": 30}`,
+		post.ID,
+		commenter.ID,
+	)
+
+	if err := ruleValidate(r); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := preview(currentApp, r, RulePreviewInput{New: []byte(sample)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := p.Matched, true; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := len(p.Messages), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := p.Messages[0].Messages["en"], fmt.Sprintf("%s commented on your post", commenter.Username); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := p.Messages[0].URN, fmt.Sprintf("tapglue/posts/%d", post.ID); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := len(p.Recipients), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := p.Recipients[0].ID, postOwner.ID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	// Stored entity which doesn't match the criteria.
+	p, err = preview(currentApp, r, RulePreviewInput{EntityID: post.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := p.Matched, false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := len(p.Messages), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	_, err = preview(currentApp, r, RulePreviewInput{EntityID: post.ID + 1})
+	if have, want := err, ErrNotFound; !IsNotFound(have) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestRuleScheduleObjectInactivity(t *testing.T) {
+	var (
+		currentApp  = testApp()
+		connections = connection.MemService()
+		objects     = object.MemService()
+		users       = user.MemService()
+		to          = time.Now().UTC().Truncate(time.Minute)
+		from        = to.Add(-time.Hour)
+		ns          = currentApp.Namespace()
+	)
+
+	owner, err := users.Put(ns, testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Created within the window, without comments.
+	silent := testPost(owner.ID).Object
+	silent.CreatedAt = from.Add(-24*time.Hour + 10*time.Minute)
+
+	// Created within the window, but commented on.
+	busy := testPost(owner.ID).Object
+	busy.CreatedAt = from.Add(-24*time.Hour + 20*time.Minute)
+
+	// Crossed the threshold before the previous run.
+	old := testPost(owner.ID).Object
+	old.CreatedAt = from.Add(-25 * time.Hour)
+
+	for _, o := range []*object.Object{silent, busy, old} {
+		if _, err := objects.Put(ns, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := objects.Put(ns, testComment(owner.ID, busy)); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &rule.Rule{
+		Criteria: &rule.CriteriaObject{
+			New: &object.QueryOptions{
+				Owned: &defaultOwned,
+				Types: []string{
+					TypePost,
+				},
+			},
+		},
+		Recipients: rule.Recipients{
+			{
+				Query: rule.Query{
+					"owner": "",
+				},
+				Templates: rule.Templates{
+					"en": "Nobody commented yet",
+				},
+				URN: "tapglue/posts/{{.Object.ID}}",
+			},
+		},
+		Schedule: &rule.Schedule{
+			Cron:       "0 * * * *",
+			Inactivity: 24 * 60 * 60,
+		},
+		Type: rule.TypeObject,
+	}
+
+	ms, err := RuleSchedule(connections, objects, users)(currentApp, r, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ms), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := ms[0].URN, fmt.Sprintf("tapglue/posts/%d", silent.ID); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestRuleScheduleUserInactivity(t *testing.T) {
+	var (
+		currentApp  = testApp()
+		connections = connection.MemService()
+		objects     = object.MemService()
+		users       = user.MemService()
+		to          = time.Now().UTC().Truncate(time.Minute)
+		from        = to.Add(-time.Hour)
+		ns          = currentApp.Namespace()
+		week        = 7 * 24 * time.Hour
+	)
+
+	lastReads := []time.Time{
+		from.Add(-week + 30*time.Minute), // Crossed the threshold since from.
+		to.Add(-time.Hour * 24),          // Still active.
+		from.Add(-week - time.Minute),    // Crossed the threshold before from.
+	}
+
+	us := user.List{}
+
+	for _, lastRead := range lastReads {
+		u, err := users.Put(ns, testUser())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := users.PutLastRead(ns, u.ID, lastRead); err != nil {
+			t.Fatal(err)
+		}
+
+		us = append(us, u)
+	}
+
+	r := &rule.Rule{
+		Criteria: &rule.CriteriaUser{},
+		Recipients: rule.Recipients{
+			{
+				Query: rule.Query{
+					"self": "",
+				},
+				Templates: rule.Templates{
+					"en": "We miss you {{.User.Username}}",
+				},
+				URN: "tapglue/users/{{.User.ID}}",
+			},
+		},
+		Schedule: &rule.Schedule{
+			Cron:       "0 * * * *",
+			Inactivity: int(week / time.Second),
+		},
+		Type: rule.TypeUser,
+	}
+
+	ms, err := RuleSchedule(connections, objects, users)(currentApp, r, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ms), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := ms[0].Recipient, us[0].ID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestRuleScheduleUserPaged(t *testing.T) {
+	var (
+		currentApp  = testApp()
+		connections = connection.MemService()
+		objects     = object.MemService()
+		users       = user.MemService()
+		to          = time.Now().UTC().Truncate(time.Minute)
+		from        = to.Add(-time.Hour)
+		ns          = currentApp.Namespace()
+	)
+
+	for i := 0; i < schedulePage+1; i++ {
+		if _, err := users.Put(ns, testUser()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &rule.Rule{
+		Criteria: &rule.CriteriaUser{
+			Expression: "new.enabled",
+		},
+		Name: "Digest",
+		Recipients: rule.Recipients{
+			{
+				Query: rule.Query{
+					"self": "",
+				},
+				Templates: rule.Templates{
+					"en": "Your weekly digest {{.User.Username}}",
+				},
+				URN: "tapglue/users/{{.User.ID}}",
+			},
+		},
+		Schedule: &rule.Schedule{
+			Cron: "0 * * * *",
+		},
+		Type: rule.TypeUser,
+	}
+
+	if err := r.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ms, err := RuleSchedule(connections, objects, users)(currentApp, r, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ms), schedulePage+1; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	valid := func() *rule.Rule {
+		return &rule.Rule{
+			Criteria: &rule.CriteriaObject{
+				New: &object.QueryOptions{
+					Types: []string{object.TypeComment},
+				},
+			},
+			Name: "Comment",
+			Recipients: rule.Recipients{
+				{
+					Aggregate: &rule.Aggregate{
+						Templates: rule.Templates{
+							"en": "{{.Count}} comments on {{.Context.Parent.ID}}",
+						},
+					},
+					Query: rule.Query{
+						queryCondObjectOwner: `{ "object_ids": [ {{.Parent.ID}} ], "owned": true }`,
+						queryCondParentOwner: "",
+					},
+					Templates: rule.Templates{
+						"en": "{{.Owner.Username}} commented on your post",
+					},
+					URN: "tapglue/posts/{{.Parent.ID}}/comments/{{.Object.ID}}",
+				},
+			},
+			Type: rule.TypeObject,
+		}
+	}
+
+	if err := ruleValidate(valid()); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []func(r *rule.Rule){
+		// Criteria not matching the type.
+		func(r *rule.Rule) { r.Type = rule.TypeEvent },
+		// Missing query.
+		func(r *rule.Rule) { r.Recipients[0].Query = nil },
+		// Unsupported query condition for type.
+		func(r *rule.Rule) { r.Recipients[0].Query[queryCondUserFrom] = "" },
+		// Query template not producing query options.
+		func(r *rule.Rule) { r.Recipients[0].Query[queryCondObjectOwner] = "{{.Parent.ID}}" },
+		// Unknown field in template.
+		func(r *rule.Rule) { r.Recipients[0].Templates["de"] = "{{.Owner.Nickname}}" },
+		// Unknown field in URN.
+		func(r *rule.Rule) { r.Recipients[0].URN = "tapglue/posts/{{.Post.ID}}" },
+		// Unknown field in email subject.
+		func(r *rule.Rule) {
+			r.Recipients[0].Email.Subject = rule.Templates{"en": "{{.Reaction.ID}}"}
+		},
+		// Unknown field in aggregate template.
+		func(r *rule.Rule) {
+			r.Recipients[0].Aggregate.Templates["en"] = "{{.Context.Event.ID}}"
+		},
+		// Unknown field in push title.
+		func(r *rule.Rule) {
+			r.Recipients[0].Push.Titles = rule.Templates{"en": "{{.Owner.Nickname}}"}
+		},
+		// Unknown field in push data.
+		func(r *rule.Rule) {
+			r.Recipients[0].Push.Data = map[string]string{"post": "{{.Post.ID}}"}
+		},
+	}
+
+	for i, c := range cases {
+		r := valid()
+
+		c(r)
+
+		if have, want := ruleValidate(r), ErrInvalidEntity; !IsInvalidEntity(have) {
+			t.Errorf("case %d: have %v, want %v", i, have, want)
+		}
+	}
+}
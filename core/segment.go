@@ -0,0 +1,233 @@
+package core
+
+import (
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/segment"
+	"github.com/tapglue/snaas/service/user"
+)
+
+// SegmentCreateFunc stores a new Segment and computes its members.
+type SegmentCreateFunc func(
+	appID uint64,
+	s *segment.Segment,
+) (*segment.Segment, error)
+
+// SegmentCreate stores a new Segment and computes its members.
+func SegmentCreate(
+	apps app.Service,
+	segments segment.Service,
+) SegmentCreateFunc {
+	return func(appID uint64, s *segment.Segment) (*segment.Segment, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		s.ID = 0
+
+		if err := s.Validate(); err != nil {
+			return nil, wrapError(ErrInvalidEntity, "%s", err)
+		}
+
+		created, err := segments.Put(currentApp.Namespace(), s)
+		if err != nil {
+			return nil, err
+		}
+
+		return segments.Refresh(currentApp.Namespace(), created)
+	}
+}
+
+// SegmentFetchFunc returns the Segment for the given id.
+type SegmentFetchFunc func(appID, id uint64) (*segment.Segment, error)
+
+// SegmentFetch returns the Segment for the given id.
+func SegmentFetch(
+	apps app.Service,
+	segments segment.Service,
+) SegmentFetchFunc {
+	return func(appID, id uint64) (*segment.Segment, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		ss, err := segments.Query(currentApp.Namespace(), segment.QueryOptions{
+			IDs: []uint64{
+				id,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ss) != 1 {
+			return nil, wrapError(ErrNotFound, "segment (%d) not found", id)
+		}
+
+		return ss[0], nil
+	}
+}
+
+// SegmentListFunc returns all Segments of the App.
+type SegmentListFunc func(appID uint64) (segment.List, error)
+
+// SegmentList returns all Segments of the App.
+func SegmentList(
+	apps app.Service,
+	segments segment.Service,
+) SegmentListFunc {
+	return func(appID uint64) (segment.List, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		return segments.Query(currentApp.Namespace(), segment.QueryOptions{})
+	}
+}
+
+// SegmentMembersFunc returns the cached members of the Segment after the given
+// user id.
+type SegmentMembersFunc func(
+	appID, id, after uint64,
+	limit int,
+) (user.List, error)
+
+// SegmentMembers returns up to limit cached members of the Segment after the
+// given user id, in ascending order of their ids. Members who were disabled
+// since the last refresh are left out.
+func SegmentMembers(
+	apps app.Service,
+	segments segment.Service,
+	users user.Service,
+) SegmentMembersFunc {
+	return func(appID, id, after uint64, limit int) (user.List, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := SegmentFetch(apps, segments)(currentApp.ID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids, err := segments.Members(
+			currentApp.Namespace(),
+			s.ID,
+			segment.MemberOptions{
+				After: after,
+				Limit: limit,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		um, err := user.MapFromIDs(users, currentApp.Namespace(), ids...)
+		if err != nil {
+			return nil, err
+		}
+
+		us := user.List{}
+
+		for _, id := range ids {
+			if u, ok := um[id]; ok {
+				us = append(us, u)
+			}
+		}
+
+		return us, nil
+	}
+}
+
+// SegmentPreviewFunc returns the number of users matching the Definition.
+type SegmentPreviewFunc func(appID uint64, d segment.Definition) (int, error)
+
+// SegmentPreview returns the number of users currently matching the
+// Definition, without storing it.
+func SegmentPreview(
+	apps app.Service,
+	segments segment.Service,
+) SegmentPreviewFunc {
+	return func(appID uint64, d segment.Definition) (int, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := d.Validate(); err != nil {
+			return 0, wrapError(ErrInvalidEntity, "%s", err)
+		}
+
+		return segments.Count(currentApp.Namespace(), d)
+	}
+}
+
+// SegmentRefreshFunc brings the cached members of the Segment up to date.
+type SegmentRefreshFunc func(appID, id uint64) (*segment.Segment, error)
+
+// SegmentRefresh brings the cached members of the Segment up to date.
+func SegmentRefresh(
+	apps app.Service,
+	segments segment.Service,
+) SegmentRefreshFunc {
+	return func(appID, id uint64) (*segment.Segment, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := SegmentFetch(apps, segments)(currentApp.ID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return segments.Refresh(currentApp.Namespace(), s)
+	}
+}
+
+// SegmentUpdateFunc changes name and Definition of the Segment.
+type SegmentUpdateFunc func(
+	appID uint64,
+	id uint64,
+	s *segment.Segment,
+) (*segment.Segment, error)
+
+// SegmentUpdate changes name and Definition of the Segment, its members are
+// refreshed right away.
+func SegmentUpdate(
+	apps app.Service,
+	segments segment.Service,
+) SegmentUpdateFunc {
+	return func(
+		appID uint64,
+		id uint64,
+		s *segment.Segment,
+	) (*segment.Segment, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		current, err := SegmentFetch(apps, segments)(currentApp.ID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		current.Definition = s.Definition
+		current.Name = s.Name
+
+		if err := current.Validate(); err != nil {
+			return nil, wrapError(ErrInvalidEntity, "%s", err)
+		}
+
+		updated, err := segments.Put(currentApp.Namespace(), current)
+		if err != nil {
+			return nil, err
+		}
+
+		return segments.Refresh(currentApp.Namespace(), updated)
+	}
+}
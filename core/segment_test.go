@@ -0,0 +1,102 @@
+//go:build integration
+// +build integration
+
+package core
+
+import (
+	"flag"
+	"fmt"
+	osUser "os/user"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/segment"
+	"github.com/tapglue/snaas/service/user"
+)
+
+var pgTestURL string
+
+func TestSegmentLastRead(t *testing.T) {
+	db, err := sqlx.Connect("postgres", pgTestURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		currentApp = testApp()
+		ns         = currentApp.Namespace()
+		segments   = segment.PostgresService(db)
+		users      = user.PostgresService(db)
+	)
+
+	if err := segments.Teardown(ns); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := users.Teardown(ns); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := users.Put(ns, testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := users.Put(ns, testUser()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The inbox is read through the same path the API uses.
+	if err := NotificationReadAll(users)(currentApp, reader.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []segment.Condition{
+		{Attribute: segment.AttributeLastRead, Op: segment.OpExists},           // Ever read
+		{Attribute: segment.AttributeLastRead, Op: segment.OpGt, Window: 3600}, // Read within the last hour
+	}
+
+	for _, c := range cases {
+		s, err := segments.Put(ns, &segment.Segment{
+			Definition: segment.Definition{
+				All: []segment.Condition{c},
+			},
+			Name: "Readers",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, err = segments.Refresh(ns, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids, err := segments.Members(ns, s.ID, segment.MemberOptions{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := ids, []uint64{reader.ID}; !reflect.DeepEqual(have, want) {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func init() {
+	u, err := osUser.Current()
+	if err != nil {
+		panic(err)
+	}
+
+	d := fmt.Sprintf(pg.URLTest, u.Username)
+
+	url := flag.String("postgres.url", d, "Postgres connection URL")
+	flag.Parse()
+
+	pgTestURL = *url
+}
@@ -576,6 +576,17 @@ func UserUpdate(
 			new.Private = old.Private
 		}
 
+		// Quiet hours are kept unless given, an empty window clears them.
+		if new.QuietHours == nil {
+			new.QuietHours = old.QuietHours
+		} else if new.QuietHours.IsZero() {
+			new.QuietHours = nil
+		}
+
+		if new.Timezone == "" {
+			new.Timezone = old.Timezone
+		}
+
 		u, err := users.Put(currentApp.Namespace(), new)
 		if err != nil {
 			return nil, err
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tapglue/snaas/platform/generate"
+	"github.com/tapglue/snaas/platform/quiet"
 	"github.com/tapglue/snaas/service/app"
 	"github.com/tapglue/snaas/service/connection"
 	"github.com/tapglue/snaas/service/session"
@@ -68,6 +69,58 @@ func TestUserUpdateConstrainPrivate(t *testing.T) {
 	}
 }
 
+func TestUserUpdateQuietHours(t *testing.T) {
+	var (
+		app         = testSetupUser()
+		connections = connection.MemService()
+		sessions    = session.MemService()
+		u           = testUser()
+		users       = user.MemService()
+		fn          = UserUpdate(connections, sessions, users)
+	)
+
+	u.QuietHours = &quiet.Hours{
+		End:   "07:00",
+		Start: "22:00",
+	}
+
+	created, err := users.Put(app.Namespace(), u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origin := Origin{
+		DeviceID:    "device",
+		Integration: IntegrationApplication,
+		UserID:      created.ID,
+	}
+
+	kept, err := fn(app, origin, created, &user.User{
+		Email:    created.Email,
+		Username: created.Username,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kept.QuietHours == nil {
+		t.Fatal("expected quiet hours to be kept")
+	}
+
+	cleared, err := fn(app, origin, kept, &user.User{
+		Email:      created.Email,
+		QuietHours: &quiet.Hours{},
+		Username:   created.Username,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cleared.QuietHours != nil {
+		t.Errorf("have %v, want %v", cleared.QuietHours, nil)
+	}
+}
+
 func TestPassword(t *testing.T) {
 	password := "foobar"
 
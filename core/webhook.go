@@ -0,0 +1,431 @@
+package core
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/app"
+	"github.com/tapglue/snaas/service/connection"
+	"github.com/tapglue/snaas/service/event"
+	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/reaction"
+	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/user"
+	"github.com/tapglue/snaas/service/webhook"
+)
+
+const webhookSecretLen = 32
+
+// WebhookCreateFunc stores a new Webhook for the App. A secret is generated if
+// none is provided.
+type WebhookCreateFunc func(appID uint64, w *webhook.Webhook) (*webhook.Webhook, error)
+
+// WebhookCreate stores a new Webhook for the App. A secret is generated if
+// none is provided.
+func WebhookCreate(
+	apps app.Service,
+	webhooks webhook.Service,
+) WebhookCreateFunc {
+	return func(appID uint64, w *webhook.Webhook) (*webhook.Webhook, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		if w.Secret == "" {
+			s, err := webhookSecret()
+			if err != nil {
+				return nil, err
+			}
+
+			w.Secret = s
+		}
+
+		w.Deleted = false
+		w.ID = 0
+
+		if err := w.Validate(); err != nil {
+			return nil, wrapError(ErrInvalidEntity, "%s", err)
+		}
+
+		return webhooks.Put(currentApp.Namespace(), w)
+	}
+}
+
+// WebhookDeleteFunc flags the Webhook as deleted, pending deliveries are
+// discarded on their next attempt.
+type WebhookDeleteFunc func(appID, id uint64) error
+
+// WebhookDelete flags the Webhook as deleted, pending deliveries are discarded
+// on their next attempt.
+func WebhookDelete(
+	apps app.Service,
+	webhooks webhook.Service,
+) WebhookDeleteFunc {
+	return func(appID, id uint64) error {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return err
+		}
+
+		w, err := WebhookFetch(apps, webhooks)(appID, id)
+		if err != nil {
+			return err
+		}
+
+		w.Active = false
+		w.Deleted = true
+
+		_, err = webhooks.Put(currentApp.Namespace(), w)
+		return err
+	}
+}
+
+// WebhookDeliverFunc hands due deliveries to their Webhook and returns how
+// many were attempted.
+type WebhookDeliverFunc func(limit int) (int, error)
+
+// WebhookDeliver hands due deliveries to their Webhook. Failed attempts are
+// retried with exponential backoff until the attempts are used up.
+func WebhookDeliver(
+	apps app.Service,
+	webhooks webhook.Service,
+	deliveries webhook.DeliveryService,
+	post webhook.PostFunc,
+	attempts int,
+	backoff time.Duration,
+) WebhookDeliverFunc {
+	return func(limit int) (int, error) {
+		ds, err := deliveries.Query(pg.MetaNamespace, webhook.DeliveryQueryOptions{
+			Due:   time.Now().UTC(),
+			Limit: limit,
+			States: []webhook.State{
+				webhook.StatePending,
+			},
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		for _, d := range ds {
+			w, err := webhookForDelivery(apps, webhooks, d)
+			if err != nil {
+				if !IsNotFound(err) {
+					return 0, err
+				}
+
+				d.Error = err.Error()
+				d.State = webhook.StateFailed
+
+				if _, err := deliveries.Put(pg.MetaNamespace, d); err != nil {
+					return 0, err
+				}
+
+				continue
+			}
+
+			code, err := post(w, d)
+
+			d.Attempts++
+			d.StatusCode = code
+
+			if err == nil {
+				d.Error = ""
+				d.State = webhook.StateSucceeded
+			} else {
+				d.Error = err.Error()
+
+				if d.Attempts >= attempts {
+					d.State = webhook.StateFailed
+				} else {
+					d.NextAttemptAt = time.Now().UTC().Add(
+						backoff * time.Duration(1<<uint(d.Attempts-1)),
+					)
+				}
+			}
+
+			if _, err := deliveries.Put(pg.MetaNamespace, d); err != nil {
+				return 0, err
+			}
+		}
+
+		return len(ds), nil
+	}
+}
+
+// WebhookDeliveryListFunc returns the most recent deliveries of the Webhook.
+type WebhookDeliveryListFunc func(
+	appID, id uint64,
+	limit int,
+) (webhook.Deliveries, error)
+
+// WebhookDeliveryList returns the most recent deliveries of the Webhook.
+func WebhookDeliveryList(
+	apps app.Service,
+	webhooks webhook.Service,
+	deliveries webhook.DeliveryService,
+) WebhookDeliveryListFunc {
+	return func(appID, id uint64, limit int) (webhook.Deliveries, error) {
+		w, err := WebhookFetch(apps, webhooks)(appID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return deliveries.Query(pg.MetaNamespace, webhook.DeliveryQueryOptions{
+			AppIDs: []uint64{
+				appID,
+			},
+			Limit: limit,
+			WebhookIDs: []uint64{
+				w.ID,
+			},
+		})
+	}
+}
+
+// WebhookEnqueueFunc records a pending delivery of the state change for every
+// active Webhook subscribed to it.
+type WebhookEnqueueFunc func(currentApp *app.App, change interface{}) error
+
+// WebhookEnqueue records a pending delivery of the state change for every
+// active Webhook subscribed to it.
+func WebhookEnqueue(
+	webhooks webhook.Service,
+	deliveries webhook.DeliveryService,
+) WebhookEnqueueFunc {
+	return func(currentApp *app.App, change interface{}) error {
+		t, name, old, new, ok := webhookChange(change)
+		if !ok {
+			return nil
+		}
+
+		ws, err := webhooks.Query(currentApp.Namespace(), webhook.QueryOptions{
+			Active:  &defaultActive,
+			Deleted: &defaultDeleted,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, w := range ws {
+			if !w.Match(t, change) {
+				continue
+			}
+
+			payload, err := json.Marshal(struct {
+				AppID   string      `json:"app_id"`
+				Event   string      `json:"event"`
+				New     interface{} `json:"new"`
+				Old     interface{} `json:"old"`
+				Webhook string      `json:"webhook_id"`
+			}{
+				AppID:   strconv.FormatUint(currentApp.ID, 10),
+				Event:   name,
+				New:     new,
+				Old:     old,
+				Webhook: strconv.FormatUint(w.ID, 10),
+			})
+			if err != nil {
+				return err
+			}
+
+			_, err = deliveries.Put(pg.MetaNamespace, &webhook.Delivery{
+				AppID:     currentApp.ID,
+				Event:     name,
+				Payload:   payload,
+				State:     webhook.StatePending,
+				WebhookID: w.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// WebhookFetchFunc returns the Webhook for the given appID and id.
+type WebhookFetchFunc func(appID, id uint64) (*webhook.Webhook, error)
+
+// WebhookFetch returns the Webhook for the given appID and id.
+func WebhookFetch(
+	apps app.Service,
+	webhooks webhook.Service,
+) WebhookFetchFunc {
+	return func(appID, id uint64) (*webhook.Webhook, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		ws, err := webhooks.Query(currentApp.Namespace(), webhook.QueryOptions{
+			Deleted: &defaultDeleted,
+			IDs: []uint64{
+				id,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ws) == 0 {
+			return nil, wrapError(ErrNotFound, "webhook (%d) not found", id)
+		}
+
+		return ws[0], nil
+	}
+}
+
+// WebhookListFunc returns all webhooks for the App.
+type WebhookListFunc func(appID uint64) (webhook.List, error)
+
+// WebhookList returns all webhooks for the App.
+func WebhookList(apps app.Service, webhooks webhook.Service) WebhookListFunc {
+	return func(appID uint64) (webhook.List, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		return webhooks.Query(currentApp.Namespace(), webhook.QueryOptions{
+			Deleted: &defaultDeleted,
+		})
+	}
+}
+
+// WebhookUpdateFunc replaces the mutable fields of an existing Webhook. The
+// secret is kept if none is provided.
+type WebhookUpdateFunc func(
+	appID, id uint64,
+	w *webhook.Webhook,
+) (*webhook.Webhook, error)
+
+// WebhookUpdate replaces the mutable fields of an existing Webhook. The secret
+// is kept if none is provided.
+func WebhookUpdate(
+	apps app.Service,
+	webhooks webhook.Service,
+) WebhookUpdateFunc {
+	return func(
+		appID, id uint64,
+		w *webhook.Webhook,
+	) (*webhook.Webhook, error) {
+		currentApp, err := AppFetch(apps)(appID)
+		if err != nil {
+			return nil, err
+		}
+
+		old, err := WebhookFetch(apps, webhooks)(appID, id)
+		if err != nil {
+			return nil, err
+		}
+
+		old.Active = w.Active
+		old.Name = w.Name
+		old.Subscriptions = w.Subscriptions
+		old.URL = w.URL
+
+		if w.Secret != "" {
+			old.Secret = w.Secret
+		}
+
+		if err := old.Validate(); err != nil {
+			return nil, wrapError(ErrInvalidEntity, "%s", err)
+		}
+
+		return webhooks.Put(currentApp.Namespace(), old)
+	}
+}
+
+func webhookChange(
+	change interface{},
+) (t rule.Type, name string, old, new interface{}, ok bool) {
+	switch c := change.(type) {
+	case *connection.StateChange:
+		return rule.TypeConnection, "connection", c.Old, c.New, true
+	case *event.StateChange:
+		return rule.TypeEvent, "event", c.Old, c.New, true
+	case *object.StateChange:
+		return rule.TypeObject, "object", c.Old, c.New, true
+	case *reaction.StateChange:
+		return rule.TypeReaction, "reaction", c.Old, c.New, true
+	case *user.StateChange:
+		return rule.TypeUser, "user", webhookUserFrom(c.Old), webhookUserFrom(c.New), true
+	}
+
+	return 0, "", nil, nil, false
+}
+
+// webhookUser is the representation of a User in webhook payloads. Secrets and
+// contact or private details are left out as payloads leave the platform.
+type webhookUser struct {
+	About     string                `json:"about"`
+	CustomID  string                `json:"custom_id,omitempty"`
+	Deleted   bool                  `json:"deleted"`
+	Enabled   bool                  `json:"enabled"`
+	Firstname string                `json:"first_name"`
+	ID        uint64                `json:"id"`
+	IDString  string                `json:"id_string"`
+	Images    map[string]user.Image `json:"images,omitempty"`
+	Lastname  string                `json:"last_name"`
+	Metadata  user.Metadata         `json:"metadata,omitempty"`
+	URL       string                `json:"url,omitempty"`
+	Username  string                `json:"user_name"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+func webhookUserFrom(u *user.User) *webhookUser {
+	if u == nil {
+		return nil
+	}
+
+	return &webhookUser{
+		About:     u.About,
+		CustomID:  u.CustomID,
+		Deleted:   u.Deleted,
+		Enabled:   u.Enabled,
+		Firstname: u.Firstname,
+		ID:        u.ID,
+		IDString:  strconv.FormatUint(u.ID, 10),
+		Images:    u.Images,
+		Lastname:  u.Lastname,
+		Metadata:  u.Metadata,
+		URL:       u.URL,
+		Username:  u.Username,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+func webhookForDelivery(
+	apps app.Service,
+	webhooks webhook.Service,
+	d *webhook.Delivery,
+) (*webhook.Webhook, error) {
+	w, err := WebhookFetch(apps, webhooks)(d.AppID, d.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !w.Active {
+		return nil, wrapError(ErrNotFound, "webhook (%d) inactive", w.ID)
+	}
+
+	return w, nil
+}
+
+func webhookSecret() (string, error) {
+	raw := make([]byte, webhookSecretLen)
+
+	if _, err := crand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/rule"
+	"github.com/tapglue/snaas/service/user"
+	"github.com/tapglue/snaas/service/webhook"
+)
+
+func TestWebhookEnqueue(t *testing.T) {
+	var (
+		currentApp = testApp()
+		deliveries = webhook.MemDeliveryService()
+		webhooks   = webhook.MemService()
+		enqueue    = WebhookEnqueue(webhooks, deliveries)
+	)
+
+	for _, active := range []bool{true, false} {
+		_, err := webhooks.Put(currentApp.Namespace(), &webhook.Webhook{
+			Active: active,
+			Name:   "backend",
+			Secret: "s3cr3t",
+			Subscriptions: webhook.Subscriptions{
+				{
+					Criteria: &rule.CriteriaObject{
+						New: &object.QueryOptions{
+							Types: []string{
+								TypePost,
+							},
+						},
+					},
+					Type: rule.TypeObject,
+				},
+			},
+			URL: "https://example.com/hooks",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes := []interface{}{
+		&object.StateChange{
+			New: &object.Object{ID: 1, Type: TypePost},
+		},
+		&object.StateChange{
+			New: &object.Object{ID: 2, Type: object.TypeComment},
+		},
+	}
+
+	for _, c := range changes {
+		if err := enqueue(currentApp, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ds, err := deliveries.Query(pg.MetaNamespace, webhook.DeliveryQueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ds), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := ds[0].State, webhook.StatePending; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	p := struct {
+		Event string         `json:"event"`
+		New   *object.Object `json:"new"`
+	}{}
+
+	if err := json.Unmarshal(ds[0].Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := p.Event, "object"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := p.New.ID, uint64(1); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestWebhookEnqueueUser(t *testing.T) {
+	var (
+		currentApp = testApp()
+		deliveries = webhook.MemDeliveryService()
+		webhooks   = webhook.MemService()
+		enqueue    = WebhookEnqueue(webhooks, deliveries)
+	)
+
+	_, err := webhooks.Put(currentApp.Namespace(), &webhook.Webhook{
+		Active: true,
+		Name:   "backend",
+		Secret: "s3cr3t",
+		Subscriptions: webhook.Subscriptions{
+			{
+				Type: rule.TypeUser,
+			},
+		},
+		URL: "https://example.com/hooks",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = enqueue(currentApp, &user.StateChange{
+		New: &user.User{
+			Email:        "alice@tapglue.test",
+			ID:           1,
+			Password:     "hash",
+			Private:      &user.Private{Type: "brand"},
+			SessionToken: "token",
+			Username:     "alice",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := deliveries.Query(pg.MetaNamespace, webhook.DeliveryQueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ds), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	p := struct {
+		New map[string]interface{} `json:"new"`
+		Old map[string]interface{} `json:"old"`
+	}{}
+
+	if err := json.Unmarshal(ds[0].Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := p.New["user_name"], "alice"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	for _, field := range []string{"email", "password", "private", "session_token"} {
+		if v, ok := p.New[field]; ok {
+			t.Errorf("unexpected %s: %v", field, v)
+		}
+	}
+
+	if p.Old != nil {
+		t.Errorf("have %v, want %v", p.Old, nil)
+	}
+}
@@ -0,0 +1,242 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/sns"
+	"github.com/tapglue/snaas/service/campaign"
+	"github.com/tapglue/snaas/service/rule"
+)
+
+// CampaignCancel stops the campaign from sending to further recipients.
+func CampaignCancel(fn core.CampaignCancelFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		campaignID, err := extractCampaignID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		c, err := fn(appID, campaignID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadCampaign{campaign: c})
+	}
+}
+
+// CampaignCreate schedules a new campaign for the app.
+func CampaignCreate(fn core.CampaignCreateFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		p := payloadCampaign{}
+
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		created, err := fn(appID, p.campaign)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, &payloadCampaign{campaign: created})
+	}
+}
+
+// CampaignList returns the most recent campaigns of the app.
+func CampaignList(fn core.CampaignListFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		limit, err := extractLimit(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		cs, err := fn(appID, campaign.QueryOptions{
+			Limit: limit,
+		})
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		if len(cs) == 0 {
+			respondJSON(w, http.StatusNoContent, nil)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadCampaigns{campaigns: cs})
+	}
+}
+
+// CampaignRetrieve returns a single campaign by id, including its progress.
+func CampaignRetrieve(fn core.CampaignFetchFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		campaignID, err := extractCampaignID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		c, err := fn(appID, campaignID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadCampaign{campaign: c})
+	}
+}
+
+type payloadAudience struct {
+	Languages []string       `json:"languages,omitempty"`
+	Platforms []sns.Platform `json:"platforms,omitempty"`
+	SegmentID string         `json:"segment_id,omitempty"`
+	UserIDs   []string       `json:"user_ids,omitempty"`
+}
+
+type payloadCampaign struct {
+	campaign *campaign.Campaign
+}
+
+func (p *payloadCampaign) MarshalJSON() ([]byte, error) {
+	a := payloadAudience{
+		Languages: p.campaign.Audience.Languages,
+		Platforms: p.campaign.Audience.Platforms,
+	}
+
+	if p.campaign.Audience.SegmentID != 0 {
+		a.SegmentID = strconv.FormatUint(p.campaign.Audience.SegmentID, 10)
+	}
+
+	for _, id := range p.campaign.Audience.UserIDs {
+		a.UserIDs = append(a.UserIDs, strconv.FormatUint(id, 10))
+	}
+
+	return json.Marshal(struct {
+		Audience  payloadAudience    `json:"audience"`
+		ID        string             `json:"id"`
+		Name      string             `json:"name"`
+		Progress  campaign.Progress  `json:"progress"`
+		Push      rule.PushTemplates `json:"push"`
+		SendAt    time.Time          `json:"send_at"`
+		State     campaign.State     `json:"state"`
+		Templates rule.Templates     `json:"templates"`
+		URN       string             `json:"urn"`
+		CreatedAt time.Time          `json:"created_at"`
+		UpdatedAt time.Time          `json:"updated_at"`
+	}{
+		Audience:  a,
+		ID:        strconv.FormatUint(p.campaign.ID, 10),
+		Name:      p.campaign.Name,
+		Progress:  p.campaign.Progress,
+		Push:      p.campaign.Push,
+		SendAt:    p.campaign.SendAt,
+		State:     p.campaign.State,
+		Templates: p.campaign.Templates,
+		URN:       p.campaign.URN,
+		CreatedAt: p.campaign.CreatedAt,
+		UpdatedAt: p.campaign.UpdatedAt,
+	})
+}
+
+func (p *payloadCampaign) UnmarshalJSON(raw []byte) error {
+	f := struct {
+		Audience  payloadAudience    `json:"audience"`
+		Name      string             `json:"name"`
+		Push      rule.PushTemplates `json:"push"`
+		SendAt    time.Time          `json:"send_at"`
+		Templates rule.Templates     `json:"templates"`
+		URN       string             `json:"urn"`
+	}{}
+
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return err
+	}
+
+	a := campaign.Audience{
+		Languages: f.Audience.Languages,
+		Platforms: f.Audience.Platforms,
+	}
+
+	if f.Audience.SegmentID != "" {
+		id, err := strconv.ParseUint(f.Audience.SegmentID, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		a.SegmentID = id
+	}
+
+	for _, s := range f.Audience.UserIDs {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		a.UserIDs = append(a.UserIDs, id)
+	}
+
+	p.campaign = &campaign.Campaign{
+		Audience:  a,
+		Name:      f.Name,
+		Push:      f.Push,
+		SendAt:    f.SendAt,
+		Templates: f.Templates,
+		URN:       f.URN,
+	}
+
+	return nil
+}
+
+type payloadCampaigns struct {
+	campaigns campaign.List
+}
+
+func (p *payloadCampaigns) MarshalJSON() ([]byte, error) {
+	cs := []*payloadCampaign{}
+
+	for _, c := range p.campaigns {
+		cs = append(cs, &payloadCampaign{campaign: c})
+	}
+
+	return json.Marshal(struct {
+		Campaigns []*payloadCampaign `json:"campaigns"`
+	}{
+		Campaigns: cs,
+	})
+}
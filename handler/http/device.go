@@ -8,7 +8,9 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/quiet"
 	"github.com/tapglue/snaas/platform/sns"
+	"github.com/tapglue/snaas/service/device"
 )
 
 // DeviceDelete removes a user's device.
@@ -46,7 +48,17 @@ func DeviceUpdate(fn core.DeviceUpdateFunc) Handler {
 			return
 		}
 
-		err = fn(currentApp, origin, deviceID, p.platform, p.token, p.language)
+		err = fn(
+			currentApp,
+			origin,
+			deviceID,
+			p.platform,
+			p.token,
+			p.keys,
+			p.language,
+			p.timezone,
+			p.quietHours,
+		)
 		if err != nil {
 			respondError(w, 0, err)
 			return
@@ -57,16 +69,26 @@ func DeviceUpdate(fn core.DeviceUpdateFunc) Handler {
 }
 
 type payloadDevice struct {
-	language string
-	platform sns.Platform
-	token    string
+	keys       *device.Keys
+	language   string
+	platform   sns.Platform
+	quietHours *quiet.Hours
+	timezone   string
+	token      string
 }
 
 func (p *payloadDevice) UnmarshalJSON(raw []byte) error {
 	f := struct {
-		Language string       `json:"language"`
-		Platform sns.Platform `json:"platform"`
-		Token    string       `json:"token"`
+		Language   string       `json:"language"`
+		Platform   sns.Platform `json:"platform"`
+		QuietHours *quiet.Hours `json:"quiet_hours"`
+		// Subscription as serialised by PushSubscription.toJSON() in browsers.
+		Subscription *struct {
+			Endpoint string      `json:"endpoint"`
+			Keys     device.Keys `json:"keys"`
+		} `json:"subscription"`
+		Timezone string `json:"timezone"`
+		Token    string `json:"token"`
 	}{}
 
 	err := json.Unmarshal(raw, &f)
@@ -76,7 +98,14 @@ func (p *payloadDevice) UnmarshalJSON(raw []byte) error {
 
 	p.language = f.Language
 	p.platform = f.Platform
+	p.quietHours = f.QuietHours
+	p.timezone = f.Timezone
 	p.token = f.Token
 
+	if f.Subscription != nil {
+		p.keys = &f.Subscription.Keys
+		p.token = f.Subscription.Endpoint
+	}
+
 	return nil
 }
@@ -0,0 +1,399 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/service/notification"
+)
+
+// NotificationCountUnread returns the badge count of unread notifications of
+// the current user.
+func NotificationCountUnread(fn core.NotificationCountUnreadFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var (
+			app         = appFromContext(ctx)
+			currentUser = userFromContext(ctx)
+		)
+
+		count, err := fn(app, currentUser.ID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, struct {
+			Unread uint `json:"unread_count"`
+		}{
+			Unread: count,
+		})
+	}
+}
+
+// NotificationList returns the inbox of the current user.
+func NotificationList(fn core.NotificationListFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var (
+			app         = appFromContext(ctx)
+			currentUser = userFromContext(ctx)
+			opts        = notification.QueryOptions{}
+			err         error
+		)
+
+		opts.Before, err = extractTimeCursorBefore(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		opts.Limit, err = extractLimit(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		ns, err := fn(app, currentUser.ID, opts)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		if len(ns) == 0 {
+			respondJSON(w, http.StatusNoContent, nil)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadNotifications{
+			notifications: ns,
+			pagination: pagination(
+				r,
+				opts.Limit,
+				notificationCursorAfter(ns, opts.Limit),
+				notificationCursorBefore(ns, opts.Limit),
+			),
+		})
+	}
+}
+
+// NotificationRead marks a single notification of the current user as read.
+func NotificationRead(fn core.NotificationReadFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var (
+			app         = appFromContext(ctx)
+			currentUser = userFromContext(ctx)
+		)
+
+		id, err := extractNotificationID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		n, err := fn(app, currentUser.ID, id)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadNotification{notification: n})
+	}
+}
+
+// NotificationReadAll marks all notifications of the current user as read.
+func NotificationReadAll(fn core.NotificationReadAllFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var (
+			app         = appFromContext(ctx)
+			currentUser = userFromContext(ctx)
+		)
+
+		err := fn(app, currentUser.ID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// NotificationMutePost stops notifications about the given post thread for the
+// current user.
+func NotificationMutePost(fn core.NotificationMuteFunc) Handler {
+	return notificationMute(fn, extractPostID, true)
+}
+
+// NotificationMuteUser stops notifications caused by the given user for the
+// current user.
+func NotificationMuteUser(fn core.NotificationMuteFunc) Handler {
+	return notificationMute(fn, extractUserID, true)
+}
+
+// NotificationSettingsRetrieve returns the notification settings of the
+// current user.
+func NotificationSettingsRetrieve(
+	fn core.NotificationSettingsFetchFunc,
+) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var (
+			app         = appFromContext(ctx)
+			currentUser = userFromContext(ctx)
+		)
+
+		s, err := fn(app, currentUser.ID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadSettings{settings: s})
+	}
+}
+
+// NotificationSettingsUpdate replaces the notification settings of the current
+// user.
+func NotificationSettingsUpdate(
+	fn core.NotificationSettingsUpdateFunc,
+) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var (
+			app         = appFromContext(ctx)
+			currentUser = userFromContext(ctx)
+			p           = &payloadSettings{}
+		)
+
+		err := json.NewDecoder(r.Body).Decode(p)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		s, err := fn(app, currentUser.ID, p.settings)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadSettings{settings: s})
+	}
+}
+
+// NotificationUnmutePost resumes notifications about the given post thread for
+// the current user.
+func NotificationUnmutePost(fn core.NotificationMuteFunc) Handler {
+	return notificationMute(fn, extractPostID, false)
+}
+
+// NotificationUnmuteUser resumes notifications caused by the given user for
+// the current user.
+func NotificationUnmuteUser(fn core.NotificationMuteFunc) Handler {
+	return notificationMute(fn, extractUserID, false)
+}
+
+func notificationMute(
+	fn core.NotificationMuteFunc,
+	extract func(*http.Request) (uint64, error),
+	mute bool,
+) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var (
+			app         = appFromContext(ctx)
+			currentUser = userFromContext(ctx)
+		)
+
+		id, err := extract(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		s, err := fn(app, currentUser.ID, id, mute)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadSettings{settings: s})
+	}
+}
+
+type payloadNotification struct {
+	notification *notification.Notification
+}
+
+func (p *payloadNotification) MarshalJSON() ([]byte, error) {
+	n := p.notification
+
+	return json.Marshal(struct {
+		ID        string            `json:"id"`
+		Messages  map[string]string `json:"messages"`
+		Read      bool              `json:"read"`
+		URN       string            `json:"urn"`
+		CreatedAt time.Time         `json:"created_at"`
+	}{
+		ID:        strconv.FormatUint(n.ID, 10),
+		Messages:  n.Messages,
+		Read:      n.Read,
+		URN:       n.URN,
+		CreatedAt: n.CreatedAt,
+	})
+}
+
+type payloadNotifications struct {
+	notifications notification.List
+	pagination    *payloadPagination
+}
+
+func (p *payloadNotifications) MarshalJSON() ([]byte, error) {
+	ns := []*payloadNotification{}
+
+	for _, n := range p.notifications {
+		ns = append(ns, &payloadNotification{notification: n})
+	}
+
+	return json.Marshal(struct {
+		Notifications      []*payloadNotification `json:"notifications"`
+		NotificationsCount int                    `json:"notifications_count"`
+		Pagination         *payloadPagination     `json:"paging"`
+	}{
+		Notifications:      ns,
+		NotificationsCount: len(ns),
+		Pagination:         p.pagination,
+	})
+}
+
+func notificationCursorAfter(ns notification.List, limit int) string {
+	var after string
+
+	if len(ns) != 0 {
+		after = toTimeCursor(ns[0].CreatedAt)
+	}
+
+	return after
+}
+
+func notificationCursorBefore(ns notification.List, limit int) string {
+	var before string
+
+	if len(ns) != 0 {
+		before = toTimeCursor(ns[len(ns)-1].CreatedAt)
+	}
+
+	return before
+}
+
+type payloadSettings struct {
+	settings *notification.Settings
+}
+
+func (p *payloadSettings) MarshalJSON() ([]byte, error) {
+	var (
+		s     = p.settings
+		posts = []string{}
+		rules = map[string]bool{}
+		users = []string{}
+	)
+
+	for _, id := range s.MutedPosts {
+		posts = append(posts, strconv.FormatUint(id, 10))
+	}
+
+	for _, id := range s.MutedUsers {
+		users = append(users, strconv.FormatUint(id, 10))
+	}
+
+	for id, enabled := range s.Rules {
+		rules[strconv.FormatUint(id, 10)] = enabled
+	}
+
+	f := struct {
+		Categories  map[string]bool `json:"categories"`
+		MutedPosts  []string        `json:"muted_posts"`
+		MutedUsers  []string        `json:"muted_users"`
+		PausedUntil *time.Time      `json:"paused_until,omitempty"`
+		Rules       map[string]bool `json:"rules"`
+		UpdatedAt   time.Time       `json:"updated_at"`
+	}{
+		Categories: s.Categories,
+		MutedPosts: posts,
+		MutedUsers: users,
+		Rules:      rules,
+		UpdatedAt:  s.UpdatedAt,
+	}
+
+	if f.Categories == nil {
+		f.Categories = map[string]bool{}
+	}
+
+	if !s.PausedUntil.IsZero() {
+		f.PausedUntil = &s.PausedUntil
+	}
+
+	return json.Marshal(f)
+}
+
+func (p *payloadSettings) UnmarshalJSON(raw []byte) error {
+	f := struct {
+		Categories  map[string]bool `json:"categories"`
+		MutedPosts  []string        `json:"muted_posts"`
+		MutedUsers  []string        `json:"muted_users"`
+		PausedUntil *time.Time      `json:"paused_until"`
+		Rules       map[string]bool `json:"rules"`
+	}{}
+
+	err := json.Unmarshal(raw, &f)
+	if err != nil {
+		return err
+	}
+
+	s := &notification.Settings{
+		Categories: f.Categories,
+		MutedPosts: []uint64{},
+		MutedUsers: []uint64{},
+		Rules:      map[uint64]bool{},
+	}
+
+	if s.Categories == nil {
+		s.Categories = map[string]bool{}
+	}
+
+	if f.PausedUntil != nil {
+		s.PausedUntil = f.PausedUntil.UTC()
+	}
+
+	for _, raw := range f.MutedPosts {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		s.MutedPosts = append(s.MutedPosts, id)
+	}
+
+	for _, raw := range f.MutedUsers {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		s.MutedUsers = append(s.MutedUsers, id)
+	}
+
+	for raw, enabled := range f.Rules {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		s.Rules[id] = enabled
+	}
+
+	p.settings = s
+
+	return nil
+}
@@ -36,6 +36,32 @@ func PlatformCreate(fn core.PlatformCreateFunc) Handler {
 	}
 }
 
+// PlatformCreateWebPush stores a Web Push platform for the app.
+func PlatformCreateWebPush(fn core.PlatformCreateWebPushFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		payload := payloadPlatform{}
+
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		p, err := fn(appID, payload.platform)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, &payloadPlatform{platform: p})
+	}
+}
+
 type payloadPlatform struct {
 	cert, key string
 	platform  *platform.Platform
@@ -43,35 +69,41 @@ type payloadPlatform struct {
 
 func (p *payloadPlatform) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Active    bool      `json:"active"`
-		ARN       string    `json:"arn"`
-		Deleted   bool      `json:"deleted"`
-		Ecosystem int       `json:"ecosystem"`
-		ID        string    `json:"id"`
-		Name      string    `json:"name"`
-		Scheme    string    `json:"scheme"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
+		Active         bool      `json:"active"`
+		ARN            string    `json:"arn"`
+		Deleted        bool      `json:"deleted"`
+		Ecosystem      int       `json:"ecosystem"`
+		ID             string    `json:"id"`
+		Name           string    `json:"name"`
+		Provider       string    `json:"provider"`
+		Scheme         string    `json:"scheme"`
+		VAPIDPublicKey string    `json:"vapid_public_key,omitempty"`
+		CreatedAt      time.Time `json:"created_at"`
+		UpdatedAt      time.Time `json:"updated_at"`
 	}{
-		Active:    p.platform.Active,
-		ARN:       p.platform.ARN,
-		Deleted:   p.platform.Deleted,
-		Ecosystem: int(p.platform.Ecosystem),
-		ID:        strconv.FormatUint(p.platform.ID, 10),
-		Name:      p.platform.Name,
-		Scheme:    p.platform.Scheme,
-		CreatedAt: p.platform.CreatedAt,
-		UpdatedAt: p.platform.UpdatedAt,
+		Active:         p.platform.Active,
+		ARN:            p.platform.ARN,
+		Deleted:        p.platform.Deleted,
+		Ecosystem:      int(p.platform.Ecosystem),
+		ID:             strconv.FormatUint(p.platform.ID, 10),
+		Name:           p.platform.Name,
+		Provider:       p.platform.Provider,
+		Scheme:         p.platform.Scheme,
+		VAPIDPublicKey: p.platform.Credentials.VAPIDPublicKey,
+		CreatedAt:      p.platform.CreatedAt,
+		UpdatedAt:      p.platform.UpdatedAt,
 	})
 }
 
 func (p *payloadPlatform) UnmarshalJSON(raw []byte) error {
 	f := struct {
-		Cert      string       `json:"cert"`
-		Ecosystem sns.Platform `json:"ecosystem"`
-		Key       string       `json:"key"`
-		Name      string       `json:"name"`
-		Scheme    string       `json:"scheme"`
+		Cert        string               `json:"cert"`
+		Credentials platform.Credentials `json:"credentials"`
+		Ecosystem   sns.Platform         `json:"ecosystem"`
+		Key         string               `json:"key"`
+		Name        string               `json:"name"`
+		Provider    string               `json:"provider"`
+		Scheme      string               `json:"scheme"`
 	}{}
 
 	if err := json.Unmarshal(raw, &f); err != nil {
@@ -81,9 +113,11 @@ func (p *payloadPlatform) UnmarshalJSON(raw []byte) error {
 	p.cert = f.Cert
 	p.key = f.Key
 	p.platform = &platform.Platform{
-		Ecosystem: f.Ecosystem,
-		Name:      f.Name,
-		Scheme:    f.Scheme,
+		Credentials: f.Credentials,
+		Ecosystem:   f.Ecosystem,
+		Name:        f.Name,
+		Provider:    f.Provider,
+		Scheme:      f.Scheme,
 	}
 
 	return nil
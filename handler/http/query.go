@@ -16,6 +16,7 @@ import (
 	"github.com/tapglue/snaas/service/event"
 	"github.com/tapglue/snaas/service/object"
 	"github.com/tapglue/snaas/service/reaction"
+	"github.com/tapglue/snaas/service/receipt"
 	"github.com/tapglue/snaas/service/user"
 )
 
@@ -25,18 +26,25 @@ const (
 	headerForwardedProto = "X-Forwarded-Proto"
 
 	keyAppID             = "appID"
+	keyCampaignID        = "campaignID"
 	keyCommentID         = "commentID"
 	keyCounterName       = "counterName"
 	keyCursorAfter       = "after"
 	keyCursorBefore      = "before"
 	keyInviteConnections = "invite-connections"
 	keyLimit             = "limit"
+	keyNotificationID    = "notificationID"
 	keyPostID            = "postID"
 	keyReactionType      = "reactionType"
+	keyReceiptRuleID     = "rule_id"
+	keyReceiptStatus     = "status"
+	keyReceiptUserID     = "user_id"
 	keyRuleID            = "ruleID"
+	keySegmentID         = "segmentID"
 	keyState             = "state"
 	keyUserID            = "userID"
 	keyUserQuery         = "q"
+	keyWebhookID         = "webhookID"
 	keyWhere             = "where"
 
 	limitDefault = 25
@@ -173,6 +181,10 @@ func extractAppID(r *http.Request) (uint64, error) {
 	return strconv.ParseUint(mux.Vars(r)[keyAppID], 10, 64)
 }
 
+func extractCampaignID(r *http.Request) (uint64, error) {
+	return strconv.ParseUint(mux.Vars(r)[keyCampaignID], 10, 64)
+}
+
 func extractEventOpts(r *http.Request) (event.QueryOptions, error) {
 	var (
 		cond  = eventCondition{}
@@ -287,6 +299,10 @@ func extractLimit(r *http.Request) (int, error) {
 	return limit, nil
 }
 
+func extractNotificationID(r *http.Request) (uint64, error) {
+	return strconv.ParseUint(mux.Vars(r)[keyNotificationID], 10, 64)
+}
+
 func extractOffsetCursorBefore(r *http.Request) (uint, error) {
 	var (
 		param = r.URL.Query().Get(keyCursorBefore)
@@ -360,10 +376,65 @@ func extractReactionOpts(r *http.Request) (reaction.QueryOptions, error) {
 	return reaction.QueryOptions{}, nil
 }
 
+func extractReceiptOpts(r *http.Request) (receipt.QueryOptions, error) {
+	var (
+		opts  = receipt.QueryOptions{}
+		query = r.URL.Query()
+	)
+
+	if p := query.Get(keyReceiptRuleID); p != "" {
+		id, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return opts, err
+		}
+
+		opts.RuleIDs = []uint64{id}
+	}
+
+	if p := query.Get(keyReceiptStatus); p != "" {
+		opts.Statuses = []receipt.Status{receipt.Status(p)}
+	}
+
+	if p := query.Get(keyReceiptUserID); p != "" {
+		id, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return opts, err
+		}
+
+		opts.Recipients = []uint64{id}
+	}
+
+	return opts, nil
+}
+
+func extractReceiptParams(r *http.Request) []string {
+	ps := []string{}
+
+	for _, key := range []string{
+		keyReceiptRuleID,
+		keyReceiptStatus,
+		keyReceiptUserID,
+	} {
+		if p := r.URL.Query().Get(key); p != "" {
+			ps = append(ps, key, p)
+		}
+	}
+
+	return ps
+}
+
 func extractRuleID(r *http.Request) (uint64, error) {
 	return strconv.ParseUint(mux.Vars(r)[keyRuleID], 10, 64)
 }
 
+func extractSegmentID(r *http.Request) (uint64, error) {
+	return strconv.ParseUint(mux.Vars(r)[keySegmentID], 10, 64)
+}
+
+func extractWebhookID(r *http.Request) (uint64, error) {
+	return strconv.ParseUint(mux.Vars(r)[keyWebhookID], 10, 64)
+}
+
 func extractState(r *http.Request) connection.State {
 	return connection.State(mux.Vars(r)[keyState])
 }
@@ -0,0 +1,273 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/sns"
+	"github.com/tapglue/snaas/service/receipt"
+)
+
+// ReceiptList returns the push delivery log of the app, optionally narrowed
+// down to a recipient, rule or status.
+func ReceiptList(fn core.ReceiptListFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		opts, err := extractReceiptOpts(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		opts.Before, err = extractTimeCursorBefore(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		opts.Limit, err = extractLimit(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		rs, err := fn(appID, opts)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		if len(rs) == 0 {
+			respondJSON(w, http.StatusNoContent, nil)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadReceipts{
+			pagination: pagination(
+				r,
+				opts.Limit,
+				toTimeCursor(rs[0].CreatedAt),
+				toTimeCursor(rs[len(rs)-1].CreatedAt),
+				extractReceiptParams(r)...,
+			),
+			receipts: rs,
+		})
+	}
+}
+
+// ReceiptOpen records that the current user opened a push, the client reports
+// it with the data of the received push.
+func ReceiptOpen(fn core.ReceiptOpenFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var (
+			app         = appFromContext(ctx)
+			currentUser = userFromContext(ctx)
+			p           = payloadReceiptOpen{}
+		)
+
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		err := fn(app, currentUser.ID, p.ruleID, p.variantID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// ReceiptVariants returns sent and opened pushes for every template variant of
+// the rule.
+func ReceiptVariants(fn core.ReceiptVariantsFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		ruleID, err := extractRuleID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		cs, err := fn(appID, ruleID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		if len(cs) == 0 {
+			respondJSON(w, http.StatusNoContent, nil)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadVariantCounts{counts: cs})
+	}
+}
+
+type payloadReceipt struct {
+	receipt *receipt.Receipt
+}
+
+func (p *payloadReceipt) MarshalJSON() ([]byte, error) {
+	var (
+		openedAt *time.Time
+		ruleID   string
+	)
+
+	if !p.receipt.OpenedAt.IsZero() {
+		openedAt = &p.receipt.OpenedAt
+	}
+
+	if p.receipt.RuleID != 0 {
+		ruleID = strconv.FormatUint(p.receipt.RuleID, 10)
+	}
+
+	return json.Marshal(struct {
+		DeviceID  string         `json:"device_id"`
+		Error     string         `json:"error,omitempty"`
+		ID        string         `json:"id"`
+		MessageID string         `json:"message_id,omitempty"`
+		OpenedAt  *time.Time     `json:"opened_at,omitempty"`
+		Platform  sns.Platform   `json:"platform"`
+		Provider  string         `json:"provider"`
+		RuleID    string         `json:"rule_id,omitempty"`
+		Status    receipt.Status `json:"status"`
+		UserID    string         `json:"user_id"`
+		VariantID string         `json:"variant_id,omitempty"`
+		CreatedAt time.Time      `json:"created_at"`
+		UpdatedAt time.Time      `json:"updated_at"`
+	}{
+		DeviceID:  strconv.FormatUint(p.receipt.DeviceID, 10),
+		Error:     p.receipt.Error,
+		ID:        strconv.FormatUint(p.receipt.ID, 10),
+		MessageID: p.receipt.MessageID,
+		OpenedAt:  openedAt,
+		Platform:  p.receipt.Platform,
+		Provider:  p.receipt.Provider,
+		RuleID:    ruleID,
+		Status:    p.receipt.Status,
+		UserID:    strconv.FormatUint(p.receipt.Recipient, 10),
+		VariantID: p.receipt.VariantID,
+		CreatedAt: p.receipt.CreatedAt,
+		UpdatedAt: p.receipt.UpdatedAt,
+	})
+}
+
+// payloadReceiptOpen reads rule and variant from the data of a push as the
+// client received it. The keys are either at the top level, like on iOS and
+// Android, or nested under data, like for web push.
+type payloadReceiptOpen struct {
+	ruleID    uint64
+	variantID string
+}
+
+func (p *payloadReceiptOpen) UnmarshalJSON(raw []byte) error {
+	f := struct {
+		Data      map[string]interface{} `json:"data"`
+		RuleID    string                 `json:"tg_rule_id"`
+		VariantID string                 `json:"tg_variant_id"`
+	}{}
+
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return err
+	}
+
+	if f.RuleID == "" {
+		f.RuleID, _ = f.Data[core.PushKeyRuleID].(string)
+	}
+
+	if f.VariantID == "" {
+		f.VariantID, _ = f.Data[core.PushKeyVariantID].(string)
+	}
+
+	if f.RuleID == "" || f.VariantID == "" {
+		return fmt.Errorf("%s and %s required", core.PushKeyRuleID, core.PushKeyVariantID)
+	}
+
+	id, err := strconv.ParseUint(f.RuleID, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	p.ruleID = id
+	p.variantID = f.VariantID
+
+	return nil
+}
+
+type payloadReceipts struct {
+	pagination *payloadPagination
+	receipts   receipt.List
+}
+
+func (p *payloadReceipts) MarshalJSON() ([]byte, error) {
+	rs := []*payloadReceipt{}
+
+	for _, r := range p.receipts {
+		rs = append(rs, &payloadReceipt{receipt: r})
+	}
+
+	return json.Marshal(struct {
+		Pagination *payloadPagination `json:"paging"`
+		Receipts   []*payloadReceipt  `json:"receipts"`
+	}{
+		Pagination: p.pagination,
+		Receipts:   rs,
+	})
+}
+
+type payloadVariantCount struct {
+	count receipt.VariantCount
+}
+
+func (p *payloadVariantCount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Opened    int     `json:"opened"`
+		OpenRate  float64 `json:"open_rate"`
+		Sent      int     `json:"sent"`
+		VariantID string  `json:"variant_id"`
+	}{
+		Opened:    p.count.Opened,
+		OpenRate:  p.count.OpenRate(),
+		Sent:      p.count.Sent,
+		VariantID: p.count.VariantID,
+	})
+}
+
+type payloadVariantCounts struct {
+	counts receipt.VariantCounts
+}
+
+func (p *payloadVariantCounts) MarshalJSON() ([]byte, error) {
+	cs := []*payloadVariantCount{}
+
+	for _, c := range p.counts {
+		cs = append(cs, &payloadVariantCount{count: c})
+	}
+
+	return json.Marshal(struct {
+		Variants      []*payloadVariantCount `json:"variants"`
+		VariantsCount int                    `json:"variants_count"`
+	}{
+		Variants:      cs,
+		VariantsCount: len(cs),
+	})
+}
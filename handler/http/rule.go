@@ -2,12 +2,14 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"golang.org/x/net/context"
 
 	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/sns"
 	"github.com/tapglue/snaas/service/rule"
 )
 
@@ -35,6 +37,32 @@ func RuleActivate(fn core.RuleActivateFunc) Handler {
 	}
 }
 
+// RuleCreate stores a new rule.
+func RuleCreate(fn core.RuleCreateFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		p := payloadRule{}
+
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		created, err := fn(appID, p.rule)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, &payloadRule{rule: created})
+	}
+}
+
 func RuleDeactivate(fn core.RuleDeactivateFunc) Handler {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 		appID, err := extractAppID(r)
@@ -131,6 +159,65 @@ func RuleRetrieve(fn core.RuleFetchFunc) Handler {
 	}
 }
 
+// RulePreview dry-runs a stored or unsaved rule against a stored or sample
+// entity and returns the Messages it would produce without delivering them.
+func RulePreview(fn core.RulePreviewFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		p := payloadRulePreviewInput{}
+
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		res, err := fn(appID, p.input)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadRuleDryRun{dryRun: res})
+	}
+}
+
+// RuleUpdate replaces an existing rule.
+func RuleUpdate(fn core.RuleUpdateFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		ruleID, err := extractRuleID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		p := payloadRule{}
+
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		updated, err := fn(appID, ruleID, p.rule)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadRule{rule: updated})
+	}
+}
+
 type payloadRule struct {
 	rule *rule.Rule
 }
@@ -145,6 +232,8 @@ func (p *payloadRule) MarshalJSON() ([]byte, error) {
 		ID         string          `json:"id"`
 		Name       string          `json:"name"`
 		Recipients rule.Recipients `json:"recipients"`
+		Schedule   *rule.Schedule  `json:"schedule,omitempty"`
+		Urgent     bool            `json:"urgent"`
 	}{
 		Active:     p.rule.Active,
 		Criteria:   p.rule.Criteria,
@@ -154,9 +243,181 @@ func (p *payloadRule) MarshalJSON() ([]byte, error) {
 		ID:         strconv.FormatUint(p.rule.ID, 10),
 		Name:       p.rule.Name,
 		Recipients: p.rule.Recipients,
+		Schedule:   p.rule.Schedule,
+		Urgent:     p.rule.Urgent,
+	})
+}
+
+func (p *payloadRule) UnmarshalJSON(raw []byte) error {
+	f := struct {
+		Active     bool            `json:"active"`
+		Criteria   json.RawMessage `json:"criteria"`
+		Ecosystem  int             `json:"ecosystem"`
+		Entity     int             `json:"entity"`
+		Name       string          `json:"name"`
+		Recipients rule.Recipients `json:"recipients"`
+		Schedule   *rule.Schedule  `json:"schedule"`
+		Urgent     bool            `json:"urgent"`
+	}{}
+
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return err
+	}
+
+	var criteria rule.Matcher
+
+	switch rule.Type(f.Entity) {
+	case rule.TypeConnection:
+		criteria = &rule.CriteriaConnection{}
+	case rule.TypeEvent:
+		criteria = &rule.CriteriaEvent{}
+	case rule.TypeObject:
+		criteria = &rule.CriteriaObject{}
+	case rule.TypeReaction:
+		criteria = &rule.CriteriaReaction{}
+	case rule.TypeUser:
+		criteria = &rule.CriteriaUser{}
+	default:
+		return fmt.Errorf("unsupported entity %d", f.Entity)
+	}
+
+	if len(f.Criteria) > 0 {
+		if err := json.Unmarshal(f.Criteria, criteria); err != nil {
+			return err
+		}
+	}
+
+	p.rule = &rule.Rule{
+		Active:     f.Active,
+		Criteria:   criteria,
+		Ecosystem:  sns.Platform(f.Ecosystem),
+		Name:       f.Name,
+		Recipients: f.Recipients,
+		Schedule:   f.Schedule,
+		Type:       rule.Type(f.Entity),
+		Urgent:     f.Urgent,
+	}
+
+	return nil
+}
+
+type payloadRuleDryRun struct {
+	dryRun *core.RuleDryRun
+}
+
+func (p *payloadRuleDryRun) MarshalJSON() ([]byte, error) {
+	type push struct {
+		Badge       string            `json:"badge,omitempty"`
+		CollapseKey string            `json:"collapse_key,omitempty"`
+		Data        map[string]string `json:"data,omitempty"`
+		Sound       string            `json:"sound,omitempty"`
+		ThreadID    string            `json:"thread_id,omitempty"`
+		Titles      map[string]string `json:"titles,omitempty"`
+	}
+
+	type message struct {
+		Bodies    map[string]string `json:"bodies,omitempty"`
+		Channels  rule.Channels     `json:"channels,omitempty"`
+		Messages  map[string]string `json:"messages"`
+		Push      *push             `json:"push,omitempty"`
+		Recipient string            `json:"recipient"`
+		Subjects  map[string]string `json:"subjects,omitempty"`
+		URN       string            `json:"urn"`
+		Urgent    bool              `json:"urgent"`
+		VariantID string            `json:"variant_id,omitempty"`
+	}
+
+	var (
+		ms = []message{}
+		us = []*payloadUser{}
+	)
+
+	for _, msg := range p.dryRun.Messages {
+		m := message{
+			Bodies:    msg.Bodies,
+			Channels:  msg.Channels,
+			Messages:  msg.Messages,
+			Recipient: strconv.FormatUint(msg.Recipient, 10),
+			Subjects:  msg.Subjects,
+			URN:       msg.URN,
+			Urgent:    msg.Urgent,
+			VariantID: msg.VariantID,
+		}
+
+		if msg.Push != nil {
+			m.Push = &push{
+				Badge:       msg.Push.Badge,
+				CollapseKey: msg.Push.CollapseKey,
+				Data:        msg.Push.Data,
+				Sound:       msg.Push.Sound,
+				ThreadID:    msg.Push.ThreadID,
+				Titles:      msg.Push.Titles,
+			}
+		}
+
+		ms = append(ms, m)
+	}
+
+	for _, u := range p.dryRun.Recipients {
+		us = append(us, &payloadUser{user: u})
+	}
+
+	return json.Marshal(struct {
+		Matched    bool           `json:"matched"`
+		Messages   []message      `json:"messages"`
+		Recipients []*payloadUser `json:"recipients"`
+	}{
+		Matched:    p.dryRun.Matched,
+		Messages:   ms,
+		Recipients: us,
 	})
 }
 
+type payloadRulePreviewInput struct {
+	input core.RulePreviewInput
+}
+
+func (p *payloadRulePreviewInput) UnmarshalJSON(raw []byte) error {
+	f := struct {
+		EntityID string          `json:"entity_id"`
+		New      json.RawMessage `json:"new"`
+		Old      json.RawMessage `json:"old"`
+		Rule     *payloadRule    `json:"rule"`
+		RuleID   string          `json:"rule_id"`
+	}{}
+
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return err
+	}
+
+	if f.EntityID != "" {
+		id, err := strconv.ParseUint(f.EntityID, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		p.input.EntityID = id
+	}
+
+	if f.RuleID != "" {
+		id, err := strconv.ParseUint(f.RuleID, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		p.input.RuleID = id
+	}
+
+	if f.Rule != nil {
+		p.input.Rule = f.Rule.rule
+	}
+
+	p.input.New = f.New
+	p.input.Old = f.Old
+
+	return nil
+}
+
 type payloadRules struct {
 	rules rule.List
 }
@@ -0,0 +1,288 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/service/segment"
+)
+
+// SegmentCreate stores a new segment and computes its members.
+func SegmentCreate(fn core.SegmentCreateFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		p := payloadSegment{}
+
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		created, err := fn(appID, p.segment)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, &payloadSegment{segment: created})
+	}
+}
+
+// SegmentList returns all segments of the app.
+func SegmentList(fn core.SegmentListFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		ss, err := fn(appID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		if len(ss) == 0 {
+			respondJSON(w, http.StatusNoContent, nil)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadSegments{segments: ss})
+	}
+}
+
+// SegmentMembers returns a page of the cached members of the segment.
+func SegmentMembers(fn core.SegmentMembersFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		segmentID, err := extractSegmentID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		after, err := extractIDCursorBefore(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		limit, err := extractLimit(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		us, err := fn(appID, segmentID, after, limit)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		if len(us) == 0 {
+			respondJSON(w, http.StatusNoContent, nil)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadUsers{
+			pagination: pagination(
+				r,
+				limit,
+				"",
+				userCursorBefore(us, limit),
+			),
+			users: us,
+		})
+	}
+}
+
+// SegmentPreview returns the number of users matching the definition without
+// storing it.
+func SegmentPreview(fn core.SegmentPreviewFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		d := segment.Definition{}
+
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		count, err := fn(appID, d)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, struct {
+			Count int `json:"count"`
+		}{
+			Count: count,
+		})
+	}
+}
+
+// SegmentRefresh brings the cached members of the segment up to date.
+func SegmentRefresh(fn core.SegmentRefreshFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		segmentID, err := extractSegmentID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		s, err := fn(appID, segmentID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadSegment{segment: s})
+	}
+}
+
+// SegmentRetrieve returns a single segment by id.
+func SegmentRetrieve(fn core.SegmentFetchFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		segmentID, err := extractSegmentID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		s, err := fn(appID, segmentID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadSegment{segment: s})
+	}
+}
+
+// SegmentUpdate replaces name and definition of the segment.
+func SegmentUpdate(fn core.SegmentUpdateFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		segmentID, err := extractSegmentID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		p := payloadSegment{}
+
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		updated, err := fn(appID, segmentID, p.segment)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadSegment{segment: updated})
+	}
+}
+
+type payloadSegment struct {
+	segment *segment.Segment
+}
+
+func (p *payloadSegment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Definition  segment.Definition `json:"definition"`
+		ID          string             `json:"id"`
+		Name        string             `json:"name"`
+		RefreshedAt time.Time          `json:"refreshed_at"`
+		Size        int                `json:"size"`
+		CreatedAt   time.Time          `json:"created_at"`
+		UpdatedAt   time.Time          `json:"updated_at"`
+	}{
+		Definition:  p.segment.Definition,
+		ID:          strconv.FormatUint(p.segment.ID, 10),
+		Name:        p.segment.Name,
+		RefreshedAt: p.segment.RefreshedAt,
+		Size:        p.segment.Size,
+		CreatedAt:   p.segment.CreatedAt,
+		UpdatedAt:   p.segment.UpdatedAt,
+	})
+}
+
+func (p *payloadSegment) UnmarshalJSON(raw []byte) error {
+	f := struct {
+		Definition segment.Definition `json:"definition"`
+		Name       string             `json:"name"`
+	}{}
+
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return err
+	}
+
+	p.segment = &segment.Segment{
+		Definition: f.Definition,
+		Name:       f.Name,
+	}
+
+	return nil
+}
+
+type payloadSegments struct {
+	segments segment.List
+}
+
+func (p *payloadSegments) MarshalJSON() ([]byte, error) {
+	ss := []*payloadSegment{}
+
+	for _, s := range p.segments {
+		ss = append(ss, &payloadSegment{segment: s})
+	}
+
+	return json.Marshal(struct {
+		Segments      []*payloadSegment `json:"segments"`
+		SegmentsCount int               `json:"segments_count"`
+	}{
+		Segments:      ss,
+		SegmentsCount: len(ss),
+	})
+}
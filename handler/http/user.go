@@ -11,6 +11,7 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/platform/quiet"
 	"github.com/tapglue/snaas/service/user"
 )
 
@@ -574,8 +575,10 @@ func (p *payloadUser) MarshalJSON() ([]byte, error) {
 		Lastname       string                `json:"last_name"`
 		Metadata       user.Metadata         `json:"metadata,omitempty"`
 		Private        *user.Private         `json:"private,omitempty"`
+		QuietHours     *quiet.Hours          `json:"quiet_hours,omitempty"`
 		SessionToken   string                `json:"session_token,omitempty"`
 		SocialIDs      map[string]string     `json:"social_ids,omitempty"`
+		Timezone       string                `json:"timezone,omitempty"`
 		URL            string                `json:"url,omitempty"`
 		Username       string                `json:"user_name"`
 		CreatedAt      time.Time             `json:"created_at"`
@@ -598,8 +601,10 @@ func (p *payloadUser) MarshalJSON() ([]byte, error) {
 		Lastname:       p.user.Lastname,
 		Metadata:       p.user.Metadata,
 		Private:        p.user.Private,
+		QuietHours:     p.user.QuietHours,
 		SessionToken:   p.user.SessionToken,
 		SocialIDs:      p.user.SocialIDs,
+		Timezone:       p.user.Timezone,
 		URL:            p.user.URL,
 		Username:       p.user.Username,
 		CreatedAt:      p.user.CreatedAt,
@@ -609,18 +614,20 @@ func (p *payloadUser) MarshalJSON() ([]byte, error) {
 
 func (p *payloadUser) UnmarshalJSON(raw []byte) error {
 	f := struct {
-		About     string                `json:"about"`
-		CustomID  string                `json:"custom_id,omitempty"`
-		Email     string                `json:"email"`
-		Firstname string                `json:"first_name"`
-		Images    map[string]user.Image `json:"images,omitempty"`
-		Lastname  string                `json:"last_name"`
-		Metadata  user.Metadata         `json:"metadata,omitempty"`
-		Password  string                `json:"password,omitempty"`
-		Private   *user.Private         `json:"private,omitempty"`
-		SocialIDs map[string]string     `json:"social_ids"`
-		URL       string                `json:"url,omitempty"`
-		Username  string                `json:"user_name"`
+		About      string                `json:"about"`
+		CustomID   string                `json:"custom_id,omitempty"`
+		Email      string                `json:"email"`
+		Firstname  string                `json:"first_name"`
+		Images     map[string]user.Image `json:"images,omitempty"`
+		Lastname   string                `json:"last_name"`
+		Metadata   user.Metadata         `json:"metadata,omitempty"`
+		Password   string                `json:"password,omitempty"`
+		Private    *user.Private         `json:"private,omitempty"`
+		QuietHours *quiet.Hours          `json:"quiet_hours,omitempty"`
+		SocialIDs  map[string]string     `json:"social_ids"`
+		Timezone   string                `json:"timezone,omitempty"`
+		URL        string                `json:"url,omitempty"`
+		Username   string                `json:"user_name"`
 	}{}
 
 	err := json.Unmarshal(raw, &f)
@@ -633,18 +640,20 @@ func (p *payloadUser) UnmarshalJSON(raw []byte) error {
 	}
 
 	p.user = &user.User{
-		About:     f.About,
-		CustomID:  f.CustomID,
-		Email:     f.Email,
-		Firstname: f.Firstname,
-		Images:    f.Images,
-		Lastname:  f.Lastname,
-		Metadata:  f.Metadata,
-		Password:  f.Password,
-		Private:   f.Private,
-		SocialIDs: f.SocialIDs,
-		URL:       f.URL,
-		Username:  f.Username,
+		About:      f.About,
+		CustomID:   f.CustomID,
+		Email:      f.Email,
+		Firstname:  f.Firstname,
+		Images:     f.Images,
+		Lastname:   f.Lastname,
+		Metadata:   f.Metadata,
+		Password:   f.Password,
+		Private:    f.Private,
+		QuietHours: f.QuietHours,
+		SocialIDs:  f.SocialIDs,
+		Timezone:   f.Timezone,
+		URL:        f.URL,
+		Username:   f.Username,
 	}
 
 	return nil
@@ -0,0 +1,308 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/tapglue/snaas/core"
+	"github.com/tapglue/snaas/service/webhook"
+)
+
+// WebhookCreate stores a new webhook for the app. The response is the only
+// place the signing secret is returned.
+func WebhookCreate(fn core.WebhookCreateFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		p := payloadWebhook{}
+
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		created, err := fn(appID, p.webhook)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusCreated, &payloadWebhook{
+			secret:  true,
+			webhook: created,
+		})
+	}
+}
+
+// WebhookDelete flags the webhook as deleted.
+func WebhookDelete(fn core.WebhookDeleteFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		webhookID, err := extractWebhookID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		err = fn(appID, webhookID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// WebhookDeliveryList returns the most recent deliveries of a webhook.
+func WebhookDeliveryList(fn core.WebhookDeliveryListFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		webhookID, err := extractWebhookID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		limit, err := extractLimit(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		ds, err := fn(appID, webhookID, limit)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		if len(ds) == 0 {
+			respondJSON(w, http.StatusNoContent, nil)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadWebhookDeliveries{deliveries: ds})
+	}
+}
+
+// WebhookList returns all webhooks of the app.
+func WebhookList(fn core.WebhookListFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		ws, err := fn(appID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		if len(ws) == 0 {
+			respondJSON(w, http.StatusNoContent, nil)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadWebhooks{webhooks: ws})
+	}
+}
+
+// WebhookRetrieve returns a single webhook by id.
+func WebhookRetrieve(fn core.WebhookFetchFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		webhookID, err := extractWebhookID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		hook, err := fn(appID, webhookID)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadWebhook{webhook: hook})
+	}
+}
+
+// WebhookUpdate replaces the mutable fields of a webhook.
+func WebhookUpdate(fn core.WebhookUpdateFunc) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		appID, err := extractAppID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		webhookID, err := extractWebhookID(r)
+		if err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		p := payloadWebhook{}
+
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			respondError(w, 0, wrapError(ErrBadRequest, err.Error()))
+			return
+		}
+
+		updated, err := fn(appID, webhookID, p.webhook)
+		if err != nil {
+			respondError(w, 0, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, &payloadWebhook{webhook: updated})
+	}
+}
+
+type payloadWebhook struct {
+	secret  bool
+	webhook *webhook.Webhook
+}
+
+func (p *payloadWebhook) MarshalJSON() ([]byte, error) {
+	secret := ""
+
+	if p.secret {
+		secret = p.webhook.Secret
+	}
+
+	return json.Marshal(struct {
+		Active        bool                  `json:"active"`
+		ID            string                `json:"id"`
+		Name          string                `json:"name"`
+		Secret        string                `json:"secret,omitempty"`
+		Subscriptions webhook.Subscriptions `json:"subscriptions"`
+		URL           string                `json:"url"`
+		CreatedAt     time.Time             `json:"created_at"`
+		UpdatedAt     time.Time             `json:"updated_at"`
+	}{
+		Active:        p.webhook.Active,
+		ID:            strconv.FormatUint(p.webhook.ID, 10),
+		Name:          p.webhook.Name,
+		Secret:        secret,
+		Subscriptions: p.webhook.Subscriptions,
+		URL:           p.webhook.URL,
+		CreatedAt:     p.webhook.CreatedAt,
+		UpdatedAt:     p.webhook.UpdatedAt,
+	})
+}
+
+func (p *payloadWebhook) UnmarshalJSON(raw []byte) error {
+	f := struct {
+		Active        bool                  `json:"active"`
+		Name          string                `json:"name"`
+		Secret        string                `json:"secret"`
+		Subscriptions webhook.Subscriptions `json:"subscriptions"`
+		URL           string                `json:"url"`
+	}{}
+
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return err
+	}
+
+	p.webhook = &webhook.Webhook{
+		Active:        f.Active,
+		Name:          f.Name,
+		Secret:        f.Secret,
+		Subscriptions: f.Subscriptions,
+		URL:           f.URL,
+	}
+
+	return nil
+}
+
+type payloadWebhooks struct {
+	webhooks webhook.List
+}
+
+func (p *payloadWebhooks) MarshalJSON() ([]byte, error) {
+	ws := []*payloadWebhook{}
+
+	for _, w := range p.webhooks {
+		ws = append(ws, &payloadWebhook{webhook: w})
+	}
+
+	return json.Marshal(struct {
+		Webhooks []*payloadWebhook `json:"webhooks"`
+	}{
+		Webhooks: ws,
+	})
+}
+
+type payloadWebhookDelivery struct {
+	delivery *webhook.Delivery
+}
+
+func (p *payloadWebhookDelivery) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Attempts      int             `json:"attempts"`
+		Error         string          `json:"error,omitempty"`
+		Event         string          `json:"event"`
+		ID            string          `json:"id"`
+		Payload       json.RawMessage `json:"payload"`
+		State         webhook.State   `json:"state"`
+		StatusCode    int             `json:"status_code"`
+		NextAttemptAt time.Time       `json:"next_attempt_at"`
+		CreatedAt     time.Time       `json:"created_at"`
+		UpdatedAt     time.Time       `json:"updated_at"`
+	}{
+		Attempts:      p.delivery.Attempts,
+		Error:         p.delivery.Error,
+		Event:         p.delivery.Event,
+		ID:            strconv.FormatUint(p.delivery.ID, 10),
+		Payload:       p.delivery.Payload,
+		State:         p.delivery.State,
+		StatusCode:    p.delivery.StatusCode,
+		NextAttemptAt: p.delivery.NextAttemptAt,
+		CreatedAt:     p.delivery.CreatedAt,
+		UpdatedAt:     p.delivery.UpdatedAt,
+	})
+}
+
+type payloadWebhookDeliveries struct {
+	deliveries webhook.Deliveries
+}
+
+func (p *payloadWebhookDeliveries) MarshalJSON() ([]byte, error) {
+	ds := []*payloadWebhookDelivery{}
+
+	for _, d := range p.deliveries {
+		ds = append(ds, &payloadWebhookDelivery{delivery: d})
+	}
+
+	return json.Marshal(struct {
+		Deliveries []*payloadWebhookDelivery `json:"deliveries"`
+	}{
+		Deliveries: ds,
+	})
+}
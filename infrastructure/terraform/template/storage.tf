@@ -298,6 +298,32 @@ EOF
   visibility_timeout_seconds = 60
 }
 
+resource "aws_sqs_queue" "user-state-change-dlq" {
+  delay_seconds              = 0
+  max_message_size           = 262144
+  message_retention_seconds  = 1209600
+  name                       = "user-state-change-dlq"
+  receive_wait_time_seconds  = 1
+  visibility_timeout_seconds = 300
+}
+
+resource "aws_sqs_queue" "user-state-change" {
+  delay_seconds             = 0
+  max_message_size          = 262144
+  message_retention_seconds = 1209600
+  name                      = "user-state-change"
+  receive_wait_time_seconds = 1
+
+  redrive_policy = <<EOF
+{
+    "deadLetterTargetArn": "${aws_sqs_queue.user-state-change-dlq.arn}",
+    "maxReceiveCount": 10
+}
+EOF
+
+  visibility_timeout_seconds = 60
+}
+
 # Device update queues, topics and subscriptions.
 resource "aws_sqs_queue" "endpoint-state-change-dlq" {
   delay_seconds              = 0
@@ -0,0 +1,276 @@
+// Package cron implements the five field cron expressions scheduled rules are
+// evaluated by. The fields are minute, hour, day of month, month and day of
+// week, each given as "*", a value, a range "a-b" or a list of those separated
+// by commas; "*" and ranges take an optional step "/n". Day of week counts
+// from 0 for Sunday, 7 is accepted as Sunday as well. If both day fields are
+// restricted a time matches if either of them does. The descriptors @yearly,
+// @monthly, @weekly, @daily and @hourly are supported as shorthands.
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxYears bounds the search for the next or previous match, expressions like
+// "0 0 30 2 *" never match.
+const maxYears = 5
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type bounds struct {
+	name     string
+	min, max uint
+}
+
+var (
+	boundsMinute = bounds{"minute", 0, 59}
+	boundsHour   = bounds{"hour", 0, 23}
+	boundsDom    = bounds{"day of month", 1, 31}
+	boundsMonth  = bounds{"month", 1, 12}
+	boundsDow    = bounds{"day of week", 0, 7}
+)
+
+// Schedule is a parsed cron expression. Times are matched in the location they
+// carry, with a resolution of minutes.
+type Schedule struct {
+	dom, dow, hour, minute, month uint64
+
+	domStar, dowStar bool
+	spec             string
+}
+
+// Parse turns the expression into a Schedule.
+func Parse(spec string) (*Schedule, error) {
+	expanded := strings.TrimSpace(spec)
+
+	if strings.HasPrefix(expanded, "@") {
+		e, ok := descriptors[expanded]
+		if !ok {
+			return nil, wrapError(ErrInvalidExpression, "unknown descriptor '%s'", expanded)
+		}
+
+		expanded = e
+	}
+
+	fields := strings.Fields(expanded)
+	if len(fields) != 5 {
+		return nil, wrapError(
+			ErrInvalidExpression,
+			"expected 5 fields, got %d",
+			len(fields),
+		)
+	}
+
+	s := &Schedule{
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+		spec:    spec,
+	}
+
+	for i, f := range []struct {
+		b   bounds
+		set *uint64
+	}{
+		{boundsMinute, &s.minute},
+		{boundsHour, &s.hour},
+		{boundsDom, &s.dom},
+		{boundsMonth, &s.month},
+		{boundsDow, &s.dow},
+	} {
+		set, err := parseField(fields[i], f.b)
+		if err != nil {
+			return nil, err
+		}
+
+		*f.set = set
+	}
+
+	// Fold 7 into 0 as both denote Sunday.
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	return s, nil
+}
+
+// Match reports if t falls into a minute the Schedule fires in.
+func (s *Schedule) Match(t time.Time) bool {
+	return s.matchDay(t) &&
+		has(s.hour, t.Hour()) &&
+		has(s.minute, t.Minute())
+}
+
+// Next returns the first minute after t the Schedule fires in, or the zero
+// time if there is none within the next years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	var (
+		c     = t.Truncate(time.Minute).Add(time.Minute)
+		limit = t.AddDate(maxYears, 0, 0)
+	)
+
+	for c.Before(limit) {
+		switch {
+		case !has(s.month, int(c.Month())):
+			c = time.Date(c.Year(), c.Month()+1, 1, 0, 0, 0, 0, c.Location())
+		case !s.matchDay(c):
+			c = time.Date(c.Year(), c.Month(), c.Day()+1, 0, 0, 0, 0, c.Location())
+		case !has(s.hour, c.Hour()):
+			c = time.Date(c.Year(), c.Month(), c.Day(), c.Hour()+1, 0, 0, 0, c.Location())
+		case !has(s.minute, c.Minute()):
+			c = c.Add(time.Minute)
+		default:
+			return c
+		}
+	}
+
+	return time.Time{}
+}
+
+// Prev returns the last minute before t the Schedule fired in, or the zero time
+// if there is none within the past years.
+func (s *Schedule) Prev(t time.Time) time.Time {
+	var (
+		c     = t.Truncate(time.Minute)
+		limit = t.AddDate(-maxYears, 0, 0)
+	)
+
+	if !c.Before(t) {
+		c = c.Add(-time.Minute)
+	}
+
+	for c.After(limit) {
+		switch {
+		case !has(s.month, int(c.Month())):
+			c = time.Date(c.Year(), c.Month(), 1, 0, 0, 0, 0, c.Location()).Add(-time.Minute)
+		case !s.matchDay(c):
+			c = time.Date(c.Year(), c.Month(), c.Day(), 0, 0, 0, 0, c.Location()).Add(-time.Minute)
+		case !has(s.hour, c.Hour()):
+			c = time.Date(c.Year(), c.Month(), c.Day(), c.Hour(), 0, 0, 0, c.Location()).Add(-time.Minute)
+		case !has(s.minute, c.Minute()):
+			c = c.Add(-time.Minute)
+		default:
+			return c
+		}
+	}
+
+	return time.Time{}
+}
+
+// String returns the expression the Schedule was parsed from.
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+func (s *Schedule) matchDay(t time.Time) bool {
+	var (
+		dom = has(s.dom, t.Day())
+		dow = has(s.dow, int(t.Weekday()))
+	)
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		var (
+			rng  = part
+			step = uint(1)
+		)
+
+		if i := strings.Index(part, "/"); i != -1 {
+			s, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || s == 0 {
+				return 0, wrapError(
+					ErrInvalidExpression,
+					"%s: invalid step '%s'",
+					b.name,
+					part[i+1:],
+				)
+			}
+
+			rng, step = part[:i], uint(s)
+		}
+
+		var lo, hi uint
+
+		switch i := strings.Index(rng, "-"); {
+		case rng == "*":
+			lo, hi = b.min, b.max
+		case i != -1:
+			l, err := parseValue(rng[:i], b)
+			if err != nil {
+				return 0, err
+			}
+
+			h, err := parseValue(rng[i+1:], b)
+			if err != nil {
+				return 0, err
+			}
+
+			if h < l {
+				return 0, wrapError(
+					ErrInvalidExpression,
+					"%s: range '%s' is reversed",
+					b.name,
+					rng,
+				)
+			}
+
+			lo, hi = l, h
+		default:
+			v, err := parseValue(rng, b)
+			if err != nil {
+				return 0, err
+			}
+
+			lo, hi = v, v
+
+			// A step on a single value runs until the end of the range.
+			if step > 1 {
+				hi = b.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+
+	return set, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil || uint(v) < b.min || uint(v) > b.max {
+		return 0, wrapError(
+			ErrInvalidExpression,
+			"%s: '%s' is not within %d-%d",
+			b.name,
+			s,
+			b.min,
+			b.max,
+		)
+	}
+
+	return uint(v), nil
+}
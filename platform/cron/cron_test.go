@@ -0,0 +1,121 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"@fortnightly",
+	} {
+		if _, err := Parse(spec); !IsInvalidExpression(err) {
+			t.Errorf("%q: have %v, want %v", spec, err, ErrInvalidExpression)
+		}
+	}
+}
+
+func TestScheduleMatch(t *testing.T) {
+	cases := []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", time.Date(2017, 3, 1, 13, 37, 42, 0, time.UTC), true},
+		{"30 9 * * *", time.Date(2017, 3, 1, 9, 30, 0, 0, time.UTC), true},
+		{"30 9 * * *", time.Date(2017, 3, 1, 9, 31, 0, 0, time.UTC), false},
+		{"*/15 * * * *", time.Date(2017, 3, 1, 9, 45, 0, 0, time.UTC), true},
+		{"*/15 * * * *", time.Date(2017, 3, 1, 9, 50, 0, 0, time.UTC), false},
+		{"0 9-17/4 * * *", time.Date(2017, 3, 1, 13, 0, 0, 0, time.UTC), true},
+		{"0 9-17/4 * * *", time.Date(2017, 3, 1, 15, 0, 0, 0, time.UTC), false},
+		{"0 0 * * 1-5", time.Date(2017, 3, 4, 0, 0, 0, 0, time.UTC), false},
+		{"0 0 * * 7", time.Date(2017, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		// Restricted day of month and day of week match if either does.
+		{"0 0 13 * 5", time.Date(2017, 3, 3, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 13 * 5", time.Date(2017, 3, 13, 0, 0, 0, 0, time.UTC), true},
+		{"0 0 13 * 5", time.Date(2017, 3, 14, 0, 0, 0, 0, time.UTC), false},
+		{"@monthly", time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, c := range cases {
+		s, err := Parse(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := s.Match(c.t), c.want; have != want {
+			t.Errorf("%q %v: have %v, want %v", c.spec, c.t, have, want)
+		}
+	}
+}
+
+func TestScheduleNextPrev(t *testing.T) {
+	cases := []struct {
+		spec string
+		t    time.Time
+		next time.Time
+		prev time.Time
+	}{
+		{
+			spec: "*/5 * * * *",
+			t:    time.Date(2017, 3, 1, 9, 30, 0, 0, time.UTC),
+			next: time.Date(2017, 3, 1, 9, 35, 0, 0, time.UTC),
+			prev: time.Date(2017, 3, 1, 9, 25, 0, 0, time.UTC),
+		},
+		{
+			spec: "*/5 * * * *",
+			t:    time.Date(2017, 3, 1, 9, 32, 10, 0, time.UTC),
+			next: time.Date(2017, 3, 1, 9, 35, 0, 0, time.UTC),
+			prev: time.Date(2017, 3, 1, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 9 * * 1-5",
+			t:    time.Date(2017, 3, 3, 9, 0, 0, 0, time.UTC),
+			next: time.Date(2017, 3, 6, 9, 0, 0, 0, time.UTC),
+			prev: time.Date(2017, 3, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "@yearly",
+			t:    time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC),
+			next: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+			prev: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 12 29 2 *",
+			t:    time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC),
+			next: time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC),
+			prev: time.Date(2016, 2, 29, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			spec: "0 0 30 2 *",
+			t:    time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, c := range cases {
+		s, err := Parse(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := s.Next(c.t), c.next; !have.Equal(want) {
+			t.Errorf("%q next: have %v, want %v", c.spec, have, want)
+		}
+
+		if have, want := s.Prev(c.t), c.prev; !have.Equal(want) {
+			t.Errorf("%q prev: have %v, want %v", c.spec, have, want)
+		}
+	}
+}
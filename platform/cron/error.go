@@ -0,0 +1,46 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// ErrInvalidExpression is returned when a cron expression can't be parsed.
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// Error wraps common cron errors.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsInvalidExpression indicates if err is ErrInvalidExpression.
+func IsInvalidExpression(err error) bool {
+	return unwrapError(err) == ErrInvalidExpression
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err,
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
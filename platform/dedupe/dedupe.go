@@ -0,0 +1,12 @@
+package dedupe
+
+import "time"
+
+// Deduper remembers keys for a window to detect repeated occurrences.
+type Deduper interface {
+	// Seen reports if the key occurred already within the window, otherwise
+	// it remembers the key for the length of the window.
+	Seen(key string, window time.Duration) (bool, error)
+	// Forget removes the key, so its next occurrence is not reported as seen.
+	Forget(key string) error
+}
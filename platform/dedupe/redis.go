@@ -0,0 +1,64 @@
+package dedupe
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	predis "github.com/tapglue/snaas/platform/redis"
+)
+
+type redisDeduper struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// Redis returns a Redis Deduper implementation.
+func Redis(pool *redis.Pool, prefix string) Deduper {
+	return &redisDeduper{
+		pool:   pool,
+		prefix: prefix,
+	}
+}
+
+func (d *redisDeduper) Forget(key string) error {
+	conn := d.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do(predis.CommandDel, d.redisKey(key))
+	if err != nil {
+		return fmt.Errorf("dedupe forget failed: %s", err)
+	}
+
+	return nil
+}
+
+func (d *redisDeduper) Seen(key string, window time.Duration) (bool, error) {
+	conn := d.pool.Get()
+	defer conn.Close()
+
+	ttl := int64(window / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	// SET with NX only succeeds for absent keys and replies nil otherwise.
+	res, err := conn.Do(
+		predis.CommandSet,
+		d.redisKey(key),
+		1,
+		predis.CommandEx,
+		ttl,
+		predis.CommandNx,
+	)
+	if err != nil {
+		return false, fmt.Errorf("dedupe seen failed: %s", err)
+	}
+
+	return res == nil, nil
+}
+
+func (d *redisDeduper) redisKey(key string) string {
+	return fmt.Sprintf("%s:%s", d.prefix, key)
+}
@@ -0,0 +1,68 @@
+package dedupe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestDeduper(t *testing.T) {
+	var (
+		pool = redis.NewPool(func() (redis.Conn, error) {
+			return redis.Dial("tcp", "127.0.0.1:6379")
+		}, 10)
+		d   = Redis(pool, "dedupetest")
+		key = "message"
+	)
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", "dedupetest:message")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen, err := d.Seen(key, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := seen, false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	seen, err = d.Seen(key, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := seen, true; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := d.Forget(key); err != nil {
+		t.Fatal(err)
+	}
+
+	seen, err = d.Seen(key, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := seen, false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	seen, err = d.Seen(key, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := seen, false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,71 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Email is a plain text message addressed to a single recipient.
+type Email struct {
+	Body    string
+	Subject string
+	To      string
+}
+
+// Validate checks for semantic correctness.
+func (e *Email) Validate() error {
+	if e.To == "" {
+		return wrapError(ErrInvalidEmail, "recipient missing")
+	}
+
+	if strings.ContainsAny(e.To, "\r\n") {
+		return wrapError(ErrInvalidEmail, "recipient contains line breaks")
+	}
+
+	if _, err := mail.ParseAddress(e.To); err != nil {
+		return wrapError(ErrInvalidEmail, "recipient malformed: %s", err)
+	}
+
+	if e.Subject == "" {
+		return wrapError(ErrInvalidEmail, "subject missing")
+	}
+
+	return nil
+}
+
+// Sender delivers Emails.
+type Sender interface {
+	Send(e *Email) error
+}
+
+// compose renders the Email as RFC 5322 message with a quoted-printable
+// encoded UTF-8 body.
+func compose(from string, e *Email, date time.Time) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	fmt.Fprintf(buf, "To: %s\r\n", e.To)
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", e.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprint(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprint(buf, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprint(buf, "Content-Transfer-Encoding: quoted-printable\r\n")
+	fmt.Fprint(buf, "\r\n")
+
+	w := quotedprintable.NewWriter(buf)
+
+	if _, err := w.Write([]byte(e.Body)); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
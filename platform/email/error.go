@@ -0,0 +1,54 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// Common errors for Sender implementations.
+var (
+	ErrDeliveryFailure = errors.New("delivery failed")
+	ErrInvalidEmail    = errors.New("invalid email")
+)
+
+// Error wraps common Sender errors.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsDeliveryFailure indicates if err is ErrDeliveryFailure.
+func IsDeliveryFailure(err error) bool {
+	return unwrapError(err) == ErrDeliveryFailure
+}
+
+// IsInvalidEmail indicates if err is ErrInvalidEmail.
+func IsInvalidEmail(err error) bool {
+	return unwrapError(err) == ErrInvalidEmail
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err,
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
@@ -0,0 +1,45 @@
+package email
+
+import (
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+type smtpSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// SMTPSender returns a Sender which hands Emails to the SMTP server at addr.
+// Permanent rejections by the server are reported as ErrDeliveryFailure.
+func SMTPSender(addr, from string, auth smtp.Auth) Sender {
+	return &smtpSender{
+		addr: addr,
+		auth: auth,
+		from: from,
+	}
+}
+
+func (s *smtpSender) Send(e *Email) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	msg, err := compose(s.from, e, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = smtp.SendMail(s.addr, s.auth, s.from, []string{e.To}, msg)
+	if err != nil {
+		if pErr, ok := err.(*textproto.Error); ok && pErr.Code >= 500 {
+			return wrapError(ErrDeliveryFailure, "%s", pErr.Msg)
+		}
+
+		return err
+	}
+
+	return nil
+}
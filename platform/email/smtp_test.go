@@ -0,0 +1,192 @@
+package email
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestSMTPSender(t *testing.T) {
+	stub := newSMTPStub(t)
+	defer stub.close()
+
+	err := SMTPSender(stub.addr(), "noreply@tapglue.test", nil).Send(&Email{
+		Body:    "Héllo,\nyou have a new follower.",
+		Subject: "New follower",
+		To:      "alice@tapglue.test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := <-stub.sessions
+
+	if have, want := s.from, "<noreply@tapglue.test>"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := s.rcpt, "<alice@tapglue.test>"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	for _, want := range []string{
+		"To: alice@tapglue.test",
+		"Subject: New follower",
+		"Content-Type: text/plain; charset=utf-8",
+		"H=C3=A9llo,",
+	} {
+		if !strings.Contains(s.data, want) {
+			t.Errorf("have %q, want it to contain %q", s.data, want)
+		}
+	}
+}
+
+func TestSMTPSenderRejected(t *testing.T) {
+	stub := newSMTPStub(t)
+	defer stub.close()
+
+	stub.reject = true
+
+	err := SMTPSender(stub.addr(), "noreply@tapglue.test", nil).Send(&Email{
+		Body:    "Hello",
+		Subject: "New follower",
+		To:      "unknown@tapglue.test",
+	})
+
+	if have, want := IsDeliveryFailure(err), true; have != want {
+		t.Errorf("have %v, want %v: %v", have, want, err)
+	}
+}
+
+func TestEmailValidate(t *testing.T) {
+	for _, e := range []*Email{
+		{Subject: "New follower"},
+		{Subject: "New follower", To: "alice@tapglue.test\r\nBcc: bob@tapglue.test"},
+		{Subject: "New follower", To: "alice"},
+		{To: "alice@tapglue.test"},
+	} {
+		if have, want := IsInvalidEmail(e.Validate()), true; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+type smtpSession struct {
+	data string
+	from string
+	rcpt string
+}
+
+// smtpStub is a minimal SMTP server which accepts a single session.
+type smtpStub struct {
+	listener net.Listener
+	reject   bool
+	sessions chan smtpSession
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &smtpStub{
+		listener: l,
+		sessions: make(chan smtpSession, 1),
+	}
+
+	go s.serve()
+
+	return s
+}
+
+func (s *smtpStub) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpStub) close() {
+	s.listener.Close()
+}
+
+func (s *smtpStub) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var (
+		c       = textproto.NewConn(conn)
+		session = smtpSession{}
+	)
+
+	reply := func(format string, args ...interface{}) {
+		c.PrintfLine(format, args...)
+	}
+
+	reply("220 stub ready")
+
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 stub")
+		case "MAIL":
+			session.from = strings.TrimPrefix(line, "MAIL FROM:")
+			reply("250 ok")
+		case "RCPT":
+			if s.reject {
+				reply("550 no such user")
+				continue
+			}
+
+			session.rcpt = strings.TrimPrefix(line, "RCPT TO:")
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+
+			data, err := readData(c.Reader.R)
+			if err != nil {
+				return
+			}
+
+			session.data = data
+			reply("250 ok")
+		case "RSET", "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			s.sessions <- session
+			return
+		default:
+			reply("502 %s not implemented", verb)
+		}
+	}
+}
+
+func readData(r *bufio.Reader) (string, error) {
+	lines := []string{}
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "." {
+			return strings.Join(lines, "\r\n"), nil
+		}
+
+		lines = append(lines, line)
+	}
+}
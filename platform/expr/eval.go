@@ -0,0 +1,538 @@
+package expr
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var typeTime = reflect.TypeOf(time.Time{})
+
+type node interface {
+	check(t reflect.Type) error
+	eval(e *env) (interface{}, error)
+}
+
+type changed struct {
+	fields []string
+	pos    int
+}
+
+func (n *changed) check(t reflect.Type) error {
+	return checkFields(t, n.fields, n.pos)
+}
+
+func (n *changed) eval(e *env) (interface{}, error) {
+	if isNil(e.old) || isNil(e.new) {
+		return false, nil
+	}
+
+	o, err := resolve(e.old, n.fields)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := resolve(e.new, n.fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return !equal(o, v), nil
+}
+
+type comparison struct {
+	left  node
+	op    string
+	pos   int
+	right node
+}
+
+func (n *comparison) check(t reflect.Type) error {
+	if err := n.left.check(t); err != nil {
+		return err
+	}
+
+	return n.right.check(t)
+}
+
+func (n *comparison) eval(e *env) (interface{}, error) {
+	a, err := n.left.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := n.right.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(a, b), nil
+	case "!=":
+		return !equal(a, b), nil
+	case "in":
+		return contains(b, a)
+	}
+
+	if a == nil || b == nil {
+		return false, nil
+	}
+
+	c, err := compare(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+
+	return nil, fmt.Errorf("unknown operator '%s'", n.op)
+}
+
+type exists struct {
+	path *path
+}
+
+func (n *exists) check(t reflect.Type) error {
+	return n.path.check(t)
+}
+
+func (n *exists) eval(e *env) (interface{}, error) {
+	v, err := n.path.eval(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return v != nil, nil
+}
+
+type list struct {
+	items []node
+}
+
+func (n *list) check(t reflect.Type) error {
+	for _, item := range n.items {
+		if err := item.check(t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *list) eval(e *env) (interface{}, error) {
+	vs := []interface{}{}
+
+	for _, item := range n.items {
+		v, err := item.eval(e)
+		if err != nil {
+			return nil, err
+		}
+
+		vs = append(vs, v)
+	}
+
+	return vs, nil
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (n *literal) check(t reflect.Type) error {
+	return nil
+}
+
+func (n *literal) eval(e *env) (interface{}, error) {
+	return n.value, nil
+}
+
+type logical struct {
+	and   bool
+	left  node
+	right node
+}
+
+func (n *logical) check(t reflect.Type) error {
+	if err := n.left.check(t); err != nil {
+		return err
+	}
+
+	return n.right.check(t)
+}
+
+func (n *logical) eval(e *env) (interface{}, error) {
+	a, err := evalBool(n.left, e)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit as soon as the outcome is known.
+	if a != n.and {
+		return a, nil
+	}
+
+	return evalBool(n.right, e)
+}
+
+type not struct {
+	operand node
+}
+
+func (n *not) check(t reflect.Type) error {
+	return n.operand.check(t)
+}
+
+func (n *not) eval(e *env) (interface{}, error) {
+	b, err := evalBool(n.operand, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return !b, nil
+}
+
+type path struct {
+	fields []string
+	pos    int
+	root   string
+}
+
+func (n *path) check(t reflect.Type) error {
+	return checkFields(t, n.fields, n.pos)
+}
+
+func (n *path) eval(e *env) (interface{}, error) {
+	return resolve(e.root(n.root), n.fields)
+}
+
+func checkFields(t reflect.Type, fields []string, pos int) error {
+	if t == nil {
+		return nil
+	}
+
+	for _, field := range fields {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		switch {
+		case t.Kind() == reflect.Struct && t != typeTime:
+			f, ok := structField(t, field)
+			if !ok {
+				return &Error{
+					Pos: pos,
+					Msg: fmt.Sprintf("unknown field '%s'", field),
+				}
+			}
+
+			t = f.Type
+		case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+			t = t.Elem()
+		case t.Kind() == reflect.Interface:
+			return nil
+		default:
+			return &Error{
+				Pos: pos,
+				Msg: fmt.Sprintf("field '%s' can't be accessed on %s", field, t),
+			}
+		}
+	}
+
+	return nil
+}
+
+func resolve(root interface{}, fields []string) (interface{}, error) {
+	v := reflect.ValueOf(root)
+
+	for _, field := range fields {
+		v = indirect(v)
+
+		if !v.IsValid() {
+			return nil, nil
+		}
+
+		switch {
+		case v.Kind() == reflect.Struct && v.Type() != typeTime:
+			f, ok := structField(v.Type(), field)
+			if !ok {
+				return nil, fmt.Errorf("unknown field '%s'", field)
+			}
+
+			v = v.FieldByIndex(f.Index)
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			v = v.MapIndex(reflect.ValueOf(field).Convert(v.Type().Key()))
+		default:
+			return nil, fmt.Errorf("field '%s' can't be accessed on %s", field, v.Type())
+		}
+	}
+
+	return normalize(v), nil
+}
+
+// normalize converts v into one of nil, bool, float64, string, []interface{}
+// or map[string]interface{}.
+func normalize(v reflect.Value) interface{} {
+	v = indirect(v)
+
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Array, reflect.Slice:
+		vs := []interface{}{}
+
+		for i := 0; i < v.Len(); i++ {
+			vs = append(vs, normalize(v.Index(i)))
+		}
+
+		return vs
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+
+		m := map[string]interface{}{}
+
+		for _, k := range v.MapKeys() {
+			m[k.String()] = normalize(v.MapIndex(k))
+		}
+
+		return m
+	case reflect.Struct:
+		if v.Type() == typeTime {
+			return v.Interface().(time.Time).Format(time.RFC3339Nano)
+		}
+
+		m := map[string]interface{}{}
+
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+
+			name := fieldName(f)
+
+			if f.PkgPath != "" || name == "" {
+				continue
+			}
+
+			m[name] = normalize(v.Field(i))
+		}
+
+		return m
+	}
+
+	return nil
+}
+
+func compare(a, b interface{}) (int, error) {
+	if x, y, ok := numbers(a, b); ok {
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		}
+
+		return 0, nil
+	}
+
+	x, okA := a.(string)
+	y, okB := b.(string)
+
+	if !okA || !okB {
+		return 0, fmt.Errorf("can't compare %s with %s", typeName(a), typeName(b))
+	}
+
+	return strings.Compare(x, y), nil
+}
+
+func contains(set, v interface{}) (bool, error) {
+	switch s := set.(type) {
+	case nil:
+		return false, nil
+	case []interface{}:
+		for _, item := range s {
+			if equal(item, v) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case map[string]interface{}:
+		k, ok := v.(string)
+		if !ok {
+			return false, nil
+		}
+
+		_, ok = s[k]
+
+		return ok, nil
+	}
+
+	return false, fmt.Errorf("can't test membership in %s", typeName(set))
+}
+
+func equal(a, b interface{}) bool {
+	if x, y, ok := numbers(a, b); ok {
+		return x == y
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func evalBool(n node, e *env) (bool, error) {
+	v, err := n.eval(e)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected boolean, got %s", typeName(v))
+	}
+
+	return b, nil
+}
+
+// fieldName returns the name f is addressed by, which is empty for hidden
+// fields.
+func fieldName(f reflect.StructField) string {
+	if f.Tag.Get("expr") == "-" {
+		return ""
+	}
+
+	tag := strings.Split(f.Tag.Get("json"), ",")[0]
+
+	switch tag {
+	case "-":
+		return ""
+	case "":
+		return snakeCase(f.Name)
+	}
+
+	return tag
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+
+		v = v.Elem()
+	}
+
+	return v
+}
+
+func isNil(v interface{}) bool {
+	return !indirect(reflect.ValueOf(v)).IsValid()
+}
+
+// numbers returns a and b as float64 if both are numeric, numeric strings are
+// accepted as long as the other value is a number.
+func numbers(a, b interface{}) (float64, float64, bool) {
+	x, okA := a.(float64)
+	y, okB := b.(float64)
+
+	switch {
+	case okA && okB:
+		return x, y, true
+	case okA:
+		s, ok := b.(string)
+		if !ok {
+			return 0, 0, false
+		}
+
+		y, err := strconv.ParseFloat(s, 64)
+
+		return x, y, err == nil
+	case okB:
+		s, ok := a.(string)
+		if !ok {
+			return 0, 0, false
+		}
+
+		x, err := strconv.ParseFloat(s, 64)
+
+		return x, y, err == nil
+	}
+
+	return 0, 0, false
+}
+
+func snakeCase(name string) string {
+	var (
+		rs = []rune(name)
+		s  = []rune{}
+	)
+
+	for i, r := range rs {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := rs[i-1]
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+				s = append(s, '_')
+			}
+		}
+
+		s = append(s, unicode.ToLower(r))
+	}
+
+	return string(s)
+}
+
+func structField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.PkgPath == "" && name != "" && fieldName(f) == name {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
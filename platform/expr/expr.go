@@ -0,0 +1,108 @@
+// Package expr implements a small expression language to match state changes,
+// evaluated against the old and new value of an entity.
+//
+// Fields are addressed by path from one of the roots old and new, using the
+// JSON names of the entity, e.g. new.visibility or new.metadata.level. Fields
+// without a JSON name are addressed in snake case. Fields tagged json:"-" or
+// expr:"-" can't be addressed at all, which keeps secrets like passwords out of
+// reach. Supported are:
+//
+//	literals     true, false, null, numbers, 'strings', "strings", [lists]
+//	comparison   ==, !=, <, <=, >, >=
+//	membership   'news' in new.tags, new.type in ['post', 'article']
+//	logic        &&, ||, ! as well as and, or, not
+//	predicates   changed(visibility), exists(new.private)
+//
+// Comparisons between a number and a numeric string are numeric, comparisons
+// involving a missing value only hold for equality with null.
+//
+//	'news' in new.tags && changed(visibility) && new.visibility == 30
+package expr
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Roots of field paths.
+const (
+	RootNew = "new"
+	RootOld = "old"
+)
+
+// Error describes a malformed expression, Pos is the byte offset of the
+// offending input.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos+1, e.Msg)
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	root node
+	src  string
+}
+
+// Parse compiles src into an Expr.
+func Parse(src string) (*Expr, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expr{
+		root: root,
+		src:  src,
+	}, nil
+}
+
+// Check verifies that all field paths of the Expr exist on entity, which is
+// only used for its type.
+func (e *Expr) Check(entity interface{}) error {
+	return e.root.check(reflect.TypeOf(entity))
+}
+
+// Eval reports if the Expr holds for the given old and new value, either of
+// which can be nil.
+func (e *Expr) Eval(old, new interface{}) (bool, error) {
+	v, err := e.root.eval(&env{
+		new: new,
+		old: old,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluates to %s, not a boolean", typeName(v))
+	}
+
+	return b, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type env struct {
+	new interface{}
+	old interface{}
+}
+
+func (e *env) root(name string) interface{} {
+	if name == RootOld {
+		return e.old
+	}
+
+	return e.new
+}
@@ -0,0 +1,193 @@
+package expr
+
+import (
+	"testing"
+	"time"
+)
+
+type testEntity struct {
+	Metadata   map[string]string `json:"metadata"`
+	OwnerID    uint64            `json:"owner_id"`
+	Password   string            `json:"password" expr:"-"`
+	Private    *testPrivate      `json:"private,omitempty"`
+	Secret     string            `json:"-"`
+	Tags       []string          `json:"tags"`
+	Type       string            `json:"type"`
+	Visibility uint8             `json:"visibility"`
+	CreatedAt  time.Time         `json:"created_at"`
+	ObjectID   uint64
+}
+
+type testPrivate struct {
+	Visible bool `json:"visible"`
+}
+
+func TestExprEval(t *testing.T) {
+	var (
+		old = &testEntity{
+			Metadata:   map[string]string{"level": "9"},
+			OwnerID:    123,
+			Tags:       []string{"news"},
+			Type:       "post",
+			Visibility: 20,
+			ObjectID:   321,
+		}
+		new = &testEntity{
+			Metadata:   map[string]string{"level": "12"},
+			OwnerID:    123,
+			Private:    &testPrivate{Visible: true},
+			Tags:       []string{"news", "sports"},
+			Type:       "post",
+			Visibility: 30,
+			ObjectID:   321,
+		}
+	)
+
+	cases := []struct {
+		src  string
+		old  interface{}
+		want bool
+	}{
+		{src: "new.type == 'post'", old: old, want: true},
+		{src: `new.type != "post"`, old: old, want: false},
+		{src: "new.visibility > old.visibility", old: old, want: true},
+		{src: "new.visibility >= 30 && new.owner_id == 123", old: old, want: true},
+		{src: "new.metadata.level >= 10", old: old, want: true},
+		{src: "old.metadata.level >= 10", old: old, want: false},
+		{src: "new.metadata.missing == null", old: old, want: true},
+		{src: "new.metadata.missing > 1", old: old, want: false},
+		{src: "'sports' in new.tags", old: old, want: true},
+		{src: "'sports' in old.tags", old: old, want: false},
+		{src: "new.type in ['article', 'post']", old: old, want: true},
+		{src: "'level' in new.metadata", old: old, want: true},
+		{src: "changed(visibility)", old: old, want: true},
+		{src: "changed(owner_id)", old: old, want: false},
+		{src: "changed(tags) and not changed(type)", old: old, want: true},
+		{src: "changed(visibility)", old: nil, want: false},
+		{src: "exists(new.private) && new.private.visible", old: old, want: true},
+		{src: "exists(old.private)", old: old, want: false},
+		{src: "exists(old.type)", old: nil, want: false},
+		{src: "!(new.object_id == 321) || old.type == 'post'", old: old, want: true},
+		{src: "new.visibility == 10 or new.visibility == 30", old: old, want: true},
+		{src: "new.owner_id > -1", old: old, want: true},
+	}
+
+	for _, c := range cases {
+		e, err := Parse(c.src)
+		if err != nil {
+			t.Fatalf("%s: %s", c.src, err)
+		}
+
+		have, err := e.Eval(c.old, new)
+		if err != nil {
+			t.Fatalf("%s: %s", c.src, err)
+		}
+
+		if want := c.want; have != want {
+			t.Errorf("%s: have %v, want %v", c.src, have, want)
+		}
+	}
+}
+
+func TestExprEvalError(t *testing.T) {
+	cases := []string{
+		"new.type",
+		"new.tags > 1",
+		"new.type && true",
+		"'a' in new.type",
+		"new.password == 'secret'",
+		"new.secret == null",
+	}
+
+	for _, src := range cases {
+		e, err := Parse(src)
+		if err != nil {
+			t.Fatalf("%s: %s", src, err)
+		}
+
+		if _, err := e.Eval(nil, &testEntity{Type: "post"}); err == nil {
+			t.Errorf("%s: expected error", src)
+		}
+	}
+}
+
+func TestExprCheck(t *testing.T) {
+	cases := []struct {
+		src   string
+		valid bool
+	}{
+		{src: "new.metadata.anything == 'x'", valid: true},
+		{src: "new.private.visible", valid: true},
+		{src: "changed(object_id)", valid: true},
+		{src: "new.created_at > '2017'", valid: true},
+		{src: "new.owner == 123", valid: false},
+		{src: "changed(visibilty)", valid: false},
+		{src: "new.type.name == 'post'", valid: false},
+		{src: "exists(old.private.hidden)", valid: false},
+		{src: "new.password == 'secret'", valid: false},
+		{src: "new.secret == 'secret'", valid: false},
+		{src: "changed(secret)", valid: false},
+	}
+
+	for _, c := range cases {
+		e, err := Parse(c.src)
+		if err != nil {
+			t.Fatalf("%s: %s", c.src, err)
+		}
+
+		if have, want := e.Check(&testEntity{}) == nil, c.valid; have != want {
+			t.Errorf("%s: have %v, want %v", c.src, have, want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		src string
+		pos int
+	}{
+		{src: "", pos: 0},
+		{src: "new.type ==", pos: 11},
+		{src: "new.type = 'post'", pos: 9},
+		{src: "type == 'post'", pos: 0},
+		{src: "new.type == 'post", pos: 12},
+		{src: "(new.type == 'post'", pos: 19},
+		{src: "new.type in ['a' 'b']", pos: 17},
+		{src: "changed(new.type)", pos: 8},
+		{src: "exists(type)", pos: 7},
+		{src: "new.type == 'post' true", pos: 19},
+	}
+
+	for _, c := range cases {
+		_, err := Parse(c.src)
+		if err == nil {
+			t.Errorf("%s: expected error", c.src)
+			continue
+		}
+
+		e, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("%s: have %T, want *Error", c.src, err)
+		}
+
+		if have, want := e.Pos, c.pos; have != want {
+			t.Errorf("%s: have %v, want %v (%s)", c.src, have, want, e)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"ID":        "id",
+		"ObjectID":  "object_id",
+		"CreatedAt": "created_at",
+		"Type":      "type",
+		"HTTPCode":  "http_code",
+	}
+
+	for in, want := range cases {
+		if have := snakeCase(in); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenComma
+	tokenDot
+	tokenIdent
+	tokenLBrack
+	tokenLParen
+	tokenNumber
+	tokenOp
+	tokenRBrack
+	tokenRParen
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	pos   int
+	text  string
+	value interface{}
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+var operators = []string{
+	"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "-",
+}
+
+func lex(src string) ([]token, error) {
+	var (
+		pos    = 0
+		tokens = []token{}
+	)
+
+	for pos < len(src) {
+		c := src[pos]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, pos: pos, text: ","})
+			pos++
+		case c == '.':
+			tokens = append(tokens, token{kind: tokenDot, pos: pos, text: "."})
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, pos: pos, text: "("})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, pos: pos, text: ")"})
+			pos++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenLBrack, pos: pos, text: "["})
+			pos++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenRBrack, pos: pos, text: "]"})
+			pos++
+		case c == '"' || c == '\'':
+			t, err := lexString(src, pos)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, t)
+			pos += len(t.text) + 2
+		case isDigit(c):
+			end := pos
+
+			for end < len(src) && (isDigit(src[end]) || src[end] == '.') {
+				end++
+			}
+
+			n, err := strconv.ParseFloat(src[pos:end], 64)
+			if err != nil {
+				return nil, &Error{Pos: pos, Msg: fmt.Sprintf("invalid number '%s'", src[pos:end])}
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, pos: pos, text: src[pos:end], value: n})
+			pos = end
+		case isLetter(c):
+			end := pos
+
+			for end < len(src) && (isLetter(src[end]) || isDigit(src[end])) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, pos: pos, text: src[pos:end]})
+			pos = end
+		default:
+			op := ""
+
+			for _, o := range operators {
+				if strings.HasPrefix(src[pos:], o) {
+					op = o
+					break
+				}
+			}
+
+			if op == "" {
+				return nil, &Error{Pos: pos, Msg: fmt.Sprintf("unexpected character '%c'", c)}
+			}
+
+			tokens = append(tokens, token{kind: tokenOp, pos: pos, text: op})
+			pos += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// lexString reads a quoted string starting at pos, the text of the returned
+// token is the raw content between the quotes.
+func lexString(src string, pos int) (token, error) {
+	var (
+		quote = src[pos]
+		b     = []byte{}
+	)
+
+	for i := pos + 1; i < len(src); i++ {
+		switch src[i] {
+		case quote:
+			return token{
+				kind:  tokenString,
+				pos:   pos,
+				text:  src[pos+1 : i],
+				value: string(b),
+			}, nil
+		case '\\':
+			if i+1 == len(src) {
+				break
+			}
+
+			i++
+			b = append(b, src[i])
+		default:
+			b = append(b, src[i])
+		}
+	}
+
+	return token{}, &Error{Pos: pos, Msg: "unterminated string"}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
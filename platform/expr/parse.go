@@ -0,0 +1,320 @@
+package expr
+
+import "fmt"
+
+const (
+	fnChanged = "changed"
+	fnExists  = "exists"
+)
+
+type parser struct {
+	pos    int
+	tokens []token
+}
+
+func newParser(src string) (*parser, error) {
+	ts, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parser{tokens: ts}, nil
+}
+
+func (p *parser) parse() (node, error) {
+	if p.peek().kind == tokenEOF {
+		return nil, &Error{Pos: 0, Msg: "empty expression"}
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, unexpected(t, "operator")
+	}
+
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(tokenOp, "||") || p.accept(tokenIdent, "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logical{and: false, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(tokenOp, "&&") || p.accept(tokenIdent, "and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logical{and: true, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept(tokenOp, "!") || p.accept(tokenIdent, "not") {
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &not{operand: n}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+
+	switch {
+	case t.kind == tokenOp && isComparison(t.text):
+	case t.kind == tokenIdent && t.text == "in":
+	default:
+		return left, nil
+	}
+
+	p.next()
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &comparison{
+		left:  left,
+		op:    t.text,
+		pos:   t.pos,
+		right: right,
+	}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literal{value: t.value}, nil
+	case tokenOp:
+		if t.text == "-" && p.peek().kind == tokenNumber {
+			return &literal{value: -p.next().value.(float64)}, nil
+		}
+	case tokenLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(tokenRParen, "')'"); err != nil {
+			return nil, err
+		}
+
+		return n, nil
+	case tokenLBrack:
+		return p.parseList()
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		case RootNew, RootOld:
+			fields, err := p.parseFields()
+			if err != nil {
+				return nil, err
+			}
+
+			return &path{fields: fields, pos: t.pos, root: t.text}, nil
+		case fnChanged, fnExists:
+			return p.parseCall(t)
+		}
+
+		return nil, &Error{
+			Pos: t.pos,
+			Msg: fmt.Sprintf(
+				"unknown identifier '%s', fields start with '%s.' or '%s.'",
+				t.text,
+				RootNew,
+				RootOld,
+			),
+		}
+	}
+
+	return nil, unexpected(t, "value")
+}
+
+func (p *parser) parseCall(fn token) (node, error) {
+	if err := p.expect(tokenLParen, "'(' after "+fn.text); err != nil {
+		return nil, err
+	}
+
+	arg := p.next()
+
+	if arg.kind != tokenIdent {
+		return nil, unexpected(arg, "field")
+	}
+
+	var n node
+
+	switch fn.text {
+	case fnChanged:
+		if arg.text == RootNew || arg.text == RootOld {
+			return nil, &Error{
+				Pos: arg.pos,
+				Msg: "changed expects a field without 'new.' or 'old.'",
+			}
+		}
+
+		fields, err := p.parseFields()
+		if err != nil {
+			return nil, err
+		}
+
+		n = &changed{fields: append([]string{arg.text}, fields...), pos: arg.pos}
+	case fnExists:
+		if arg.text != RootNew && arg.text != RootOld {
+			return nil, &Error{
+				Pos: arg.pos,
+				Msg: "exists expects a field starting with 'new.' or 'old.'",
+			}
+		}
+
+		fields, err := p.parseFields()
+		if err != nil {
+			return nil, err
+		}
+
+		n = &exists{
+			path: &path{fields: fields, pos: arg.pos, root: arg.text},
+		}
+	}
+
+	if err := p.expect(tokenRParen, "')'"); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (p *parser) parseFields() ([]string, error) {
+	fields := []string{}
+
+	for p.accept(tokenDot, ".") {
+		t := p.next()
+
+		if t.kind != tokenIdent {
+			return nil, unexpected(t, "field name")
+		}
+
+		fields = append(fields, t.text)
+	}
+
+	return fields, nil
+}
+
+func (p *parser) parseList() (node, error) {
+	l := &list{}
+
+	if p.accept(tokenRBrack, "]") {
+		return l, nil
+	}
+
+	for {
+		n, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		l.items = append(l.items, n)
+
+		if p.accept(tokenRBrack, "]") {
+			return l, nil
+		}
+
+		if err := p.expect(tokenComma, "',' or ']'"); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) accept(kind tokenKind, text string) bool {
+	t := p.peek()
+
+	if t.kind != kind || t.text != text {
+		return false
+	}
+
+	p.pos++
+
+	return true
+}
+
+func (p *parser) expect(kind tokenKind, what string) error {
+	t := p.next()
+
+	if t.kind != kind {
+		return unexpected(t, what)
+	}
+
+	return nil
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+
+	return false
+}
+
+func unexpected(t token, want string) error {
+	return &Error{
+		Pos: t.pos,
+		Msg: fmt.Sprintf("unexpected %s, expected %s", t, want),
+	}
+}
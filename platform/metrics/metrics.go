@@ -12,6 +12,7 @@ const (
 	FieldComponent = "component"
 	FieldMethod    = "method"
 	FieldNamespace = "namespace"
+	FieldReason    = "reason"
 	FieldRoute     = "route"
 	FieldService   = "service"
 	FieldSource    = "source"
@@ -0,0 +1,78 @@
+package pg
+
+import (
+	"database/sql"
+	"hash/fnv"
+)
+
+const (
+	pgAlive         = `SELECT 1`
+	pgTryAdvisoryTx = `SELECT pg_try_advisory_xact_lock($1)`
+)
+
+// Lock elects a single holder among processes sharing a database through a
+// Postgres advisory lock. The lock is bound to a transaction which is kept open
+// while it is held, so it is released by Postgres once the holding connection
+// goes away. A Lock is not safe for concurrent use.
+type Lock struct {
+	db  *sql.DB
+	key int64
+	tx  *sql.Tx
+}
+
+// NewLock returns a Lock whose key is derived from name.
+func NewLock(db *sql.DB, name string) *Lock {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+
+	return &Lock{
+		db:  db,
+		key: int64(h.Sum64()),
+	}
+}
+
+// Acquire reports if the Lock is held after the call. It is meant to be called
+// repeatedly: a held Lock is checked for its connection still being alive and
+// otherwise acquisition is attempted again.
+func (l *Lock) Acquire() (bool, error) {
+	if l.tx != nil {
+		if _, err := l.tx.Exec(pgAlive); err == nil {
+			return true, nil
+		}
+
+		_ = l.tx.Rollback()
+		l.tx = nil
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	var acquired bool
+
+	if err := tx.QueryRow(pgTryAdvisoryTx, l.key).Scan(&acquired); err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+
+	if !acquired {
+		return false, tx.Rollback()
+	}
+
+	l.tx = tx
+
+	return true, nil
+}
+
+// Release gives up the Lock if it is held.
+func (l *Lock) Release() error {
+	if l.tx == nil {
+		return nil
+	}
+
+	err := l.tx.Rollback()
+	l.tx = nil
+
+	return err
+}
@@ -19,6 +19,7 @@ const TimeFormat = "2006-01-02 15:04:05.000000 UTC"
 const URLTest = "postgres://%s@127.0.0.1:5432/tapglue_test?sslmode=disable&connect_timeout=5"
 
 const (
+	codeColumnNotFound        = "42703"
 	codeDuplicateKeyViolation = "23505"
 	codeRelationNotFound      = "42P01"
 
@@ -26,6 +27,10 @@ const (
 	fmtWHERE  = "WHERE\n%s"
 )
 
+// ErrColumnNotFound is returned as equivalent to the Postgres error, it
+// indicates a table which predates the addition of the column.
+var ErrColumnNotFound = errors.New("column not found")
+
 // ErrRelationNotFound is returned as equivalent to the Postgres error.
 var ErrRelationNotFound = errors.New("relation not found")
 
@@ -68,6 +73,11 @@ func GuardIndex(namespace, index, query string, args ...interface{}) string {
 	)
 }
 
+// IsColumnNotFound indicates if err is ErrColumnNotFound.
+func IsColumnNotFound(err error) bool {
+	return err == ErrColumnNotFound
+}
+
 // IsNotUnique indicates if err is ErrNotUnique.
 func IsNotUnique(err error) bool {
 	return err == ErrNotUnique
@@ -78,11 +88,13 @@ func IsRelationNotFound(err error) bool {
 	return err == ErrRelationNotFound
 }
 
-// WrapError check the given error if it indicates that the relation wasn't
-// present, otherwise returns the original error.
+// WrapError check the given error if it indicates that the relation or column
+// wasn't present, otherwise returns the original error.
 func WrapError(err error) error {
 	if err, ok := err.(*pq.Error); ok {
 		switch err.Code {
+		case codeColumnNotFound:
+			return ErrColumnNotFound
 		case codeDuplicateKeyViolation:
 			return ErrNotUnique
 		case codeRelationNotFound:
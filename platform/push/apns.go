@@ -0,0 +1,213 @@
+package push
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/sns"
+)
+
+// APNs hosts.
+const (
+	APNSHostProduction = "https://api.push.apple.com"
+	APNSHostSandbox    = "https://api.sandbox.push.apple.com"
+)
+
+// Apple rejects provider tokens older than an hour and throttles refreshes
+// happening more often than every 20 minutes.
+const apnsTokenTTL = 40 * time.Minute
+
+// Reasons reported by APNs we act on.
+const (
+	apnsReasonBadDeviceToken       = "BadDeviceToken"
+	apnsReasonDeviceTokenNotTopic  = "DeviceTokenNotForTopic"
+	apnsReasonExpiredProviderToken = "ExpiredProviderToken"
+	apnsReasonUnregistered         = "Unregistered"
+)
+
+const fmtAPNSPath = "%s/3/device/%s"
+
+// APNSCredentials are used for token-based authentication against APNs. The
+// PrivateKey is the content of the .p8 file identified by KeyID, issued for the
+// developer account TeamID. Topic is the bundle id of the receiving app.
+type APNSCredentials struct {
+	KeyID      string
+	PrivateKey []byte
+	TeamID     string
+	Topic      string
+}
+
+type apnsClaims struct {
+	IAT int64  `json:"iat"`
+	ISS string `json:"iss"`
+}
+
+type apnsResponse struct {
+	Reason string `json:"reason"`
+}
+
+type apnsProvider struct {
+	client *http.Client
+	creds  APNSCredentials
+	host   string
+	key    crypto.Signer
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// APNS returns a Provider which talks HTTP/2 to APNs under host, the client is
+// expected to be configured for HTTP/2.
+func APNS(c *http.Client, host string, creds APNSCredentials) (Provider, error) {
+	if creds.KeyID == "" || creds.TeamID == "" || creds.Topic == "" {
+		return nil, wrapError(
+			ErrInvalidCredentials,
+			"apns: key id, team id and topic must be set",
+		)
+	}
+
+	key, err := parsePrivateKey(creds.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apnsProvider{
+		client: c,
+		creds:  creds,
+		host:   host,
+		key:    key,
+	}, nil
+}
+
+func (p *apnsProvider) Push(t Target, payload *sns.Payload) (string, error) {
+	body, err := json.Marshal(payload.APNS(sns.FormatURN(t.Scheme, payload.URN)))
+	if err != nil {
+		return "", err
+	}
+
+	id, reason, err := p.send(t.Token, payload.CollapseKey, body)
+	if err != nil {
+		return "", err
+	}
+
+	// The cached token was not accepted anymore, one retry with a fresh one.
+	if reason == apnsReasonExpiredProviderToken {
+		p.resetToken()
+
+		id, reason, err = p.send(t.Token, payload.CollapseKey, body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	switch reason {
+	case "":
+		return id, nil
+	case apnsReasonBadDeviceToken,
+		apnsReasonDeviceTokenNotTopic,
+		apnsReasonUnregistered:
+		return id, wrapError(ErrInvalidToken, "apns: %s", reason)
+	default:
+		return id, wrapError(ErrDeliveryFailure, "apns: %s", reason)
+	}
+}
+
+// send returns the apns-id of the notification and the reason for rejected
+// ones, errors are only returned for failures which might go away on retry.
+func (p *apnsProvider) send(
+	token, collapseID string,
+	body []byte,
+) (string, string, error) {
+	jwt, err := p.providerToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		fmt.Sprintf(fmtAPNSPath, p.host, token),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return "", "", err
+	}
+
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-topic", p.creds.Topic)
+	req.Header.Set("authorization", "bearer "+jwt)
+	req.Header.Set("content-type", "application/json")
+
+	if collapseID != "" {
+		req.Header.Set("apns-collapse-id", collapseID)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+
+	id := res.Header.Get("apns-id")
+
+	if res.StatusCode == http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		return id, "", nil
+	}
+
+	r := apnsResponse{}
+
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil || r.Reason == "" {
+		r.Reason = http.StatusText(res.StatusCode)
+	}
+
+	if res.StatusCode >= http.StatusInternalServerError ||
+		res.StatusCode == http.StatusTooManyRequests {
+		return "", "", fmt.Errorf("apns: %d %s", res.StatusCode, r.Reason)
+	}
+
+	if res.StatusCode == http.StatusForbidden &&
+		r.Reason != apnsReasonExpiredProviderToken {
+		return "", "", wrapError(ErrInvalidCredentials, "apns: %s", r.Reason)
+	}
+
+	return id, r.Reason, nil
+}
+
+func (p *apnsProvider) providerToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+
+	if p.token != "" && now.Sub(p.issuedAt) < apnsTokenTTL {
+		return p.token, nil
+	}
+
+	token, err := signJWT(
+		jwtHeader{Alg: jwtES256, Kid: p.creds.KeyID},
+		apnsClaims{IAT: now.Unix(), ISS: p.creds.TeamID},
+		p.key,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	p.token, p.issuedAt = token, now
+
+	return token, nil
+}
+
+func (p *apnsProvider) resetToken() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.token = ""
+}
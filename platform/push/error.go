@@ -0,0 +1,60 @@
+package push
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// Common errors for Provider implementations.
+var (
+	ErrDeliveryFailure    = errors.New("delivery failed")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+)
+
+// Error wraps common Provider errors.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsDeliveryFailure indicates if err is ErrDeliveryFailure.
+func IsDeliveryFailure(err error) bool {
+	return unwrapError(err) == ErrDeliveryFailure
+}
+
+// IsInvalidCredentials indicates if err is ErrInvalidCredentials.
+func IsInvalidCredentials(err error) bool {
+	return unwrapError(err) == ErrInvalidCredentials
+}
+
+// IsInvalidToken indicates if err is ErrInvalidToken.
+func IsInvalidToken(err error) bool {
+	return unwrapError(err) == ErrInvalidToken
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err,
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
@@ -0,0 +1,290 @@
+package push
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/sns"
+)
+
+// FCMEndpoint is the base URL of the FCM HTTP v1 API.
+const FCMEndpoint = "https://fcm.googleapis.com"
+
+const (
+	fcmGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	fcmScope     = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmTokenTTL  = time.Hour
+	// Access tokens are renewed ahead of their expiry to account for clock
+	// skew and requests in flight.
+	fcmTokenLeeway = time.Minute
+)
+
+// Error codes reported by FCM we act on.
+const (
+	fcmStatusNotFound     = "NOT_FOUND"
+	fcmStatusUnregistered = "UNREGISTERED"
+)
+
+const fmtFCMPath = "%s/v1/projects/%s/messages:send"
+
+type fcmAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	ProjectID    string `json:"project_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+type fcmClaims struct {
+	AUD   string `json:"aud"`
+	EXP   int64  `json:"exp"`
+	IAT   int64  `json:"iat"`
+	ISS   string `json:"iss"`
+	Scope string `json:"scope"`
+}
+
+type fcmToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type fcmAndroid struct {
+	CollapseKey string `json:"collapse_key,omitempty"`
+}
+
+type fcmMessage struct {
+	Android *fcmAndroid       `json:"android,omitempty"`
+	Data    map[string]string `json:"data"`
+	Token   string            `json:"token"`
+}
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmResponse struct {
+	Name  string `json:"name"`
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+type fcmProvider struct {
+	account  fcmAccount
+	client   *http.Client
+	endpoint string
+	key      crypto.Signer
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// FCM returns a Provider which sends data messages through the FCM HTTP v1 API
+// under endpoint. It authenticates with the service account JSON key, access
+// tokens are obtained from the token_uri of the account.
+func FCM(c *http.Client, endpoint string, serviceAccount []byte) (Provider, error) {
+	a := fcmAccount{}
+
+	if err := json.Unmarshal(serviceAccount, &a); err != nil {
+		return nil, wrapError(ErrInvalidCredentials, "fcm: service account: %s", err)
+	}
+
+	if a.ClientEmail == "" || a.ProjectID == "" || a.TokenURI == "" {
+		return nil, wrapError(
+			ErrInvalidCredentials,
+			"fcm: client_email, project_id and token_uri must be set",
+		)
+	}
+
+	key, err := parsePrivateKey([]byte(a.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return &fcmProvider{
+		account:  a,
+		client:   c,
+		endpoint: endpoint,
+		key:      key,
+	}, nil
+}
+
+func (p *fcmProvider) Push(t Target, payload *sns.Payload) (string, error) {
+	msg := fcmMessage{
+		Data:  payload.AndroidData(sns.FormatURN(t.Scheme, payload.URN)),
+		Token: t.Token,
+	}
+
+	if payload.CollapseKey != "" {
+		msg.Android = &fcmAndroid{CollapseKey: payload.CollapseKey}
+	}
+
+	body, err := json.Marshal(fcmRequest{Message: msg})
+	if err != nil {
+		return "", err
+	}
+
+	status, r, err := p.send(body)
+	if err != nil {
+		return "", err
+	}
+
+	// The access token might have been revoked before its expiry, one retry
+	// with a fresh one.
+	if status == http.StatusUnauthorized {
+		p.resetToken()
+
+		status, r, err = p.send(body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if status == http.StatusOK {
+		return r.Name, nil
+	}
+
+	reason := r.Error.Status
+
+	for _, d := range r.Error.Details {
+		if d.ErrorCode != "" {
+			reason = d.ErrorCode
+		}
+	}
+
+	switch {
+	case status >= http.StatusInternalServerError,
+		status == http.StatusTooManyRequests:
+		return "", fmt.Errorf("fcm: %d %s", status, r.Error.Message)
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return "", wrapError(ErrInvalidCredentials, "fcm: %s", r.Error.Message)
+	case reason == fcmStatusUnregistered, reason == fcmStatusNotFound:
+		return "", wrapError(ErrInvalidToken, "fcm: %s", reason)
+	default:
+		return "", wrapError(ErrDeliveryFailure, "fcm: %s %s", reason, r.Error.Message)
+	}
+}
+
+func (p *fcmProvider) send(body []byte) (int, *fcmResponse, error) {
+	token, err := p.accessToken()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		fmt.Sprintf(fmtFCMPath, p.endpoint, p.account.ProjectID),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	r := &fcmResponse{}
+
+	if err := json.NewDecoder(res.Body).Decode(r); err != nil &&
+		res.StatusCode != http.StatusOK {
+		r.Error.Message = http.StatusText(res.StatusCode)
+	}
+
+	return res.StatusCode, r, nil
+}
+
+// accessToken exchanges a self-signed JWT for an OAuth2 access token as
+// described for server to server applications.
+func (p *fcmProvider) accessToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+
+	if p.token != "" && now.Before(p.expiresAt) {
+		return p.token, nil
+	}
+
+	assertion, err := signJWT(
+		jwtHeader{Alg: jwtRS256, Kid: p.account.PrivateKeyID, Typ: "JWT"},
+		fcmClaims{
+			AUD:   p.account.TokenURI,
+			EXP:   now.Add(fcmTokenTTL).Unix(),
+			IAT:   now.Unix(),
+			ISS:   p.account.ClientEmail,
+			Scope: fcmScope,
+		},
+		p.key,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"assertion":  {assertion},
+		"grant_type": {fcmGrantType},
+	}
+
+	res, err := p.client.Post(
+		p.account.TokenURI,
+		"application/x-www-form-urlencoded",
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		return "", fmt.Errorf("fcm: token: %d", res.StatusCode)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", wrapError(
+			ErrInvalidCredentials,
+			"fcm: token: %d",
+			res.StatusCode,
+		)
+	}
+
+	t := fcmToken{}
+
+	if err := json.NewDecoder(res.Body).Decode(&t); err != nil {
+		return "", err
+	}
+
+	if t.AccessToken == "" {
+		return "", wrapError(ErrInvalidCredentials, "fcm: token: empty access token")
+	}
+
+	p.token = t.AccessToken
+	p.expiresAt = now.Add(time.Duration(t.ExpiresIn)*time.Second - fcmTokenLeeway)
+
+	return p.token, nil
+}
+
+func (p *fcmProvider) resetToken() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.token = ""
+}
@@ -0,0 +1,279 @@
+package push
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tapglue/snaas/platform/sns"
+)
+
+func TestFCMPush(t *testing.T) {
+	stub := newFCMStub(t)
+	defer stub.close()
+
+	p := stub.provider(t)
+
+	for i := 0; i < 2; i++ {
+		id, err := p.Push(Target{
+			Platform: sns.PlatformGCM,
+			Scheme:   "app",
+			Token:    "registration-token",
+		}, &sns.Payload{
+			CollapseKey: "post-123",
+			Data:        map[string]string{"post_id": "123"},
+			Message:     "Alice liked your post",
+			Title:       "New like",
+			URN:         "posts/123",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := id, "projects/tapglue-test/messages/1"; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	// The access token is reused for subsequent sends.
+	if have, want := stub.tokensIssued(), 1; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	r := <-stub.requests
+
+	if have, want := r.path, "/v1/projects/tapglue-test/messages:send"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	want := map[string]interface{}{
+		"message": map[string]interface{}{
+			"android": map[string]interface{}{
+				"collapse_key": "post-123",
+			},
+			"data": map[string]interface{}{
+				"body":    "Alice liked your post",
+				"post_id": "123",
+				"title":   "New like",
+				"urn":     "app://posts/123",
+			},
+			"token": "registration-token",
+		},
+	}
+
+	if have := r.body; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+}
+
+func TestFCMPushRejected(t *testing.T) {
+	stub := newFCMStub(t)
+	defer stub.close()
+
+	p := stub.provider(t)
+
+	for _, c := range []struct {
+		code   int
+		status string
+		check  func(error) bool
+	}{
+		{http.StatusNotFound, fcmStatusUnregistered, IsInvalidToken},
+		{http.StatusBadRequest, "INVALID_ARGUMENT", IsDeliveryFailure},
+		{http.StatusForbidden, "SENDER_ID_MISMATCH", IsInvalidCredentials},
+	} {
+		stub.errors <- fcmError{code: c.code, status: c.status}
+
+		_, err := p.Push(Target{Token: "registration-token"}, &sns.Payload{})
+
+		if have, want := c.check(err), true; have != want {
+			t.Errorf("%s: have %v, want %v: %v", c.status, have, want, err)
+		}
+
+		<-stub.requests
+	}
+
+	// Unavailability is worth a retry and therefore not classified.
+	stub.errors <- fcmError{code: http.StatusServiceUnavailable, status: "UNAVAILABLE"}
+
+	_, err := p.Push(Target{Token: "registration-token"}, &sns.Payload{})
+
+	if err == nil || IsDeliveryFailure(err) || IsInvalidToken(err) {
+		t.Errorf("have %v, want unclassified error", err)
+	}
+}
+
+func TestFCMInvalidCredentials(t *testing.T) {
+	for _, account := range []string{
+		`not json`,
+		`{"client_email":"push@tapglue.test","token_uri":"http://localhost/token"}`,
+		`{"client_email":"push@tapglue.test","private_key":"garbage","project_id":"tapglue-test","token_uri":"http://localhost/token"}`,
+	} {
+		_, err := FCM(http.DefaultClient, FCMEndpoint, []byte(account))
+
+		if have, want := IsInvalidCredentials(err), true; have != want {
+			t.Errorf("have %v, want %v: %v", have, want, err)
+		}
+	}
+}
+
+type fcmError struct {
+	code   int
+	status string
+}
+
+type fcmSent struct {
+	body interface{}
+	path string
+}
+
+type fcmStub struct {
+	mu         sync.Mutex
+	assertions []string
+	errors     chan fcmError
+	key        *rsa.PrivateKey
+	requests   chan fcmSent
+	srv        *httptest.Server
+}
+
+func newFCMStub(t *testing.T) *fcmStub {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fcmStub{
+		errors:   make(chan fcmError, 1),
+		key:      key,
+		requests: make(chan fcmSent, 4),
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assertion := r.FormValue("assertion")
+
+		if r.FormValue("grant_type") != fcmGrantType ||
+			!verifyRS256(assertion, &key.PublicKey, s.srv.URL+"/token") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		s.assertions = append(s.assertions, assertion)
+		n := len(s.assertions)
+		s.mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(fcmToken{
+			AccessToken: fmt.Sprintf("access-%d", n),
+			ExpiresIn:   3600,
+		})
+	})
+
+	mux.HandleFunc("/v1/projects/", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer access-") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		req := fcmSent{path: r.URL.Path}
+
+		_ = json.NewDecoder(r.Body).Decode(&req.body)
+
+		s.requests <- req
+
+		select {
+		case e := <-s.errors:
+			w.WriteHeader(e.code)
+			_, _ = fmt.Fprintf(
+				w,
+				`{"error":{"code":%d,"message":"failed","status":"%s"}}`,
+				e.code,
+				e.status,
+			)
+		default:
+			_, _ = w.Write([]byte(`{"name":"projects/tapglue-test/messages/1"}`))
+		}
+	})
+
+	s.srv = httptest.NewServer(mux)
+
+	return s
+}
+
+func (s *fcmStub) close() {
+	s.srv.Close()
+}
+
+func (s *fcmStub) tokensIssued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.assertions)
+}
+
+func (s *fcmStub) provider(t *testing.T) Provider {
+	account, err := json.Marshal(map[string]string{
+		"client_email": "push@tapglue-test.iam.gserviceaccount.com",
+		"private_key": string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(s.key),
+		})),
+		"private_key_id": "KEY",
+		"project_id":     "tapglue-test",
+		"token_uri":      s.srv.URL + "/token",
+		"type":           "service_account",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := FCM(http.DefaultClient, s.srv.URL, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func verifyRS256(token string, pub *rsa.PublicKey, aud string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	h := jwtHeader{}
+
+	if err := decodeSegment(parts[0], &h); err != nil || h.Alg != jwtRS256 {
+		return false
+	}
+
+	c := fcmClaims{}
+
+	if err := decodeSegment(parts[1], &c); err != nil ||
+		c.AUD != aud ||
+		c.Scope != fcmScope ||
+		c.EXP <= c.IAT {
+		return false
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+}
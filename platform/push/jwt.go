@@ -0,0 +1,101 @@
+package push
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+)
+
+const (
+	jwtES256 = "ES256"
+	jwtRS256 = "RS256"
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// signJWT returns the compact serialisation of a JWT with the given claims.
+// The key is expected to be an *ecdsa.PrivateKey for ES256 and an
+// *rsa.PrivateKey for RS256.
+func signJWT(header jwtHeader, claims interface{}, key crypto.Signer) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		input  = encodeSegment(h) + "." + encodeSegment(c)
+		digest = sha256.Sum256([]byte(input))
+		sig    []byte
+	)
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			return "", err
+		}
+
+		// JWS expects the fixed size concatenation of r and s instead of the
+		// ASN.1 structure.
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[size-len(rb):size], rb)
+		copy(sig[2*size-len(sb):], sb)
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", wrapError(ErrInvalidCredentials, "unsupported key type %T", key)
+	}
+
+	return input + "." + encodeSegment(sig), nil
+}
+
+// parsePrivateKey reads a PEM encoded PKCS#8 key as found in .p8 files from
+// Apple and service account keys from Google.
+func parsePrivateKey(raw []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, wrapError(ErrInvalidCredentials, "private key is not PEM encoded")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		// Older service account keys use PKCS#1.
+		if k, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes); rsaErr == nil {
+			return k, nil
+		}
+
+		return nil, wrapError(ErrInvalidCredentials, "private key: %s", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, wrapError(ErrInvalidCredentials, "unsupported key type %T", key)
+	}
+
+	return signer, nil
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
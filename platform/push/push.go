@@ -0,0 +1,29 @@
+// Package push delivers notifications to devices through interchangeable
+// providers: AWS SNS, APNs over HTTP/2, the FCM HTTP v1 API and Web Push.
+package push
+
+import "github.com/tapglue/snaas/platform/sns"
+
+// Provider delivers push notifications to a single device and returns the id
+// the provider assigned to the message, if it hands out any.
+//
+// ErrInvalidToken is returned if the provider reports the device as no longer
+// reachable, it should not be addressed again. ErrDeliveryFailure is returned
+// if the provider rejected the notification for any other reason which would
+// not go away on retry.
+type Provider interface {
+	Push(target Target, payload *sns.Payload) (string, error)
+}
+
+// Target addresses a device. SNS uses the EndpointARN while the direct
+// providers send to the Token. For Web Push the Token is the subscription
+// endpoint and messages are encrypted for the subscription keys Auth and
+// P256DH.
+type Target struct {
+	Auth        string
+	EndpointARN string
+	P256DH      string
+	Platform    sns.Platform
+	Scheme      string
+	Token       string
+}
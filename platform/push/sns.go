@@ -0,0 +1,21 @@
+package push
+
+import "github.com/tapglue/snaas/platform/sns"
+
+type snsProvider struct {
+	push sns.PushFunc
+}
+
+// SNS returns a Provider which publishes to the platform endpoints of AWS SNS.
+func SNS(api sns.API) Provider {
+	return &snsProvider{push: sns.Push(api)}
+}
+
+func (p *snsProvider) Push(t Target, payload *sns.Payload) (string, error) {
+	id, err := p.push(t.Platform, t.EndpointARN, t.Scheme, payload)
+	if sns.IsDeliveryFailure(err) {
+		return "", wrapError(ErrDeliveryFailure, "sns: %s", t.EndpointARN)
+	}
+
+	return id, err
+}
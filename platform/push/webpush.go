@@ -0,0 +1,422 @@
+package push
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/tapglue/snaas/platform/sns"
+)
+
+// Push services accept at most 4096 bytes of encrypted content, we send a
+// single record with the header carrying the 65 byte sender key.
+const (
+	webpushHeaderSize = 16 + 4 + 1 + 65
+	webpushRecordSize = 4096
+	webpushMaxPayload = webpushRecordSize - webpushHeaderSize - aesGCMTagSize - 1
+	aesGCMTagSize     = 16
+)
+
+const (
+	webpushTTL = 24 * time.Hour
+	// VAPID tokens must not be valid for longer than a day, they are reused
+	// until they are about to expire.
+	vapidTokenTTL    = 12 * time.Hour
+	vapidTokenLeeway = time.Hour
+)
+
+// WebPushHosts are the push services subscription endpoints are accepted for,
+// subdomains included. Endpoints are handed in by clients, restricting them
+// keeps deliveries from being pointed at arbitrary hosts.
+var WebPushHosts = []string{
+	"android.googleapis.com",
+	"fcm.googleapis.com",
+	"notify.windows.com",
+	"push.apple.com",
+	"push.services.mozilla.com",
+}
+
+var (
+	infoCEK    = []byte("Content-Encoding: aes128gcm\x00")
+	infoKey    = []byte("WebPush: info\x00")
+	infoNonce  = []byte("Content-Encoding: nonce\x00")
+	validTopic = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+)
+
+// VAPIDCredentials identify the application server towards push services.
+// PrivateKey is the base64url encoded P-256 private key, Subject a mailto: or
+// https: contact for the operators of push services.
+type VAPIDCredentials struct {
+	PrivateKey string
+	Subject    string
+}
+
+type vapidClaims struct {
+	AUD string `json:"aud"`
+	EXP int64  `json:"exp"`
+	SUB string `json:"sub"`
+}
+
+type vapidToken struct {
+	expiresAt time.Time
+	token     string
+}
+
+type webNotification struct {
+	Badge *int              `json:"badge,omitempty"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"`
+	Sound string            `json:"sound,omitempty"`
+	Tag   string            `json:"tag,omitempty"`
+	Title string            `json:"title,omitempty"`
+	URN   string            `json:"urn"`
+}
+
+type webpushProvider struct {
+	client    *http.Client
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+	validate  func(endpoint string) error
+
+	mu     sync.Mutex
+	tokens map[string]vapidToken
+}
+
+// GenerateVAPIDKeys returns a new base64url encoded P-256 key pair. The public
+// key is handed to browsers as applicationServerKey when subscribing.
+func GenerateVAPIDKeys() (public, private string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	return encodeSegment(elliptic.Marshal(key.Curve, key.X, key.Y)),
+		encodeSegment(padBytes(key.D.Bytes(), 32)),
+		nil
+}
+
+// VAPIDPublicKey returns the base64url encoded public key for the private key.
+func VAPIDPublicKey(private string) (string, error) {
+	key, err := parseVAPIDKey(private)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeSegment(elliptic.Marshal(key.Curve, key.X, key.Y)), nil
+}
+
+// ValidWebPushEndpoint checks that the subscription endpoint is an https URL of
+// one of the WebPushHosts.
+func ValidWebPushEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return wrapError(ErrInvalidToken, "webpush: endpoint invalid: %s", err)
+	}
+
+	if u.Scheme != "https" || u.User != nil {
+		return wrapError(ErrInvalidToken, "webpush: endpoint must be https")
+	}
+
+	host := strings.ToLower(u.Hostname())
+
+	for _, h := range WebPushHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return nil
+		}
+	}
+
+	return wrapError(ErrInvalidToken, "webpush: host '%s' is no known push service", host)
+}
+
+// WebPush returns a Provider which delivers to browser subscriptions as
+// described in RFC 8030. Messages are encrypted per RFC 8291 for the keys of
+// the Target and the application server is identified with VAPID (RFC 8292).
+// Only endpoints of the WebPushHosts are addressed and redirects are not
+// followed.
+func WebPush(c *http.Client, creds VAPIDCredentials) (Provider, error) {
+	if creds.Subject == "" {
+		return nil, wrapError(ErrInvalidCredentials, "webpush: subject must be set")
+	}
+
+	key, err := parseVAPIDKey(creds.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	client := *c
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &webpushProvider{
+		client:    &client,
+		key:       key,
+		publicKey: encodeSegment(elliptic.Marshal(key.Curve, key.X, key.Y)),
+		subject:   creds.Subject,
+		tokens:    map[string]vapidToken{},
+		validate:  ValidWebPushEndpoint,
+	}, nil
+}
+
+func (p *webpushProvider) Push(t Target, payload *sns.Payload) (string, error) {
+	// Subscriptions stored before endpoints were restricted are checked here.
+	if err := p.validate(t.Token); err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(webNotification{
+		Badge: payload.Badge,
+		Body:  payload.Message,
+		Data:  payload.Data,
+		Sound: payload.Sound,
+		Tag:   payload.CollapseKey,
+		Title: payload.Title,
+		URN:   sns.FormatURN(t.Scheme, payload.URN),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(body) > webpushMaxPayload {
+		return "", wrapError(
+			ErrDeliveryFailure,
+			"webpush: payload of %d bytes exceeds %d",
+			len(body),
+			webpushMaxPayload,
+		)
+	}
+
+	p256dh, err := decodeBase64URL(t.P256DH)
+	if err != nil {
+		return "", wrapError(ErrInvalidToken, "webpush: p256dh: %s", err)
+	}
+
+	auth, err := decodeBase64URL(t.Auth)
+	if err != nil {
+		return "", wrapError(ErrInvalidToken, "webpush: auth: %s", err)
+	}
+
+	content, err := encrypt(body, p256dh, auth)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := p.vapidToken(t.Token)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.Token, bytes.NewReader(content))
+	if err != nil {
+		return "", wrapError(ErrInvalidToken, "webpush: %s", err)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", token, p.publicKey))
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(webpushTTL.Seconds())))
+
+	if validTopic.MatchString(payload.CollapseKey) {
+		req.Header.Set("Topic", payload.CollapseKey)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+
+	switch c := res.StatusCode; {
+	case c >= 200 && c < 300:
+		// The push service identifies the message by its location.
+		return res.Header.Get("Location"), nil
+	case c >= http.StatusInternalServerError, c == http.StatusTooManyRequests:
+		return "", fmt.Errorf("webpush: %d %s", c, msg)
+	case c == http.StatusNotFound, c == http.StatusGone:
+		return "", wrapError(ErrInvalidToken, "webpush: %d %s", c, msg)
+	case c == http.StatusUnauthorized, c == http.StatusForbidden:
+		return "", wrapError(ErrInvalidCredentials, "webpush: %d %s", c, msg)
+	default:
+		return "", wrapError(ErrDeliveryFailure, "webpush: %d %s", c, msg)
+	}
+}
+
+// vapidToken returns a token for the origin of the subscription endpoint.
+func (p *webpushProvider) vapidToken(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", wrapError(ErrInvalidToken, "webpush: endpoint '%s' invalid", endpoint)
+	}
+
+	aud := u.Scheme + "://" + u.Host
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+
+	if t, ok := p.tokens[aud]; ok && now.Add(vapidTokenLeeway).Before(t.expiresAt) {
+		return t.token, nil
+	}
+
+	expiresAt := now.Add(vapidTokenTTL)
+
+	token, err := signJWT(
+		jwtHeader{Alg: jwtES256, Typ: "JWT"},
+		vapidClaims{
+			AUD: aud,
+			EXP: expiresAt.Unix(),
+			SUB: p.subject,
+		},
+		p.key,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	p.tokens[aud] = vapidToken{expiresAt: expiresAt, token: token}
+
+	return token, nil
+}
+
+// encrypt seals plaintext in a single aes128gcm record for the user agent key
+// p256dh and its auth secret as described in RFC 8291.
+func encrypt(plaintext, p256dh, auth []byte) ([]byte, error) {
+	curve := elliptic.P256()
+
+	uaX, uaY := elliptic.Unmarshal(curve, p256dh)
+	if uaX == nil || !curve.IsOnCurve(uaX, uaY) {
+		return nil, wrapError(ErrInvalidToken, "webpush: p256dh is not a P-256 point")
+	}
+
+	if len(auth) != 16 {
+		return nil, wrapError(ErrInvalidToken, "webpush: auth must be 16 bytes")
+	}
+
+	asPrivate, asX, asY, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		asPublic = elliptic.Marshal(curve, asX, asY)
+		sx, _    = curve.ScalarMult(uaX, uaY, asPrivate)
+		salt     = make([]byte, 16)
+	)
+
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	cek, nonce, err := deriveKeys(padBytes(sx.Bytes(), 32), auth, salt, p256dh, asPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, webpushHeaderSize)
+	header = append(header, salt...)
+	header = append(header, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(header[16:20], webpushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// The delimiter marks the last and only record.
+	record := append(plaintext, 0x02)
+
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// deriveKeys returns the content encryption key and nonce from the shared ECDH
+// secret, combining it with the auth secret of the subscription and the salt.
+func deriveKeys(secret, auth, salt, uaPublic, asPublic []byte) ([]byte, []byte, error) {
+	info := make([]byte, 0, len(infoKey)+len(uaPublic)+len(asPublic))
+	info = append(info, infoKey...)
+	info = append(info, uaPublic...)
+	info = append(info, asPublic...)
+
+	ikm := make([]byte, 32)
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, auth, info), ikm); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		cek   = make([]byte, 16)
+		nonce = make([]byte, 12)
+	)
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, infoCEK), cek); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, infoNonce), nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return cek, nonce, nil
+}
+
+func parseVAPIDKey(private string) (*ecdsa.PrivateKey, error) {
+	d, err := decodeBase64URL(private)
+	if err != nil || len(d) != 32 {
+		return nil, wrapError(
+			ErrInvalidCredentials,
+			"webpush: private key must be 32 bytes base64url encoded",
+		)
+	}
+
+	key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	key.Curve = elliptic.P256()
+	key.X, key.Y = key.Curve.ScalarBaseMult(d)
+
+	return key, nil
+}
+
+// decodeBase64URL accepts keys with and without padding, browsers differ.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+
+	p := make([]byte, size)
+	copy(p[size-len(b):], b)
+
+	return p
+}
@@ -0,0 +1,406 @@
+package push
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tapglue/snaas/platform/sns"
+)
+
+func TestWebPush(t *testing.T) {
+	stub := newWebPushStub(t)
+	defer stub.close()
+
+	badge := 4
+
+	id, err := stub.provider(t).Push(stub.target(), &sns.Payload{
+		Badge:       &badge,
+		CollapseKey: "post-123",
+		Data:        map[string]string{"post_id": "123"},
+		Message:     "Alice liked your post",
+		Title:       "New like",
+		URN:         "posts/123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := id, stub.srv.URL+"/messages/1"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	r := <-stub.requests
+
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+
+	for h, want := range map[string]string{
+		"Content-Encoding": "aes128gcm",
+		"TTL":              "86400",
+		"Topic":            "post-123",
+	} {
+		if have := r.header.Get(h); have != want {
+			t.Errorf("%s: have %v, want %v", h, have, want)
+		}
+	}
+
+	want := map[string]interface{}{
+		"badge": float64(4),
+		"body":  "Alice liked your post",
+		"data": map[string]interface{}{
+			"post_id": "123",
+		},
+		"tag":   "post-123",
+		"title": "New like",
+		"urn":   "app://posts/123",
+	}
+
+	if have := r.body; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+}
+
+func TestWebPushGone(t *testing.T) {
+	stub := newWebPushStub(t)
+	defer stub.close()
+
+	stub.status <- http.StatusGone
+
+	_, err := stub.provider(t).Push(stub.target(), &sns.Payload{Message: "Hello"})
+
+	if have, want := IsInvalidToken(err), true; have != want {
+		t.Errorf("have %v, want %v: %v", have, want, err)
+	}
+}
+
+func TestWebPushInvalidKeys(t *testing.T) {
+	stub := newWebPushStub(t)
+	defer stub.close()
+
+	target := stub.target()
+	target.P256DH = encodeSegment([]byte("not a point"))
+
+	_, err := stub.provider(t).Push(target, &sns.Payload{Message: "Hello"})
+
+	if have, want := IsInvalidToken(err), true; have != want {
+		t.Errorf("have %v, want %v: %v", have, want, err)
+	}
+}
+
+func TestWebPushPayloadTooLarge(t *testing.T) {
+	stub := newWebPushStub(t)
+	defer stub.close()
+
+	_, err := stub.provider(t).Push(stub.target(), &sns.Payload{
+		Message: strings.Repeat("a", webpushMaxPayload),
+	})
+
+	if have, want := IsDeliveryFailure(err), true; have != want {
+		t.Errorf("have %v, want %v: %v", have, want, err)
+	}
+}
+
+func TestWebPushRedirect(t *testing.T) {
+	stub := newWebPushStub(t)
+	defer stub.close()
+
+	redirect := httptest.NewServer(http.RedirectHandler(stub.srv.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+
+	target := stub.target()
+	target.Token = redirect.URL
+
+	_, err := stub.provider(t).Push(target, &sns.Payload{Message: "Hello"})
+
+	if have, want := IsDeliveryFailure(err), true; have != want {
+		t.Errorf("have %v, want %v: %v", have, want, err)
+	}
+
+	select {
+	case <-stub.requests:
+		t.Error("expected redirect not to be followed")
+	default:
+	}
+}
+
+func TestValidWebPushEndpoint(t *testing.T) {
+	for endpoint, valid := range map[string]bool{
+		"https://fcm.googleapis.com/fcm/send/abc":               true,
+		"https://updates.push.services.mozilla.com/wpush/v2/ab": true,
+		"https://wns2-db5p.notify.windows.com/w/?token=abc":     true,
+		"https://web.push.apple.com/abc":                        true,
+		"https://FCM.googleapis.com:443/fcm/send/abc":           true,
+		"http://fcm.googleapis.com/fcm/send/abc":                false,
+		"https://user@fcm.googleapis.com/fcm/send/abc":          false,
+		"https://fcm.googleapis.com.evil.test/abc":              false,
+		"https://evilfcm.googleapis.com.test/abc":               false,
+		"https://127.0.0.1/abc":                                 false,
+		"https://169.254.169.254/latest/meta-data":              false,
+		"https://[::1]/abc":                                     false,
+		"https://localhost/abc":                                 false,
+	} {
+		if have, want := ValidWebPushEndpoint(endpoint) == nil, valid; have != want {
+			t.Errorf("%s: have %v, want %v", endpoint, have, want)
+		}
+	}
+}
+
+// Test vector from RFC 8291, Appendix A.
+func TestWebPushDeriveKeys(t *testing.T) {
+	decode := func(s string) []byte {
+		b, err := decodeBase64URL(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return b
+	}
+
+	cek, nonce, err := deriveKeys(
+		decode("kyrL1jIIOHEzg3sM2ZWRHDRB62YACZhhSlknJ672kSs"),
+		decode("BTBZMqHH6r4Tts7J_aSIgg"),
+		decode("DGv6ra1nlYgDCS1FRnbzlw"),
+		decode("BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"),
+		decode("BP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A8"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := encodeSegment(cek), "oIhVW04MRdy2XN9CiKLxTg"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := encodeSegment(nonce), "4h_95klXJ5E_qnoN"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestVAPIDKeys(t *testing.T) {
+	public, private, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	have, err := VAPIDPublicKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := public; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if _, err := VAPIDPublicKey("short"); !IsInvalidCredentials(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+type webpushRequest struct {
+	body   interface{}
+	err    error
+	header http.Header
+}
+
+type webpushStub struct {
+	auth     []byte
+	private  string
+	requests chan webpushRequest
+	srv      *httptest.Server
+	status   chan int
+	ua       *ecdsa.PrivateKey
+}
+
+// newWebPushStub acts as user agent and push service at once, it decrypts
+// every message with the subscription keys.
+func newWebPushStub(t *testing.T) *webpushStub {
+	ua, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, private, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &webpushStub{
+		auth:     make([]byte, 16),
+		private:  private,
+		requests: make(chan webpushRequest, 1),
+		status:   make(chan int, 1),
+		ua:       ua,
+	}
+
+	if _, err := io.ReadFull(rand.Reader, s.auth); err != nil {
+		t.Fatal(err)
+	}
+
+	vapid, err := decodeBase64URL(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, y := elliptic.Unmarshal(elliptic.P256(), vapid)
+	vapidKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := webpushRequest{header: r.Header}
+
+		select {
+		case code := <-s.status:
+			w.WriteHeader(code)
+			return
+		default:
+		}
+
+		if !verifyVAPID(r.Header.Get("Authorization"), public, vapidKey, s.srv.URL) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		content, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			req.err = err
+		} else {
+			req.err = s.decrypt(content, &req.body)
+		}
+
+		s.requests <- req
+
+		w.Header().Set("Location", s.srv.URL+"/messages/1")
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	return s
+}
+
+func (s *webpushStub) close() {
+	s.srv.Close()
+}
+
+func (s *webpushStub) decrypt(content []byte, v interface{}) error {
+	var (
+		salt   = content[:16]
+		idlen  = int(content[20])
+		keyID  = content[21 : 21+idlen]
+		sealed = content[21+idlen:]
+		curve  = elliptic.P256()
+	)
+
+	if have, want := binary.BigEndian.Uint32(content[16:20]), uint32(webpushRecordSize); have != want {
+		return fmt.Errorf("record size: have %v, want %v", have, want)
+	}
+
+	asX, asY := elliptic.Unmarshal(curve, keyID)
+	sx, _ := curve.ScalarMult(asX, asY, s.ua.D.Bytes())
+
+	cek, nonce, err := deriveKeys(
+		padBytes(sx.Bytes(), 32),
+		s.auth,
+		salt,
+		elliptic.Marshal(curve, s.ua.X, s.ua.Y),
+		keyID,
+	)
+	if err != nil {
+		return err
+	}
+
+	plain, err := openGCM(cek, nonce, sealed)
+	if err != nil {
+		return err
+	}
+
+	if plain[len(plain)-1] != 0x02 {
+		return fmt.Errorf("delimiter: have %x, want %x", plain[len(plain)-1], 0x02)
+	}
+
+	return json.Unmarshal(plain[:len(plain)-1], v)
+}
+
+func (s *webpushStub) provider(t *testing.T) Provider {
+	p, err := WebPush(http.DefaultClient, VAPIDCredentials{
+		PrivateKey: s.private,
+		Subject:    "mailto:ops@tapglue.test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stub listens on loopback which is no push service.
+	p.(*webpushProvider).validate = func(string) error { return nil }
+
+	return p
+}
+
+func (s *webpushStub) target() Target {
+	return Target{
+		Auth:     encodeSegment(s.auth),
+		P256DH:   encodeSegment(elliptic.Marshal(elliptic.P256(), s.ua.X, s.ua.Y)),
+		Platform: sns.PlatformWeb,
+		Scheme:   "app",
+		Token:    s.srv.URL + "/push/subscription-1",
+	}
+}
+
+func openGCM(key, nonce, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func verifyVAPID(header, public string, pub *ecdsa.PublicKey, aud string) bool {
+	var token, key string
+
+	for _, part := range strings.Split(strings.TrimPrefix(header, "vapid "), ",") {
+		part = strings.TrimSpace(part)
+
+		switch {
+		case strings.HasPrefix(part, "t="):
+			token = part[2:]
+		case strings.HasPrefix(part, "k="):
+			key = part[2:]
+		}
+	}
+
+	if key != public {
+		return false
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	c := vapidClaims{}
+
+	if err := decodeSegment(parts[1], &c); err != nil ||
+		c.AUD != aud ||
+		c.SUB != "mailto:ops@tapglue.test" {
+		return false
+	}
+
+	return verifyES256Signature(parts, pub)
+}
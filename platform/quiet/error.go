@@ -0,0 +1,46 @@
+package quiet
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// ErrInvalidHours is returned when Hours or a timezone fail validation.
+var ErrInvalidHours = errors.New("invalid quiet hours")
+
+// Error wraps common quiet hours errors.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsInvalidHours indicates if err is ErrInvalidHours.
+func IsInvalidHours(err error) bool {
+	return unwrapError(err) == ErrInvalidHours
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err,
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
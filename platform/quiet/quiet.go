@@ -0,0 +1,96 @@
+// Package quiet implements daily recurring windows of local time during which
+// non-urgent notifications are held back.
+package quiet
+
+import (
+	"fmt"
+	"time"
+)
+
+const clockFormat = "15:04"
+
+// Hours is a daily window of local time given as "15:04" formatted Start and
+// End. A window with End before Start spans midnight.
+type Hours struct {
+	End   string `json:"end"`
+	Start string `json:"start"`
+}
+
+// Until reports the end of the window if now falls into it, in which case the
+// returned time is after now, otherwise the zero time.
+func (h *Hours) Until(now time.Time, loc *time.Location) time.Time {
+	start, end, err := h.parse()
+	if err != nil || start == end {
+		return time.Time{}
+	}
+
+	var (
+		local  = now.In(loc)
+		minute = local.Hour()*60 + local.Minute()
+		day    = local.Day()
+	)
+
+	switch {
+	case start < end:
+		if minute < start || minute >= end {
+			return time.Time{}
+		}
+	case minute >= start:
+		// Inside a window spanning midnight which ends on the next day.
+		day++
+	case minute >= end:
+		return time.Time{}
+	}
+
+	return time.Date(local.Year(), local.Month(), day, end/60, end%60, 0, 0, loc)
+}
+
+// IsZero reports if the window has neither Start nor End, which is how a window
+// is cleared.
+func (h *Hours) IsZero() bool {
+	return h.Start == "" && h.End == ""
+}
+
+// Validate checks for semantic correctness. Hours without Start and End are
+// valid and never active.
+func (h *Hours) Validate() error {
+	if h.IsZero() {
+		return nil
+	}
+
+	if _, _, err := h.parse(); err != nil {
+		return wrapError(ErrInvalidHours, "%s", err)
+	}
+
+	return nil
+}
+
+// parse returns start and end as minutes since midnight.
+func (h *Hours) parse() (int, int, error) {
+	start, err := time.Parse(clockFormat, h.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("start '%s' not in format %s", h.Start, clockFormat)
+	}
+
+	end, err := time.Parse(clockFormat, h.End)
+	if err != nil {
+		return 0, 0, fmt.Errorf("end '%s' not in format %s", h.End, clockFormat)
+	}
+
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
+
+// Location returns the location for the IANA timezone name, where an empty name
+// is treated as UTC.
+func Location(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, wrapError(ErrInvalidHours, "timezone '%s' unknown", timezone)
+	}
+
+	return loc, nil
+}
@@ -0,0 +1,103 @@
+package quiet
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHoursUntil(t *testing.T) {
+	loc, err := Location("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		night = &Hours{Start: "22:00", End: "07:30"}
+		noon  = &Hours{Start: "12:00", End: "14:00"}
+	)
+
+	cases := []struct {
+		hours *Hours
+		now   time.Time
+		want  time.Time
+	}{
+		{
+			hours: night,
+			now:   time.Date(2017, 3, 1, 23, 15, 0, 0, loc),
+			want:  time.Date(2017, 3, 2, 7, 30, 0, 0, loc),
+		},
+		{
+			hours: night,
+			now:   time.Date(2017, 3, 1, 3, 0, 0, 0, loc),
+			want:  time.Date(2017, 3, 1, 7, 30, 0, 0, loc),
+		},
+		{
+			hours: night,
+			now:   time.Date(2017, 3, 1, 7, 30, 0, 0, loc),
+		},
+		{
+			hours: night,
+			now:   time.Date(2017, 3, 1, 21, 59, 0, 0, loc),
+		},
+		{
+			hours: noon,
+			now:   time.Date(2017, 3, 1, 13, 0, 0, 0, loc),
+			want:  time.Date(2017, 3, 1, 14, 0, 0, 0, loc),
+		},
+		{
+			hours: noon,
+			now:   time.Date(2017, 3, 1, 15, 0, 0, 0, loc),
+		},
+		{
+			hours: &Hours{Start: "10:00", End: "10:00"},
+			now:   time.Date(2017, 3, 1, 10, 0, 0, 0, loc),
+		},
+	}
+
+	for _, c := range cases {
+		// The window is evaluated in the given location independent of the
+		// location of now.
+		have := c.hours.Until(c.now.UTC(), loc)
+
+		if !have.Equal(c.want) {
+			t.Errorf("%v at %v: have %v, want %v", c.hours, c.now, have, c.want)
+		}
+	}
+}
+
+func TestHoursValidate(t *testing.T) {
+	for _, h := range []*Hours{
+		{Start: "22:00"},
+		{Start: "25:00", End: "07:00"},
+		{Start: "10pm", End: "07:00"},
+	} {
+		if have, want := IsInvalidHours(h.Validate()), true; have != want {
+			t.Errorf("%v: have %v, want %v", h, have, want)
+		}
+	}
+
+	for _, h := range []*Hours{
+		{},
+		{Start: "22:00", End: "07:00"},
+	} {
+		if err := h.Validate(); err != nil {
+			t.Errorf("%v: %s", h, err)
+		}
+	}
+}
+
+func TestLocation(t *testing.T) {
+	loc, err := Location("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := loc, time.UTC; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	_, err = Location("Mars/Olympus_Mons")
+	if have, want := IsInvalidHours(err), true; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
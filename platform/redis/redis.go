@@ -8,16 +8,25 @@ import (
 
 // Commands.
 const (
-	CommandAuth   = "AUTH"
-	CommandDecr   = "DECR"
-	CommandEx     = "EX"
-	CommandExec   = "Exec"
-	CommandExpire = "EXPIRE"
-	CommandGet    = "GET"
-	CommandIncr   = "INCR"
-	CommandMulti  = "MULTI"
-	CommandPing   = "PING"
-	CommandSet    = "SET"
+	CommandAuth       = "AUTH"
+	CommandDecr       = "DECR"
+	CommandDel        = "DEL"
+	CommandEx         = "EX"
+	CommandExec       = "Exec"
+	CommandExpire     = "EXPIRE"
+	CommandGet        = "GET"
+	CommandIncr       = "INCR"
+	CommandMulti      = "MULTI"
+	CommandNx         = "NX"
+	CommandPing       = "PING"
+	CommandSet        = "SET"
+	CommandXAck       = "XACK"
+	CommandXAdd       = "XADD"
+	CommandXClaim     = "XCLAIM"
+	CommandXDel       = "XDEL"
+	CommandXGroup     = "XGROUP"
+	CommandXPending   = "XPENDING"
+	CommandXReadGroup = "XREADGROUP"
 )
 
 // Defaults.
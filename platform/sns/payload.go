@@ -0,0 +1,148 @@
+package sns
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Message attribute which carries the collapse identifier for APNS.
+const attributeAPNSCollapseID = "AWS.SNS.MOBILE.APNS.COLLAPSE_ID"
+
+// Keys of the push payloads set by the Payload itself, custom Data can't
+// override them.
+const (
+	keyAPS   = "aps"
+	keyBadge = "badge"
+	keyBody  = "body"
+	keySound = "sound"
+	keyTitle = "title"
+	keyURN   = "urn"
+)
+
+// Payload is the content of a push notification independent of the platform
+// it is delivered to. The URN is prefixed with the scheme of the platform app
+// on delivery. A nil Badge leaves the badge of the receiving app untouched.
+type Payload struct {
+	Badge       *int
+	CollapseKey string
+	Data        map[string]string
+	Message     string
+	Sound       string
+	ThreadID    string
+	Title       string
+	URN         string
+}
+
+type apnsAlert struct {
+	Body  string `json:"body"`
+	Title string `json:"title"`
+}
+
+type apnsAPS struct {
+	Alert    interface{} `json:"alert"`
+	Badge    *int        `json:"badge,omitempty"`
+	Sound    string      `json:"sound,omitempty"`
+	ThreadID string      `json:"thread-id,omitempty"`
+}
+
+type gcmMessage struct {
+	CollapseKey string            `json:"collapse_key,omitempty"`
+	Data        map[string]string `json:"data"`
+}
+
+// encode returns the JSON structured SNS message for the platform.
+func (p *Payload) encode(platform Platform, scheme string) (string, error) {
+	var (
+		body interface{}
+		urn  = FormatURN(scheme, p.URN)
+	)
+
+	switch platform {
+	case PlatformAPNS, PlatformAPNSSandbox:
+		body = p.APNS(urn)
+	case PlatformGCM:
+		body = gcmMessage{
+			CollapseKey: p.CollapseKey,
+			Data:        p.AndroidData(urn),
+		}
+	default:
+		return "", fmt.Errorf("platform %d not supported", platform)
+	}
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	msg, err := json.Marshal(map[string]string{
+		PlatformIdentifiers[platform]: string(raw),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(msg), nil
+}
+
+// FormatURN prefixes the urn with the scheme of the receiving app.
+func FormatURN(scheme, urn string) string {
+	return fmt.Sprintf(fmtURN, scheme, urn)
+}
+
+// APNS returns the notification for Apple devices with the custom data next
+// to the aps dictionary as suggested by Apple. The alert stays a plain string
+// as long as there is no title.
+func (p *Payload) APNS(urn string) map[string]interface{} {
+	var alert interface{} = p.Message
+
+	if p.Title != "" {
+		alert = apnsAlert{
+			Body:  p.Message,
+			Title: p.Title,
+		}
+	}
+
+	m := map[string]interface{}{}
+
+	for k, v := range p.Data {
+		m[k] = v
+	}
+
+	m[keyAPS] = apnsAPS{
+		Alert:    alert,
+		Badge:    p.Badge,
+		Sound:    p.Sound,
+		ThreadID: p.ThreadID,
+	}
+	m[keyURN] = urn
+
+	return m
+}
+
+// AndroidData returns the data message for Android devices. It leaves
+// presentation to the app, therefore title, badge and sound are part of it.
+func (p *Payload) AndroidData(urn string) map[string]string {
+	data := map[string]string{}
+
+	for k, v := range p.Data {
+		data[k] = v
+	}
+
+	data[keyBody] = p.Message
+	data[keyURN] = urn
+
+	if p.Badge != nil {
+		data[keyBadge] = strconv.Itoa(*p.Badge)
+	}
+
+	if p.Sound != "" {
+		data[keySound] = p.Sound
+	}
+
+	if p.Title != "" {
+		data[keyTitle] = p.Title
+	}
+
+	return data
+}
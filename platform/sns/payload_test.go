@@ -0,0 +1,122 @@
+package sns
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPayloadEncodeAPNS(t *testing.T) {
+	var (
+		badge = 3
+		p     = &Payload{
+			Badge: &badge,
+			Data: map[string]string{
+				"aps":     "overridden",
+				"post_id": "123",
+			},
+			Message:  `Alice said "hi"`,
+			Sound:    "default",
+			ThreadID: "post-123",
+			Title:    "New comment",
+			URN:      "posts/123",
+		}
+	)
+
+	for _, platform := range []Platform{PlatformAPNS, PlatformAPNSSandbox} {
+		have := decodePayload(t, p, platform)
+
+		want := map[string]interface{}{
+			"aps": map[string]interface{}{
+				"alert": map[string]interface{}{
+					"body":  `Alice said "hi"`,
+					"title": "New comment",
+				},
+				"badge":     float64(3),
+				"sound":     "default",
+				"thread-id": "post-123",
+			},
+			"post_id": "123",
+			"urn":     "app://posts/123",
+		}
+
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("\nhave %#v\nwant %#v", have, want)
+		}
+	}
+}
+
+func TestPayloadEncodeAPNSPlain(t *testing.T) {
+	have := decodePayload(t, &Payload{
+		Message: "Hello",
+		URN:     "users/1",
+	}, PlatformAPNS)
+
+	want := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": "Hello",
+		},
+		"urn": "app://users/1",
+	}
+
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+}
+
+func TestPayloadEncodeGCM(t *testing.T) {
+	var (
+		badge = 0
+		p     = &Payload{
+			Badge:       &badge,
+			CollapseKey: "post-123",
+			Data: map[string]string{
+				"body":    "overridden",
+				"post_id": "123",
+			},
+			Message: "Line\nbreak",
+			URN:     "posts/123",
+		}
+	)
+
+	have := decodePayload(t, p, PlatformGCM)
+
+	want := map[string]interface{}{
+		"collapse_key": "post-123",
+		"data": map[string]interface{}{
+			"badge":   "0",
+			"body":    "Line\nbreak",
+			"post_id": "123",
+			"urn":     "app://posts/123",
+		},
+	}
+
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+}
+
+func decodePayload(t *testing.T, p *Payload, platform Platform) interface{} {
+	raw, err := p.encode(platform, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := map[string]string{}
+
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(msg), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	var payload interface{}
+
+	if err := json.Unmarshal([]byte(msg[PlatformIdentifiers[platform]]), &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	return payload
+}
@@ -1,8 +1,6 @@
 package sns
 
 import (
-	"fmt"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/sns"
@@ -26,11 +24,13 @@ const (
 	TypeNotification    = "Notification"
 )
 
-// Platform supported by SNS for push.
+// Platform supported by SNS for push. PlatformWeb is not backed by SNS, web
+// browsers are only reached through Web Push.
 const (
 	PlatformAPNSSandbox Platform = iota + 1
 	PlatformAPNS
 	PlatformGCM
+	PlatformWeb
 )
 
 // Publish structures.
@@ -40,10 +40,7 @@ const (
 
 // Push formats.
 const (
-	fmtURN         = `%s://%s`
-	msgAPNS        = `{"APNS": "{\"aps\": {\"alert\": \"%s\"}, \"urn\":\"%s\"}" }`
-	msgAPNSSandbox = `{"APNS_SANDBOX": "{\"aps\": {\"alert\": \"%s\"}, \"urn\":\"%s\"}" }`
-	msgGCM         = `{"GCM": "{\"data\": {\"title\": \"Friends\", \"body\": \"%s\", \"urn\": \"%s\"} }" }`
+	fmtURN = `%s://%s`
 )
 
 // PlatformIdentifiers helps to map Platfrom to human-readable strings.
@@ -51,6 +48,7 @@ var PlatformIdentifiers = map[Platform]string{
 	PlatformAPNS:        "APNS",
 	PlatformAPNSSandbox: "APNS_SANDBOX",
 	PlatformGCM:         "GCM",
+	PlatformWeb:         "WEB",
 }
 
 // API bundles common SNS interactions in a reasonably sized interface.
@@ -204,47 +202,50 @@ func EndpointUpdate(api API) EndpointUpdateFunc {
 	}
 }
 
-// PushFunc pushes a new notification to the device for the given endpoint ARN.
+// PushFunc pushes a new notification to the device for the given endpoint ARN
+// and returns the id SNS assigned to the message.
 type PushFunc func(
 	platform Platform,
-	endpointARN, scheme, urn, message string,
-) error
+	endpointARN, scheme string,
+	payload *Payload,
+) (string, error)
 
 // Push pushes a new notification to the device for the given endpoint ARN.
 func Push(api API) PushFunc {
-	return func(p Platform, arn, scheme, urn, message string) error {
-		fmtMsg := ""
-
-		switch p {
-		case PlatformAPNS:
-			fmtMsg = msgAPNS
-		case PlatformAPNSSandbox:
-			fmtMsg = msgAPNSSandbox
-		case PlatformGCM:
-			fmtMsg = msgGCM
+	return func(p Platform, arn, scheme string, payload *Payload) (string, error) {
+		m, err := payload.encode(p, scheme)
+		if err != nil {
+			return "", err
 		}
 
-		var (
-			u = fmt.Sprintf(fmtURN, scheme, urn)
-			m = fmt.Sprintf(fmtMsg, message, u)
-		)
-
-		_, err := api.Publish(&sns.PublishInput{
+		input := &sns.PublishInput{
 			Message:          aws.String(m),
 			MessageStructure: aws.String(structureJSON),
 			TargetArn:        aws.String(arn),
-		})
+		}
+
+		if payload.CollapseKey != "" &&
+			(p == PlatformAPNS || p == PlatformAPNSSandbox) {
+			input.MessageAttributes = map[string]*sns.MessageAttributeValue{
+				attributeAPNSCollapseID: {
+					DataType:    aws.String("String"),
+					StringValue: aws.String(payload.CollapseKey),
+				},
+			}
+		}
+
+		out, err := api.Publish(input)
 		if err != nil {
 			if awsErr, ok := err.(awserr.RequestFailure); ok {
 				if awsErr.StatusCode() == 400 {
-					return ErrDeliveryFailure
+					return "", ErrDeliveryFailure
 				}
 			}
 
-			return err
+			return "", err
 		}
 
-		return nil
+		return aws.StringValue(out.MessageId), nil
 	}
 }
 
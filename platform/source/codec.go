@@ -0,0 +1,81 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Codec translates state changes to and from their wire format. Decode returns
+// the namespace along with the error if only the payload is malformed.
+type Codec interface {
+	Decode(body []byte) (namespace string, old, new interface{}, err error)
+	Encode(namespace string, old, new interface{}) ([]byte, error)
+}
+
+// PayloadFunc returns a pointer to a zero value of the payload type a Codec
+// decodes into, e.g. func() interface{} { return &object.Object{} }.
+type PayloadFunc func() interface{}
+
+type jsonCodec struct {
+	payload PayloadFunc
+}
+
+// JSONCodec returns a Codec which encodes state changes as JSON documents with
+// the namespace and both versions of the payload.
+func JSONCodec(payload PayloadFunc) Codec {
+	return &jsonCodec{
+		payload: payload,
+	}
+}
+
+func (c *jsonCodec) Decode(
+	body []byte,
+) (ns string, old, new interface{}, err error) {
+	f := struct {
+		Namespace string          `json:"namespace"`
+		New       json.RawMessage `json:"new"`
+		Old       json.RawMessage `json:"old"`
+	}{}
+
+	if err := json.Unmarshal(body, &f); err != nil {
+		return "", nil, nil, err
+	}
+
+	old, err = c.decodePayload(f.Old)
+	if err != nil {
+		return f.Namespace, nil, nil, err
+	}
+
+	new, err = c.decodePayload(f.New)
+	if err != nil {
+		return f.Namespace, nil, nil, err
+	}
+
+	return f.Namespace, old, new, nil
+}
+
+func (c *jsonCodec) Encode(ns string, old, new interface{}) ([]byte, error) {
+	return json.Marshal(&struct {
+		Namespace string      `json:"namespace"`
+		New       interface{} `json:"new"`
+		Old       interface{} `json:"old"`
+	}{
+		Namespace: ns,
+		New:       new,
+		Old:       old,
+	})
+}
+
+func (c *jsonCodec) decodePayload(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	p := c.payload()
+
+	if err := json.Unmarshal(raw, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,71 @@
+package source
+
+import (
+	"github.com/garyburd/redigo/redis"
+	"github.com/jmoiron/sqlx"
+
+	platformSQS "github.com/tapglue/snaas/platform/sqs"
+)
+
+// Entity declares the state changes of a domain type. It is all a domain
+// package provides to plug its state changes into the transports, codecs and
+// middlewares of the framework.
+type Entity struct {
+	// Name identifies the entity in logs and metrics, e.g. object.
+	Name string
+	// Outbox is the table the PostgresOutbox stores state changes in.
+	Outbox string
+	// Payload returns the zero value state changes are decoded into.
+	Payload PayloadFunc
+	// Queue is the name of the SQS queue or Redis stream.
+	Queue string
+	// Redact reduces payloads to what is safe to log, if not set payloads are
+	// logged as they are.
+	Redact func(payload interface{}) interface{}
+}
+
+// Codec returns the Codec for the payload of the Entity.
+func (e Entity) Codec() Codec {
+	return JSONCodec(e.Payload)
+}
+
+// PostgresSource returns a Source which stores state changes of the Entity in
+// an outbox table per namespace.
+func PostgresSource(db *sqlx.DB, e Entity) Source {
+	return New(
+		NewPostgresOutbox(db, e.Outbox, TimeoutVisibility, TimeoutWait),
+		e.Codec(),
+	)
+}
+
+// RedisSource returns a Source backed by a Redis stream. Consumers sharing the
+// same group split the state changes of the Entity between them.
+func RedisSource(
+	pool *redis.Pool,
+	e Entity,
+	group, consumer string,
+) (Source, error) {
+	stream, err := NewRedisStream(
+		pool,
+		e.Queue,
+		group,
+		consumer,
+		TimeoutVisibility,
+		TimeoutWait,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(stream, e.Codec()), nil
+}
+
+// SQSSource returns a Source backed by the SQS queue of the Entity.
+func SQSSource(api platformSQS.API, e Entity) (Source, error) {
+	queue, err := NewSQSQueue(api, e.Queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(queue, e.Codec()), nil
+}
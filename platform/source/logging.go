@@ -0,0 +1,108 @@
+package source
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+type logSource struct {
+	entity Entity
+	logger log.Logger
+	next   Source
+}
+
+// LogMiddleware given a Logger wraps the next Source with logging
+// capabilities. Payloads are logged under keys prefixed with the name of the
+// entity, e.g. object_new and object_old, and redacted if the entity asks for
+// it.
+func LogMiddleware(e Entity, store string, logger log.Logger) SourceMiddleware {
+	return func(next Source) Source {
+		logger = log.With(
+			logger,
+			"source", e.Name,
+			"store", store,
+		)
+
+		return &logSource{
+			entity: e,
+			logger: logger,
+			next:   next,
+		}
+	}
+}
+
+func (s *logSource) Ack(id string) (err error) {
+	defer func(begin time.Time) {
+		ps := []interface{}{
+			"ack_id", id,
+			"duration_ns", time.Since(begin).Nanoseconds(),
+			"method", "Ack",
+		}
+
+		if err != nil {
+			ps = append(ps, "err", err)
+		}
+
+		_ = s.logger.Log(ps...)
+	}(time.Now())
+
+	return s.next.Ack(id)
+}
+
+func (s *logSource) Consume() (change *Change, err error) {
+	defer func(begin time.Time) {
+		ps := []interface{}{
+			"duration_ns", time.Since(begin).Nanoseconds(),
+			"method", "Consume",
+		}
+
+		if change != nil {
+			ps = append(ps,
+				"namespace", change.Namespace,
+				s.entity.Name+"_new", s.redact(change.New),
+				s.entity.Name+"_old", s.redact(change.Old),
+			)
+		}
+
+		if err != nil {
+			ps = append(ps, "err", err)
+		}
+
+		_ = s.logger.Log(ps...)
+	}(time.Now())
+
+	return s.next.Consume()
+}
+
+func (s *logSource) Propagate(
+	ns string,
+	old, new interface{},
+) (id string, err error) {
+	defer func(begin time.Time) {
+		ps := []interface{}{
+			"duration_ns", time.Since(begin).Nanoseconds(),
+			"id", id,
+			"method", "Propagate",
+			"namespace", ns,
+			s.entity.Name + "_new", s.redact(new),
+			s.entity.Name + "_old", s.redact(old),
+		}
+
+		if err != nil {
+			ps = append(ps, "err", err)
+		}
+
+		_ = s.logger.Log(ps...)
+	}(time.Now())
+
+	return s.next.Propagate(ns, old, new)
+}
+
+func (s *logSource) redact(payload interface{}) interface{} {
+	if s.entity.Redact == nil {
+		return payload
+	}
+
+	return s.entity.Redact(payload)
+}
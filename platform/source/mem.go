@@ -0,0 +1,158 @@
+package source
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Common timeouts for MemQueue.
+var (
+	TimeoutVisibility = 60 * time.Second
+	TimeoutWait       = 10 * time.Second
+)
+
+// ErrInvalidAckID is returned when an ack id is unknown to the queue or its
+// delivery has already expired.
+var ErrInvalidAckID = errors.New("invalid ack id")
+
+// Message is a workload delivered by a Transport.
+type Message struct {
+	AckID  string
+	Body   []byte
+	ID     string
+	SentAt time.Time
+}
+
+type memMessage struct {
+	ackID     string
+	body      []byte
+	id        string
+	sentAt    time.Time
+	visibleAt time.Time
+}
+
+// MemQueue is an in-process queue which mimics the delivery semantics of SQS.
+// Received messages are hidden for the visibility timeout and are redelivered
+// with a new ack id if they are not acked in time.
+type MemQueue struct {
+	mu         sync.Mutex
+	msgs       []*memMessage
+	seq        uint64
+	signalc    chan struct{}
+	visibility time.Duration
+	wait       time.Duration
+}
+
+// NewMemQueue returns a MemQueue which hides received messages for the given
+// visibility and waits at most wait for a message to become available.
+func NewMemQueue(visibility, wait time.Duration) *MemQueue {
+	return &MemQueue{
+		msgs:       []*memMessage{},
+		signalc:    make(chan struct{}),
+		visibility: visibility,
+		wait:       wait,
+	}
+}
+
+// Ack permanently removes the message delivered with the given ack id.
+func (q *MemQueue) Ack(ackID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, m := range q.msgs {
+		if m.ackID != ackID || ackID == "" {
+			continue
+		}
+
+		q.msgs = append(q.msgs[:i], q.msgs[i+1:]...)
+
+		return nil
+	}
+
+	return ErrInvalidAckID
+}
+
+// Len returns the number of messages which are not acked yet.
+func (q *MemQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.msgs)
+}
+
+// Receive returns the oldest visible message. If no message becomes visible
+// within the wait timeout nil is returned.
+func (q *MemQueue) Receive() (*Message, error) {
+	deadline := time.Now().Add(q.wait)
+
+	for {
+		q.mu.Lock()
+
+		var (
+			now     = time.Now()
+			next    = deadline
+			signalc = q.signalc
+		)
+
+		for _, m := range q.msgs {
+			if !m.visibleAt.After(now) {
+				q.seq++
+
+				m.ackID = strconv.FormatUint(q.seq, 10)
+				m.visibleAt = now.Add(q.visibility)
+
+				q.mu.Unlock()
+
+				return &Message{
+					AckID:  m.ackID,
+					Body:   m.body,
+					ID:     m.id,
+					SentAt: m.sentAt,
+				}, nil
+			}
+
+			if m.visibleAt.Before(next) {
+				next = m.visibleAt
+			}
+		}
+
+		q.mu.Unlock()
+
+		if !now.Before(deadline) {
+			return nil, nil
+		}
+
+		t := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-signalc:
+		case <-t.C:
+		}
+
+		t.Stop()
+	}
+}
+
+// Send enqueues the body and returns the id of the message. The namespace is
+// not retained as all messages share the same queue.
+func (q *MemQueue) Send(ns string, body []byte) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seq++
+
+	id := strconv.FormatUint(q.seq, 10)
+
+	q.msgs = append(q.msgs, &memMessage{
+		body:   body,
+		id:     id,
+		sentAt: time.Now(),
+	})
+
+	close(q.signalc)
+	q.signalc = make(chan struct{})
+
+	return id, nil
+}
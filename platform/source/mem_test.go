@@ -0,0 +1,111 @@
+package source
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemQueueAck(t *testing.T) {
+	q := NewMemQueue(time.Minute, 10*time.Millisecond)
+
+	id, err := q.Send("test", []byte("ack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := q.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := m.ID, id; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := string(m.Body), "ack"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := q.Ack(m.AckID); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := q.Len(), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := q.Ack(m.AckID), ErrInvalidAckID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestMemQueueReceiveEmpty(t *testing.T) {
+	q := NewMemQueue(time.Minute, 10*time.Millisecond)
+
+	m, err := q.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m != nil {
+		t.Errorf("have %v, want %v", m, nil)
+	}
+}
+
+func TestMemQueueReceiveWait(t *testing.T) {
+	q := NewMemQueue(time.Minute, time.Second)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+
+		_, _ = q.Send("test", []byte("wait"))
+	}()
+
+	m, err := q.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m == nil {
+		t.Fatal("expected message")
+	}
+	if have, want := string(m.Body), "wait"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestMemQueueRedeliver(t *testing.T) {
+	q := NewMemQueue(20*time.Millisecond, 100*time.Millisecond)
+
+	_, err := q.Send("test", []byte("redeliver"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := q.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := q.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second == nil {
+		t.Fatal("expected redelivery")
+	}
+	if have, want := second.ID, first.ID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if second.AckID == first.AckID {
+		t.Errorf("expected new ack id, got %v", second.AckID)
+	}
+
+	if have, want := q.Ack(first.AckID), ErrInvalidAckID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := q.Ack(second.AckID); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,4 +1,4 @@
-package connection
+package source
 
 type nopSource struct{}
 
@@ -11,10 +11,10 @@ func (s *nopSource) Ack(id string) error {
 	return nil
 }
 
-func (s *nopSource) Consume() (*StateChange, error) {
-	return &StateChange{}, nil
+func (s *nopSource) Consume() (*Change, error) {
+	return nil, ErrEmptySource
 }
 
-func (s *nopSource) Propagate(ns string, old, new *Connection) (string, error) {
+func (s *nopSource) Propagate(ns string, old, new interface{}) (string, error) {
 	return "", nil
 }
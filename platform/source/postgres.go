@@ -0,0 +1,304 @@
+package source
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tapglue/snaas/platform/generate"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+const (
+	ackIDSeparator = ":"
+
+	// PollInterval is the pause between two outbox scans when no state change
+	// was found.
+	PollInterval = 500 * time.Millisecond
+)
+
+const (
+	pgDeleteMessage = `DELETE FROM %s.%s WHERE id = $1 AND ack_id = $2`
+	pgInsertMessage = `INSERT INTO %s.%s(payload, sent_at, visible_at)
+		VALUES($1, $2, $2)
+		RETURNING id`
+	pgReceiveMessage = `
+		UPDATE
+			%s.%s
+		SET
+			ack_id = $1,
+			visible_at = $2
+		WHERE
+			id = (
+				SELECT id FROM %s.%s
+				WHERE visible_at <= $3
+				ORDER BY id
+				LIMIT 1
+			)
+			AND visible_at <= $3
+		RETURNING id, payload, sent_at`
+	pgListNamespaces = `SELECT table_schema FROM information_schema.tables
+		WHERE table_name = $1
+		ORDER BY table_schema`
+
+	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
+	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.%s(
+		id BIGSERIAL PRIMARY KEY,
+		ack_id TEXT,
+		payload JSONB NOT NULL,
+		sent_at TIMESTAMP NOT NULL,
+		visible_at TIMESTAMP NOT NULL
+	)`
+	pgIndexVisibleAt = `CREATE INDEX %s ON %s.%s(visible_at, id)`
+	pgDropTable      = `DROP TABLE IF EXISTS %s.%s`
+)
+
+// PostgresOutbox is a transactional outbox which stores state changes in a
+// table per namespace. Received messages are hidden for the visibility
+// timeout and are redelivered with a new ack id if they are not acked in time,
+// which gives at-least-once delivery.
+type PostgresOutbox struct {
+	db         *sqlx.DB
+	table      string
+	visibility time.Duration
+	wait       time.Duration
+}
+
+// NewPostgresOutbox returns a PostgresOutbox which stores messages in the
+// given table of every namespace.
+func NewPostgresOutbox(
+	db *sqlx.DB,
+	table string,
+	visibility, wait time.Duration,
+) *PostgresOutbox {
+	return &PostgresOutbox{
+		db:         db,
+		table:      table,
+		visibility: visibility,
+		wait:       wait,
+	}
+}
+
+// Ack permanently removes the message delivered with the given ack id.
+func (o *PostgresOutbox) Ack(ackID string) error {
+	ps := strings.SplitN(ackID, ackIDSeparator, 3)
+	if len(ps) != 3 {
+		return ErrInvalidAckID
+	}
+
+	id, err := strconv.ParseUint(ps[1], 10, 64)
+	if err != nil {
+		return ErrInvalidAckID
+	}
+
+	res, err := o.db.Exec(o.wrapNamespace(pgDeleteMessage, ps[0]), id, ps[2])
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrInvalidAckID
+	}
+
+	return nil
+}
+
+// Receive returns the oldest visible message of any namespace. If no message
+// becomes visible within the wait timeout nil is returned.
+func (o *PostgresOutbox) Receive() (*Message, error) {
+	deadline := time.Now().Add(o.wait)
+
+	for {
+		nss := []string{}
+
+		err := o.db.Select(&nss, pgListNamespaces, o.table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ns := range nss {
+			m, err := o.receive(ns)
+			if err != nil {
+				return nil, err
+			}
+
+			if m != nil {
+				return m, nil
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, nil
+		}
+
+		time.Sleep(PollInterval)
+	}
+}
+
+// Send stores the body in the outbox of the namespace and returns the id of
+// the message.
+func (o *PostgresOutbox) Send(ns string, body []byte) (string, error) {
+	id, err := o.send(o.db, ns, body)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := o.Setup(ns); err != nil {
+			return "", err
+		}
+
+		id, err = o.send(o.db, ns, body)
+	}
+
+	return id, err
+}
+
+// Setup creates the outbox table for the namespace.
+func (o *PostgresOutbox) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		o.wrapNamespace(pgCreateTable, ns),
+		pg.GuardIndex(
+			ns,
+			fmt.Sprintf("%s_visible_at", o.table),
+			pgIndexVisibleAt,
+			o.table,
+		),
+	}
+
+	for _, q := range qs {
+		_, err := o.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+// Teardown removes the outbox table for the namespace.
+func (o *PostgresOutbox) Teardown(ns string) error {
+	_, err := o.db.Exec(o.wrapNamespace(pgDropTable, ns))
+	return err
+}
+
+func (o *PostgresOutbox) send(
+	q sqlx.Queryer,
+	ns string,
+	body []byte,
+) (string, error) {
+	var id uint64
+
+	err := sqlx.Get(q, &id, o.wrapNamespace(pgInsertMessage, ns), body, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(id, 10), nil
+}
+
+func (o *PostgresOutbox) receive(ns string) (*Message, error) {
+	token, err := generate.UUID()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		now   = time.Now().UTC()
+		query = fmt.Sprintf(pgReceiveMessage, ns, o.table, ns, o.table)
+
+		id      uint64
+		payload []byte
+		sentAt  time.Time
+	)
+
+	err = o.db.QueryRow(query, token, now.Add(o.visibility), now).Scan(
+		&id,
+		&payload,
+		&sentAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &Message{
+		AckID:  strings.Join([]string{ns, strconv.FormatUint(id, 10), token}, ackIDSeparator),
+		Body:   payload,
+		ID:     strconv.FormatUint(id, 10),
+		SentAt: sentAt.UTC(),
+	}, nil
+}
+
+func (o *PostgresOutbox) wrapNamespace(query, ns string) string {
+	return fmt.Sprintf(query, ns, o.table)
+}
+
+// Outbox stores the state changes of an Entity in its PostgresOutbox as part of
+// the transaction which changes the entity, a state change is therefore
+// recorded if and only if the change itself is committed.
+type Outbox struct {
+	codec  Codec
+	db     *sqlx.DB
+	outbox *PostgresOutbox
+}
+
+// NewOutbox returns an Outbox for the Entity.
+func NewOutbox(db *sqlx.DB, e Entity) *Outbox {
+	return &Outbox{
+		codec:  e.Codec(),
+		db:     db,
+		outbox: NewPostgresOutbox(db, e.Outbox, TimeoutVisibility, TimeoutWait),
+	}
+}
+
+// Exec runs the query and stores the state change from old to new within one
+// transaction. Errors are returned unwrapped so callers can setup missing
+// relations and retry.
+func (o *Outbox) Exec(
+	ns string,
+	old, new interface{},
+	query string,
+	params ...interface{},
+) error {
+	body, err := o.codec.Encode(ns, old, new)
+	if err != nil {
+		return err
+	}
+
+	tx, err := o.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(query, params...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if _, err := o.outbox.send(tx, ns, body); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Setup creates the outbox table for the namespace.
+func (o *Outbox) Setup(ns string) error {
+	return o.outbox.Setup(ns)
+}
+
+// Teardown removes the outbox table for the namespace.
+func (o *Outbox) Teardown(ns string) error {
+	return o.outbox.Teardown(ns)
+}
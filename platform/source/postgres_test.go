@@ -0,0 +1,177 @@
+// +build integration
+
+package source
+
+import (
+	"flag"
+	"fmt"
+	"os/user"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+var pgTestURL string
+
+func TestPostgresOutboxAck(t *testing.T) {
+	var (
+		namespace = "source_outbox_ack"
+		outbox    = preparePostgres(t, namespace, time.Minute)
+	)
+
+	id, err := outbox.Send(namespace, []byte(`{"ack":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := outbox.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m == nil {
+		t.Fatal("expected message")
+	}
+	if have, want := m.ID, id; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := outbox.Ack(m.AckID); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := outbox.Ack(m.AckID), ErrInvalidAckID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestPostgresOutboxRedeliver(t *testing.T) {
+	var (
+		namespace = "source_outbox_redeliver"
+		outbox    = preparePostgres(t, namespace, 10*time.Millisecond)
+	)
+
+	_, err := outbox.Send(namespace, []byte(`{"redeliver":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := outbox.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	second, err := outbox.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second == nil {
+		t.Fatal("expected redelivery")
+	}
+	if have, want := second.ID, first.ID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := outbox.Ack(first.AckID), ErrInvalidAckID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := outbox.Ack(second.AckID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutboxExec(t *testing.T) {
+	var (
+		namespace = "source_outbox_exec"
+		outbox    = preparePostgres(t, namespace, time.Minute)
+		tx        = NewOutbox(outbox.db, Entity{
+			Outbox:  outbox.table,
+			Payload: func() interface{} { return &testPayload{} },
+		})
+	)
+
+	if err := tx.Setup(namespace); err != nil {
+		t.Fatal(err)
+	}
+
+	err := tx.Exec(
+		namespace,
+		nil,
+		&testPayload{ID: 1},
+		fmt.Sprintf("SELECT * FROM %s.missing", namespace),
+	)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	m, err := outbox.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m != nil {
+		t.Fatalf("have %v, want %v", m, nil)
+	}
+
+	err = tx.Exec(namespace, nil, &testPayload{ID: 2}, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = outbox.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m == nil {
+		t.Fatal("expected message")
+	}
+
+	_, _, n, err := tx.codec.Decode(m.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := n.(*testPayload).ID, uint64(2); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func preparePostgres(
+	t *testing.T,
+	namespace string,
+	visibility time.Duration,
+) *PostgresOutbox {
+	db, err := sqlx.Connect("postgres", pgTestURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := NewPostgresOutbox(db, "test_outbox", visibility, 10*time.Millisecond)
+
+	if err := o.Teardown(namespace); err != nil {
+		t.Fatal(err)
+	}
+
+	return o
+}
+
+func init() {
+	user, err := user.Current()
+	if err != nil {
+		panic(err)
+	}
+
+	url := flag.String("postgres.url", fmt.Sprintf(pg.URLTest, user.Username), "Postgres connection URL")
+	flag.Parse()
+
+	pgTestURL = *url
+}
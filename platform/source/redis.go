@@ -0,0 +1,276 @@
+package source
+
+import (
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	predis "github.com/tapglue/snaas/platform/redis"
+)
+
+const (
+	errBusyGroup = "BUSYGROUP"
+
+	fieldPayload = "payload"
+	fieldSentAt  = "sent_at"
+
+	pendingBatch = 10
+)
+
+// RedisStream is a queue backed by a Redis stream. Consumers of the same group
+// share the load, each message is only delivered to one of them. Messages
+// which stay unacked for longer than the visibility timeout, e.g. because
+// their consumer crashed, are claimed by the next consumer asking for work.
+type RedisStream struct {
+	consumer   string
+	group      string
+	key        string
+	pool       *redis.Pool
+	visibility time.Duration
+	wait       time.Duration
+}
+
+// NewRedisStream returns a RedisStream for the stream key and makes sure the
+// consumer group exists.
+func NewRedisStream(
+	pool *redis.Pool,
+	key, group, consumer string,
+	visibility, wait time.Duration,
+) (*RedisStream, error) {
+	s := &RedisStream{
+		consumer:   consumer,
+		group:      group,
+		key:        key,
+		pool:       pool,
+		visibility: visibility,
+		wait:       wait,
+	}
+
+	con := pool.Get()
+	defer con.Close()
+
+	_, err := con.Do(predis.CommandXGroup, "CREATE", key, group, "0", "MKSTREAM")
+	if err != nil && !strings.HasPrefix(err.Error(), errBusyGroup) {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Ack acknowledges the message for the consumer group and removes it from the
+// stream.
+func (s *RedisStream) Ack(ackID string) error {
+	con := s.pool.Get()
+	defer con.Close()
+
+	n, err := redis.Int(con.Do(predis.CommandXAck, s.key, s.group, ackID))
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrInvalidAckID
+	}
+
+	_, err = con.Do(predis.CommandXDel, s.key, ackID)
+
+	return err
+}
+
+// Receive returns a message whose previous delivery timed out or otherwise the
+// next new message of the stream. If no message arrives within the wait
+// timeout nil is returned.
+func (s *RedisStream) Receive() (*Message, error) {
+	con := s.pool.Get()
+	defer con.Close()
+
+	m, err := s.claim(con)
+	if err != nil {
+		return nil, err
+	}
+
+	if m != nil {
+		return m, nil
+	}
+
+	res, err := redis.Values(con.Do(
+		predis.CommandXReadGroup,
+		"GROUP", s.group, s.consumer,
+		"COUNT", 1,
+		"BLOCK", int64(s.wait/time.Millisecond),
+		"STREAMS", s.key, ">",
+	))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	// Reply is shaped as [[key, [[id, [field, value, ...]], ...]], ...].
+	for _, stream := range res {
+		vs, err := redis.Values(stream, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(vs) != 2 {
+			continue
+		}
+
+		ms, _, err := parseEntries(vs[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ms) > 0 {
+			return ms[0], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Send appends the body to the stream and returns the id of the message. The
+// namespace is not retained as all messages share the same stream.
+func (s *RedisStream) Send(ns string, body []byte) (string, error) {
+	con := s.pool.Get()
+	defer con.Close()
+
+	return redis.String(con.Do(
+		predis.CommandXAdd,
+		s.key,
+		"*",
+		fieldPayload, body,
+		fieldSentAt, time.Now().UTC().Format(time.RFC3339Nano),
+	))
+}
+
+// claim transfers the oldest message which exceeded the visibility timeout to
+// the consumer. XCLAIM only succeeds for entries still idle for at least the
+// visibility timeout, so concurrent consumers never claim the same delivery.
+func (s *RedisStream) claim(con redis.Conn) (*Message, error) {
+	minIdle := int64(s.visibility / time.Millisecond)
+
+	res, err := redis.Values(con.Do(
+		predis.CommandXPending,
+		s.key, s.group,
+		"-", "+", pendingBatch,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	// Reply is shaped as [[id, consumer, idle, deliveries], ...].
+	for _, r := range res {
+		var (
+			id         string
+			consumer   string
+			idle       int64
+			deliveries int64
+		)
+
+		vs, err := redis.Values(r, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = redis.Scan(vs, &id, &consumer, &idle, &deliveries)
+		if err != nil {
+			return nil, err
+		}
+
+		if idle < minIdle {
+			continue
+		}
+
+		claimed, err := con.Do(
+			predis.CommandXClaim,
+			s.key, s.group, s.consumer,
+			minIdle,
+			id,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		ms, deleted, err := parseEntries(claimed)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ms) > 0 {
+			return ms[0], nil
+		}
+
+		// Drop pending entries whose message is gone from the stream.
+		if len(deleted) > 0 {
+			_, err := con.Do(predis.CommandXAck, s.key, s.group, deleted[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func parseEntries(reply interface{}) ([]*Message, []string, error) {
+	es, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		deleted = []string{}
+		ms      = []*Message{}
+	)
+
+	for _, e := range es {
+		vs, err := redis.Values(e, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(vs) != 2 {
+			continue
+		}
+
+		id, err := redis.String(vs[0], nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Entries deleted while pending are reported without fields.
+		if vs[1] == nil {
+			deleted = append(deleted, id)
+			continue
+		}
+
+		fields, err := redis.StringMap(vs[1], nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		m := &Message{
+			AckID: id,
+			Body:  []byte(fields[fieldPayload]),
+			ID:    id,
+		}
+
+		if raw, ok := fields[fieldSentAt]; ok {
+			t, err := time.Parse(time.RFC3339Nano, raw)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			m.SentAt = t
+		}
+
+		ms = append(ms, m)
+	}
+
+	return ms, deleted, nil
+}
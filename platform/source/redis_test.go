@@ -0,0 +1,130 @@
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestRedisStreamAck(t *testing.T) {
+	var (
+		pool = newPool()
+		s    = prepareRedis(t, pool, "ack", "consumer-a", time.Minute)
+	)
+
+	id, err := s.Send("test", []byte(`{"ack":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := s.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m == nil {
+		t.Fatal("expected message")
+	}
+	if have, want := m.ID, id; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := string(m.Body), `{"ack":true}`; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := s.Ack(m.AckID); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := s.Ack(m.AckID), ErrInvalidAckID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestRedisStreamReclaim(t *testing.T) {
+	var (
+		pool    = newPool()
+		crashed = prepareRedis(t, pool, "reclaim", "consumer-a", 10*time.Millisecond)
+	)
+
+	other, err := NewRedisStream(
+		pool,
+		crashed.key,
+		crashed.group,
+		"consumer-b",
+		10*time.Millisecond,
+		10*time.Millisecond,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := crashed.Send("test", []byte(`{"reclaim":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := crashed.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m == nil {
+		t.Fatal("expected message")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	m, err = other.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m == nil {
+		t.Fatal("expected reclaimed message")
+	}
+	if have, want := m.ID, id; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := other.Ack(m.AckID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newPool() *redis.Pool {
+	return redis.NewPool(func() (redis.Conn, error) {
+		return redis.Dial("tcp", "127.0.0.1:6379")
+	}, 10)
+}
+
+func prepareRedis(
+	t *testing.T,
+	pool *redis.Pool,
+	key, consumer string,
+	visibility time.Duration,
+) *RedisStream {
+	key = "test:source:" + key
+
+	con := pool.Get()
+	defer con.Close()
+
+	if _, err := con.Do("DEL", key); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewRedisStream(
+		pool,
+		key,
+		"test",
+		consumer,
+		visibility,
+		10*time.Millisecond,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
@@ -0,0 +1,52 @@
+package source
+
+import "time"
+
+// RelayBackoffMax caps the pause between two attempts of a Relay.
+const RelayBackoffMax = time.Minute
+
+// Relay drains the state changes of src into dst. A state change is only acked
+// on src after dst accepted it, which gives at-least-once delivery. Failures
+// are passed to report and retried after a backoff which doubles with every
+// consecutive failure, so Relay never returns.
+func Relay(src Source, dst Producer, backoff time.Duration, report func(error)) {
+	delay := backoff
+
+	fail := func(err error) {
+		report(err)
+		time.Sleep(delay)
+
+		if delay *= 2; delay > RelayBackoffMax {
+			delay = RelayBackoffMax
+		}
+	}
+
+	for {
+		change, err := src.Consume()
+		if err != nil {
+			if !IsEmptySource(err) {
+				fail(err)
+			}
+
+			continue
+		}
+
+		for {
+			_, err = dst.Propagate(change.Namespace, change.Old, change.New)
+			if err == nil {
+				break
+			}
+
+			fail(err)
+		}
+
+		// A failed ack leads to a redelivery of the state change once the
+		// visibility timeout passed, which at-least-once delivery permits.
+		if err := src.Ack(change.AckID); err != nil {
+			fail(err)
+			continue
+		}
+
+		delay = backoff
+	}
+}
@@ -0,0 +1,87 @@
+package source
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type flakyProducer struct {
+	mu       sync.Mutex
+	failures int
+	received []interface{}
+}
+
+func (p *flakyProducer) Propagate(
+	ns string,
+	old, new interface{},
+) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failures > 0 {
+		p.failures--
+		return "", errors.New("unavailable")
+	}
+
+	p.received = append(p.received, new)
+
+	return "", nil
+}
+
+func TestRelay(t *testing.T) {
+	var (
+		dst = &flakyProducer{failures: 3}
+		src = New(
+			NewMemQueue(time.Minute, 10*time.Millisecond),
+			JSONCodec(func() interface{} { return &testPayload{} }),
+		)
+
+		mu      sync.Mutex
+		reports = 0
+	)
+
+	_, err := src.Propagate("app_1_1", nil, &testPayload{ID: 123})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go Relay(src, dst, time.Millisecond, func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		reports++
+	})
+
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		dst.mu.Lock()
+		n := len(dst.received)
+		dst.mu.Unlock()
+
+		if n > 0 {
+			break
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	if have, want := len(dst.received), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	if have, want := dst.received[0].(*testPayload).ID, uint64(123); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if have, want := reports, 3; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
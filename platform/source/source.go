@@ -1,6 +1,141 @@
 package source
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEmptySource is returned when no state change arrived within the wait
+// timeout of the Transport.
+var ErrEmptySource = errors.New("empty source")
+
+// DecodeError is returned by Consume for a message whose body can't be decoded.
+// Redelivering it won't help, it carries what is needed to set the message
+// aside and ack it. Namespace is empty if the envelope itself is malformed.
+type DecodeError struct {
+	AckID     string
+	Body      []byte
+	Err       error
+	Namespace string
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode: %s", e.Err)
+}
+
 // Acker permantly removes the workload from the Source.
 type Acker interface {
 	Ack(id string) error
 }
+
+// Change is a decoded state change. New and Old hold the payload type
+// declared through the Codec, either can be nil.
+type Change struct {
+	AckID     string
+	ID        string
+	Namespace string
+	New       interface{}
+	Old       interface{}
+	SentAt    time.Time
+}
+
+// Consumer observes state changes.
+type Consumer interface {
+	Consume() (*Change, error)
+}
+
+// Producer creates a state change notification.
+type Producer interface {
+	Propagate(namespace string, old, new interface{}) (string, error)
+}
+
+// Source combines state change consumption and propagation for a single
+// entity.
+type Source interface {
+	Acker
+	Consumer
+	Producer
+}
+
+// SourceMiddleware is a chainable behaviour modifier for Source.
+type SourceMiddleware func(Source) Source
+
+// Transport moves encoded state changes from producers to consumers. MemQueue,
+// PostgresOutbox, RedisStream and SQSQueue are the available implementations.
+type Transport interface {
+	Acker
+	Receive() (*Message, error)
+	Send(namespace string, body []byte) (string, error)
+}
+
+type transportSource struct {
+	codec     Codec
+	transport Transport
+}
+
+// New returns a Source which encodes state changes with the Codec and moves
+// them over the Transport.
+func New(transport Transport, codec Codec) Source {
+	return &transportSource{
+		codec:     codec,
+		transport: transport,
+	}
+}
+
+func (s *transportSource) Ack(id string) error {
+	return s.transport.Ack(id)
+}
+
+func (s *transportSource) Consume() (*Change, error) {
+	m, err := s.transport.Receive()
+	if err != nil {
+		return nil, err
+	}
+
+	if m == nil {
+		return nil, ErrEmptySource
+	}
+
+	ns, old, new, err := s.codec.Decode(m.Body)
+	if err != nil {
+		return nil, &DecodeError{
+			AckID:     m.AckID,
+			Body:      m.Body,
+			Err:       err,
+			Namespace: ns,
+		}
+	}
+
+	return &Change{
+		AckID:     m.AckID,
+		ID:        m.ID,
+		Namespace: ns,
+		New:       new,
+		Old:       old,
+		SentAt:    m.SentAt,
+	}, nil
+}
+
+func (s *transportSource) Propagate(
+	ns string,
+	old, new interface{},
+) (string, error) {
+	body, err := s.codec.Encode(ns, old, new)
+	if err != nil {
+		return "", err
+	}
+
+	return s.transport.Send(ns, body)
+}
+
+// IsDecodeError indicates if err is a DecodeError.
+func IsDecodeError(err error) bool {
+	_, ok := err.(*DecodeError)
+	return ok
+}
+
+// IsEmptySource indicates if err is ErrEmptySource.
+func IsEmptySource(err error) bool {
+	return err == ErrEmptySource
+}
@@ -0,0 +1,112 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testPayload struct {
+	ID   uint64 `json:"id"`
+	Type string `json:"type"`
+}
+
+func TestSource(t *testing.T) {
+	var (
+		s = New(
+			NewMemQueue(time.Minute, 10*time.Millisecond),
+			JSONCodec(func() interface{} { return &testPayload{} }),
+		)
+		new = &testPayload{ID: 123, Type: "post"}
+	)
+
+	id, err := s.Propagate("app_1_1", nil, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := s.Consume()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := c.ID, id; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := c.Namespace, "app_1_1"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := c.New, new; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if c.Old != nil {
+		t.Errorf("have %v, want %v", c.Old, nil)
+	}
+
+	if err := s.Ack(c.AckID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Consume()
+	if have, want := err, ErrEmptySource; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestSourceDecodeError(t *testing.T) {
+	var (
+		q = NewMemQueue(time.Minute, 10*time.Millisecond)
+		s = New(q, JSONCodec(func() interface{} { return &testPayload{} }))
+	)
+
+	for body, ns := range map[string]string{
+		`{"namespace": "app_1_1", "new": {"id": "123"}}`: "app_1_1",
+		`{"namespace": `: "",
+	} {
+		if _, err := q.Send(ns, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := s.Consume()
+
+		e, ok := err.(*DecodeError)
+		if !ok {
+			t.Fatalf("have %v, want %v", err, "DecodeError")
+		}
+
+		if have, want := string(e.Body), body; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+		if have, want := e.Namespace, ns; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+
+		if err := s.Ack(e.AckID); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestJSONCodecNil(t *testing.T) {
+	var (
+		c   = JSONCodec(func() interface{} { return &testPayload{} })
+		old *testPayload
+	)
+
+	body, err := c.Encode("app_1_1", old, &testPayload{ID: 321})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, o, n, err := c.Decode(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o != nil {
+		t.Errorf("have %v, want %v", o, nil)
+	}
+	if have, want := n.(*testPayload).ID, uint64(321); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
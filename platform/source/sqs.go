@@ -0,0 +1,87 @@
+package source
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+
+	platformSQS "github.com/tapglue/snaas/platform/sqs"
+)
+
+// SQSQueue is a Transport backed by an SQS queue.
+type SQSQueue struct {
+	api      platformSQS.API
+	queueURL string
+}
+
+// NewSQSQueue looks up the url of the queue with the given name and returns an
+// SQSQueue operating on it.
+func NewSQSQueue(api platformSQS.API, name string) (*SQSQueue, error) {
+	res, err := api.GetQueueUrl(&sqs.GetQueueUrlInput{
+		QueueName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQSQueue{
+		api:      api,
+		queueURL: *res.QueueUrl,
+	}, nil
+}
+
+// Ack deletes the message with the given receipt handle.
+func (q *SQSQueue) Ack(ackID string) error {
+	_, err := q.api.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(q.queueURL),
+		ReceiptHandle: aws.String(ackID),
+	})
+
+	return err
+}
+
+// Receive long-polls for the next message and returns nil if none arrived
+// within the wait timeout.
+func (q *SQSQueue) Receive() (*Message, error) {
+	o, err := platformSQS.ReceiveMessage(q.api, q.queueURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.Messages) == 0 {
+		return nil, nil
+	}
+
+	var (
+		m = o.Messages[0]
+
+		sentAt time.Time
+	)
+
+	if attr, ok := m.MessageAttributes[platformSQS.AttributeSentAt]; ok {
+		t, err := time.Parse(platformSQS.FormatSentAt, *attr.StringValue)
+		if err != nil {
+			return nil, err
+		}
+
+		sentAt = t
+	}
+
+	return &Message{
+		AckID:  *m.ReceiptHandle,
+		Body:   []byte(*m.Body),
+		ID:     *m.MessageId,
+		SentAt: sentAt,
+	}, nil
+}
+
+// Send publishes the body to the queue and returns the id of the message.
+func (q *SQSQueue) Send(ns string, body []byte) (string, error) {
+	o, err := q.api.SendMessage(platformSQS.MessageInput(body, q.queueURL))
+	if err != nil {
+		return "", err
+	}
+
+	return *o.MessageId, nil
+}
@@ -0,0 +1,42 @@
+package source
+
+// WorkFunc processes a single unit of work, usually one consumed state change.
+// Returning an error stops all workers.
+type WorkFunc func() error
+
+// Work runs fn in a loop on the given number of concurrent workers until one
+// of them fails and returns the first error. ErrEmptySource is not treated as
+// failure. Ordering of state changes is only preserved for a single worker.
+func Work(workers int, fn WorkFunc) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		donec = make(chan struct{})
+		errc  = make(chan error, workers)
+	)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-donec:
+					return
+				default:
+				}
+
+				err := fn()
+				if err != nil && !IsEmptySource(err) {
+					errc <- err
+					return
+				}
+			}
+		}()
+	}
+
+	err := <-errc
+	close(donec)
+
+	return err
+}
@@ -0,0 +1,40 @@
+package source
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestWork(t *testing.T) {
+	var (
+		errStop = errors.New("stop")
+
+		mu   sync.Mutex
+		runs = 0
+	)
+
+	err := Work(4, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		runs++
+
+		if runs < 10 {
+			return ErrEmptySource
+		}
+
+		return errStop
+	})
+
+	if have, want := err, errStop; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if runs < 10 {
+		t.Errorf("have %v, want >= %v", runs, 10)
+	}
+}
@@ -0,0 +1,93 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tapglue/snaas/platform/service"
+)
+
+// Entry is a message buffered until the aggregation window of its Key closes
+// at DueAt, when all Entries of the Key are collapsed into one message.
+// ChangeID identifies the state change the message originates from, so a
+// redelivered state change is only buffered once.
+type Entry struct {
+	AppID     uint64          `json:"app_id"`
+	ChangeID  string          `json:"change_id"`
+	DueAt     time.Time       `json:"due_at"`
+	ID        uint64          `json:"id"`
+	Key       string          `json:"key"`
+	Message   json.RawMessage `json:"message"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Validate checks for semantic correctness.
+func (e *Entry) Validate() error {
+	if e.AppID == 0 {
+		return wrapError(ErrInvalidEntry, "missing app id")
+	}
+
+	if e.ChangeID == "" {
+		return wrapError(ErrInvalidEntry, "missing change id")
+	}
+
+	if e.DueAt.IsZero() {
+		return wrapError(ErrInvalidEntry, "missing due time")
+	}
+
+	if e.Key == "" {
+		return wrapError(ErrInvalidEntry, "missing key")
+	}
+
+	if len(e.Message) == 0 {
+		return wrapError(ErrInvalidEntry, "missing message")
+	}
+
+	return nil
+}
+
+// List is an Entry collection.
+type List []*Entry
+
+func (l List) Len() int {
+	return len(l)
+}
+
+func (l List) Less(i, j int) bool {
+	if l[i].DueAt.Equal(l[j].DueAt) {
+		return l[i].ID < l[j].ID
+	}
+
+	return l[i].DueAt.Before(l[j].DueAt)
+}
+
+func (l List) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// QueryOptions to narrow-down Entry queries.
+type QueryOptions struct {
+	AppIDs []uint64
+	Due    time.Time
+	IDs    []uint64
+	Keys   []string
+	Limit  int
+}
+
+// Service for Entry interactions. Put returns ErrExists for an Entry whose
+// state change was already buffered under the same Key.
+type Service interface {
+	service.Lifecycle
+
+	Delete(namespace string, id uint64) error
+	Put(namespace string, e *Entry) (*Entry, error)
+	Query(namespace string, opts QueryOptions) (List, error)
+}
+
+// ServiceMiddleware is a chainable behaviour modifier for Service.
+type ServiceMiddleware func(Service) Service
+
+func flakeNamespace(ns string) string {
+	return fmt.Sprintf("%s_%s", ns, "aggregates")
+}
@@ -0,0 +1,60 @@
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// Common errors for Entry service implementations and validations.
+var (
+	ErrExists       = errors.New("entry exists")
+	ErrInvalidEntry = errors.New("invalid entry")
+	ErrNotFound     = errors.New("entry not found")
+)
+
+// Error wrapper.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsExists indicates if err is ErrExists.
+func IsExists(err error) bool {
+	return unwrapError(err) == ErrExists
+}
+
+// IsInvalidEntry indicates if err is ErrInvalidEntry.
+func IsInvalidEntry(err error) bool {
+	return unwrapError(err) == ErrInvalidEntry
+}
+
+// IsNotFound indicates if err is ErrNotFound.
+func IsNotFound(err error) bool {
+	return unwrapError(err) == ErrNotFound
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err.Error(),
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
@@ -0,0 +1,162 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type prepareFunc func(t *testing.T, namespace string) Service
+
+func testServiceDelete(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_delete"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testEntry(1, "1:2:a", "c1", time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Delete(namespace, created.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	es, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(es), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := service.Delete(namespace, created.ID); !IsNotFound(err) {
+		t.Errorf("have %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testServicePut(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_put"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testEntry(1, "1:2:a", "c1", time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.ID == 0 {
+		t.Error("expected id to be set")
+	}
+
+	es, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(es), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	var have, want map[string]interface{}
+
+	if err := json.Unmarshal(es[0].Message, &have); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(created.Message, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := es[0].DueAt, created.DueAt; !have.Equal(want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	// A redelivered state change is only buffered once per key.
+	_, err = service.Put(namespace, testEntry(1, "1:2:a", "c1", time.Now()))
+	if !IsExists(err) {
+		t.Errorf("have %v, want %v", err, ErrExists)
+	}
+
+	if _, err := service.Put(namespace, testEntry(1, "1:3:a", "c1", time.Now())); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := service.Put(namespace, created); !IsInvalidEntry(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidEntry)
+	}
+
+	if _, err := service.Put(namespace, &Entry{}); !IsInvalidEntry(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidEntry)
+	}
+}
+
+func testServiceQuery(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_query"
+		service   = p(t, namespace)
+		now       = time.Now().UTC()
+	)
+
+	for _, e := range []*Entry{
+		testEntry(1, "1:2:a", "c1", now.Add(-2*time.Hour)),
+		testEntry(1, "1:2:a", "c2", now.Add(-2*time.Hour)),
+		testEntry(1, "1:2:b", "c3", now.Add(time.Hour)),
+		testEntry(2, "1:2:a", "c4", now.Add(-time.Minute)),
+		testEntry(2, "1:3:a", "c5", now.Add(2*time.Hour)),
+	} {
+		_, err := service.Put(namespace, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[*QueryOptions]int{
+		&QueryOptions{}:                                             5,
+		&QueryOptions{AppIDs: []uint64{1}}:                          3,
+		&QueryOptions{Due: now}:                                     3,
+		&QueryOptions{AppIDs: []uint64{2}, Due: now}:                1,
+		&QueryOptions{Due: now, Limit: 2}:                           2,
+		&QueryOptions{Due: now.Add(-3 * time.Hour)}:                 0,
+		&QueryOptions{Keys: []string{"1:2:a"}}:                      3,
+		&QueryOptions{AppIDs: []uint64{1}, Keys: []string{"1:2:a"}}: 2,
+		&QueryOptions{Keys: []string{"1:2:b", "1:3:a"}}:             2,
+	}
+
+	for opts, want := range cases {
+		es, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := len(es); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func testEntry(appID uint64, key, changeID string, dueAt time.Time) *Entry {
+	return &Entry{
+		AppID:    appID,
+		ChangeID: changeID,
+		DueAt:    dueAt,
+		Key:      key,
+		Message:  json.RawMessage(`{"Recipient":123,"URN":"tapglue/posts/1"}`),
+	}
+}
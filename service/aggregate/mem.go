@@ -0,0 +1,152 @@
+package aggregate
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/flake"
+)
+
+type memService struct {
+	sync.Mutex
+
+	entries map[string]map[uint64]*Entry
+}
+
+// MemService returns a memory backed implementation of Service.
+func MemService() Service {
+	return &memService{
+		entries: map[string]map[uint64]*Entry{},
+	}
+}
+
+func (s *memService) Delete(ns string, id uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.entries[ns][id]; !ok {
+		return wrapError(ErrNotFound, "%d", id)
+	}
+
+	delete(s.entries[ns], id)
+
+	return nil
+}
+
+func (s *memService) Put(ns string, e *Entry) (*Entry, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	if e.ID != 0 {
+		return nil, wrapError(ErrInvalidEntry, "entries are immutable")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.entries[ns]; !ok {
+		s.entries[ns] = map[uint64]*Entry{}
+	}
+
+	for _, o := range s.entries[ns] {
+		if o.AppID == e.AppID && o.Key == e.Key && o.ChangeID == e.ChangeID {
+			return nil, wrapError(ErrExists, "%s %s", e.Key, e.ChangeID)
+		}
+	}
+
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	e.ID = id
+	e.DueAt = e.DueAt.UTC()
+	e.CreatedAt = time.Now().UTC()
+
+	s.entries[ns][e.ID] = copy(e)
+
+	return copy(e), nil
+}
+
+func (s *memService) Query(ns string, opts QueryOptions) (List, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	es := List{}
+
+	for _, e := range s.entries[ns] {
+		if !inIDs(e.AppID, opts.AppIDs) {
+			continue
+		}
+
+		if !opts.Due.IsZero() && e.DueAt.After(opts.Due) {
+			continue
+		}
+
+		if !inIDs(e.ID, opts.IDs) {
+			continue
+		}
+
+		if !inKeys(e.Key, opts.Keys) {
+			continue
+		}
+
+		es = append(es, copy(e))
+	}
+
+	sort.Sort(es)
+
+	if opts.Limit > 0 && len(es) > opts.Limit {
+		es = es[:opts.Limit]
+	}
+
+	return es, nil
+}
+
+func (s *memService) Setup(ns string) error {
+	return nil
+}
+
+func (s *memService) Teardown(ns string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.entries, ns)
+
+	return nil
+}
+
+func copy(e *Entry) *Entry {
+	old := *e
+	return &old
+}
+
+func inIDs(id uint64, ids []uint64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inKeys(key string, keys []string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,19 @@
+package aggregate
+
+import "testing"
+
+func TestMemDelete(t *testing.T) {
+	testServiceDelete(t, prepareMem)
+}
+
+func TestMemPut(t *testing.T) {
+	testServicePut(t, prepareMem)
+}
+
+func TestMemQuery(t *testing.T) {
+	testServiceQuery(t, prepareMem)
+}
+
+func prepareMem(t *testing.T, namespace string) Service {
+	return MemService()
+}
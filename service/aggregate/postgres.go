@@ -0,0 +1,312 @@
+package aggregate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tapglue/snaas/platform/flake"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+const (
+	pgDeleteEntry = `DELETE FROM %s.aggregates WHERE id = $1`
+	pgInsertEntry = `INSERT INTO
+		%s.aggregates(app_id, change_id, due_at, id, key, message, created_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7)`
+
+	pgClauseAppIDs = `app_id IN (?)`
+	pgClauseDue    = `due_at <= ?`
+	pgClauseIDs    = `id IN (?)`
+	pgClauseKeys   = `key IN (?)`
+
+	pgListEntries = `
+		SELECT
+			app_id, change_id, due_at, id, key, message, created_at
+		FROM
+			%s.aggregates
+		%s`
+	pgOrderDueAt = `ORDER BY due_at ASC, id ASC`
+
+	pgIndexDueAt  = `CREATE INDEX %s ON %s.aggregates (due_at)`
+	pgIndexChange = `CREATE UNIQUE INDEX %s ON %s.aggregates (app_id, key, change_id)`
+
+	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
+	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.aggregates(
+		app_id BIGINT NOT NULL,
+		change_id TEXT NOT NULL,
+		due_at TIMESTAMP NOT NULL,
+		id BIGINT NOT NULL UNIQUE,
+		key TEXT NOT NULL,
+		message JSONB NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`
+	pgDropTable = `DROP TABLE IF EXISTS %s.aggregates`
+)
+
+type pgService struct {
+	db *sqlx.DB
+}
+
+// PostgresService returns a Postgres based Service implementation.
+func PostgresService(db *sqlx.DB) Service {
+	return &pgService{
+		db: db,
+	}
+}
+
+func (s *pgService) Delete(ns string, id uint64) error {
+	res, err := s.db.Exec(fmt.Sprintf(pgDeleteEntry, ns), id)
+	if err != nil {
+		if pg.IsRelationNotFound(pg.WrapError(err)) {
+			return wrapError(ErrNotFound, "%d", id)
+		}
+
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return wrapError(ErrNotFound, "%d", id)
+	}
+
+	return nil
+}
+
+func (s *pgService) Put(ns string, e *Entry) (*Entry, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	if e.ID != 0 {
+		return nil, wrapError(ErrInvalidEntry, "entries are immutable")
+	}
+
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	dueAt, err := time.Parse(pg.TimeFormat, e.DueAt.UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	e.ID = id
+	e.DueAt = dueAt
+	e.CreatedAt = ts
+
+	var (
+		params = []interface{}{
+			e.AppID,
+			e.ChangeID,
+			e.DueAt,
+			e.ID,
+			e.Key,
+			[]byte(e.Message),
+			e.CreatedAt,
+		}
+		query = fmt.Sprintf(pgInsertEntry, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		if pg.IsNotUnique(pg.WrapError(err)) {
+			return nil, wrapError(ErrExists, "%s %s", e.Key, e.ChangeID)
+		}
+
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
+	where, params, err := convertOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	es, err := s.listEntries(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		es, err = s.listEntries(ns, where, params...)
+	}
+
+	return es, err
+}
+
+func (s *pgService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateTable, ns),
+		pg.GuardIndex(ns, "aggregate_due_at", pgIndexDueAt),
+		pg.GuardIndex(ns, "aggregate_change", pgIndexChange),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) Teardown(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgDropTable, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("teardown '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) listEntries(
+	ns, where string,
+	params ...interface{},
+) (List, error) {
+	query := fmt.Sprintf(pgListEntries, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	es := List{}
+
+	for rows.Next() {
+		var (
+			e = &Entry{}
+
+			message []byte
+		)
+
+		err := rows.Scan(
+			&e.AppID,
+			&e.ChangeID,
+			&e.DueAt,
+			&e.ID,
+			&e.Key,
+			&message,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		e.Message = message
+		e.DueAt = e.DueAt.UTC()
+		e.CreatedAt = e.CreatedAt.UTC()
+
+		es = append(es, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
+
+func convertOpts(opts QueryOptions) (string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+	)
+
+	if len(opts.AppIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.AppIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseAppIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if !opts.Due.IsZero() {
+		clauses = append(clauses, pgClauseDue)
+		params = append(params, opts.Due.UTC().Format(pg.TimeFormat))
+	}
+
+	if len(opts.IDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.IDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.Keys) > 0 {
+		ps := []interface{}{}
+
+		for _, k := range opts.Keys {
+			ps = append(ps, k)
+		}
+
+		clause, _, err := sqlx.In(pgClauseKeys, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	where = fmt.Sprintf("%s\n%s", where, pgOrderDueAt)
+
+	if opts.Limit > 0 {
+		where = fmt.Sprintf("%s\nLIMIT %d", where, opts.Limit)
+	}
+
+	return where, params, nil
+}
@@ -0,0 +1,156 @@
+package campaign
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/tapglue/snaas/platform/service"
+	"github.com/tapglue/snaas/platform/sns"
+	"github.com/tapglue/snaas/service/rule"
+)
+
+// State of a Campaign.
+type State string
+
+// States a Campaign goes through. Canceled and done are final, a Campaign in
+// one of them is not changed anymore.
+const (
+	StateCanceled  State = "canceled"
+	StateDone      State = "done"
+	StateScheduled State = "scheduled"
+	StateSending   State = "sending"
+)
+
+var states = map[State]struct{}{
+	StateCanceled:  {},
+	StateDone:      {},
+	StateScheduled: {},
+	StateSending:   {},
+}
+
+// Audience describes the users a Campaign is sent to. All criteria have to
+// match, an empty Audience targets every user with a device. Languages match
+// the base language of devices, "de" covers "de-AT" as well. SegmentID limits
+// the Audience to the cached members of a segment, in place of UserIDs.
+type Audience struct {
+	Languages []string       `json:"languages,omitempty"`
+	Platforms []sns.Platform `json:"platforms,omitempty"`
+	SegmentID uint64         `json:"segment_id,omitempty"`
+	UserIDs   []uint64       `json:"user_ids,omitempty"`
+}
+
+// Progress tracks how far a Campaign got. Recipients are processed ordered by
+// their id, Cursor is the id of the last one processed.
+type Progress struct {
+	Cursor uint64 `json:"cursor"`
+	Sent   int    `json:"sent"`
+	Total  int    `json:"total"`
+}
+
+// Campaign is a one-off announcement sent to the Audience of an app once
+// SendAt passed. Templates and Push follow the semantics of rule recipients,
+// they are rendered with the recipient as User.
+type Campaign struct {
+	AppID     uint64
+	Audience  Audience
+	ID        uint64
+	Name      string
+	Progress  Progress
+	Push      rule.PushTemplates
+	SendAt    time.Time
+	State     State
+	Templates rule.Templates
+	URN       string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Final reports if the Campaign reached a State it doesn't leave anymore.
+func (c *Campaign) Final() bool {
+	return c.State == StateCanceled || c.State == StateDone
+}
+
+// Validate checks for semantic correctness.
+func (c *Campaign) Validate() error {
+	if c.AppID == 0 {
+		return wrapError(ErrInvalidCampaign, "missing app id")
+	}
+
+	if c.Name == "" {
+		return wrapError(ErrInvalidCampaign, "missing name")
+	}
+
+	for _, l := range c.Audience.Languages {
+		if _, err := language.Parse(l); err != nil {
+			return wrapError(ErrInvalidCampaign, "invalid language '%s'", l)
+		}
+	}
+
+	for _, p := range c.Audience.Platforms {
+		if p < sns.PlatformAPNSSandbox || p > sns.PlatformWeb {
+			return wrapError(ErrInvalidCampaign, "unsupported platform '%d'", p)
+		}
+	}
+
+	if c.Audience.SegmentID != 0 && len(c.Audience.UserIDs) > 0 {
+		return wrapError(ErrInvalidCampaign, "segment and user ids are exclusive")
+	}
+
+	err := rule.Recipient{
+		Push:      c.Push,
+		Templates: c.Templates,
+		URN:       c.URN,
+	}.Validate()
+	if err != nil {
+		return wrapError(ErrInvalidCampaign, "%s", err)
+	}
+
+	if _, ok := states[c.State]; !ok {
+		return wrapError(ErrInvalidCampaign, "unsupported state '%s'", c.State)
+	}
+
+	return nil
+}
+
+// List is a Campaign collection.
+type List []*Campaign
+
+func (l List) Len() int {
+	return len(l)
+}
+
+func (l List) Less(i, j int) bool {
+	return l[i].CreatedAt.After(l[j].CreatedAt)
+}
+
+func (l List) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// QueryOptions to narrow-down Campaign queries. Campaigns are returned newest
+// first, with Due set only those to be sent by then in order of SendAt.
+type QueryOptions struct {
+	AppIDs []uint64
+	Due    time.Time
+	IDs    []uint64
+	Limit  int
+	States []State
+}
+
+// Service for Campaign interactions. Updates of Campaigns in a final State
+// fail with ErrFinal.
+type Service interface {
+	service.Lifecycle
+
+	Put(namespace string, c *Campaign) (*Campaign, error)
+	Query(namespace string, opts QueryOptions) (List, error)
+}
+
+// ServiceMiddleware is a chainable behaviour modifier for Service.
+type ServiceMiddleware func(Service) Service
+
+func flakeNamespace(ns string) string {
+	return fmt.Sprintf("%s_%s", ns, "campaigns")
+}
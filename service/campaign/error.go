@@ -0,0 +1,60 @@
+package campaign
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// Common errors for Campaign service implementations and validations.
+var (
+	ErrFinal           = errors.New("campaign final")
+	ErrInvalidCampaign = errors.New("invalid campaign")
+	ErrNotFound        = errors.New("campaign not found")
+)
+
+// Error wrapper.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsFinal indicates if err is ErrFinal.
+func IsFinal(err error) bool {
+	return unwrapError(err) == ErrFinal
+}
+
+// IsInvalidCampaign indicates if err is ErrInvalidCampaign.
+func IsInvalidCampaign(err error) bool {
+	return unwrapError(err) == ErrInvalidCampaign
+}
+
+// IsNotFound indicates if err is ErrNotFound.
+func IsNotFound(err error) bool {
+	return unwrapError(err) == ErrNotFound
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err.Error(),
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
@@ -0,0 +1,161 @@
+package campaign
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tapglue/snaas/platform/sns"
+	"github.com/tapglue/snaas/service/rule"
+)
+
+type prepareFunc func(t *testing.T, namespace string) Service
+
+func testServicePut(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_put"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testCampaign(1, StateScheduled))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.ID == 0 {
+		t.Error("expected id to be set")
+	}
+
+	cs, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(cs), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := cs[0], created; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+
+	created.Progress = Progress{Cursor: 123, Sent: 1, Total: 2}
+	created.State = StateCanceled
+
+	updated, err := service.Put(namespace, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err = service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(cs), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := cs[0], updated; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+
+	updated.State = StateSending
+
+	if _, err := service.Put(namespace, updated); !IsFinal(err) {
+		t.Errorf("have %v, want %v", err, ErrFinal)
+	}
+
+	missing := testCampaign(1, StateScheduled)
+	missing.ID = created.ID + 1
+
+	if _, err := service.Put(namespace, missing); !IsNotFound(err) {
+		t.Errorf("have %v, want %v", err, ErrNotFound)
+	}
+
+	if _, err := service.Put(namespace, &Campaign{}); !IsInvalidCampaign(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidCampaign)
+	}
+}
+
+func testServiceQuery(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_query"
+		service   = p(t, namespace)
+		now       = time.Now().UTC()
+	)
+
+	for _, c := range []*Campaign{
+		testCampaign(1, StateScheduled),
+		testCampaign(1, StateSending),
+		testCampaign(1, StateDone),
+		testCampaign(2, StateScheduled),
+		testCampaign(2, StateCanceled),
+	} {
+		_, err := service.Put(namespace, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	later := testCampaign(1, StateScheduled)
+	later.SendAt = now.Add(time.Hour)
+
+	if _, err := service.Put(namespace, later); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[*QueryOptions]int{
+		&QueryOptions{}:                                                    6,
+		&QueryOptions{AppIDs: []uint64{1}}:                                 4,
+		&QueryOptions{AppIDs: []uint64{3}}:                                 0,
+		&QueryOptions{Due: now}:                                            5,
+		&QueryOptions{IDs: []uint64{later.ID}}:                             1,
+		&QueryOptions{States: []State{StateScheduled}}:                     3,
+		&QueryOptions{States: []State{StateScheduled, StateSending}}:       4,
+		&QueryOptions{Due: now, States: []State{StateScheduled}}:           2,
+		&QueryOptions{AppIDs: []uint64{2}, States: []State{StateCanceled}}: 1,
+		&QueryOptions{Limit: 3}:                                            3,
+	}
+
+	for opts, want := range cases {
+		cs, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := len(cs); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func testCampaign(appID uint64, state State) *Campaign {
+	return &Campaign{
+		AppID: appID,
+		Audience: Audience{
+			Languages: []string{"de"},
+			Platforms: []sns.Platform{sns.PlatformAPNS},
+		},
+		Name: "Launch",
+		Push: rule.PushTemplates{
+			Titles: rule.Templates{
+				"en": "News",
+			},
+		},
+		SendAt: time.Now().Add(-time.Minute).UTC(),
+		State:  state,
+		Templates: rule.Templates{
+			"en": "Hello {{.User.Username}}",
+		},
+		URN: "news/launch",
+	}
+}
@@ -0,0 +1,163 @@
+package campaign
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/flake"
+)
+
+type memService struct {
+	sync.Mutex
+
+	campaigns map[string]map[uint64]*Campaign
+}
+
+// MemService returns a memory backed implementation of Service.
+func MemService() Service {
+	return &memService{
+		campaigns: map[string]map[uint64]*Campaign{},
+	}
+}
+
+func (s *memService) Put(ns string, c *Campaign) (*Campaign, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.campaigns[ns]; !ok {
+		s.campaigns[ns] = map[uint64]*Campaign{}
+	}
+
+	now := time.Now().UTC()
+
+	if c.ID == 0 {
+		id, err := flake.NextID(flakeNamespace(ns))
+		if err != nil {
+			return nil, err
+		}
+
+		c.ID = id
+		c.CreatedAt = now
+	} else {
+		old, ok := s.campaigns[ns][c.ID]
+		if !ok {
+			return nil, wrapError(ErrNotFound, "%d", c.ID)
+		}
+
+		if old.Final() {
+			return nil, wrapError(ErrFinal, "%d is %s", c.ID, old.State)
+		}
+
+		c.CreatedAt = old.CreatedAt
+	}
+
+	c.UpdatedAt = now
+
+	s.campaigns[ns][c.ID] = copy(c)
+
+	return copy(c), nil
+}
+
+func (s *memService) Query(ns string, opts QueryOptions) (List, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	cs := List{}
+
+	for _, c := range s.campaigns[ns] {
+		if !inIDs(c.AppID, opts.AppIDs) {
+			continue
+		}
+
+		if !opts.Due.IsZero() && c.SendAt.After(opts.Due) {
+			continue
+		}
+
+		if !inIDs(c.ID, opts.IDs) {
+			continue
+		}
+
+		if !inStates(c.State, opts.States) {
+			continue
+		}
+
+		cs = append(cs, copy(c))
+	}
+
+	if opts.Due.IsZero() {
+		sort.Sort(cs)
+	} else {
+		sort.Sort(bySendAt(cs))
+	}
+
+	if opts.Limit > 0 && len(cs) > opts.Limit {
+		cs = cs[:opts.Limit]
+	}
+
+	return cs, nil
+}
+
+func (s *memService) Setup(ns string) error {
+	return nil
+}
+
+func (s *memService) Teardown(ns string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.campaigns, ns)
+
+	return nil
+}
+
+type bySendAt List
+
+func (l bySendAt) Len() int {
+	return len(l)
+}
+
+func (l bySendAt) Less(i, j int) bool {
+	return l[i].SendAt.Before(l[j].SendAt)
+}
+
+func (l bySendAt) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+func copy(c *Campaign) *Campaign {
+	old := *c
+	return &old
+}
+
+func inIDs(id uint64, ids []uint64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inStates(state State, states []State) bool {
+	if len(states) == 0 {
+		return true
+	}
+
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,15 @@
+package campaign
+
+import "testing"
+
+func TestMemPut(t *testing.T) {
+	testServicePut(t, prepareMem)
+}
+
+func TestMemQuery(t *testing.T) {
+	testServiceQuery(t, prepareMem)
+}
+
+func prepareMem(t *testing.T, namespace string) Service {
+	return MemService()
+}
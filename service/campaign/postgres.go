@@ -0,0 +1,459 @@
+package campaign
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tapglue/snaas/platform/flake"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+const (
+	pgInsertCampaign = `INSERT INTO
+		%s.campaigns(app_id, audience, id, name, progress, push, send_at, state, templates, urn, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	pgUpdateCampaign = `
+		UPDATE
+			%s.campaigns
+		SET
+			audience = $2,
+			name = $3,
+			progress = $4,
+			push = $5,
+			send_at = $6,
+			state = $7,
+			templates = $8,
+			urn = $9,
+			updated_at = $10
+		WHERE
+			id = $1
+			AND state NOT IN ('canceled', 'done')`
+
+	pgClauseAppIDs = `app_id IN (?)`
+	pgClauseDue    = `send_at <= ?`
+	pgClauseIDs    = `id IN (?)`
+	pgClauseStates = `state IN (?)`
+
+	pgListCampaigns = `
+		SELECT
+			app_id, audience, id, name, progress, push, send_at, state, templates, urn, created_at, updated_at
+		FROM
+			%s.campaigns
+		%s`
+	pgOrderCreatedAt = `ORDER BY created_at DESC`
+	pgOrderSendAt    = `ORDER BY send_at ASC`
+
+	pgIndexApp = `
+		CREATE INDEX
+			%s
+		ON
+			%s.campaigns(app_id, created_at)`
+	pgIndexDue = `
+		CREATE INDEX
+			%s
+		ON
+			%s.campaigns(send_at)
+		WHERE
+			state IN ('scheduled', 'sending')`
+
+	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
+	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.campaigns(
+		app_id BIGINT NOT NULL,
+		audience JSONB NOT NULL,
+		id BIGINT NOT NULL UNIQUE,
+		name TEXT NOT NULL,
+		progress JSONB NOT NULL,
+		push JSONB NOT NULL,
+		send_at TIMESTAMP NOT NULL,
+		state TEXT NOT NULL,
+		templates JSONB NOT NULL,
+		urn TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`
+	pgDropTable = `DROP TABLE IF EXISTS %s.campaigns`
+)
+
+type pgService struct {
+	db *sqlx.DB
+}
+
+// PostgresService returns a Postgres based Service implementation.
+func PostgresService(db *sqlx.DB) Service {
+	return &pgService{
+		db: db,
+	}
+}
+
+func (s *pgService) Put(ns string, c *Campaign) (*Campaign, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.ID == 0 {
+		return s.insert(ns, c)
+	}
+
+	return s.update(ns, c)
+}
+
+func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
+	where, params, err := convertOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := s.listCampaigns(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		cs, err = s.listCampaigns(ns, where, params...)
+	}
+
+	return cs, err
+}
+
+func (s *pgService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateTable, ns),
+		pg.GuardIndex(ns, "campaign_app", pgIndexApp),
+		pg.GuardIndex(ns, "campaign_due", pgIndexDue),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) Teardown(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgDropTable, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("teardown '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) insert(ns string, c *Campaign) (*Campaign, error) {
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	sendAt, err := time.Parse(pg.TimeFormat, c.SendAt.UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	c.ID = id
+	c.SendAt = sendAt
+	c.CreatedAt = ts
+	c.UpdatedAt = ts
+
+	audience, progress, push, templates, err := marshalCampaign(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		params = []interface{}{
+			c.AppID,
+			audience,
+			c.ID,
+			c.Name,
+			progress,
+			push,
+			c.SendAt,
+			string(c.State),
+			templates,
+			c.URN,
+			c.CreatedAt,
+			c.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgInsertCampaign, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *pgService) update(ns string, c *Campaign) (*Campaign, error) {
+	now, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	sendAt, err := time.Parse(pg.TimeFormat, c.SendAt.UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	c.SendAt = sendAt
+	c.UpdatedAt = now
+
+	audience, progress, push, templates, err := marshalCampaign(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		params = []interface{}{
+			c.ID,
+			audience,
+			c.Name,
+			progress,
+			push,
+			c.SendAt,
+			string(c.State),
+			templates,
+			c.URN,
+			c.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgUpdateCampaign, ns)
+	)
+
+	res, err := s.db.Exec(query, params...)
+	if err != nil {
+		if pg.IsRelationNotFound(pg.WrapError(err)) {
+			return nil, wrapError(ErrNotFound, "%d", c.ID)
+		}
+
+		return nil, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if n > 0 {
+		return c, nil
+	}
+
+	// Nothing was updated, either the campaign is missing or already final.
+	cs, err := s.Query(ns, QueryOptions{
+		IDs: []uint64{
+			c.ID,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cs) == 0 {
+		return nil, wrapError(ErrNotFound, "%d", c.ID)
+	}
+
+	return nil, wrapError(ErrFinal, "%d is %s", c.ID, cs[0].State)
+}
+
+func (s *pgService) listCampaigns(
+	ns, where string,
+	params ...interface{},
+) (List, error) {
+	query := fmt.Sprintf(pgListCampaigns, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cs := List{}
+
+	for rows.Next() {
+		var (
+			c = &Campaign{}
+
+			audience  = []byte{}
+			progress  = []byte{}
+			push      = []byte{}
+			state     string
+			templates = []byte{}
+		)
+
+		err := rows.Scan(
+			&c.AppID,
+			&audience,
+			&c.ID,
+			&c.Name,
+			&progress,
+			&push,
+			&c.SendAt,
+			&state,
+			&templates,
+			&c.URN,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(audience, &c.Audience); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(progress, &c.Progress); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(push, &c.Push); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(templates, &c.Templates); err != nil {
+			return nil, err
+		}
+
+		c.State = State(state)
+		c.SendAt = c.SendAt.UTC()
+		c.CreatedAt = c.CreatedAt.UTC()
+		c.UpdatedAt = c.UpdatedAt.UTC()
+
+		cs = append(cs, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+func convertOpts(opts QueryOptions) (string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+	)
+
+	if len(opts.AppIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.AppIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseAppIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if !opts.Due.IsZero() {
+		clauses = append(clauses, pgClauseDue)
+		params = append(params, opts.Due.UTC().Format(pg.TimeFormat))
+	}
+
+	if len(opts.IDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.IDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.States) > 0 {
+		ps := []interface{}{}
+
+		for _, s := range opts.States {
+			ps = append(ps, string(s))
+		}
+
+		clause, _, err := sqlx.In(pgClauseStates, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	order := pgOrderCreatedAt
+
+	if !opts.Due.IsZero() {
+		order = pgOrderSendAt
+	}
+
+	where = fmt.Sprintf("%s\n%s", where, order)
+
+	if opts.Limit > 0 {
+		where = fmt.Sprintf("%s\nLIMIT %d", where, opts.Limit)
+	}
+
+	return where, params, nil
+}
+
+func marshalCampaign(c *Campaign) (audience, progress, push, templates []byte, err error) {
+	audience, err = json.Marshal(c.Audience)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	progress, err = json.Marshal(c.Progress)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	push, err = json.Marshal(c.Push)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	templates, err = json.Marshal(c.Templates)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return audience, progress, push, templates, nil
+}
@@ -4,7 +4,6 @@ import (
 	"time"
 
 	"github.com/tapglue/snaas/platform/service"
-	"github.com/tapglue/snaas/platform/source"
 )
 
 // Supported states for connections.
@@ -31,11 +30,6 @@ type Connection struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// Consumer observes state changes.
-type Consumer interface {
-	Consume() (*StateChange, error)
-}
-
 // MatchOpts indicates if the Connection matches the given QueryOptions.
 func (c *Connection) MatchOpts(opts *QueryOptions) bool {
 	if opts == nil {
@@ -157,11 +151,6 @@ func (l List) ToIDs() []uint64 {
 	return ids
 }
 
-// Producer creates state change notifications.
-type Producer interface {
-	Propagate(namespace string, old, new *Connection) (string, error)
-}
-
 // QueryOptions are used to narrow down Connection queries.
 type QueryOptions struct {
 	After   time.Time `json:"-"`
@@ -200,15 +189,5 @@ type StateChange struct {
 	SentAt    time.Time
 }
 
-// Source encapsulates state change notification operations.
-type Source interface {
-	source.Acker
-	Consumer
-	Producer
-}
-
-// SourceMiddleware is a chainable behaviour modifier for Source.
-type SourceMiddleware func(Source) Source
-
 // Type of a user relation.
 type Type string
@@ -8,6 +8,7 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/source"
 )
 
 const (
@@ -118,7 +119,8 @@ const (
 type ordering int
 
 type pgService struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	outbox *source.Outbox
 }
 
 // PostgresService returns a Postgres based Service implementation.
@@ -126,6 +128,16 @@ func PostgresService(db *sqlx.DB) Service {
 	return &pgService{db: db}
 }
 
+// PostgresOutboxService returns a Postgres based Service implementation which
+// stores the state changes of every Put in the outbox of the Entity as part of
+// the same transaction.
+func PostgresOutboxService(db *sqlx.DB) Service {
+	return &pgService{
+		db:     db,
+		outbox: source.NewOutbox(db, Entity),
+	}
+}
+
 func (s *pgService) Count(ns string, opts QueryOptions) (int, error) {
 	where, params, err := convertOpts(opts, orderNone)
 	if err != nil {
@@ -196,6 +208,7 @@ func (s *pgService) Put(ns string, con *Connection) (*Connection, error) {
 		now    = time.Now().UTC()
 		params = []interface{}{con.FromID, con.ToID, string(con.Type)}
 
+		old   *Connection
 		query string
 	)
 
@@ -217,7 +230,8 @@ func (s *pgService) Put(ns string, con *Connection) (*Connection, error) {
 	if len(cs) > 0 {
 		query = wrapNamespace(pgUpdateConnection, ns)
 
-		con.CreatedAt = cs[0].CreatedAt
+		old = cs[0]
+		con.CreatedAt = old.CreatedAt
 		con.UpdatedAt = now
 	} else {
 		params = []interface{}{}
@@ -240,7 +254,7 @@ func (s *pgService) Put(ns string, con *Connection) (*Connection, error) {
 		return nil, err
 	}
 
-	_, err = s.db.Exec(query, append(params, data)...)
+	err = s.exec(ns, old, con, query, append(params, data)...)
 	if err != nil {
 		return nil, err
 	}
@@ -276,12 +290,34 @@ func (s *pgService) Setup(ns string) error {
 		}
 	}
 
+	if s.outbox != nil {
+		return s.outbox.Setup(ns)
+	}
+
 	return nil
 }
 
 func (s *pgService) Teardown(ns string) error {
 	_, err := s.db.Exec(wrapNamespace(pgDropTable, ns))
-	return err
+	if err != nil || s.outbox == nil {
+		return err
+	}
+
+	return s.outbox.Teardown(ns)
+}
+
+func (s *pgService) exec(
+	ns string,
+	old, new *Connection,
+	query string,
+	params ...interface{},
+) error {
+	if s.outbox == nil {
+		_, err := s.db.Exec(query, params...)
+		return err
+	}
+
+	return s.outbox.Exec(ns, old, new, query, params...)
 }
 
 func (s *pgService) countConnections(
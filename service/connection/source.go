@@ -0,0 +1,26 @@
+package connection
+
+import "github.com/tapglue/snaas/platform/source"
+
+// Entity declares Connection state changes to the source framework.
+var Entity = source.Entity{
+	Name:    serviceName,
+	Outbox:  "connection_outbox",
+	Payload: func() interface{} { return &Connection{} },
+	Queue:   "connection-state-change",
+}
+
+// ChangeFrom returns the Connection view on a decoded state change.
+func ChangeFrom(c *source.Change) *StateChange {
+	new, _ := c.New.(*Connection)
+	old, _ := c.Old.(*Connection)
+
+	return &StateChange{
+		AckID:     c.AckID,
+		ID:        c.ID,
+		Namespace: c.Namespace,
+		New:       new,
+		Old:       old,
+		SentAt:    c.SentAt,
+	}
+}
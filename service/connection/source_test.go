@@ -0,0 +1,53 @@
+package connection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tapglue/snaas/platform/source"
+)
+
+func TestEntity(t *testing.T) {
+	var (
+		namespace = "source_entity"
+		src       = source.New(
+			source.NewMemQueue(time.Minute, 10*time.Millisecond),
+			Entity.Codec(),
+		)
+		new = &Connection{
+			FromID: 1,
+			State:  StateConfirmed,
+			ToID:   2,
+			Type:   TypeFollow,
+		}
+	)
+
+	id, err := src.Propagate(namespace, nil, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := src.Consume()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	change := ChangeFrom(c)
+
+	if have, want := change.ID, id; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := change.Namespace, namespace; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if change.Old != nil {
+		t.Errorf("have %v, want %v", change.Old, nil)
+	}
+	if have, want := change.New.ToID, new.ToID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := src.Ack(change.AckID); err != nil {
+		t.Fatal(err)
+	}
+}
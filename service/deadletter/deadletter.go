@@ -0,0 +1,89 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tapglue/snaas/platform/service"
+)
+
+// Supported types of parked state changes.
+const (
+	TypeConnection Type = "connection"
+	TypeEvent      Type = "event"
+	TypeObject     Type = "object"
+	TypeReaction   Type = "reaction"
+	TypeUser       Type = "user"
+)
+
+// Letter is a state change which could not be processed within the retry
+// budget, parked together with the reason of the last failure.
+type Letter struct {
+	Attempts  int             `json:"attempts"`
+	ID        uint64          `json:"id"`
+	Namespace string          `json:"namespace"`
+	New       json.RawMessage `json:"new"`
+	Old       json.RawMessage `json:"old"`
+	Reason    string          `json:"reason"`
+	Type      Type            `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Validate checks for semantic correctness.
+func (l *Letter) Validate() error {
+	if l.Namespace == "" {
+		return wrapError(ErrInvalidLetter, "missing namespace")
+	}
+
+	switch l.Type {
+	case TypeConnection, TypeEvent, TypeObject, TypeReaction, TypeUser:
+		// valid
+	default:
+		return wrapError(ErrInvalidLetter, "unsupported type '%s'", l.Type)
+	}
+
+	return nil
+}
+
+// List is a Letter collection.
+type List []*Letter
+
+func (ls List) Len() int {
+	return len(ls)
+}
+
+func (ls List) Less(i, j int) bool {
+	return ls[i].CreatedAt.Before(ls[j].CreatedAt)
+}
+
+func (ls List) Swap(i, j int) {
+	ls[i], ls[j] = ls[j], ls[i]
+}
+
+// QueryOptions to narrow-down Letter queries.
+type QueryOptions struct {
+	IDs        []uint64
+	Limit      int
+	Namespaces []string
+	Types      []Type
+}
+
+// Service for Letter interactions.
+type Service interface {
+	service.Lifecycle
+
+	Delete(namespace string, id uint64) error
+	Put(namespace string, letter *Letter) (*Letter, error)
+	Query(namespace string, opts QueryOptions) (List, error)
+}
+
+// ServiceMiddleware is a chainable behaviour modifier for Service.
+type ServiceMiddleware func(Service) Service
+
+// Type of the entity the parked state change belongs to.
+type Type string
+
+func flakeNamespace(ns string) string {
+	return fmt.Sprintf("%s_%s", ns, "dead_letters")
+}
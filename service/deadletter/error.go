@@ -0,0 +1,54 @@
+package deadletter
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// Common errors for Letter service implementations and validations.
+var (
+	ErrInvalidLetter = errors.New("invalid letter")
+	ErrNotFound      = errors.New("letter not found")
+)
+
+// Error wrapper.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsInvalidLetter indicates if err is ErrInvalidLetter.
+func IsInvalidLetter(err error) bool {
+	return unwrapError(err) == ErrInvalidLetter
+}
+
+// IsNotFound indicates if err is ErrNotFound.
+func IsNotFound(err error) bool {
+	return unwrapError(err) == ErrNotFound
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err.Error(),
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
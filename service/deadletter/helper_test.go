@@ -0,0 +1,145 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type prepareFunc func(t *testing.T, namespace string) Service
+
+func testServiceDelete(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_delete"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testLetter(TypeObject))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Delete(namespace, created.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	ls, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ls), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := service.Delete(namespace, created.ID); !IsNotFound(err) {
+		t.Errorf("have %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testServicePut(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_put"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testLetter(TypeConnection))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.ID == 0 {
+		t.Error("expected id to be set")
+	}
+
+	ls, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ls), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	var have, want map[string]interface{}
+
+	if err := json.Unmarshal(ls[0].New, &have); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(created.New, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if ls[0].Old != nil {
+		t.Errorf("have %s, want %v", ls[0].Old, nil)
+	}
+
+	if _, err := service.Put(namespace, created); !IsInvalidLetter(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidLetter)
+	}
+}
+
+func testServiceQuery(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_query"
+		service   = p(t, namespace)
+	)
+
+	for _, ty := range []Type{
+		TypeConnection,
+		TypeEvent,
+		TypeEvent,
+		TypeObject,
+		TypeReaction,
+		TypeReaction,
+		TypeReaction,
+	} {
+		_, err := service.Put(namespace, testLetter(ty))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[*QueryOptions]int{
+		&QueryOptions{}:                                7,
+		&QueryOptions{Limit: 2}:                        2,
+		&QueryOptions{Namespaces: []string{"app_1_1"}}: 7,
+		&QueryOptions{Namespaces: []string{"app_2_2"}}: 0,
+		&QueryOptions{Types: []Type{TypeEvent}}:        2,
+		&QueryOptions{Types: []Type{TypeReaction}}:     3,
+	}
+
+	for opts, want := range cases {
+		ls, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := len(ls); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func testLetter(t Type) *Letter {
+	return &Letter{
+		Attempts:  5,
+		Namespace: "app_1_1",
+		New:       json.RawMessage(`{"id":123,"type":"post"}`),
+		Reason:    "pipeline failed",
+		Type:      t,
+	}
+}
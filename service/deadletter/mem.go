@@ -0,0 +1,155 @@
+package deadletter
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/flake"
+)
+
+type memService struct {
+	sync.Mutex
+
+	letters map[string]map[uint64]*Letter
+}
+
+// MemService returns a memory backed implementation of Service.
+func MemService() Service {
+	return &memService{
+		letters: map[string]map[uint64]*Letter{},
+	}
+}
+
+func (s *memService) Delete(ns string, id uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.letters[ns][id]; !ok {
+		return wrapError(ErrNotFound, "%d", id)
+	}
+
+	delete(s.letters[ns], id)
+
+	return nil
+}
+
+func (s *memService) Put(ns string, l *Letter) (*Letter, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
+	if l.ID != 0 {
+		return nil, wrapError(ErrInvalidLetter, "letters are immutable")
+	}
+
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.letters[ns]; !ok {
+		s.letters[ns] = map[uint64]*Letter{}
+	}
+
+	l.ID = id
+	l.CreatedAt = time.Now().UTC()
+
+	s.letters[ns][l.ID] = copy(l)
+
+	return copy(l), nil
+}
+
+func (s *memService) Query(ns string, opts QueryOptions) (List, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	ls := List{}
+
+	for _, l := range s.letters[ns] {
+		if !inIDs(l.ID, opts.IDs) {
+			continue
+		}
+
+		if !inNamespaces(l.Namespace, opts.Namespaces) {
+			continue
+		}
+
+		if !inTypes(l.Type, opts.Types) {
+			continue
+		}
+
+		ls = append(ls, copy(l))
+	}
+
+	sort.Sort(ls)
+
+	if opts.Limit > 0 && len(ls) > opts.Limit {
+		ls = ls[:opts.Limit]
+	}
+
+	return ls, nil
+}
+
+func (s *memService) Setup(ns string) error {
+	return nil
+}
+
+func (s *memService) Teardown(ns string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.letters, ns)
+
+	return nil
+}
+
+func copy(l *Letter) *Letter {
+	old := *l
+	return &old
+}
+
+func inIDs(id uint64, ids []uint64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inNamespaces(ns string, nss []string) bool {
+	if len(nss) == 0 {
+		return true
+	}
+
+	for _, n := range nss {
+		if n == ns {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inTypes(t Type, ts []Type) bool {
+	if len(ts) == 0 {
+		return true
+	}
+
+	for _, ty := range ts {
+		if ty == t {
+			return true
+		}
+	}
+
+	return false
+}
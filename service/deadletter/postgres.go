@@ -0,0 +1,304 @@
+package deadletter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tapglue/snaas/platform/flake"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+const (
+	pgDeleteLetter = `DELETE FROM %s.dead_letters WHERE id = $1`
+	pgInsertLetter = `INSERT INTO
+		%s.dead_letters(attempts, id, namespace, new, old, reason, type, created_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	pgClauseIDs        = `id IN (?)`
+	pgClauseNamespaces = `namespace IN (?)`
+	pgClauseTypes      = `type IN (?)`
+
+	pgListLetters = `
+		SELECT
+			attempts, id, namespace, new, old, reason, type, created_at
+		FROM
+			%s.dead_letters
+		%s`
+	pgOrderCreatedAt = `ORDER BY created_at ASC`
+
+	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
+	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.dead_letters(
+		attempts INT NOT NULL,
+		id BIGINT NOT NULL UNIQUE,
+		namespace TEXT NOT NULL,
+		new JSONB,
+		old JSONB,
+		reason TEXT NOT NULL,
+		type TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`
+	pgDropTable = `DROP TABLE IF EXISTS %s.dead_letters`
+)
+
+type pgService struct {
+	db *sqlx.DB
+}
+
+// PostgresService returns a Postgres based Service implementation.
+func PostgresService(db *sqlx.DB) Service {
+	return &pgService{
+		db: db,
+	}
+}
+
+func (s *pgService) Delete(ns string, id uint64) error {
+	res, err := s.db.Exec(fmt.Sprintf(pgDeleteLetter, ns), id)
+	if err != nil {
+		if pg.IsRelationNotFound(pg.WrapError(err)) {
+			return wrapError(ErrNotFound, "%d", id)
+		}
+
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return wrapError(ErrNotFound, "%d", id)
+	}
+
+	return nil
+}
+
+func (s *pgService) Put(ns string, l *Letter) (*Letter, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
+	if l.ID != 0 {
+		return nil, wrapError(ErrInvalidLetter, "letters are immutable")
+	}
+
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	l.ID = id
+	l.CreatedAt = ts
+
+	var (
+		params = []interface{}{
+			l.Attempts,
+			l.ID,
+			l.Namespace,
+			nullJSON(l.New),
+			nullJSON(l.Old),
+			l.Reason,
+			string(l.Type),
+			l.CreatedAt,
+		}
+		query = fmt.Sprintf(pgInsertLetter, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
+	where, params, err := convertOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ls, err := s.listLetters(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		ls, err = s.listLetters(ns, where, params...)
+	}
+
+	return ls, err
+}
+
+func (s *pgService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateTable, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) Teardown(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgDropTable, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("teardown '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) listLetters(
+	ns, where string,
+	params ...interface{},
+) (List, error) {
+	query := fmt.Sprintf(pgListLetters, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ls := List{}
+
+	for rows.Next() {
+		var (
+			l = &Letter{}
+
+			new, old []byte
+			t        string
+		)
+
+		err := rows.Scan(
+			&l.Attempts,
+			&l.ID,
+			&l.Namespace,
+			&new,
+			&old,
+			&l.Reason,
+			&t,
+			&l.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		l.New = new
+		l.Old = old
+		l.Type = Type(t)
+		l.CreatedAt = l.CreatedAt.UTC()
+
+		ls = append(ls, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ls, nil
+}
+
+func convertOpts(opts QueryOptions) (string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+	)
+
+	if len(opts.IDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.IDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.Namespaces) > 0 {
+		ps := []interface{}{}
+
+		for _, ns := range opts.Namespaces {
+			ps = append(ps, ns)
+		}
+
+		clause, _, err := sqlx.In(pgClauseNamespaces, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.Types) > 0 {
+		ps := []interface{}{}
+
+		for _, t := range opts.Types {
+			ps = append(ps, string(t))
+		}
+
+		clause, _, err := sqlx.In(pgClauseTypes, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	where = fmt.Sprintf("%s\n%s", where, pgOrderCreatedAt)
+
+	if opts.Limit > 0 {
+		where = fmt.Sprintf("%s\nLIMIT %d", where, opts.Limit)
+	}
+
+	return where, params, nil
+}
+
+func nullJSON(raw []byte) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return raw
+}
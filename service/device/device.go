@@ -6,6 +6,8 @@ import (
 
 	"golang.org/x/text/language"
 
+	"github.com/tapglue/snaas/platform/push"
+	"github.com/tapglue/snaas/platform/quiet"
 	"github.com/tapglue/snaas/platform/service"
 	"github.com/tapglue/snaas/platform/sns"
 )
@@ -20,6 +22,7 @@ const (
 	PlatformIOS        = sns.PlatformAPNS
 	PlatformIOSSandbox = sns.PlatformAPNSSandbox
 	PlatformAndroid    = sns.PlatformGCM
+	PlatformWeb        = sns.PlatformWeb
 )
 
 // Device represents a physical device like mobile phone or tablet of a user.
@@ -31,10 +34,23 @@ type Device struct {
 	ID          uint64
 	Language    string
 	Platform    sns.Platform
+	QuietHours  *quiet.Hours
+	Timezone    string
 	Token       string
 	UserID      uint64
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+
+	// Keys of the browser subscription for PlatformWeb, the subscription
+	// endpoint is the Token of those devices.
+	Keys *Keys
+}
+
+// Keys are used to encrypt Web Push messages for a browser subscription, both
+// base64url encoded as handed out by the browser.
+type Keys struct {
+	Auth   string `json:"auth"`
+	P256DH string `json:"p256dh"`
 }
 
 // Validate returns an error when a semantic check fails.
@@ -51,14 +67,37 @@ func (d *Device) Validate() error {
 		return wrapError(ErrInvalidDevice, "Platform must be set")
 	}
 
-	if d.Platform > PlatformAndroid {
+	if d.Platform > PlatformWeb {
 		return wrapError(ErrInvalidDevice, "Platform '%d' not supported", d.Platform)
 	}
 
+	if d.QuietHours != nil {
+		if err := d.QuietHours.Validate(); err != nil {
+			return wrapError(ErrInvalidDevice, "%s", err)
+		}
+	}
+
+	if _, err := quiet.Location(d.Timezone); err != nil {
+		return wrapError(ErrInvalidDevice, "%s", err)
+	}
+
 	if d.Token == "" {
 		return wrapError(ErrInvalidDevice, "Token must be set")
 	}
 
+	if d.Platform == PlatformWeb {
+		if err := push.ValidWebPushEndpoint(d.Token); err != nil {
+			return wrapError(
+				ErrInvalidDevice,
+				"Token must be the https subscription endpoint of a push service for web",
+			)
+		}
+
+		if d.Keys == nil || d.Keys.Auth == "" || d.Keys.P256DH == "" {
+			return wrapError(ErrInvalidDevice, "Keys must be set for web")
+		}
+	}
+
 	if d.UserID == 0 {
 		return wrapError(ErrInvalidDevice, "UserID must be set")
 	}
@@ -69,16 +108,21 @@ func (d *Device) Validate() error {
 // List is a collection of devices.
 type List []*Device
 
-// QueryOptions is used to narrow-down user queries.
+// QueryOptions is used to narrow-down user queries. Limited queries return
+// devices ordered by user instead of creation, UserIDAfter continues such a
+// page after the given user.
 type QueryOptions struct {
-	Deleted      *bool
-	DeviceIDs    []string
-	Disabled     *bool
-	EndpointARNs []string
-	IDs          []uint64
-	Platforms    []sns.Platform
-	Tokens       []string
-	UserIDs      []uint64
+	Deleted       *bool
+	DeviceIDs     []string
+	Disabled      *bool
+	EndpointARNs  []string
+	IDs           []uint64
+	Limit         int
+	Platforms     []sns.Platform
+	Tokens        []string
+	UpdatedBefore time.Time
+	UserIDAfter   uint64
+	UserIDs       []uint64
 }
 
 // Service for device interactions.
@@ -86,6 +130,7 @@ type Service interface {
 	service.Lifecycle
 
 	Count(namespace string, opts QueryOptions) (uint, error)
+	Delete(namespace string, id uint64) error
 	Put(namespace string, device *Device) (*Device, error)
 	Query(namespace string, opts QueryOptions) (List, error)
 }
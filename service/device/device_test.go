@@ -1,17 +1,51 @@
 package device
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/tapglue/snaas/platform/quiet"
+)
 
 func TestValidate(t *testing.T) {
 	var (
 		d  = testDevice()
 		ds = List{
-			{}, // Missing DeviceID
-			{DeviceID: d.DeviceID},                                                                  // Missing Language
-			{DeviceID: d.DeviceID, Language: DefaultLanguage},                                       // Missing Platform
-			{DeviceID: d.DeviceID, Language: DefaultLanguage, Platform: 4},                          // Unsupported Platform
-			{DeviceID: d.DeviceID, Language: DefaultLanguage, Platform: d.Platform},                 // Missing Token
-			{DeviceID: d.DeviceID, Language: DefaultLanguage, Platform: d.Platform, Token: d.Token}, // Missing UserID
+			{},                     // Missing DeviceID
+			{DeviceID: d.DeviceID}, // Missing Language
+			{DeviceID: d.DeviceID, Language: DefaultLanguage},                                         // Missing Platform
+			{DeviceID: d.DeviceID, Language: DefaultLanguage, Platform: 5},                            // Unsupported Platform
+			{DeviceID: d.DeviceID, Language: DefaultLanguage, Platform: d.Platform, Timezone: "Mars"}, // Unknown Timezone
+			{
+				DeviceID:   d.DeviceID,
+				Language:   DefaultLanguage,
+				Platform:   d.Platform,
+				QuietHours: &quiet.Hours{End: "7am", Start: "22:00"},
+			}, // Invalid QuietHours
+			{DeviceID: d.DeviceID, Language: DefaultLanguage, Platform: d.Platform},                   // Missing Token
+			{DeviceID: d.DeviceID, Language: DefaultLanguage, Platform: d.Platform, Token: d.Token},   // Missing UserID
+			{
+				DeviceID: d.DeviceID,
+				Keys:     &Keys{Auth: "auth", P256DH: "key"},
+				Language: DefaultLanguage,
+				Platform: PlatformWeb,
+				Token:    "http://push.tapglue.test/sub",
+				UserID:   d.UserID,
+			}, // Insecure subscription endpoint
+			{
+				DeviceID: d.DeviceID,
+				Keys:     &Keys{Auth: "auth", P256DH: "key"},
+				Language: DefaultLanguage,
+				Platform: PlatformWeb,
+				Token:    "https://169.254.169.254/latest/meta-data",
+				UserID:   d.UserID,
+			}, // Subscription endpoint of no push service
+			{
+				DeviceID: d.DeviceID,
+				Language: DefaultLanguage,
+				Platform: PlatformWeb,
+				Token:    "https://fcm.googleapis.com/fcm/send/sub",
+				UserID:   d.UserID,
+			}, // Missing Keys
 		}
 	)
 
@@ -10,6 +10,7 @@ const errFmt = "%s: %s"
 // Common errors for Device serive implementations and validations.
 var (
 	ErrInvalidDevice = errors.New("invalid device")
+	ErrNotFound      = errors.New("device not found")
 )
 
 // Error wraps common Device errors.
@@ -27,6 +28,11 @@ func IsInvalidDevice(err error) bool {
 	return unwrapError(err) == ErrInvalidDevice
 }
 
+// IsNotFound indicates if err is ErrNotFound.
+func IsNotFound(err error) bool {
+	return unwrapError(err) == ErrNotFound
+}
+
 func unwrapError(err error) error {
 	switch e := err.(type) {
 	case *Error:
@@ -4,13 +4,48 @@ import (
 	"math/rand"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/tapglue/snaas/platform/generate"
+	"github.com/tapglue/snaas/platform/quiet"
 	"github.com/tapglue/snaas/platform/sns"
 )
 
 type prepareFunc func(t *testing.T, namespace string) Service
 
+func testServiceDelete(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_delete"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testDevice())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Delete(namespace, created.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(list), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := service.Delete(namespace, created.ID); !IsNotFound(err) {
+		t.Errorf("have %v, want %v", err, ErrNotFound)
+	}
+}
+
 func testServicePut(t *testing.T, p prepareFunc) {
 	var (
 		device    = testDevice()
@@ -39,7 +74,16 @@ func testServicePut(t *testing.T, p prepareFunc) {
 		t.Errorf("have %v, want %v", have, want)
 	}
 
-	list[0].Token = generate.RandomString(18)
+	list[0].Keys = &Keys{
+		Auth:   generate.RandomString(16),
+		P256DH: generate.RandomString(65),
+	}
+	list[0].Platform = PlatformWeb
+	list[0].QuietHours = &quiet.Hours{
+		End:   "07:00",
+		Start: "22:00",
+	}
+	list[0].Token = "https://fcm.googleapis.com/fcm/send/" + generate.RandomString(18)
 
 	updated, err := service.Put(namespace, list[0])
 	if err != nil {
@@ -94,8 +138,11 @@ func testServiceQuery(t *testing.T, p prepareFunc) {
 		&QueryOptions{Disabled: &deleted}:                            7,
 		&QueryOptions{EndpointARNs: []string{created.EndpointARN}}:   1,
 		&QueryOptions{IDs: []uint64{created.ID}}:                     1,
+		&QueryOptions{Limit: 5}:                                      5,
 		&QueryOptions{Platforms: []sns.Platform{PlatformIOSSandbox}}: 13,
 		&QueryOptions{Tokens: []string{created.Token}}:               1,
+		&QueryOptions{UpdatedBefore: created.UpdatedAt}:              0,
+		&QueryOptions{UpdatedBefore: time.Now().Add(time.Hour)}:      20,
 		&QueryOptions{UserIDs: []uint64{created.UserID}}:             1,
 	}
 
@@ -109,6 +156,29 @@ func testServiceQuery(t *testing.T, p prepareFunc) {
 			t.Errorf("have %v, want %v", have, want)
 		}
 	}
+
+	page, err := service.Query(namespace, QueryOptions{Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < len(page); i++ {
+		if page[i].UserID < page[i-1].UserID {
+			t.Errorf("expected devices ordered by user")
+		}
+	}
+
+	rest, err := service.Query(namespace, QueryOptions{
+		Limit:       20,
+		UserIDAfter: page[len(page)-1].UserID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(rest), 15; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
 }
 
 func testDevice() *Device {
@@ -51,6 +51,14 @@ func (s *instrumentService) Count(
 	return s.next.Count(ns, opts)
 }
 
+func (s *instrumentService) Delete(ns string, id uint64) (err error) {
+	defer func(begin time.Time) {
+		s.track("Delete", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Delete(ns, id)
+}
+
 func (s *instrumentService) Put(
 	ns string,
 	input *Device,
@@ -44,6 +44,25 @@ func (s *logService) Count(ns string, opts QueryOptions) (count uint, err error)
 	return s.next.Count(ns, opts)
 }
 
+func (s *logService) Delete(ns string, id uint64) (err error) {
+	defer func(begin time.Time) {
+		ps := []interface{}{
+			"device_id", id,
+			"duration_ns", time.Since(begin).Nanoseconds(),
+			"method", "Delete",
+			"namespace", ns,
+		}
+
+		if err != nil {
+			ps = append(ps, "err", err)
+		}
+
+		_ = s.logger.Log(ps...)
+	}(time.Now())
+
+	return s.next.Delete(ns, id)
+}
+
 func (s *logService) Put(ns string, input *Device) (output *Device, err error) {
 	defer func(begin time.Time) {
 		ps := []interface{}{
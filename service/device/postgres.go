@@ -1,6 +1,7 @@
 package device
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -9,12 +10,14 @@ import (
 
 	"github.com/tapglue/snaas/platform/flake"
 	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/quiet"
 )
 
 const (
+	pgDeleteDevice = `DELETE FROM %s.devices WHERE id = $1`
 	pgInsertDevice = `INSERT INTO
-		%s.devices(deleted, device_id, disabled, endpoint_arn, id, language, platform, token, user_id, created_at, updated_at)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+		%s.devices(deleted, device_id, disabled, endpoint_arn, id, keys, language, platform, quiet_hours, timezone, token, user_id, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
 	pgUpdateDevice = `
 		UPDATE
 			%s.devices
@@ -23,11 +26,14 @@ const (
 			device_id = $3,
 			disabled = $4,
 			endpoint_arn = $5,
-			language = $6,
-			platform = $7,
-			token = $8,
-			user_id = $9,
-			updated_at = $10
+			keys = $6,
+			language = $7,
+			platform = $8,
+			quiet_hours = $9,
+			timezone = $10,
+			token = $11,
+			user_id = $12,
+			updated_at = $13
 		WHERE
 			id = $1`
 
@@ -35,21 +41,24 @@ const (
 		%s`
 	pgListDevices = `
 		SELECT
-			deleted, device_id, disabled, endpoint_arn, id, language, platform, token, user_id, created_at, updated_at
+			deleted, device_id, disabled, endpoint_arn, id, keys, language, platform, quiet_hours, timezone, token, user_id, created_at, updated_at
 		FROM
 			%s.devices
 		%s`
 
-	pgClauseDeleted      = `deleted = ?`
-	pgClauseDeviceIDs    = `device_id IN (?)`
-	pgClauseDisabled     = `disabled = ?`
-	pgClauseEndpointARNs = `endpoint_arn IN (?)`
-	pgClauseIDs          = `id IN (?)`
-	pgClausePlatforms    = `platform IN (?)`
-	pgClauseTokens       = `token IN (?)`
-	pgClauseUserIDs      = `user_id IN (?)`
+	pgClauseDeleted       = `deleted = ?`
+	pgClauseDeviceIDs     = `device_id IN (?)`
+	pgClauseDisabled      = `disabled = ?`
+	pgClauseEndpointARNs  = `endpoint_arn IN (?)`
+	pgClauseIDs           = `id IN (?)`
+	pgClausePlatforms     = `platform IN (?)`
+	pgClauseTokens        = `token IN (?)`
+	pgClauseUpdatedBefore = `updated_at < ?`
+	pgClauseUserIDAfter   = `user_id > ?`
+	pgClauseUserIDs       = `user_id IN (?)`
 
 	pgOrderCreatedAt = `ORDER BY created_at DESC`
+	pgOrderUserID    = `ORDER BY user_id ASC`
 
 	pgIndexDeviceIDUserID = `
 		CREATE INDEX
@@ -74,7 +83,23 @@ const (
 		WHERE
 			deleted = false
 			AND disabled = false
-			AND platform IN (1, 2, 3)`
+			AND platform IN (1, 2, 3, 4)`
+
+	pgAddColumnKeys = `
+		ALTER TABLE
+			%s.devices
+		ADD COLUMN IF NOT EXISTS
+			keys JSONB`
+	pgAddColumnQuietHours = `
+		ALTER TABLE
+			%s.devices
+		ADD COLUMN IF NOT EXISTS
+			quiet_hours JSONB`
+	pgAddColumnTimezone = `
+		ALTER TABLE
+			%s.devices
+		ADD COLUMN IF NOT EXISTS
+			timezone TEXT NOT NULL DEFAULT ''`
 
 	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
 	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.devices (
@@ -83,8 +108,11 @@ const (
 		disabled BOOL DEFAULT false,
 		endpoint_arn TEXT,
 		id BIGINT NOT NULL,
+		keys JSONB,
 		language TEXT NOT NULL,
 		platform INT NOT NULL,
+		quiet_hours JSONB,
+		timezone TEXT NOT NULL DEFAULT '',
 		token TEXT NOT NULL,
 		user_id BIGINT NOT NULL,
 		created_at TIMESTAMP NOT NULL,
@@ -112,7 +140,7 @@ func (s *pgService) Count(ns string, opts QueryOptions) (uint, error) {
 
 	count, err := s.countDevices(ns, clauses, params...)
 	if err != nil {
-		if pg.IsRelationNotFound(pg.WrapError(err)) {
+		if isSetupRequired(err) {
 			if err := s.Setup(ns); err != nil {
 				return 0, err
 			}
@@ -124,6 +152,28 @@ func (s *pgService) Count(ns string, opts QueryOptions) (uint, error) {
 	return count, err
 }
 
+func (s *pgService) Delete(ns string, id uint64) error {
+	res, err := s.db.Exec(fmt.Sprintf(pgDeleteDevice, ns), id)
+	if err != nil {
+		if pg.IsRelationNotFound(pg.WrapError(err)) {
+			return wrapError(ErrNotFound, "%d", id)
+		}
+
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return wrapError(ErrNotFound, "%d", id)
+	}
+
+	return nil
+}
+
 func (s *pgService) Put(ns string, d *Device) (*Device, error) {
 	var (
 		params []interface{}
@@ -134,6 +184,16 @@ func (s *pgService) Put(ns string, d *Device) (*Device, error) {
 		return nil, err
 	}
 
+	keys, err := marshalKeys(d.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	hours, err := marshalQuietHours(d.QuietHours)
+	if err != nil {
+		return nil, err
+	}
+
 	if d.ID == 0 {
 		if d.CreatedAt.IsZero() {
 			d.CreatedAt = time.Now().UTC()
@@ -160,8 +220,11 @@ func (s *pgService) Put(ns string, d *Device) (*Device, error) {
 			d.Disabled,
 			d.EndpointARN,
 			d.ID,
+			keys,
 			d.Language,
 			d.Platform,
+			hours,
+			d.Timezone,
 			d.Token,
 			d.UserID,
 			ts,
@@ -182,8 +245,11 @@ func (s *pgService) Put(ns string, d *Device) (*Device, error) {
 			d.DeviceID,
 			d.Disabled,
 			d.EndpointARN,
+			keys,
 			d.Language,
 			d.Platform,
+			hours,
+			d.Timezone,
 			d.Token,
 			d.UserID,
 			d.UpdatedAt,
@@ -191,9 +257,9 @@ func (s *pgService) Put(ns string, d *Device) (*Device, error) {
 		query = fmt.Sprintf(pgUpdateDevice, ns)
 	}
 
-	_, err := s.db.Exec(query, params...)
+	_, err = s.db.Exec(query, params...)
 	if err != nil {
-		if pg.IsRelationNotFound(pg.WrapError(err)) {
+		if isSetupRequired(err) {
 			if err := s.Setup(ns); err != nil {
 				return nil, err
 			}
@@ -211,15 +277,15 @@ func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
 		return nil, err
 	}
 
-	ds, err := s.listDevices(ns, clauses, params...)
+	ds, err := s.listDevices(ns, clauses, orderOpts(opts), params...)
 	if err != nil {
-		if pg.IsRelationNotFound(pg.WrapError(err)) {
+		if isSetupRequired(err) {
 			if err := s.Setup(ns); err != nil {
 				return nil, err
 			}
 		}
 
-		ds, err = s.listDevices(ns, clauses, params...)
+		ds, err = s.listDevices(ns, clauses, orderOpts(opts), params...)
 	}
 
 	return ds, err
@@ -247,6 +313,7 @@ func (s *pgService) countDevices(
 func (s *pgService) listDevices(
 	ns string,
 	clauses []string,
+	order string,
 	params ...interface{},
 ) (List, error) {
 	c := strings.Join(clauses, "\nAND ")
@@ -257,7 +324,7 @@ func (s *pgService) listDevices(
 
 	query := strings.Join([]string{
 		fmt.Sprintf(pgListDevices, ns, c),
-		pgOrderCreatedAt,
+		order,
 	}, "\n")
 
 	query = sqlx.Rebind(sqlx.DOLLAR, query)
@@ -271,7 +338,11 @@ func (s *pgService) listDevices(
 	ds := List{}
 
 	for rows.Next() {
-		d := &Device{}
+		var (
+			d     = &Device{}
+			hours []byte
+			keys  []byte
+		)
 
 		err := rows.Scan(
 			&d.Deleted,
@@ -279,8 +350,11 @@ func (s *pgService) listDevices(
 			&d.Disabled,
 			&d.EndpointARN,
 			&d.ID,
+			&keys,
 			&d.Language,
 			&d.Platform,
+			&hours,
+			&d.Timezone,
 			&d.Token,
 			&d.UserID,
 			&d.CreatedAt,
@@ -290,6 +364,22 @@ func (s *pgService) listDevices(
 			return nil, err
 		}
 
+		if len(keys) > 0 {
+			d.Keys = &Keys{}
+
+			if err := json.Unmarshal(keys, d.Keys); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(hours) > 0 {
+			d.QuietHours = &quiet.Hours{}
+
+			if err := json.Unmarshal(hours, d.QuietHours); err != nil {
+				return nil, err
+			}
+		}
+
 		d.CreatedAt = d.CreatedAt.UTC()
 		d.UpdatedAt = d.UpdatedAt.UTC()
 
@@ -307,6 +397,9 @@ func (s *pgService) Setup(ns string) error {
 	qs := []string{
 		fmt.Sprintf(pgCreateSchema, ns),
 		fmt.Sprintf(pgCreateTable, ns),
+		fmt.Sprintf(pgAddColumnTimezone, ns),
+		fmt.Sprintf(pgAddColumnKeys, ns),
+		fmt.Sprintf(pgAddColumnQuietHours, ns),
 		pg.GuardIndex(ns, "device_device_id_user_id", pgIndexDeviceIDUserID),
 		pg.GuardIndex(ns, "device_endpoint_arn", pgIndexEndpointARN),
 		pg.GuardIndex(ns, "device_id", pgIndexID),
@@ -444,6 +537,16 @@ func convertOpts(opts QueryOptions) ([]string, []interface{}, error) {
 		params = append(params, ps...)
 	}
 
+	if !opts.UpdatedBefore.IsZero() {
+		clauses = append(clauses, pgClauseUpdatedBefore)
+		params = append(params, opts.UpdatedBefore.UTC().Format(pg.TimeFormat))
+	}
+
+	if opts.UserIDAfter > 0 {
+		clauses = append(clauses, pgClauseUserIDAfter)
+		params = append(params, opts.UserIDAfter)
+	}
+
 	if len(opts.UserIDs) > 0 {
 		ps := []interface{}{}
 
@@ -462,3 +565,35 @@ func convertOpts(opts QueryOptions) ([]string, []interface{}, error) {
 
 	return clauses, params, nil
 }
+
+// isSetupRequired indicates if err was caused by a missing table or a table
+// which predates the addition of a column.
+func isSetupRequired(err error) bool {
+	err = pg.WrapError(err)
+
+	return pg.IsRelationNotFound(err) || pg.IsColumnNotFound(err)
+}
+
+func marshalKeys(k *Keys) ([]byte, error) {
+	if k == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(k)
+}
+
+func marshalQuietHours(h *quiet.Hours) ([]byte, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(h)
+}
+
+func orderOpts(opts QueryOptions) string {
+	if opts.Limit > 0 {
+		return fmt.Sprintf("%s\nLIMIT %d", pgOrderUserID, opts.Limit)
+	}
+
+	return pgOrderCreatedAt
+}
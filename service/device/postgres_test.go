@@ -14,6 +14,10 @@ import (
 
 var pgTestURL string
 
+func TestPostgresDelete(t *testing.T) {
+	testServiceDelete(t, preparePostgres)
+}
+
 func TestPostgresPut(t *testing.T) {
 	testServicePut(t, preparePostgres)
 }
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/tapglue/snaas/platform/service"
-	"github.com/tapglue/snaas/platform/source"
 )
 
 // Predefined time periods to use for aggregates.
@@ -32,11 +31,6 @@ const (
 	TypeReaction = "tg_reaction"
 )
 
-// Consumer observes state changes.
-type Consumer interface {
-	Consume() (*StateChange, error)
-}
-
 // Event is the buidling block to express interaction on internal/external
 // objects.
 type Event struct {
@@ -167,11 +161,6 @@ type Object struct {
 	URL          string            `json:"url"`
 }
 
-// Producer creates a state change notification.
-type Producer interface {
-	Propagate(namespace string, old, new *Event) (string, error)
-}
-
 // QueryOptions are used to narrow down Event queries.
 type QueryOptions struct {
 	After               time.Time    `json:"-"`
@@ -205,16 +194,6 @@ type Service interface {
 // ServiceMiddleware is a chainable behaviour modifier for Service.
 type ServiceMiddleware func(Service) Service
 
-// Source encapsulates state change notifications operations.
-type Source interface {
-	source.Acker
-	Consumer
-	Producer
-}
-
-// SourceMiddleware is a chainable behaviour modifier for Source.
-type SourceMiddleware func(Source) Source
-
 // StateChange transports all information necessary to observe state changes.
 type StateChange struct {
 	AckID     string
@@ -119,88 +119,3 @@ func (s *logService) Teardown(ns string) (err error) {
 
 	return s.next.Teardown(ns)
 }
-
-type logSource struct {
-	logger log.Logger
-	next   Source
-}
-
-// LogSourceMiddleware given a Logger wraps the next Source logging capabilities.
-func LogSourceMiddleware(store string, logger log.Logger) SourceMiddleware {
-	return func(next Source) Source {
-		logger = log.With(
-			logger,
-			"source", "event",
-			"store", store,
-		)
-
-		return &logSource{
-			logger: logger,
-			next:   next,
-		}
-	}
-}
-
-func (s *logSource) Ack(id string) (err error) {
-	defer func(begin time.Time) {
-		ps := []interface{}{
-			"ack_id", id,
-			"duration_ns", time.Since(begin).Nanoseconds(),
-			"method", "Ack",
-		}
-
-		if err != nil {
-			ps = append(ps, "err", err)
-		}
-
-		_ = s.logger.Log(ps...)
-	}(time.Now())
-
-	return s.next.Ack(id)
-}
-
-func (s *logSource) Consume() (change *StateChange, err error) {
-	defer func(begin time.Time) {
-		ps := []interface{}{
-			"duration_ns", time.Since(begin).Nanoseconds(),
-			"method", "Consume",
-		}
-
-		if change != nil {
-			ps = append(ps,
-				"namespace", change.Namespace,
-				"event_new", change.New,
-				"event_old", change.Old,
-			)
-		}
-
-		if err != nil {
-			ps = append(ps, "err", err)
-		}
-
-		_ = s.logger.Log(ps...)
-	}(time.Now())
-
-	return s.next.Consume()
-}
-
-func (s *logSource) Propagate(ns string, old, new *Event) (id string, err error) {
-	defer func(begin time.Time) {
-		ps := []interface{}{
-			"duration_ns", time.Since(begin).Nanoseconds(),
-			"id", id,
-			"method", "Propagate",
-			"namespace", ns,
-			"event_new", new,
-			"event_old", old,
-		}
-
-		if err != nil {
-			ps = append(ps, "err", err)
-		}
-
-		_ = s.logger.Log(ps...)
-	}(time.Now())
-
-	return s.next.Propagate(ns, old, new)
-}
@@ -8,6 +8,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/tapglue/snaas/platform/flake"
 	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/source"
 )
 
 const (
@@ -107,7 +108,8 @@ const (
 )
 
 type pgService struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	outbox *source.Outbox
 }
 
 // PostgresService returns a Postgres based Service implementation.
@@ -115,6 +117,16 @@ func PostgresService(db *sqlx.DB) Service {
 	return &pgService{db: db}
 }
 
+// PostgresOutboxService returns a Postgres based Service implementation which
+// stores the state changes of every Put in the outbox of the Entity as part of
+// the same transaction.
+func PostgresOutboxService(db *sqlx.DB) Service {
+	return &pgService{
+		db:     db,
+		outbox: source.NewOutbox(db, Entity),
+	}
+}
+
 func (s *pgService) Count(ns string, opts QueryOptions) (int, error) {
 	where, params, err := convertOpts(opts)
 	if err != nil {
@@ -136,6 +148,8 @@ func (s *pgService) Put(ns string, event *Event) (*Event, error) {
 		return nil, err
 	}
 
+	var old *Event
+
 	if event.ID != 0 {
 		params = []interface{}{
 			event.ID,
@@ -154,7 +168,8 @@ func (s *pgService) Put(ns string, event *Event) (*Event, error) {
 			return nil, ErrNotFound
 		}
 
-		event.CreatedAt = es[0].CreatedAt
+		old = es[0]
+		event.CreatedAt = old.CreatedAt
 	} else {
 		id, err := flake.NextID(flakeNamespace(ns))
 		if err != nil {
@@ -180,13 +195,13 @@ func (s *pgService) Put(ns string, event *Event) (*Event, error) {
 
 	params = append([]interface{}{data}, params...)
 
-	_, err = s.db.Exec(wrapNamespace(query, ns), params...)
+	err = s.exec(ns, old, event, wrapNamespace(query, ns), params...)
 	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
 		if err := s.Setup(ns); err != nil {
 			return nil, err
 		}
 
-		_, err = s.db.Exec(wrapNamespace(query, ns), params...)
+		err = s.exec(ns, old, event, wrapNamespace(query, ns), params...)
 	}
 
 	return event, err
@@ -222,6 +237,10 @@ func (s *pgService) Setup(ns string) error {
 		}
 	}
 
+	if s.outbox != nil {
+		return s.outbox.Setup(ns)
+	}
+
 	return nil
 }
 
@@ -237,9 +256,27 @@ func (s *pgService) Teardown(ns string) error {
 		}
 	}
 
+	if s.outbox != nil {
+		return s.outbox.Teardown(ns)
+	}
+
 	return nil
 }
 
+func (s *pgService) exec(
+	ns string,
+	old, new *Event,
+	query string,
+	params ...interface{},
+) error {
+	if s.outbox == nil {
+		_, err := s.db.Exec(query, params...)
+		return err
+	}
+
+	return s.outbox.Exec(ns, old, new, query, params...)
+}
+
 func (s *pgService) countEvents(
 	ns string, where string,
 	params ...interface{},
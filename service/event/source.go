@@ -0,0 +1,26 @@
+package event
+
+import "github.com/tapglue/snaas/platform/source"
+
+// Entity declares Event state changes to the source framework.
+var Entity = source.Entity{
+	Name:    serviceName,
+	Outbox:  "event_outbox",
+	Payload: func() interface{} { return &Event{} },
+	Queue:   "event-state-change",
+}
+
+// ChangeFrom returns the Event view on a decoded state change.
+func ChangeFrom(c *source.Change) *StateChange {
+	new, _ := c.New.(*Event)
+	old, _ := c.Old.(*Event)
+
+	return &StateChange{
+		AckID:     c.AckID,
+		ID:        c.ID,
+		Namespace: c.Namespace,
+		New:       new,
+		Old:       old,
+		SentAt:    c.SentAt,
+	}
+}
@@ -1,13 +1,15 @@
 package event
 
+import "github.com/tapglue/snaas/platform/source"
+
 type sourcingService struct {
-	producer Producer
+	producer source.Producer
 	service  Service
 }
 
 // SourcingServiceMiddleware propagates state changes for the Service via the
-// given Producer.
-func SourcingServiceMiddleware(producer Producer) ServiceMiddleware {
+// given Producer. A failed propagation is reported as error of the operation.
+func SourcingServiceMiddleware(producer source.Producer) ServiceMiddleware {
 	return func(service Service) Service {
 		return &sourcingService{
 			producer: producer,
@@ -25,7 +27,7 @@ func (s *sourcingService) Put(ns string, input *Event) (new *Event, err error) {
 
 	defer func() {
 		if err == nil {
-			_, _ = s.producer.Propagate(ns, old, new)
+			_, err = s.producer.Propagate(ns, old, new)
 		}
 	}()
 
@@ -0,0 +1,54 @@
+package hold
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// Common errors for Hold service implementations and validations.
+var (
+	ErrInvalidHold = errors.New("invalid hold")
+	ErrNotFound    = errors.New("hold not found")
+)
+
+// Error wrapper.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsInvalidHold indicates if err is ErrInvalidHold.
+func IsInvalidHold(err error) bool {
+	return unwrapError(err) == ErrInvalidHold
+}
+
+// IsNotFound indicates if err is ErrNotFound.
+func IsNotFound(err error) bool {
+	return unwrapError(err) == ErrNotFound
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err.Error(),
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
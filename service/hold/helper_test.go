@@ -0,0 +1,151 @@
+package hold
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type prepareFunc func(t *testing.T, namespace string) Service
+
+func testServiceDelete(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_delete"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testHold(1, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Delete(namespace, created.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	hs, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(hs), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := service.Delete(namespace, created.ID); !IsNotFound(err) {
+		t.Errorf("have %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testServicePut(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_put"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testHold(1, time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.ID == 0 {
+		t.Error("expected id to be set")
+	}
+
+	hs, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(hs), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	var have, want map[string]interface{}
+
+	if err := json.Unmarshal(hs[0].Message, &have); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(created.Message, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := hs[0].ReleaseAt, created.ReleaseAt; !have.Equal(want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if _, err := service.Put(namespace, created); !IsInvalidHold(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidHold)
+	}
+
+	if _, err := service.Put(namespace, &Hold{}); !IsInvalidHold(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidHold)
+	}
+}
+
+func testServiceQuery(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_query"
+		service   = p(t, namespace)
+		now       = time.Now().UTC()
+	)
+
+	for _, h := range []*Hold{
+		testHold(1, now.Add(-2*time.Hour)),
+		testHold(1, now.Add(-time.Hour)),
+		testHold(1, now.Add(time.Hour)),
+		testHold(2, now.Add(-time.Minute)),
+		testHold(2, now.Add(2*time.Hour)),
+	} {
+		_, err := service.Put(namespace, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[*QueryOptions]int{
+		&QueryOptions{}:                                 5,
+		&QueryOptions{AppIDs: []uint64{1}}:              3,
+		&QueryOptions{Due: now}:                         3,
+		&QueryOptions{AppIDs: []uint64{2}, Due: now}:    1,
+		&QueryOptions{Due: now, Limit: 2}:               2,
+		&QueryOptions{Due: now.Add(-3 * time.Hour)}:     0,
+		&QueryOptions{AppIDs: []uint64{3}, Due: now}:    0,
+		&QueryOptions{Due: now.Add(3 * time.Hour)}:      5,
+		&QueryOptions{AppIDs: []uint64{1, 2}, Limit: 4}: 4,
+	}
+
+	for opts, want := range cases {
+		hs, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := len(hs); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func testHold(appID uint64, releaseAt time.Time) *Hold {
+	return &Hold{
+		AppID:     appID,
+		Message:   json.RawMessage(`{"Recipient":123,"URN":"tapglue/posts/1"}`),
+		Recipient: 123,
+		ReleaseAt: releaseAt,
+	}
+}
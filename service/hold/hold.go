@@ -0,0 +1,80 @@
+package hold
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tapglue/snaas/platform/service"
+)
+
+// Hold is a message kept back during the quiet hours of its recipient until it
+// is released at ReleaseAt.
+type Hold struct {
+	AppID     uint64          `json:"app_id"`
+	ID        uint64          `json:"id"`
+	Message   json.RawMessage `json:"message"`
+	Recipient uint64          `json:"recipient"`
+	ReleaseAt time.Time       `json:"release_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Validate checks for semantic correctness.
+func (h *Hold) Validate() error {
+	if h.AppID == 0 {
+		return wrapError(ErrInvalidHold, "missing app id")
+	}
+
+	if len(h.Message) == 0 {
+		return wrapError(ErrInvalidHold, "missing message")
+	}
+
+	if h.Recipient == 0 {
+		return wrapError(ErrInvalidHold, "missing recipient")
+	}
+
+	if h.ReleaseAt.IsZero() {
+		return wrapError(ErrInvalidHold, "missing release time")
+	}
+
+	return nil
+}
+
+// List is a Hold collection.
+type List []*Hold
+
+func (l List) Len() int {
+	return len(l)
+}
+
+func (l List) Less(i, j int) bool {
+	return l[i].ReleaseAt.Before(l[j].ReleaseAt)
+}
+
+func (l List) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// QueryOptions to narrow-down Hold queries.
+type QueryOptions struct {
+	AppIDs []uint64
+	Due    time.Time
+	IDs    []uint64
+	Limit  int
+}
+
+// Service for Hold interactions.
+type Service interface {
+	service.Lifecycle
+
+	Delete(namespace string, id uint64) error
+	Put(namespace string, h *Hold) (*Hold, error)
+	Query(namespace string, opts QueryOptions) (List, error)
+}
+
+// ServiceMiddleware is a chainable behaviour modifier for Service.
+type ServiceMiddleware func(Service) Service
+
+func flakeNamespace(ns string) string {
+	return fmt.Sprintf("%s_%s", ns, "holds")
+}
@@ -0,0 +1,128 @@
+package hold
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/flake"
+)
+
+type memService struct {
+	sync.Mutex
+
+	holds map[string]map[uint64]*Hold
+}
+
+// MemService returns a memory backed implementation of Service.
+func MemService() Service {
+	return &memService{
+		holds: map[string]map[uint64]*Hold{},
+	}
+}
+
+func (s *memService) Delete(ns string, id uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.holds[ns][id]; !ok {
+		return wrapError(ErrNotFound, "%d", id)
+	}
+
+	delete(s.holds[ns], id)
+
+	return nil
+}
+
+func (s *memService) Put(ns string, h *Hold) (*Hold, error) {
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+
+	if h.ID != 0 {
+		return nil, wrapError(ErrInvalidHold, "holds are immutable")
+	}
+
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.holds[ns]; !ok {
+		s.holds[ns] = map[uint64]*Hold{}
+	}
+
+	h.ID = id
+	h.ReleaseAt = h.ReleaseAt.UTC()
+	h.CreatedAt = time.Now().UTC()
+
+	s.holds[ns][h.ID] = copy(h)
+
+	return copy(h), nil
+}
+
+func (s *memService) Query(ns string, opts QueryOptions) (List, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	hs := List{}
+
+	for _, h := range s.holds[ns] {
+		if !inIDs(h.AppID, opts.AppIDs) {
+			continue
+		}
+
+		if !opts.Due.IsZero() && h.ReleaseAt.After(opts.Due) {
+			continue
+		}
+
+		if !inIDs(h.ID, opts.IDs) {
+			continue
+		}
+
+		hs = append(hs, copy(h))
+	}
+
+	sort.Sort(hs)
+
+	if opts.Limit > 0 && len(hs) > opts.Limit {
+		hs = hs[:opts.Limit]
+	}
+
+	return hs, nil
+}
+
+func (s *memService) Setup(ns string) error {
+	return nil
+}
+
+func (s *memService) Teardown(ns string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.holds, ns)
+
+	return nil
+}
+
+func copy(h *Hold) *Hold {
+	old := *h
+	return &old
+}
+
+func inIDs(id uint64, ids []uint64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,286 @@
+package hold
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tapglue/snaas/platform/flake"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+const (
+	pgDeleteHold = `DELETE FROM %s.holds WHERE id = $1`
+	pgInsertHold = `INSERT INTO
+		%s.holds(app_id, id, message, recipient, release_at, created_at)
+		VALUES($1, $2, $3, $4, $5, $6)`
+
+	pgClauseAppIDs = `app_id IN (?)`
+	pgClauseDue    = `release_at <= ?`
+	pgClauseIDs    = `id IN (?)`
+
+	pgListHolds = `
+		SELECT
+			app_id, id, message, recipient, release_at, created_at
+		FROM
+			%s.holds
+		%s`
+	pgOrderReleaseAt = `ORDER BY release_at ASC`
+
+	pgIndexReleaseAt = `CREATE INDEX %s ON %s.holds (release_at)`
+
+	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
+	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.holds(
+		app_id BIGINT NOT NULL,
+		id BIGINT NOT NULL UNIQUE,
+		message JSONB NOT NULL,
+		recipient BIGINT NOT NULL,
+		release_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`
+	pgDropTable = `DROP TABLE IF EXISTS %s.holds`
+)
+
+type pgService struct {
+	db *sqlx.DB
+}
+
+// PostgresService returns a Postgres based Service implementation.
+func PostgresService(db *sqlx.DB) Service {
+	return &pgService{
+		db: db,
+	}
+}
+
+func (s *pgService) Delete(ns string, id uint64) error {
+	res, err := s.db.Exec(fmt.Sprintf(pgDeleteHold, ns), id)
+	if err != nil {
+		if pg.IsRelationNotFound(pg.WrapError(err)) {
+			return wrapError(ErrNotFound, "%d", id)
+		}
+
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return wrapError(ErrNotFound, "%d", id)
+	}
+
+	return nil
+}
+
+func (s *pgService) Put(ns string, h *Hold) (*Hold, error) {
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+
+	if h.ID != 0 {
+		return nil, wrapError(ErrInvalidHold, "holds are immutable")
+	}
+
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	releaseAt, err := time.Parse(pg.TimeFormat, h.ReleaseAt.UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	h.ID = id
+	h.ReleaseAt = releaseAt
+	h.CreatedAt = ts
+
+	var (
+		params = []interface{}{
+			h.AppID,
+			h.ID,
+			[]byte(h.Message),
+			h.Recipient,
+			h.ReleaseAt,
+			h.CreatedAt,
+		}
+		query = fmt.Sprintf(pgInsertHold, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
+	where, params, err := convertOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	hs, err := s.listHolds(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		hs, err = s.listHolds(ns, where, params...)
+	}
+
+	return hs, err
+}
+
+func (s *pgService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateTable, ns),
+		pg.GuardIndex(ns, "hold_release_at", pgIndexReleaseAt),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) Teardown(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgDropTable, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("teardown '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) listHolds(
+	ns, where string,
+	params ...interface{},
+) (List, error) {
+	query := fmt.Sprintf(pgListHolds, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hs := List{}
+
+	for rows.Next() {
+		var (
+			h = &Hold{}
+
+			message []byte
+		)
+
+		err := rows.Scan(
+			&h.AppID,
+			&h.ID,
+			&message,
+			&h.Recipient,
+			&h.ReleaseAt,
+			&h.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		h.Message = message
+		h.ReleaseAt = h.ReleaseAt.UTC()
+		h.CreatedAt = h.CreatedAt.UTC()
+
+		hs = append(hs, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hs, nil
+}
+
+func convertOpts(opts QueryOptions) (string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+	)
+
+	if len(opts.AppIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.AppIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseAppIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if !opts.Due.IsZero() {
+		clauses = append(clauses, pgClauseDue)
+		params = append(params, opts.Due.UTC().Format(pg.TimeFormat))
+	}
+
+	if len(opts.IDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.IDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	where = fmt.Sprintf("%s\n%s", where, pgOrderReleaseAt)
+
+	if opts.Limit > 0 {
+		where = fmt.Sprintf("%s\nLIMIT %d", where, opts.Limit)
+	}
+
+	return where, params, nil
+}
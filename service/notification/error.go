@@ -0,0 +1,54 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// Common errors for Notification service implementations and validations.
+var (
+	ErrInvalidNotification = errors.New("invalid notification")
+	ErrInvalidSettings     = errors.New("invalid settings")
+)
+
+// Error wraps common Notification errors.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsInvalidNotification indicates if err is ErrInvalidNotification.
+func IsInvalidNotification(err error) bool {
+	return unwrapError(err) == ErrInvalidNotification
+}
+
+// IsInvalidSettings indicates if err is ErrInvalidSettings.
+func IsInvalidSettings(err error) bool {
+	return unwrapError(err) == ErrInvalidSettings
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err,
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
@@ -0,0 +1,226 @@
+package notification
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type prepareFunc func(t *testing.T, namespace string) Service
+
+type prepareSettingsFunc func(t *testing.T, namespace string) SettingsService
+
+func testServicePut(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_put"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testNotification(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created.Read = true
+
+	_, err = service.Put(namespace, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ns), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := ns[0].Read, true; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := ns[0].Messages["en"], created.Messages["en"]; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if _, err := service.Put(namespace, &Notification{}); !IsInvalidNotification(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidNotification)
+	}
+}
+
+func testServiceQuery(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_query"
+		service   = p(t, namespace)
+		now       = time.Now().UTC()
+		read      = true
+	)
+
+	for i := 0; i < 4; i++ {
+		n := testNotification(uint64(i%2 + 1))
+		n.CreatedAt = now.Add(-time.Duration(i) * time.Hour)
+		n.Read = i == 0
+
+		_, err := service.Put(namespace, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[*QueryOptions]uint{
+		&QueryOptions{}: 4,
+		&QueryOptions{After: now.Add(-90 * time.Minute)}:      2,
+		&QueryOptions{Before: now.Add(-90 * time.Minute)}:     2,
+		&QueryOptions{Limit: 3}:                               3,
+		&QueryOptions{Read: &read}:                            1,
+		&QueryOptions{UserIDs: []uint64{2}}:                   2,
+		&QueryOptions{Read: &read, UserIDs: []uint64{2}}:      0,
+		&QueryOptions{After: now.Add(-time.Minute), Limit: 1}: 1,
+	}
+
+	for opts, want := range cases {
+		ns, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := uint(len(ns)); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+
+		if opts.Limit > 0 {
+			continue
+		}
+
+		count, err := service.Count(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := count; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	ns, err := service.Query(namespace, QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < len(ns); i++ {
+		if ns[i].CreatedAt.After(ns[i-1].CreatedAt) {
+			t.Errorf("have %v after %v, want descending order", ns[i].CreatedAt, ns[i-1].CreatedAt)
+		}
+	}
+}
+
+func testSettingsServicePut(t *testing.T, p prepareSettingsFunc) {
+	var (
+		namespace = "service_settings_put"
+		service   = p(t, namespace)
+		settings  = testSettings(1)
+	)
+
+	created, err := service.Put(namespace, settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created.MutedUsers = append(created.MutedUsers, 4)
+	created.Rules[7] = true
+
+	updated, err := service.Put(namespace, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := updated.CreatedAt, created.CreatedAt; !have.Equal(want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	ss, err := service.Query(namespace, SettingsQueryOptions{
+		UserIDs: []uint64{
+			settings.UserID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ss), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := ss[0].MutedUsers, []uint64{3, 4}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := ss[0].Rules, map[uint64]bool{7: true}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	_, err = service.Put(namespace, &Settings{})
+	if have, want := IsInvalidSettings(err), true; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func testSettingsServiceQuery(t *testing.T, p prepareSettingsFunc) {
+	var (
+		namespace = "service_settings_query"
+		service   = p(t, namespace)
+	)
+
+	for i := uint64(1); i <= 3; i++ {
+		_, err := service.Put(namespace, testSettings(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[*SettingsQueryOptions]int{
+		&SettingsQueryOptions{}:                          3,
+		&SettingsQueryOptions{UserIDs: []uint64{2}}:      1,
+		&SettingsQueryOptions{UserIDs: []uint64{1, 3}}:   2,
+		&SettingsQueryOptions{UserIDs: []uint64{123456}}: 0,
+	}
+
+	for opts, want := range cases {
+		ss, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := len(ss); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func testNotification(userID uint64) *Notification {
+	return &Notification{
+		Messages: map[string]string{
+			"en": "alice started following you",
+		},
+		URN:    "tapglue/users/1",
+		UserID: userID,
+	}
+}
+
+func testSettings(userID uint64) *Settings {
+	return &Settings{
+		Categories: map[string]bool{
+			"reaction": false,
+		},
+		MutedPosts: []uint64{2},
+		MutedUsers: []uint64{3},
+		Rules:      map[uint64]bool{},
+		UserID:     userID,
+	}
+}
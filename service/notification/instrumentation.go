@@ -0,0 +1,225 @@
+package notification
+
+import (
+	"time"
+
+	kitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/tapglue/snaas/platform/metrics"
+)
+
+const (
+	serviceName         = "notification"
+	serviceNameSettings = "notification_settings"
+)
+
+type instrumentService struct {
+	component string
+	errCount  kitmetrics.Counter
+	opCount   kitmetrics.Counter
+	opLatency *prometheus.HistogramVec
+	next      Service
+	store     string
+}
+
+// InstrumentServiceMiddleware observes key aspects of Service operations and
+// exposes Prometheus metrics.
+func InstrumentServiceMiddleware(
+	component, store string,
+	errCount kitmetrics.Counter,
+	opCount kitmetrics.Counter,
+	opLatency *prometheus.HistogramVec,
+) ServiceMiddleware {
+	return func(next Service) Service {
+		return &instrumentService{
+			component: component,
+			errCount:  errCount,
+			opCount:   opCount,
+			opLatency: opLatency,
+			next:      next,
+			store:     store,
+		}
+	}
+}
+
+func (s *instrumentService) Count(
+	ns string,
+	opts QueryOptions,
+) (count uint, err error) {
+	defer func(begin time.Time) {
+		s.track("Count", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Count(ns, opts)
+}
+
+func (s *instrumentService) Put(
+	ns string,
+	input *Notification,
+) (output *Notification, err error) {
+	defer func(begin time.Time) {
+		s.track("Put", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Put(ns, input)
+}
+
+func (s *instrumentService) Query(
+	ns string,
+	opts QueryOptions,
+) (list List, err error) {
+	defer func(begin time.Time) {
+		s.track("Query", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Query(ns, opts)
+}
+
+func (s *instrumentService) Setup(ns string) (err error) {
+	defer func(begin time.Time) {
+		s.track("Setup", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Setup(ns)
+}
+
+func (s *instrumentService) Teardown(ns string) (err error) {
+	defer func(begin time.Time) {
+		s.track("Teardown", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Teardown(ns)
+}
+
+func (s *instrumentService) track(
+	method, namespace string,
+	begin time.Time,
+	err error,
+) {
+	track(
+		s.component, serviceName, s.store,
+		s.errCount, s.opCount, s.opLatency,
+		method, namespace,
+		begin,
+		err,
+	)
+}
+
+type instrumentSettingsService struct {
+	component string
+	errCount  kitmetrics.Counter
+	opCount   kitmetrics.Counter
+	opLatency *prometheus.HistogramVec
+	next      SettingsService
+	store     string
+}
+
+// InstrumentSettingsServiceMiddleware observes key aspects of SettingsService
+// operations and exposes Prometheus metrics.
+func InstrumentSettingsServiceMiddleware(
+	component, store string,
+	errCount kitmetrics.Counter,
+	opCount kitmetrics.Counter,
+	opLatency *prometheus.HistogramVec,
+) SettingsServiceMiddleware {
+	return func(next SettingsService) SettingsService {
+		return &instrumentSettingsService{
+			component: component,
+			errCount:  errCount,
+			opCount:   opCount,
+			opLatency: opLatency,
+			next:      next,
+			store:     store,
+		}
+	}
+}
+
+func (s *instrumentSettingsService) Put(
+	ns string,
+	input *Settings,
+) (output *Settings, err error) {
+	defer func(begin time.Time) {
+		s.track("Put", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Put(ns, input)
+}
+
+func (s *instrumentSettingsService) Query(
+	ns string,
+	opts SettingsQueryOptions,
+) (list SettingsList, err error) {
+	defer func(begin time.Time) {
+		s.track("Query", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Query(ns, opts)
+}
+
+func (s *instrumentSettingsService) Setup(ns string) (err error) {
+	defer func(begin time.Time) {
+		s.track("Setup", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Setup(ns)
+}
+
+func (s *instrumentSettingsService) Teardown(ns string) (err error) {
+	defer func(begin time.Time) {
+		s.track("Teardown", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Teardown(ns)
+}
+
+func (s *instrumentSettingsService) track(
+	method, namespace string,
+	begin time.Time,
+	err error,
+) {
+	track(
+		s.component, serviceNameSettings, s.store,
+		s.errCount, s.opCount, s.opLatency,
+		method, namespace,
+		begin,
+		err,
+	)
+}
+
+func track(
+	component, service, store string,
+	errCount, opCount kitmetrics.Counter,
+	opLatency *prometheus.HistogramVec,
+	method, namespace string,
+	begin time.Time,
+	err error,
+) {
+	if err != nil {
+		errCount.With(
+			metrics.FieldComponent, component,
+			metrics.FieldMethod, method,
+			metrics.FieldNamespace, namespace,
+			metrics.FieldService, service,
+			metrics.FieldStore, store,
+		).Add(1)
+
+		return
+	}
+
+	opCount.With(
+		metrics.FieldComponent, component,
+		metrics.FieldMethod, method,
+		metrics.FieldNamespace, namespace,
+		metrics.FieldService, service,
+		metrics.FieldStore, store,
+	).Add(1)
+
+	opLatency.With(prometheus.Labels{
+		metrics.FieldComponent: component,
+		metrics.FieldMethod:    method,
+		metrics.FieldNamespace: namespace,
+		metrics.FieldService:   service,
+		metrics.FieldStore:     store,
+	}).Observe(time.Since(begin).Seconds())
+}
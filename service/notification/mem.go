@@ -0,0 +1,246 @@
+package notification
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/flake"
+)
+
+type memService struct {
+	sync.Mutex
+
+	notifications map[string]map[uint64]*Notification
+}
+
+// MemService returns a memory backed implementation of Service.
+func MemService() Service {
+	return &memService{
+		notifications: map[string]map[uint64]*Notification{},
+	}
+}
+
+func (s *memService) Count(ns string, opts QueryOptions) (uint, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return uint(len(filterList(s.notifications[ns], opts))), nil
+}
+
+func (s *memService) Put(ns string, n *Notification) (*Notification, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.notifications[ns]; !ok {
+		s.notifications[ns] = map[uint64]*Notification{}
+	}
+
+	now := time.Now().UTC()
+
+	if n.ID == 0 {
+		id, err := flake.NextID(flakeNamespace(ns))
+		if err != nil {
+			return nil, err
+		}
+
+		n.ID = id
+
+		if n.CreatedAt.IsZero() {
+			n.CreatedAt = now
+		}
+	} else {
+		old, ok := s.notifications[ns][n.ID]
+		if !ok {
+			return nil, wrapError(ErrInvalidNotification, "%d not found", n.ID)
+		}
+
+		n.CreatedAt = old.CreatedAt
+		n.UserID = old.UserID
+	}
+
+	n.UpdatedAt = now
+
+	s.notifications[ns][n.ID] = copyNotification(n)
+
+	return copyNotification(n), nil
+}
+
+func (s *memService) Query(ns string, opts QueryOptions) (List, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	l := filterList(s.notifications[ns], opts)
+
+	sort.Sort(sort.Reverse(l))
+
+	if opts.Limit > 0 && len(l) > opts.Limit {
+		l = l[:opts.Limit]
+	}
+
+	return l, nil
+}
+
+func (s *memService) Setup(ns string) error {
+	return nil
+}
+
+func (s *memService) Teardown(ns string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.notifications, ns)
+
+	return nil
+}
+
+func copyNotification(n *Notification) *Notification {
+	old := *n
+	old.Messages = map[string]string{}
+
+	for lang, msg := range n.Messages {
+		old.Messages[lang] = msg
+	}
+
+	return &old
+}
+
+func filterList(ns map[uint64]*Notification, opts QueryOptions) List {
+	l := List{}
+
+	for _, n := range ns {
+		if !opts.After.IsZero() && !n.CreatedAt.After(opts.After) {
+			continue
+		}
+
+		if !opts.Before.IsZero() && !n.CreatedAt.Before(opts.Before) {
+			continue
+		}
+
+		if !inIDs(n.ID, opts.IDs) {
+			continue
+		}
+
+		if opts.Read != nil && n.Read != *opts.Read {
+			continue
+		}
+
+		if !inIDs(n.UserID, opts.UserIDs) {
+			continue
+		}
+
+		l = append(l, copyNotification(n))
+	}
+
+	return l
+}
+
+func inIDs(id uint64, ids []uint64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+type memSettingsService struct {
+	sync.Mutex
+
+	settings map[string]map[uint64]*Settings
+}
+
+// MemSettingsService returns a memory backed implementation of
+// SettingsService.
+func MemSettingsService() SettingsService {
+	return &memSettingsService{
+		settings: map[string]map[uint64]*Settings{},
+	}
+}
+
+func (s *memSettingsService) Put(ns string, input *Settings) (*Settings, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.settings[ns]; !ok {
+		s.settings[ns] = map[uint64]*Settings{}
+	}
+
+	now := time.Now().UTC()
+
+	if old, ok := s.settings[ns][input.UserID]; ok {
+		input.CreatedAt = old.CreatedAt
+	} else if input.CreatedAt.IsZero() {
+		input.CreatedAt = now
+	}
+
+	input.UpdatedAt = now
+
+	s.settings[ns][input.UserID] = copySettings(input)
+
+	return copySettings(input), nil
+}
+
+func (s *memSettingsService) Query(
+	ns string,
+	opts SettingsQueryOptions,
+) (SettingsList, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	l := SettingsList{}
+
+	for _, settings := range s.settings[ns] {
+		if !inIDs(settings.UserID, opts.UserIDs) {
+			continue
+		}
+
+		l = append(l, copySettings(settings))
+	}
+
+	return l, nil
+}
+
+func (s *memSettingsService) Setup(ns string) error {
+	return nil
+}
+
+func (s *memSettingsService) Teardown(ns string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.settings, ns)
+
+	return nil
+}
+
+func copySettings(s *Settings) *Settings {
+	old := *s
+	old.Categories = map[string]bool{}
+	old.MutedPosts = append([]uint64{}, s.MutedPosts...)
+	old.MutedUsers = append([]uint64{}, s.MutedUsers...)
+	old.Rules = map[uint64]bool{}
+
+	for category, enabled := range s.Categories {
+		old.Categories[category] = enabled
+	}
+
+	for id, enabled := range s.Rules {
+		old.Rules[id] = enabled
+	}
+
+	return &old
+}
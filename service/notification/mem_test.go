@@ -0,0 +1,27 @@
+package notification
+
+import "testing"
+
+func TestMemPut(t *testing.T) {
+	testServicePut(t, prepareMem)
+}
+
+func TestMemQuery(t *testing.T) {
+	testServiceQuery(t, prepareMem)
+}
+
+func TestMemSettingsPut(t *testing.T) {
+	testSettingsServicePut(t, prepareMemSettings)
+}
+
+func TestMemSettingsQuery(t *testing.T) {
+	testSettingsServiceQuery(t, prepareMemSettings)
+}
+
+func prepareMem(t *testing.T, namespace string) Service {
+	return MemService()
+}
+
+func prepareMemSettings(t *testing.T, namespace string) SettingsService {
+	return MemSettingsService()
+}
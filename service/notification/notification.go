@@ -0,0 +1,152 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tapglue/snaas/platform/service"
+)
+
+// List is a Notification collection.
+type List []*Notification
+
+func (l List) Len() int {
+	return len(l)
+}
+
+func (l List) Less(i, j int) bool {
+	return l[i].CreatedAt.Before(l[j].CreatedAt)
+}
+
+func (l List) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// Notification is a persisted message in the inbox of a user.
+type Notification struct {
+	ID        uint64
+	Messages  map[string]string
+	Read      bool
+	URN       string
+	UserID    uint64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Validate checks for semantic correctness.
+func (n *Notification) Validate() error {
+	if len(n.Messages) == 0 {
+		return wrapError(ErrInvalidNotification, "messages missing")
+	}
+
+	if n.UserID == 0 {
+		return wrapError(ErrInvalidNotification, "user id missing")
+	}
+
+	return nil
+}
+
+// QueryOptions to narrow-down Notification queries.
+type QueryOptions struct {
+	After   time.Time
+	Before  time.Time
+	IDs     []uint64
+	Limit   int
+	Read    *bool
+	UserIDs []uint64
+}
+
+// Service for Notification interactions. The last read watermark of a user,
+// before which every Notification counts as read, is kept by user.Service.
+type Service interface {
+	service.Lifecycle
+
+	Count(namespace string, opts QueryOptions) (uint, error)
+	Put(namespace string, n *Notification) (*Notification, error)
+	Query(namespace string, opts QueryOptions) (List, error)
+}
+
+// ServiceMiddleware is a chainable behaviour modifier for Service.
+type ServiceMiddleware func(Service) Service
+
+// Settings are the notification preferences of a user. Categories and Rules
+// only hold explicit toggles, everything not mentioned is enabled.
+type Settings struct {
+	Categories  map[string]bool
+	MutedPosts  []uint64
+	MutedUsers  []uint64
+	PausedUntil time.Time
+	Rules       map[uint64]bool
+	UserID      uint64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Allows reports if a notification for the given category and rule, caused by
+// actorID in the thread of postID, may be delivered at the given time.
+func (s *Settings) Allows(
+	category string,
+	ruleID, postID, actorID uint64,
+	now time.Time,
+) bool {
+	if now.Before(s.PausedUntil) {
+		return false
+	}
+
+	if enabled, ok := s.Categories[category]; ok && !enabled {
+		return false
+	}
+
+	if enabled, ok := s.Rules[ruleID]; ok && !enabled {
+		return false
+	}
+
+	if containsID(s.MutedPosts, postID) || containsID(s.MutedUsers, actorID) {
+		return false
+	}
+
+	return true
+}
+
+// Validate checks for semantic correctness.
+func (s *Settings) Validate() error {
+	if s.UserID == 0 {
+		return wrapError(ErrInvalidSettings, "user id missing")
+	}
+
+	return nil
+}
+
+// SettingsService for Settings interactions.
+type SettingsService interface {
+	service.Lifecycle
+
+	Put(namespace string, s *Settings) (*Settings, error)
+	Query(namespace string, opts SettingsQueryOptions) (SettingsList, error)
+}
+
+// SettingsServiceMiddleware is a chainable behaviour modifier for
+// SettingsService.
+type SettingsServiceMiddleware func(SettingsService) SettingsService
+
+// SettingsList is a Settings collection.
+type SettingsList []*Settings
+
+// SettingsQueryOptions to narrow-down Settings queries.
+type SettingsQueryOptions struct {
+	UserIDs []uint64
+}
+
+func containsID(ids []uint64, id uint64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func flakeNamespace(ns string) string {
+	return fmt.Sprintf("%s_%s", ns, "notifications")
+}
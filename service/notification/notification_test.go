@@ -0,0 +1,54 @@
+package notification
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSettingsAllows(t *testing.T) {
+	var (
+		now = time.Now().UTC()
+		s   = &Settings{
+			Categories: map[string]bool{
+				"connection": true,
+				"reaction":   false,
+			},
+			MutedPosts: []uint64{2},
+			MutedUsers: []uint64{3},
+			Rules: map[uint64]bool{
+				5: false,
+			},
+			UserID: 1,
+		}
+	)
+
+	cases := []struct {
+		category                string
+		ruleID, postID, actorID uint64
+		want                    bool
+	}{
+		{"connection", 4, 0, 9, true},
+		{"event", 4, 7, 9, true},
+		{"reaction", 4, 7, 9, false},
+		{"object", 5, 7, 9, false},
+		{"object", 4, 2, 9, false},
+		{"object", 4, 7, 3, false},
+	}
+
+	for _, c := range cases {
+		have := s.Allows(c.category, c.ruleID, c.postID, c.actorID, now)
+		if have != c.want {
+			t.Errorf("%v: have %v, want %v", c, have, c.want)
+		}
+	}
+
+	s.PausedUntil = now.Add(time.Hour)
+
+	if have, want := s.Allows("event", 4, 7, 9, now), false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := s.Allows("event", 4, 7, 9, now.Add(2*time.Hour)), true; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
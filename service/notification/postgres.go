@@ -0,0 +1,606 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tapglue/snaas/platform/flake"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+const (
+	pgInsertNotification = `INSERT INTO
+		%s.notifications(id, messages, read, urn, user_id, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7)`
+	pgUpdateNotification = `
+		UPDATE
+			%s.notifications
+		SET
+			messages = $2,
+			read = $3,
+			urn = $4,
+			updated_at = $5
+		WHERE
+			id = $1`
+
+	pgClauseAfter   = `created_at > ?`
+	pgClauseBefore  = `created_at < ?`
+	pgClauseIDs     = `id IN (?)`
+	pgClauseRead    = `read = ?`
+	pgClauseUserIDs = `user_id IN (?)`
+
+	pgCountNotifications = `SELECT count(*) FROM %s.notifications
+		%s`
+	pgListNotifications = `
+		SELECT
+			id, messages, read, urn, user_id, created_at, updated_at
+		FROM
+			%s.notifications
+		%s`
+	pgOrderCreatedAt = `ORDER BY created_at DESC`
+
+	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
+	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.notifications(
+		id BIGINT NOT NULL UNIQUE,
+		messages JSONB NOT NULL,
+		read BOOL DEFAULT false,
+		urn TEXT NOT NULL,
+		user_id BIGINT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`
+	pgDropTable = `DROP TABLE IF EXISTS %s.notifications`
+
+	pgIndexUserCreatedAt = `
+		CREATE INDEX
+			%s
+		ON
+			%s.notifications(user_id, created_at)`
+)
+
+type pgService struct {
+	db *sqlx.DB
+}
+
+// PostgresService returns a Postgres based Service implementation.
+func PostgresService(db *sqlx.DB) Service {
+	return &pgService{
+		db: db,
+	}
+}
+
+func (s *pgService) Count(ns string, opts QueryOptions) (uint, error) {
+	where, params, err := convertOpts(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.countNotifications(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return 0, err
+		}
+
+		count, err = s.countNotifications(ns, where, params...)
+	}
+
+	return count, err
+}
+
+func (s *pgService) Put(ns string, n *Notification) (*Notification, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+
+	if n.ID == 0 {
+		return s.insert(ns, n)
+	}
+
+	return s.update(ns, n)
+}
+
+func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
+	where, params, err := convertOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Limit > 0 {
+		where = fmt.Sprintf("%s\n%s\nLIMIT %d", where, pgOrderCreatedAt, opts.Limit)
+	} else {
+		where = fmt.Sprintf("%s\n%s", where, pgOrderCreatedAt)
+	}
+
+	notifications, err := s.listNotifications(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		notifications, err = s.listNotifications(ns, where, params...)
+	}
+
+	return notifications, err
+}
+
+func (s *pgService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateTable, ns),
+		pg.GuardIndex(ns, "notification_user_created_at", pgIndexUserCreatedAt),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) Teardown(ns string) error {
+	_, err := s.db.Exec(fmt.Sprintf(pgDropTable, ns))
+	return err
+}
+
+func (s *pgService) countNotifications(
+	ns, where string,
+	params ...interface{},
+) (uint, error) {
+	var count uint
+
+	err := s.db.Get(&count, fmt.Sprintf(pgCountNotifications, ns, where), params...)
+
+	return count, err
+}
+
+func (s *pgService) insert(ns string, n *Notification) (*Notification, error) {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+
+	ts, err := time.Parse(pg.TimeFormat, n.CreatedAt.UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	n.CreatedAt = ts
+	n.UpdatedAt = ts
+
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	n.ID = id
+
+	messages, err := json.Marshal(n.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		params = []interface{}{
+			n.ID,
+			messages,
+			n.Read,
+			n.URN,
+			n.UserID,
+			n.CreatedAt,
+			n.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgInsertNotification, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (s *pgService) listNotifications(
+	ns, where string,
+	params ...interface{},
+) (List, error) {
+	query := fmt.Sprintf(pgListNotifications, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := List{}
+
+	for rows.Next() {
+		var (
+			n = &Notification{}
+
+			messages []byte
+		)
+
+		err := rows.Scan(
+			&n.ID,
+			&messages,
+			&n.Read,
+			&n.URN,
+			&n.UserID,
+			&n.CreatedAt,
+			&n.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(messages, &n.Messages); err != nil {
+			return nil, err
+		}
+
+		n.CreatedAt = n.CreatedAt.UTC()
+		n.UpdatedAt = n.UpdatedAt.UTC()
+
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (s *pgService) update(ns string, n *Notification) (*Notification, error) {
+	now, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	n.UpdatedAt = now
+
+	messages, err := json.Marshal(n.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		params = []interface{}{
+			n.ID,
+			messages,
+			n.Read,
+			n.URN,
+			n.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgUpdateNotification, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func convertOpts(opts QueryOptions) (string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+	)
+
+	if !opts.After.IsZero() {
+		clauses = append(clauses, pgClauseAfter)
+		params = append(params, opts.After.UTC().Format(pg.TimeFormat))
+	}
+
+	if !opts.Before.IsZero() {
+		clauses = append(clauses, pgClauseBefore)
+		params = append(params, opts.Before.UTC().Format(pg.TimeFormat))
+	}
+
+	if len(opts.IDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.IDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if opts.Read != nil {
+		clause, _, err := sqlx.In(pgClauseRead, []interface{}{*opts.Read})
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, *opts.Read)
+	}
+
+	if len(opts.UserIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.UserIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseUserIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	return where, params, nil
+}
+
+const (
+	pgPutSettings = `
+		INSERT INTO %s.notification_settings(user_id, settings, created_at, updated_at)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO
+		UPDATE SET
+			settings = $2,
+			updated_at = $4`
+
+	pgListSettings = `
+		SELECT
+			user_id, settings, created_at, updated_at
+		FROM
+			%s.notification_settings
+		%s`
+
+	pgCreateTableSettings = `CREATE TABLE IF NOT EXISTS %s.notification_settings(
+		user_id BIGINT NOT NULL PRIMARY KEY,
+		settings JSONB NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`
+	pgDropTableSettings = `DROP TABLE IF EXISTS %s.notification_settings`
+)
+
+// settingsFields is the stored representation of Settings.
+type settingsFields struct {
+	Categories  map[string]bool `json:"categories"`
+	MutedPosts  []uint64        `json:"muted_posts"`
+	MutedUsers  []uint64        `json:"muted_users"`
+	PausedUntil time.Time       `json:"paused_until"`
+	Rules       map[uint64]bool `json:"rules"`
+}
+
+type pgSettingsService struct {
+	db *sqlx.DB
+}
+
+// PostgresSettingsService returns a Postgres based SettingsService
+// implementation.
+func PostgresSettingsService(db *sqlx.DB) SettingsService {
+	return &pgSettingsService{
+		db: db,
+	}
+}
+
+func (s *pgSettingsService) Put(ns string, input *Settings) (*Settings, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	now, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	if input.CreatedAt.IsZero() {
+		input.CreatedAt = now
+	}
+
+	input.UpdatedAt = now
+
+	fields, err := json.Marshal(&settingsFields{
+		Categories:  input.Categories,
+		MutedPosts:  input.MutedPosts,
+		MutedUsers:  input.MutedUsers,
+		PausedUntil: input.PausedUntil.UTC(),
+		Rules:       input.Rules,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		params = []interface{}{
+			input.UserID,
+			fields,
+			input.CreatedAt.UTC(),
+			input.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgPutSettings, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return input, nil
+}
+
+func (s *pgSettingsService) Query(
+	ns string,
+	opts SettingsQueryOptions,
+) (SettingsList, error) {
+	where, params, err := convertSettingsOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ss, err := s.listSettings(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		ss, err = s.listSettings(ns, where, params...)
+	}
+
+	return ss, err
+}
+
+func (s *pgSettingsService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateTableSettings, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgSettingsService) Teardown(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgDropTableSettings, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("teardown '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgSettingsService) listSettings(
+	ns, where string,
+	params ...interface{},
+) (SettingsList, error) {
+	query := fmt.Sprintf(pgListSettings, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ss := SettingsList{}
+
+	for rows.Next() {
+		var (
+			fields = settingsFields{}
+			raw    []byte
+			s      = &Settings{}
+		)
+
+		err := rows.Scan(
+			&s.UserID,
+			&raw,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, err
+		}
+
+		s.Categories = fields.Categories
+		s.MutedPosts = fields.MutedPosts
+		s.MutedUsers = fields.MutedUsers
+		s.PausedUntil = fields.PausedUntil.UTC()
+		s.Rules = fields.Rules
+		s.CreatedAt = s.CreatedAt.UTC()
+		s.UpdatedAt = s.UpdatedAt.UTC()
+
+		ss = append(ss, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ss, nil
+}
+
+func convertSettingsOpts(
+	opts SettingsQueryOptions,
+) (string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+	)
+
+	if len(opts.UserIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.UserIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseUserIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	return where, params, nil
+}
@@ -194,6 +194,10 @@ func filterList(os List, opts QueryOptions) List {
 	rs := List{}
 
 	for _, object := range os {
+		if !opts.After.IsZero() && !object.CreatedAt.UTC().After(opts.After.UTC()) {
+			continue
+		}
+
 		if !opts.Before.IsZero() && object.CreatedAt.UTC().After(opts.Before.UTC()) {
 			continue
 		}
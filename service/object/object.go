@@ -8,7 +8,6 @@ import (
 	"golang.org/x/text/language"
 
 	"github.com/tapglue/snaas/platform/service"
-	"github.com/tapglue/snaas/platform/source"
 )
 
 // Attachment variants available for Objects.
@@ -99,11 +98,6 @@ func URLAttachment(name string, contents Contents) Attachment {
 	}
 }
 
-// Consumer observes state changes.
-type Consumer interface {
-	Consume() (*StateChange, error)
-}
-
 // Contents is the mapping of content to locale.
 type Contents map[string]string
 
@@ -277,11 +271,6 @@ type Private struct {
 	Visible bool  `json:"visible"`
 }
 
-// Producer creates a state change notification.
-type Producer interface {
-	Propagate(namespace string, old, new *Object) (string, error)
-}
-
 // QueryOptions are passed to narrow down query for objects.
 type QueryOptions struct {
 	After        time.Time    `json:"-"`
@@ -328,16 +317,6 @@ type StateChange struct {
 	SentAt    time.Time
 }
 
-// Source encapsulates state change notification operations.
-type Source interface {
-	source.Acker
-	Consumer
-	Producer
-}
-
-// SourceMiddleware is a chainable behaviour modifier for Source.
-type SourceMiddleware func(Source) Source
-
 // State reflects the progress of an object through a review process.
 type State uint8
 
@@ -10,6 +10,7 @@ import (
 
 	"github.com/tapglue/snaas/platform/flake"
 	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/source"
 )
 
 const (
@@ -94,7 +95,8 @@ const (
 type ordering int
 
 type pgService struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	outbox *source.Outbox
 }
 
 // PostgresService returns a Postgres based Service implementation.
@@ -104,6 +106,16 @@ func PostgresService(db *sqlx.DB) Service {
 	}
 }
 
+// PostgresOutboxService returns a Postgres based Service implementation which
+// stores the state changes of every Put in the outbox of the Entity as part of
+// the same transaction.
+func PostgresOutboxService(db *sqlx.DB) Service {
+	return &pgService{
+		db:     db,
+		outbox: source.NewOutbox(db, Entity),
+	}
+}
+
 func (s *pgService) Count(ns string, opts QueryOptions) (int, error) {
 	where, params, err := convertOpts(opts, orderNone)
 	if err != nil {
@@ -179,6 +191,8 @@ func (s *pgService) Put(ns string, object *Object) (*Object, error) {
 		return nil, err
 	}
 
+	var old *Object
+
 	if object.ID != 0 {
 		params = []interface{}{
 			object.ID,
@@ -195,7 +209,8 @@ func (s *pgService) Put(ns string, object *Object) (*Object, error) {
 			return nil, ErrNotFound
 		}
 
-		object.CreatedAt = os[0].CreatedAt
+		old = os[0]
+		object.CreatedAt = old.CreatedAt
 	} else {
 		id, err := flake.NextID(flakeNamespace(ns))
 		if err != nil {
@@ -221,13 +236,13 @@ func (s *pgService) Put(ns string, object *Object) (*Object, error) {
 
 	params = append([]interface{}{data}, params...)
 
-	_, err = s.db.Exec(wrapNamespace(query, ns), params...)
+	err = s.exec(ns, old, object, wrapNamespace(query, ns), params...)
 	if err != nil {
 		if pg.IsRelationNotFound(pg.WrapError(err)) {
 			if err := s.Setup(ns); err != nil {
 				return nil, err
 			}
-			if _, err := s.db.Exec(wrapNamespace(query, ns), params...); err != nil {
+			if err := s.exec(ns, old, object, wrapNamespace(query, ns), params...); err != nil {
 				return nil, err
 			}
 		} else {
@@ -270,6 +285,10 @@ func (s *pgService) Setup(ns string) error {
 		}
 	}
 
+	if s.outbox != nil {
+		return s.outbox.Setup(ns)
+	}
+
 	return nil
 }
 
@@ -285,9 +304,27 @@ func (s *pgService) Teardown(namespace string) error {
 		}
 	}
 
+	if s.outbox != nil {
+		return s.outbox.Teardown(namespace)
+	}
+
 	return nil
 }
 
+func (s *pgService) exec(
+	ns string,
+	old, new *Object,
+	query string,
+	params ...interface{},
+) error {
+	if s.outbox == nil {
+		_, err := s.db.Exec(query, params...)
+		return err
+	}
+
+	return s.outbox.Exec(ns, old, new, query, params...)
+}
+
 func (s *pgService) countObjects(
 	ns, where string,
 	params ...interface{},
@@ -0,0 +1,26 @@
+package object
+
+import "github.com/tapglue/snaas/platform/source"
+
+// Entity declares Object state changes to the source framework.
+var Entity = source.Entity{
+	Name:    serviceName,
+	Outbox:  "object_outbox",
+	Payload: func() interface{} { return &Object{} },
+	Queue:   "object-state-change",
+}
+
+// ChangeFrom returns the Object view on a decoded state change.
+func ChangeFrom(c *source.Change) *StateChange {
+	new, _ := c.New.(*Object)
+	old, _ := c.Old.(*Object)
+
+	return &StateChange{
+		AckID:     c.AckID,
+		ID:        c.ID,
+		Namespace: c.Namespace,
+		New:       new,
+		Old:       old,
+		SentAt:    c.SentAt,
+	}
+}
@@ -40,8 +40,11 @@ func testServicePut(t *testing.T, p prepareFunc) {
 	}
 
 	overwrite := testPlatform()
+	overwrite.ARN = ""
 	overwrite.CreatedAt = list[0].CreatedAt
+	overwrite.Credentials = Credentials{ServiceAccount: `{"project_id":"test"}`}
 	overwrite.ID = list[0].ID
+	overwrite.Provider = ProviderFCM
 
 	updated, err := service.Put(namespace, overwrite)
 	if err != nil {
@@ -14,33 +14,62 @@ const (
 	IOS        = sns.PlatformAPNS
 	IOSSandbox = sns.PlatformAPNSSandbox
 	Android    = sns.PlatformGCM
+	Web        = sns.PlatformWeb
 )
 
+// Provider delivering the pushes of a Platform. Platforms without one predate
+// the direct providers and are served by SNS.
+const (
+	ProviderAPNS    = "apns"
+	ProviderFCM     = "fcm"
+	ProviderSNS     = "sns"
+	ProviderWebPush = "webpush"
+)
+
+// Credentials of the direct providers. APNS authenticates with the .p8
+// PrivateKey identified by KeyID and issued for TeamID, Topic is the bundle id
+// of the app. FCM expects the JSON key of a service account. Web Push signs
+// with the VAPID key pair and gives VAPIDSubject as contact.
+type Credentials struct {
+	KeyID           string `json:"key_id,omitempty"`
+	PrivateKey      string `json:"private_key,omitempty"`
+	ServiceAccount  string `json:"service_account,omitempty"`
+	TeamID          string `json:"team_id,omitempty"`
+	Topic           string `json:"topic,omitempty"`
+	VAPIDPrivateKey string `json:"vapid_private_key,omitempty"`
+	VAPIDPublicKey  string `json:"vapid_public_key,omitempty"`
+	VAPIDSubject    string `json:"vapid_subject,omitempty"`
+}
+
 // Platform represents an ecosystem like Android or iOS for user device management.
 type Platform struct {
-	Active    bool
-	AppID     uint64
-	ARN       string
-	Deleted   bool
-	Ecosystem sns.Platform
-	ID        uint64
-	Name      string
-	Scheme    string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Active      bool
+	AppID       uint64
+	ARN         string
+	Credentials Credentials
+	Deleted     bool
+	Ecosystem   sns.Platform
+	ID          uint64
+	Name        string
+	Provider    string
+	Scheme      string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Direct indicates if pushes are sent to the provider of the ecosystem without
+// going through SNS, devices are then addressed by their token.
+func (p *Platform) Direct() bool {
+	return p.Provider != "" && p.Provider != ProviderSNS
 }
 
 // Validate check for semantic correctness.
 func (p *Platform) Validate() error {
-	if p.ARN == "" {
-		return pErr.Wrap(pErr.ErrInvalidPlatform, "ARN must be set")
-	}
-
 	if p.Ecosystem == 0 {
 		return pErr.Wrap(pErr.ErrInvalidPlatform, "Ecosystem must be set")
 	}
 
-	if p.Ecosystem > Android {
+	if p.Ecosystem > Web {
 		return pErr.Wrap(pErr.ErrInvalidPlatform, "Ecosystem '%d' not supported", p.Ecosystem)
 	}
 
@@ -52,6 +81,53 @@ func (p *Platform) Validate() error {
 		return pErr.Wrap(pErr.ErrInvalidPlatform, "Scheme must be set")
 	}
 
+	if p.Ecosystem == Web && p.Provider != ProviderWebPush {
+		return pErr.Wrap(pErr.ErrInvalidPlatform, "Ecosystem web requires Provider '%s'", ProviderWebPush)
+	}
+
+	switch p.Provider {
+	case "", ProviderSNS:
+		if p.ARN == "" {
+			return pErr.Wrap(pErr.ErrInvalidPlatform, "ARN must be set")
+		}
+	case ProviderAPNS:
+		if p.Ecosystem == Android {
+			return pErr.Wrap(pErr.ErrInvalidPlatform, "Provider '%s' only supports iOS", p.Provider)
+		}
+
+		c := p.Credentials
+
+		if c.KeyID == "" || c.PrivateKey == "" || c.TeamID == "" || c.Topic == "" {
+			return pErr.Wrap(
+				pErr.ErrInvalidPlatform,
+				"Credentials key_id, private_key, team_id and topic must be set",
+			)
+		}
+	case ProviderFCM:
+		if p.Ecosystem != Android {
+			return pErr.Wrap(pErr.ErrInvalidPlatform, "Provider '%s' only supports Android", p.Provider)
+		}
+
+		if p.Credentials.ServiceAccount == "" {
+			return pErr.Wrap(pErr.ErrInvalidPlatform, "Credentials service_account must be set")
+		}
+	case ProviderWebPush:
+		if p.Ecosystem != Web {
+			return pErr.Wrap(pErr.ErrInvalidPlatform, "Provider '%s' only supports web", p.Provider)
+		}
+
+		c := p.Credentials
+
+		if c.VAPIDPrivateKey == "" || c.VAPIDPublicKey == "" || c.VAPIDSubject == "" {
+			return pErr.Wrap(
+				pErr.ErrInvalidPlatform,
+				"Credentials vapid_private_key, vapid_public_key and vapid_subject must be set",
+			)
+		}
+	default:
+		return pErr.Wrap(pErr.ErrInvalidPlatform, "Provider '%s' not supported", p.Provider)
+	}
+
 	return nil
 }
 
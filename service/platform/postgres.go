@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -12,8 +13,8 @@ import (
 
 const (
 	pgInsertPlatform = `INSERT INTO
-		%s.platforms(active, app_id, arn, deleted, ecosystem, id, name, scheme, created_at, updated_at)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+		%s.platforms(active, app_id, arn, credentials, deleted, ecosystem, id, name, provider, scheme, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
 	pgUpdatePlatform = `
 		UPDATE
 			%s.platforms
@@ -21,11 +22,13 @@ const (
 			active = $2,
 			app_id = $3,
 			arn = $4,
-			deleted = $5,
-			ecosystem = $6,
-			name = $7,
-			scheme = $8,
-			updated_at = $9
+			credentials = $5,
+			deleted = $6,
+			ecosystem = $7,
+			name = $8,
+			provider = $9,
+			scheme = $10,
+			updated_at = $11
 		WHERE
 			id = $1`
 
@@ -38,22 +41,35 @@ const (
 
 	pgListPlatforms = `
 		SELECT
-			active, app_id, arn, deleted, ecosystem, id, name, scheme, created_at, updated_at
+			active, app_id, arn, credentials, deleted, ecosystem, id, name, provider, scheme, created_at, updated_at
 		FROM
 			%s.platforms
 		%s`
 
 	pgOrderCreatedAt = `ORDER BY created_at DESC`
 
+	pgAddColumnCredentials = `
+		ALTER TABLE
+			%s.platforms
+		ADD COLUMN IF NOT EXISTS
+			credentials JSONB`
+	pgAddColumnProvider = `
+		ALTER TABLE
+			%s.platforms
+		ADD COLUMN IF NOT EXISTS
+			provider TEXT NOT NULL DEFAULT 'sns'`
+
 	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
 	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.platforms(
 		active BOOL DEFAULT false,
 		app_id BIGINT NOT NULL,
 		arn TEXT NOT NULL,
+		credentials JSONB,
 		deleted BOOL DEFAULT false,
 		ecosystem INT NOT NULL,
 		id BIGINT NOT NULL UNIQUE,
 		name CITEXT NOT NULL UNIQUE,
+		provider TEXT NOT NULL DEFAULT 'sns',
 		scheme TEXT NOT NULL,
 		created_at TIMESTAMP NOT NULL,
 		updated_at TIMESTAMP NOT NULL
@@ -92,7 +108,7 @@ func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
 
 	ps, err := s.listPlatforms(ns, where, params...)
 	if err != nil {
-		if pg.IsRelationNotFound(pg.WrapError(err)) {
+		if isSetupRequired(err) {
 			if err := s.Setup(ns); err != nil {
 				return nil, err
 			}
@@ -108,6 +124,8 @@ func (s *pgService) Setup(ns string) error {
 	qs := []string{
 		fmt.Sprintf(pgCreateSchema, ns),
 		fmt.Sprintf(pgCreateTable, ns),
+		fmt.Sprintf(pgAddColumnCredentials, ns),
+		fmt.Sprintf(pgAddColumnProvider, ns),
 	}
 
 	for _, q := range qs {
@@ -155,15 +173,26 @@ func (s *pgService) insert(ns string, p *Platform) (*Platform, error) {
 
 	p.ID = id
 
+	if p.Provider == "" {
+		p.Provider = ProviderSNS
+	}
+
+	credentials, err := marshalCredentials(p.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
 		params = []interface{}{
 			p.Active,
 			p.AppID,
 			p.ARN,
+			credentials,
 			p.Deleted,
 			p.Ecosystem,
 			p.ID,
 			p.Name,
+			p.Provider,
 			p.Scheme,
 			p.CreatedAt,
 			p.UpdatedAt,
@@ -173,7 +202,7 @@ func (s *pgService) insert(ns string, p *Platform) (*Platform, error) {
 
 	_, err = s.db.Exec(query, params...)
 	if err != nil {
-		if pg.IsRelationNotFound(pg.WrapError(err)) {
+		if isSetupRequired(err) {
 			if err := s.Setup(ns); err != nil {
 				return nil, err
 			}
@@ -200,16 +229,21 @@ func (s *pgService) listPlatforms(
 	ps := List{}
 
 	for rows.Next() {
-		p := &Platform{}
+		var (
+			p           = &Platform{}
+			credentials []byte
+		)
 
 		err := rows.Scan(
 			&p.Active,
 			&p.AppID,
 			&p.ARN,
+			&credentials,
 			&p.Deleted,
 			&p.Ecosystem,
 			&p.ID,
 			&p.Name,
+			&p.Provider,
 			&p.Scheme,
 			&p.CreatedAt,
 			&p.UpdatedAt,
@@ -218,6 +252,12 @@ func (s *pgService) listPlatforms(
 			return nil, err
 		}
 
+		if len(credentials) > 0 {
+			if err := json.Unmarshal(credentials, &p.Credentials); err != nil {
+				return nil, err
+			}
+		}
+
 		p.CreatedAt = p.CreatedAt.UTC()
 		p.UpdatedAt = p.UpdatedAt.UTC()
 
@@ -239,15 +279,26 @@ func (s *pgService) update(ns string, p *Platform) (*Platform, error) {
 
 	p.UpdatedAt = now
 
+	if p.Provider == "" {
+		p.Provider = ProviderSNS
+	}
+
+	credentials, err := marshalCredentials(p.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
 		params = []interface{}{
 			p.ID,
 			p.Active,
 			p.AppID,
 			p.ARN,
+			credentials,
 			p.Deleted,
 			p.Ecosystem,
 			p.Name,
+			p.Provider,
 			p.Scheme,
 			p.UpdatedAt,
 		}
@@ -256,7 +307,7 @@ func (s *pgService) update(ns string, p *Platform) (*Platform, error) {
 
 	_, err = s.db.Exec(query, params...)
 	if err != nil {
-		if pg.IsRelationNotFound(pg.WrapError(err)) {
+		if isSetupRequired(err) {
 			if err := s.Setup(ns); err != nil {
 				return nil, err
 			}
@@ -366,3 +417,19 @@ func convertOpts(opts QueryOptions) (string, []interface{}, error) {
 
 	return where, params, nil
 }
+
+func isSetupRequired(err error) bool {
+	err = pg.WrapError(err)
+
+	return pg.IsRelationNotFound(err) || pg.IsColumnNotFound(err)
+}
+
+// marshalCredentials stores no credentials for SNS platforms, which keep them
+// with the platform application in AWS.
+func marshalCredentials(c Credentials) ([]byte, error) {
+	if c == (Credentials{}) {
+		return nil, nil
+	}
+
+	return json.Marshal(c)
+}
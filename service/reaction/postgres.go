@@ -8,6 +8,7 @@ import (
 
 	"github.com/tapglue/snaas/platform/flake"
 	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/source"
 )
 
 const (
@@ -91,7 +92,8 @@ const (
 )
 
 type pgService struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	outbox *source.Outbox
 }
 
 // PostgresService returns a Postgres based Service inplementation.
@@ -101,6 +103,16 @@ func PostgresService(db *sqlx.DB) Service {
 	}
 }
 
+// PostgresOutboxService returns a Postgres based Service implementation which
+// stores the state changes of every Put in the outbox of the Entity as part of
+// the same transaction.
+func PostgresOutboxService(db *sqlx.DB) Service {
+	return &pgService{
+		db:     db,
+		outbox: source.NewOutbox(db, Entity),
+	}
+}
+
 func (s *pgService) Count(ns string, opts QueryOptions) (uint, error) {
 	where, params, err := convertOpts(opts)
 	if err != nil {
@@ -222,6 +234,10 @@ func (s *pgService) Setup(ns string) error {
 		}
 	}
 
+	if s.outbox != nil {
+		return s.outbox.Setup(ns)
+	}
+
 	return nil
 }
 
@@ -236,6 +252,10 @@ func (s *pgService) Teardown(ns string) error {
 		}
 	}
 
+	if s.outbox != nil {
+		return s.outbox.Teardown(ns)
+	}
+
 	return nil
 }
 
@@ -261,6 +281,20 @@ func (s *pgService) countReactions(
 	return count, err
 }
 
+func (s *pgService) exec(
+	ns string,
+	old, new *Reaction,
+	query string,
+	params ...interface{},
+) error {
+	if s.outbox == nil {
+		_, err := s.db.Exec(query, params...)
+		return err
+	}
+
+	return s.outbox.Exec(ns, old, new, query, params...)
+}
+
 func (s *pgService) insert(ns string, r *Reaction) (*Reaction, error) {
 	if r.CreatedAt.IsZero() {
 		r.CreatedAt = time.Now().UTC()
@@ -294,13 +328,13 @@ func (s *pgService) insert(ns string, r *Reaction) (*Reaction, error) {
 		query = fmt.Sprintf(pgInsertReaction, ns)
 	)
 
-	_, err = s.db.Exec(query, params...)
+	err = s.exec(ns, nil, r, query, params...)
 	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
 		if err := s.Setup(ns); err != nil {
 			return nil, err
 		}
 
-		_, err = s.db.Exec(query, params...)
+		err = s.exec(ns, nil, r, query, params...)
 	}
 
 	return r, err
@@ -365,6 +399,24 @@ func (s *pgService) update(ns string, r *Reaction) (*Reaction, error) {
 		return nil, err
 	}
 
+	var old *Reaction
+
+	// The previous state is only needed to record the state change.
+	if s.outbox != nil {
+		rs, err := s.Query(ns, QueryOptions{
+			IDs: []uint64{
+				r.ID,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rs) == 1 {
+			old = rs[0]
+		}
+	}
+
 	r.UpdatedAt = now
 
 	var (
@@ -376,13 +428,13 @@ func (s *pgService) update(ns string, r *Reaction) (*Reaction, error) {
 		query = fmt.Sprintf(pgUpdateReaction, ns)
 	)
 
-	_, err = s.db.Exec(query, params...)
+	err = s.exec(ns, old, r, query, params...)
 	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
 		if err := s.Setup(ns); err != nil {
 			return nil, err
 		}
 
-		_, err = s.db.Exec(query, params...)
+		err = s.exec(ns, old, r, query, params...)
 	}
 
 	return r, err
@@ -7,7 +7,6 @@ import (
 
 	serr "github.com/tapglue/snaas/error"
 	"github.com/tapglue/snaas/platform/service"
-	"github.com/tapglue/snaas/platform/source"
 )
 
 // Supported Reaction types.
@@ -31,11 +30,6 @@ var TypeToIdentifier = map[Type]string{
 	TypeAngry: "angry",
 }
 
-// Consumer observes state changes.
-type Consumer interface {
-	Consume() (*StateChange, error)
-}
-
 // Counts bundles all Reaction counts by type.
 type Counts struct {
 	Angry uint64
@@ -91,11 +85,6 @@ func (m Map) ToList() List {
 	return rs
 }
 
-// Producer creates a state change notification.
-type Producer interface {
-	Propagate(namespace string, old, new *Reaction) (string, error)
-}
-
 // QueryOptions to narrow-down queries.
 type QueryOptions struct {
 	Before    time.Time `json:"-"`
@@ -176,16 +165,6 @@ type Service interface {
 // ServiceMiddleware is a chainable behaviour modifier for Service.
 type ServiceMiddleware func(Service) Service
 
-// Source encapsulates state change notification operations.
-type Source interface {
-	source.Acker
-	Consumer
-	Producer
-}
-
-// SourceMiddleware is a chainable behaviour modifier for Source.
-type SourceMiddleware func(Source) Source
-
 // StateChange transports all information necessary to observe state changes.
 type StateChange struct {
 	AckID     string
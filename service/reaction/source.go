@@ -0,0 +1,26 @@
+package reaction
+
+import "github.com/tapglue/snaas/platform/source"
+
+// Entity declares Reaction state changes to the source framework.
+var Entity = source.Entity{
+	Name:    serviceName,
+	Outbox:  "reaction_outbox",
+	Payload: func() interface{} { return &Reaction{} },
+	Queue:   "reaction-state-change",
+}
+
+// ChangeFrom returns the Reaction view on a decoded state change.
+func ChangeFrom(c *source.Change) *StateChange {
+	new, _ := c.New.(*Reaction)
+	old, _ := c.Old.(*Reaction)
+
+	return &StateChange{
+		AckID:     c.AckID,
+		ID:        c.ID,
+		Namespace: c.Namespace,
+		New:       new,
+		Old:       old,
+		SentAt:    c.SentAt,
+	}
+}
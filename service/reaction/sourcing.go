@@ -1,11 +1,15 @@
 package reaction
 
+import "github.com/tapglue/snaas/platform/source"
+
 type sourcingService struct {
-	producer Producer
+	producer source.Producer
 	service  Service
 }
 
-func SourcingServiceMiddleware(producer Producer) ServiceMiddleware {
+// SourcingServiceMiddleware propagates state changes for the Service via the
+// given Producer. A failed propagation is reported as error of the operation.
+func SourcingServiceMiddleware(producer source.Producer) ServiceMiddleware {
 	return func(service Service) Service {
 		return &sourcingService{
 			service:  service,
@@ -27,7 +31,7 @@ func (s *sourcingService) Put(ns string, input *Reaction) (new *Reaction, err er
 
 	defer func() {
 		if err == nil {
-			_, _ = s.producer.Propagate(ns, old, new)
+			_, err = s.producer.Propagate(ns, old, new)
 		}
 	}()
 
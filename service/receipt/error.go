@@ -0,0 +1,54 @@
+package receipt
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// Common errors for Receipt service implementations and validations.
+var (
+	ErrInvalidReceipt = errors.New("invalid receipt")
+	ErrNotFound       = errors.New("receipt not found")
+)
+
+// Error wrapper.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsInvalidReceipt indicates if err is ErrInvalidReceipt.
+func IsInvalidReceipt(err error) bool {
+	return unwrapError(err) == ErrInvalidReceipt
+}
+
+// IsNotFound indicates if err is ErrNotFound.
+func IsNotFound(err error) bool {
+	return unwrapError(err) == ErrNotFound
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err.Error(),
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
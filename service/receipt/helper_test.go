@@ -0,0 +1,233 @@
+package receipt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tapglue/snaas/platform/sns"
+)
+
+type prepareFunc func(t *testing.T, namespace string) Service
+
+func testServicePut(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_put"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testReceipt(1, 123, 321, StatusSent))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.ID == 0 {
+		t.Error("expected id to be set")
+	}
+
+	rs, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(rs), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := rs[0], created; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+
+	created.Error = "EndpointDisabled"
+	created.OpenedAt = time.Now()
+	created.Status = StatusFailed
+
+	updated, err := service.Put(namespace, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err = service.Query(namespace, QueryOptions{
+		MessageIDs: []string{
+			created.MessageID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(rs), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := rs[0], updated; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+
+	missing := testReceipt(1, 123, 321, StatusSent)
+	missing.ID = created.ID + 1
+
+	if _, err := service.Put(namespace, missing); !IsNotFound(err) {
+		t.Errorf("have %v, want %v", err, ErrNotFound)
+	}
+
+	if _, err := service.Put(namespace, &Receipt{}); !IsInvalidReceipt(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidReceipt)
+	}
+}
+
+func testServiceCountVariants(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_count_variants"
+		service   = p(t, namespace)
+	)
+
+	for _, r := range []*Receipt{
+		testVariantReceipt(1, 123, 321, "a", true),
+		testVariantReceipt(1, 123, 321, "a", false), // Second device
+		testVariantReceipt(1, 124, 321, "a", false),
+		testVariantReceipt(1, 124, 321, "a", false), // Second device
+		testVariantReceipt(1, 125, 321, "a", false),
+		testVariantReceipt(1, 123, 321, "b", true),
+		testVariantReceipt(1, 123, 321, "b", true), // Second device
+		testVariantReceipt(1, 123, 321, "", true),
+		testVariantReceipt(1, 123, 322, "a", true),
+		testVariantReceipt(2, 123, 321, "b", false),
+	} {
+		_, err := service.Put(namespace, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cs, err := service.CountVariants(namespace, QueryOptions{
+		AppIDs: []uint64{
+			1,
+		},
+		RuleIDs: []uint64{
+			321,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := VariantCounts{
+		{Opened: 1, Sent: 3, VariantID: "a"},
+		{Opened: 1, Sent: 1, VariantID: "b"},
+	}
+
+	if have := cs; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+
+	cs, err = service.CountVariants(namespace, QueryOptions{
+		VariantIDs: []string{
+			"b",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = VariantCounts{
+		{Opened: 1, Sent: 2, VariantID: "b"},
+	}
+
+	if have := cs; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+}
+
+func testServiceQuery(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_query"
+		service   = p(t, namespace)
+	)
+
+	for _, r := range []*Receipt{
+		testReceipt(1, 123, 321, StatusSent),
+		testReceipt(1, 123, 321, StatusFailed),
+		testReceipt(1, 123, 0, StatusSent),
+		testReceipt(1, 124, 321, StatusUnregistered),
+		testReceipt(2, 123, 321, StatusErrored),
+		testVariantReceipt(1, 123, 321, "a", true),
+	} {
+		_, err := service.Put(namespace, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[*QueryOptions]int{
+		&QueryOptions{}:                    6,
+		&QueryOptions{AppIDs: []uint64{1}}: 5,
+		&QueryOptions{AppIDs: []uint64{1}, Recipients: []uint64{123}}:  4,
+		&QueryOptions{AppIDs: []uint64{1}, RuleIDs: []uint64{321}}:     4,
+		&QueryOptions{Recipients: []uint64{124}}:                       1,
+		&QueryOptions{Statuses: []Status{StatusSent}}:                  3,
+		&QueryOptions{VariantIDs: []string{"a", "b"}}:                  1,
+		&QueryOptions{Statuses: []Status{StatusFailed, StatusErrored}}: 2,
+		&QueryOptions{AppIDs: []uint64{3}}:                             0,
+		&QueryOptions{Limit: 3}:                                        3,
+	}
+
+	for opts, want := range cases {
+		rs, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := len(rs); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	rs, err := service.Query(namespace, QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err = service.Query(namespace, QueryOptions{
+		Before: rs[len(rs)-1].CreatedAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(rs), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func testReceipt(appID, recipient, ruleID uint64, status Status) *Receipt {
+	return &Receipt{
+		AppID:     appID,
+		DeviceID:  456,
+		MessageID: "7a0c3d5e-1b2f-4c6d-8e9f-0a1b2c3d4e5f",
+		Platform:  sns.PlatformAPNS,
+		Provider:  "sns",
+		Recipient: recipient,
+		RuleID:    ruleID,
+		Status:    status,
+	}
+}
+
+func testVariantReceipt(
+	appID, recipient, ruleID uint64,
+	variantID string,
+	opened bool,
+) *Receipt {
+	r := testReceipt(appID, recipient, ruleID, StatusSent)
+	r.VariantID = variantID
+
+	if opened {
+		r.OpenedAt = time.Now()
+	}
+
+	return r
+}
@@ -0,0 +1,234 @@
+package receipt
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/flake"
+)
+
+type memService struct {
+	sync.Mutex
+
+	receipts map[string]map[uint64]*Receipt
+}
+
+// MemService returns a memory backed implementation of Service.
+func MemService() Service {
+	return &memService{
+		receipts: map[string]map[uint64]*Receipt{},
+	}
+}
+
+func (s *memService) CountVariants(
+	ns string,
+	opts QueryOptions,
+) (VariantCounts, error) {
+	opts.Limit = 0
+
+	rs, err := s.Query(ns, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	type recipient struct {
+		appID, userID uint64
+		variantID     string
+	}
+
+	var (
+		counts = map[string]*VariantCount{}
+		ids    = []string{}
+		opened = map[recipient]struct{}{}
+		sent   = map[recipient]struct{}{}
+	)
+
+	for _, r := range rs {
+		if r.VariantID == "" {
+			continue
+		}
+
+		c, ok := counts[r.VariantID]
+		if !ok {
+			c = &VariantCount{VariantID: r.VariantID}
+			counts[r.VariantID] = c
+			ids = append(ids, r.VariantID)
+		}
+
+		key := recipient{
+			appID:     r.AppID,
+			userID:    r.Recipient,
+			variantID: r.VariantID,
+		}
+
+		if _, ok := sent[key]; !ok {
+			sent[key] = struct{}{}
+			c.Sent++
+		}
+
+		if _, ok := opened[key]; !ok && !r.OpenedAt.IsZero() {
+			opened[key] = struct{}{}
+			c.Opened++
+		}
+	}
+
+	sort.Strings(ids)
+
+	cs := VariantCounts{}
+
+	for _, id := range ids {
+		cs = append(cs, *counts[id])
+	}
+
+	return cs, nil
+}
+
+func (s *memService) Put(ns string, r *Receipt) (*Receipt, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.receipts[ns]; !ok {
+		s.receipts[ns] = map[uint64]*Receipt{}
+	}
+
+	now := time.Now().UTC()
+
+	if r.ID == 0 {
+		id, err := flake.NextID(flakeNamespace(ns))
+		if err != nil {
+			return nil, err
+		}
+
+		r.ID = id
+		r.CreatedAt = now
+	} else {
+		old, ok := s.receipts[ns][r.ID]
+		if !ok {
+			return nil, wrapError(ErrNotFound, "%d", r.ID)
+		}
+
+		r.CreatedAt = old.CreatedAt
+	}
+
+	r.UpdatedAt = now
+
+	s.receipts[ns][r.ID] = copy(r)
+
+	return copy(r), nil
+}
+
+func (s *memService) Query(ns string, opts QueryOptions) (List, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	rs := List{}
+
+	for _, r := range s.receipts[ns] {
+		if !inIDs(r.AppID, opts.AppIDs) {
+			continue
+		}
+
+		if !opts.Before.IsZero() && !r.CreatedAt.Before(opts.Before) {
+			continue
+		}
+
+		if !inIDs(r.ID, opts.IDs) {
+			continue
+		}
+
+		if !inStrings(r.MessageID, opts.MessageIDs) {
+			continue
+		}
+
+		if !inIDs(r.Recipient, opts.Recipients) {
+			continue
+		}
+
+		if !inIDs(r.RuleID, opts.RuleIDs) {
+			continue
+		}
+
+		if !inStatuses(r.Status, opts.Statuses) {
+			continue
+		}
+
+		if !inStrings(r.VariantID, opts.VariantIDs) {
+			continue
+		}
+
+		rs = append(rs, copy(r))
+	}
+
+	sort.Sort(rs)
+
+	if opts.Limit > 0 && len(rs) > opts.Limit {
+		rs = rs[:opts.Limit]
+	}
+
+	return rs, nil
+}
+
+func (s *memService) Setup(ns string) error {
+	return nil
+}
+
+func (s *memService) Teardown(ns string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.receipts, ns)
+
+	return nil
+}
+
+func copy(r *Receipt) *Receipt {
+	old := *r
+	return &old
+}
+
+func inIDs(id uint64, ids []uint64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inStrings(s string, ss []string) bool {
+	if len(ss) == 0 {
+		return true
+	}
+
+	for _, i := range ss {
+		if i == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inStatuses(status Status, statuses []Status) bool {
+	if len(statuses) == 0 {
+		return true
+	}
+
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,558 @@
+package receipt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tapglue/snaas/platform/flake"
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/sns"
+)
+
+const (
+	pgInsertReceipt = `INSERT INTO
+		%s.receipts(app_id, device_id, error, id, message_id, opened_at, platform, provider, recipient, rule_id, status, variant_id, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	pgUpdateReceipt = `
+		UPDATE
+			%s.receipts
+		SET
+			error = $2,
+			message_id = $3,
+			opened_at = $4,
+			status = $5,
+			updated_at = $6
+		WHERE
+			id = $1`
+
+	pgCountVariants = `
+		SELECT
+			variant_id,
+			count(DISTINCT (app_id, recipient)),
+			count(DISTINCT (app_id, recipient)) FILTER (WHERE opened_at > '0001-01-01 00:00:00')
+		FROM
+			%s.receipts
+		%s
+		GROUP BY
+			variant_id
+		ORDER BY
+			variant_id ASC`
+
+	pgClauseAppIDs     = `app_id IN (?)`
+	pgClauseBefore     = `created_at < ?`
+	pgClauseIDs        = `id IN (?)`
+	pgClauseMessageIDs = `message_id IN (?)`
+	pgClauseRecipients = `recipient IN (?)`
+	pgClauseRuleIDs    = `rule_id IN (?)`
+	pgClauseStatuses   = `status IN (?)`
+	pgClauseVariant    = `variant_id <> ''`
+	pgClauseVariantIDs = `variant_id IN (?)`
+
+	pgListReceipts = `
+		SELECT
+			app_id, device_id, error, id, message_id, opened_at, platform, provider, recipient, rule_id, status, variant_id, created_at, updated_at
+		FROM
+			%s.receipts
+		%s`
+	pgOrderCreatedAt = `ORDER BY created_at DESC`
+
+	pgIndexMessageID = `
+		CREATE INDEX
+			%s
+		ON
+			%s.receipts(message_id)`
+	pgIndexRecipient = `
+		CREATE INDEX
+			%s
+		ON
+			%s.receipts(app_id, recipient, created_at)`
+	pgIndexRule = `
+		CREATE INDEX
+			%s
+		ON
+			%s.receipts(app_id, rule_id, created_at)`
+
+	pgAddColumnOpenedAt = `
+		ALTER TABLE
+			%s.receipts
+		ADD COLUMN IF NOT EXISTS
+			opened_at TIMESTAMP DEFAULT '0001-01-01 00:00:00 UTC' NOT NULL`
+	pgAddColumnVariantID = `
+		ALTER TABLE
+			%s.receipts
+		ADD COLUMN IF NOT EXISTS
+			variant_id TEXT DEFAULT '' NOT NULL`
+
+	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
+	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.receipts(
+		app_id BIGINT NOT NULL,
+		device_id BIGINT NOT NULL,
+		error TEXT NOT NULL,
+		id BIGINT NOT NULL UNIQUE,
+		message_id TEXT NOT NULL,
+		opened_at TIMESTAMP DEFAULT '0001-01-01 00:00:00 UTC' NOT NULL,
+		platform INT NOT NULL,
+		provider TEXT NOT NULL,
+		recipient BIGINT NOT NULL,
+		rule_id BIGINT NOT NULL,
+		status TEXT NOT NULL,
+		variant_id TEXT DEFAULT '' NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`
+	pgDropTable = `DROP TABLE IF EXISTS %s.receipts`
+)
+
+type pgService struct {
+	db *sqlx.DB
+}
+
+// PostgresService returns a Postgres based Service implementation.
+func PostgresService(db *sqlx.DB) Service {
+	return &pgService{
+		db: db,
+	}
+}
+
+func (s *pgService) CountVariants(
+	ns string,
+	opts QueryOptions,
+) (VariantCounts, error) {
+	clauses, params, err := convertClauses(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	clauses = append(clauses, pgClauseVariant)
+
+	where := sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+
+	cs, err := s.countVariants(ns, where, params...)
+	if err != nil && isSetupRequired(err) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		cs, err = s.countVariants(ns, where, params...)
+	}
+
+	return cs, err
+}
+
+func (s *pgService) Put(ns string, r *Receipt) (*Receipt, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	if r.ID == 0 {
+		return s.insert(ns, r)
+	}
+
+	return s.update(ns, r)
+}
+
+func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
+	where, params, err := convertOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := s.listReceipts(ns, where, params...)
+	if err != nil && isSetupRequired(err) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		rs, err = s.listReceipts(ns, where, params...)
+	}
+
+	return rs, err
+}
+
+func (s *pgService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateTable, ns),
+		fmt.Sprintf(pgAddColumnOpenedAt, ns),
+		fmt.Sprintf(pgAddColumnVariantID, ns),
+		pg.GuardIndex(ns, "receipt_message_id", pgIndexMessageID),
+		pg.GuardIndex(ns, "receipt_recipient", pgIndexRecipient),
+		pg.GuardIndex(ns, "receipt_rule", pgIndexRule),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) Teardown(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgDropTable, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("teardown '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) insert(ns string, r *Receipt) (*Receipt, error) {
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	openedAt, err := time.Parse(pg.TimeFormat, r.OpenedAt.UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	r.ID = id
+	r.OpenedAt = openedAt
+	r.CreatedAt = ts
+	r.UpdatedAt = ts
+
+	var (
+		params = []interface{}{
+			r.AppID,
+			r.DeviceID,
+			r.Error,
+			r.ID,
+			r.MessageID,
+			r.OpenedAt,
+			int(r.Platform),
+			r.Provider,
+			r.Recipient,
+			r.RuleID,
+			string(r.Status),
+			r.VariantID,
+			r.CreatedAt,
+			r.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgInsertReceipt, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && isSetupRequired(err) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (s *pgService) update(ns string, r *Receipt) (*Receipt, error) {
+	now, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	openedAt, err := time.Parse(pg.TimeFormat, r.OpenedAt.UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	r.OpenedAt = openedAt
+	r.UpdatedAt = now
+
+	var (
+		params = []interface{}{
+			r.ID,
+			r.Error,
+			r.MessageID,
+			r.OpenedAt,
+			string(r.Status),
+			r.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgUpdateReceipt, ns)
+	)
+
+	res, err := s.db.Exec(query, params...)
+	if err != nil && isSetupRequired(err) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		res, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, wrapError(ErrNotFound, "%d", r.ID)
+	}
+
+	return r, nil
+}
+
+func (s *pgService) countVariants(
+	ns, where string,
+	params ...interface{},
+) (VariantCounts, error) {
+	query := fmt.Sprintf(pgCountVariants, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cs := VariantCounts{}
+
+	for rows.Next() {
+		c := VariantCount{}
+
+		if err := rows.Scan(&c.VariantID, &c.Sent, &c.Opened); err != nil {
+			return nil, err
+		}
+
+		cs = append(cs, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+func (s *pgService) listReceipts(
+	ns, where string,
+	params ...interface{},
+) (List, error) {
+	query := fmt.Sprintf(pgListReceipts, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rs := List{}
+
+	for rows.Next() {
+		var (
+			r = &Receipt{}
+
+			platform int
+			status   string
+		)
+
+		err := rows.Scan(
+			&r.AppID,
+			&r.DeviceID,
+			&r.Error,
+			&r.ID,
+			&r.MessageID,
+			&r.OpenedAt,
+			&platform,
+			&r.Provider,
+			&r.Recipient,
+			&r.RuleID,
+			&status,
+			&r.VariantID,
+			&r.CreatedAt,
+			&r.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Platform = sns.Platform(platform)
+		r.Status = Status(status)
+		r.OpenedAt = r.OpenedAt.UTC()
+		r.CreatedAt = r.CreatedAt.UTC()
+		r.UpdatedAt = r.UpdatedAt.UTC()
+
+		rs = append(rs, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+func convertOpts(opts QueryOptions) (string, []interface{}, error) {
+	clauses, params, err := convertClauses(opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	where = fmt.Sprintf("%s\n%s", where, pgOrderCreatedAt)
+
+	if opts.Limit > 0 {
+		where = fmt.Sprintf("%s\nLIMIT %d", where, opts.Limit)
+	}
+
+	return where, params, nil
+}
+
+func convertClauses(opts QueryOptions) ([]string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+	)
+
+	if len(opts.AppIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.AppIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseAppIDs, ps)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if !opts.Before.IsZero() {
+		clauses = append(clauses, pgClauseBefore)
+		params = append(params, opts.Before.UTC().Format(pg.TimeFormat))
+	}
+
+	if len(opts.IDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.IDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseIDs, ps)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.MessageIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.MessageIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseMessageIDs, ps)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.Recipients) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.Recipients {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseRecipients, ps)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.RuleIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.RuleIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseRuleIDs, ps)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.Statuses) > 0 {
+		ps := []interface{}{}
+
+		for _, s := range opts.Statuses {
+			ps = append(ps, string(s))
+		}
+
+		clause, _, err := sqlx.In(pgClauseStatuses, ps)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.VariantIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.VariantIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseVariantIDs, ps)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	return clauses, params, nil
+}
+
+// isSetupRequired indicates if err was caused by a missing table or a table
+// which predates the addition of a column.
+func isSetupRequired(err error) bool {
+	err = pg.WrapError(err)
+
+	return pg.IsRelationNotFound(err) || pg.IsColumnNotFound(err)
+}
@@ -0,0 +1,142 @@
+package receipt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tapglue/snaas/platform/service"
+	"github.com/tapglue/snaas/platform/sns"
+)
+
+// Status of a delivery attempt.
+type Status string
+
+// Statuses a Receipt can be in. Errored attempts failed for reasons which
+// might go away, the message is retried and a new Receipt recorded.
+const (
+	StatusErrored      Status = "errored"
+	StatusFailed       Status = "failed"
+	StatusSent         Status = "sent"
+	StatusUnregistered Status = "unregistered"
+)
+
+var statuses = map[Status]struct{}{
+	StatusErrored:      {},
+	StatusFailed:       {},
+	StatusSent:         {},
+	StatusUnregistered: {},
+}
+
+// Receipt records a single push delivery attempt to a device of the recipient.
+// MessageID is the identifier the provider handed out for accepted messages,
+// it is used to correlate failures reported later on. VariantID names the
+// template variant delivered, OpenedAt is set once the recipient opened it.
+type Receipt struct {
+	AppID     uint64
+	DeviceID  uint64
+	Error     string
+	ID        uint64
+	MessageID string
+	OpenedAt  time.Time
+	Platform  sns.Platform
+	Provider  string
+	Recipient uint64
+	RuleID    uint64
+	Status    Status
+	VariantID string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Validate checks for semantic correctness.
+func (r *Receipt) Validate() error {
+	if r.AppID == 0 {
+		return wrapError(ErrInvalidReceipt, "missing app id")
+	}
+
+	if r.DeviceID == 0 {
+		return wrapError(ErrInvalidReceipt, "missing device id")
+	}
+
+	if r.Provider == "" {
+		return wrapError(ErrInvalidReceipt, "missing provider")
+	}
+
+	if r.Recipient == 0 {
+		return wrapError(ErrInvalidReceipt, "missing recipient")
+	}
+
+	if _, ok := statuses[r.Status]; !ok {
+		return wrapError(ErrInvalidReceipt, "unsupported status '%s'", r.Status)
+	}
+
+	return nil
+}
+
+// List is a Receipt collection.
+type List []*Receipt
+
+func (l List) Len() int {
+	return len(l)
+}
+
+func (l List) Less(i, j int) bool {
+	return l[i].CreatedAt.After(l[j].CreatedAt)
+}
+
+func (l List) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+// QueryOptions to narrow-down Receipt queries. Receipts are returned newest
+// first, Before only considers those created earlier.
+type QueryOptions struct {
+	AppIDs     []uint64
+	Before     time.Time
+	IDs        []uint64
+	Limit      int
+	MessageIDs []string
+	Recipients []uint64
+	RuleIDs    []uint64
+	Statuses   []Status
+	VariantIDs []string
+}
+
+// VariantCount sums up the recipients of a template variant. Recipients are
+// counted once no matter how many devices they got it on, Opened is the number
+// of them who opened it on any device.
+type VariantCount struct {
+	Opened    int
+	Sent      int
+	VariantID string
+}
+
+// OpenRate is the share of Receipts which were opened.
+func (c VariantCount) OpenRate() float64 {
+	if c.Sent == 0 {
+		return 0
+	}
+
+	return float64(c.Opened) / float64(c.Sent)
+}
+
+// VariantCounts is a VariantCount collection.
+type VariantCounts []VariantCount
+
+// Service for Receipt interactions. CountVariants sums up the Receipts
+// matching opts per variant, ordered by variant id, Receipts without a variant
+// are left out.
+type Service interface {
+	service.Lifecycle
+
+	CountVariants(namespace string, opts QueryOptions) (VariantCounts, error)
+	Put(namespace string, r *Receipt) (*Receipt, error)
+	Query(namespace string, opts QueryOptions) (List, error)
+}
+
+// ServiceMiddleware is a chainable behaviour modifier for Service.
+type ServiceMiddleware func(Service) Service
+
+func flakeNamespace(ns string) string {
+	return fmt.Sprintf("%s_%s", ns, "receipts")
+}
@@ -12,8 +12,8 @@ import (
 
 const (
 	pgInsertRule = `INSERT INTO
-		%s.rules(active, criteria, deleted, ecosystem, id, name, recipients, type, created_at, updated_at)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+		%s.rules(active, criteria, deleted, ecosystem, id, name, recipients, schedule, type, urgent, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
 	pgUpdateRule = `
 		UPDATE
 			%s.rules
@@ -24,24 +24,39 @@ const (
 			ecosystem = $5,
 			name = $6,
 			recipients = $7,
-			type = $8,
-			updated_at = $9
+			schedule = $8,
+			type = $9,
+			urgent = $10,
+			updated_at = $11
 		WHERE
 			id = $1`
 
-	pgClauseActive  = `active = ?`
-	pgClauseDeleted = `deleted = ?`
-	pgClauseIDs     = `id IN (?)`
-	pgClauseTypes   = `type IN (?)`
+	pgClauseActive      = `active = ?`
+	pgClauseDeleted     = `deleted = ?`
+	pgClauseIDs         = `id IN (?)`
+	pgClauseScheduled   = `schedule IS NOT NULL`
+	pgClauseTypes       = `type IN (?)`
+	pgClauseUnscheduled = `schedule IS NULL`
 
 	pgListRules = `
 		SELECT
-			active, criteria, deleted, ecosystem, id, name, recipients, type, created_at, updated_at
+			active, criteria, deleted, ecosystem, id, name, recipients, schedule, type, urgent, created_at, updated_at
 		FROM
 			%s.rules
 		%s`
 	pgOrderCreatedAt = `ORDER BY created_at DESC`
 
+	pgAddColumnSchedule = `
+		ALTER TABLE
+			%s.rules
+		ADD COLUMN IF NOT EXISTS
+			schedule JSONB`
+	pgAddColumnUrgent = `
+		ALTER TABLE
+			%s.rules
+		ADD COLUMN IF NOT EXISTS
+			urgent BOOL DEFAULT false`
+
 	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
 	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.rules(
 		active BOOL DEFAULT false,
@@ -51,7 +66,9 @@ const (
 		id BIGINT NOT NULL UNIQUE,
 		name TEXT NOT NULL,
 		recipients JSONB NOT NULL,
+		schedule JSONB,
 		type INT NOT NULL,
+		urgent BOOL DEFAULT false,
 		created_at TIMESTAMP NOT NULL,
 		updated_at TIMESTAMP NOT NULL
 	)`
@@ -89,7 +106,7 @@ func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
 
 	rs, err := s.listRules(ns, where, params...)
 	if err != nil {
-		if pg.IsRelationNotFound(pg.WrapError(err)) {
+		if isSetupRequired(err) {
 			if err := s.Setup(ns); err != nil {
 				return nil, err
 			}
@@ -105,6 +122,8 @@ func (s *pgService) Setup(ns string) error {
 	qs := []string{
 		fmt.Sprintf(pgCreateSchema, ns),
 		fmt.Sprintf(pgCreateTable, ns),
+		fmt.Sprintf(pgAddColumnUrgent, ns),
+		fmt.Sprintf(pgAddColumnSchedule, ns),
 	}
 
 	for _, q := range qs {
@@ -162,6 +181,11 @@ func (s *pgService) insert(ns string, r *Rule) (*Rule, error) {
 		return nil, err
 	}
 
+	schedule, err := marshalSchedule(r.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
 		params = []interface{}{
 			r.Active,
@@ -171,7 +195,9 @@ func (s *pgService) insert(ns string, r *Rule) (*Rule, error) {
 			r.ID,
 			r.Name,
 			recipients,
+			schedule,
 			r.Type,
+			r.Urgent,
 			r.CreatedAt,
 			r.UpdatedAt,
 		}
@@ -180,7 +206,7 @@ func (s *pgService) insert(ns string, r *Rule) (*Rule, error) {
 
 	_, err = s.db.Exec(query, params...)
 	if err != nil {
-		if pg.IsRelationNotFound(pg.WrapError(err)) {
+		if isSetupRequired(err) {
 			if err := s.Setup(ns); err != nil {
 				return nil, err
 			}
@@ -212,6 +238,7 @@ func (s *pgService) listRules(
 		var (
 			criteria   = []byte{}
 			recipients = []byte{}
+			schedule   = []byte{}
 			r          = &Rule{}
 		)
 
@@ -223,7 +250,9 @@ func (s *pgService) listRules(
 			&r.ID,
 			&r.Name,
 			&recipients,
+			&schedule,
 			&r.Type,
+			&r.Urgent,
 			&r.CreatedAt,
 			&r.UpdatedAt,
 		)
@@ -240,6 +269,8 @@ func (s *pgService) listRules(
 			r.Criteria = &CriteriaObject{}
 		case TypeReaction:
 			r.Criteria = &CriteriaReaction{}
+		case TypeUser:
+			r.Criteria = &CriteriaUser{}
 		default:
 			return nil, fmt.Errorf("type not supported")
 		}
@@ -248,10 +279,27 @@ func (s *pgService) listRules(
 			return nil, err
 		}
 
+		if err := compileCriteria(r.Criteria); err != nil {
+			return nil, wrapError(
+				ErrInvalidRule,
+				"rule (%d) criteria expression: %s",
+				r.ID,
+				err,
+			)
+		}
+
 		if err := json.Unmarshal(recipients, &r.Recipients); err != nil {
 			return nil, err
 		}
 
+		if len(schedule) > 0 {
+			r.Schedule = &Schedule{}
+
+			if err := json.Unmarshal(schedule, r.Schedule); err != nil {
+				return nil, err
+			}
+		}
+
 		r.CreatedAt = r.CreatedAt.UTC()
 		r.UpdatedAt = r.UpdatedAt.UTC()
 
@@ -283,6 +331,11 @@ func (s *pgService) update(ns string, r *Rule) (*Rule, error) {
 		return nil, err
 	}
 
+	schedule, err := marshalSchedule(r.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
 		params = []interface{}{
 			r.ID,
@@ -292,14 +345,16 @@ func (s *pgService) update(ns string, r *Rule) (*Rule, error) {
 			r.Ecosystem,
 			r.Name,
 			recipients,
+			schedule,
 			r.Type,
+			r.Urgent,
 			r.UpdatedAt,
 		}
 		query = fmt.Sprintf(pgUpdateRule, ns)
 	)
 
 	_, err = s.db.Exec(query, params...)
-	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+	if err != nil && isSetupRequired(err) {
 		if err := s.Setup(ns); err != nil {
 			return nil, err
 		}
@@ -352,6 +407,14 @@ func convertOpts(opts QueryOptions) (string, []interface{}, error) {
 		params = append(params, ps...)
 	}
 
+	if opts.Scheduled != nil {
+		if *opts.Scheduled {
+			clauses = append(clauses, pgClauseScheduled)
+		} else {
+			clauses = append(clauses, pgClauseUnscheduled)
+		}
+	}
+
 	if len(opts.Types) > 0 {
 		ps := []interface{}{}
 
@@ -376,3 +439,21 @@ func convertOpts(opts QueryOptions) (string, []interface{}, error) {
 
 	return where, params, nil
 }
+
+// marshalSchedule encodes s for storage, Rules without a Schedule are stored
+// with NULL to keep them apart from scheduled ones.
+func marshalSchedule(s *Schedule) (interface{}, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(s)
+}
+
+// isSetupRequired indicates if err was caused by a missing table or a table
+// which predates the addition of a column.
+func isSetupRequired(err error) bool {
+	err = pg.WrapError(err)
+
+	return pg.IsRelationNotFound(err) || pg.IsColumnNotFound(err)
+}
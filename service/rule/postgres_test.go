@@ -137,6 +137,36 @@ func TestPostgresPut(t *testing.T) {
 			},
 			Type: TypeObject,
 		},
+		{
+			Active: true,
+			Criteria: &CriteriaObject{
+				New: &object.QueryOptions{
+					Owned: &enabled,
+					Types: []string{
+						"review",
+					},
+				},
+			},
+			Deleted:   false,
+			Ecosystem: sns.PlatformAPNS,
+			Name:      "Unanswered review",
+			Recipients: Recipients{
+				{
+					Query: map[string]string{
+						"foo": "bar",
+					},
+					Templates: map[string]string{
+						"en": "Where we mesage.",
+					},
+					URN: "",
+				},
+			},
+			Schedule: &Schedule{
+				Cron:       "0 9 * * *",
+				Inactivity: 86400,
+			},
+			Type: TypeObject,
+		},
 		{
 			Active: true,
 			Criteria: &CriteriaReaction{
@@ -1,15 +1,32 @@
 package rule
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"reflect"
+	"text/template"
 	"time"
 
+	"github.com/tapglue/snaas/platform/cron"
+	"github.com/tapglue/snaas/platform/expr"
 	"github.com/tapglue/snaas/platform/service"
 	"github.com/tapglue/snaas/platform/sns"
 	"github.com/tapglue/snaas/service/connection"
 	"github.com/tapglue/snaas/service/event"
 	"github.com/tapglue/snaas/service/object"
 	"github.com/tapglue/snaas/service/reaction"
+	"github.com/tapglue/snaas/service/user"
+)
+
+// BadgeUnread as Badge of PushTemplates sets the badge to the number of unread
+// notifications of the recipient at the time of delivery.
+const BadgeUnread = "unread"
+
+// Channels over which Messages can be delivered.
+const (
+	ChannelEmail Channel = "email"
+	ChannelPush  Channel = "push"
 )
 
 // Type to distinct between different stored criteria.
@@ -18,11 +35,41 @@ const (
 	TypeEvent
 	TypeObject
 	TypeReaction
+	TypeUser
 )
 
+// Aggregate configures the collapsing of Messages for the same recipient, rule
+// and URN which occur within Window seconds into a single one rendered from
+// Templates. The first Actors distinct actors are exposed to the Templates.
+type Aggregate struct {
+	Actors    int       `json:"actors"`
+	Templates Templates `json:"templates"`
+	Window    int       `json:"window"`
+}
+
+// Channel is a medium over which a Recipient is notified.
+type Channel string
+
+// Channels is a Channel collection.
+type Channels []Channel
+
+// Has reports if c is part of the collection.
+func (cs Channels) Has(c Channel) bool {
+	for _, channel := range cs {
+		if channel == c {
+			return true
+		}
+	}
+
+	return false
+}
+
 type CriteriaConnection struct {
-	New *connection.QueryOptions `json:"new"`
-	Old *connection.QueryOptions `json:"old"`
+	Expression string                   `json:"expression,omitempty"`
+	New        *connection.QueryOptions `json:"new"`
+	Old        *connection.QueryOptions `json:"old"`
+
+	compiled *expr.Expr
 }
 
 func (c *CriteriaConnection) Match(i interface{}) bool {
@@ -36,15 +83,19 @@ func (c *CriteriaConnection) Match(i interface{}) bool {
 	}
 
 	if s.Old == nil {
-		return s.New.MatchOpts(c.New)
+		return s.New.MatchOpts(c.New) && matchExpression(c.Expression, c.compiled, nil, s.New)
 	}
 
-	return s.New.MatchOpts(c.New) && s.Old.MatchOpts(c.Old)
+	return s.New.MatchOpts(c.New) && s.Old.MatchOpts(c.Old) &&
+		matchExpression(c.Expression, c.compiled, s.Old, s.New)
 }
 
 type CriteriaReaction struct {
-	New *reaction.QueryOptions `json:"new"`
-	Old *reaction.QueryOptions `json:"old"`
+	Expression string                 `json:"expression,omitempty"`
+	New        *reaction.QueryOptions `json:"new"`
+	Old        *reaction.QueryOptions `json:"old"`
+
+	compiled *expr.Expr
 }
 
 func (c *CriteriaReaction) Match(i interface{}) bool {
@@ -58,15 +109,19 @@ func (c *CriteriaReaction) Match(i interface{}) bool {
 	}
 
 	if s.Old == nil {
-		return s.New.MatchOpts(c.New)
+		return s.New.MatchOpts(c.New) && matchExpression(c.Expression, c.compiled, nil, s.New)
 	}
 
-	return s.New.MatchOpts(c.New) && s.Old.MatchOpts(c.Old)
+	return s.New.MatchOpts(c.New) && s.Old.MatchOpts(c.Old) &&
+		matchExpression(c.Expression, c.compiled, s.Old, s.New)
 }
 
 type CriteriaEvent struct {
-	New *event.QueryOptions `json:"new"`
-	Old *event.QueryOptions `json:"old"`
+	Expression string              `json:"expression,omitempty"`
+	New        *event.QueryOptions `json:"new"`
+	Old        *event.QueryOptions `json:"old"`
+
+	compiled *expr.Expr
 }
 
 func (c *CriteriaEvent) Match(i interface{}) bool {
@@ -80,15 +135,19 @@ func (c *CriteriaEvent) Match(i interface{}) bool {
 	}
 
 	if s.Old == nil {
-		return s.New.MatchOpts(c.New)
+		return s.New.MatchOpts(c.New) && matchExpression(c.Expression, c.compiled, nil, s.New)
 	}
 
-	return s.New.MatchOpts(c.New) && s.Old.MatchOpts(c.Old)
+	return s.New.MatchOpts(c.New) && s.Old.MatchOpts(c.Old) &&
+		matchExpression(c.Expression, c.compiled, s.Old, s.New)
 }
 
 type CriteriaObject struct {
-	New *object.QueryOptions `json:"new"`
-	Old *object.QueryOptions `json:"old"`
+	Expression string               `json:"expression,omitempty"`
+	New        *object.QueryOptions `json:"new"`
+	Old        *object.QueryOptions `json:"old"`
+
+	compiled *expr.Expr
 }
 
 func (c *CriteriaObject) Match(i interface{}) bool {
@@ -102,10 +161,50 @@ func (c *CriteriaObject) Match(i interface{}) bool {
 	}
 
 	if s.Old == nil {
-		return s.New.MatchOpts(c.New)
+		return s.New.MatchOpts(c.New) && matchExpression(c.Expression, c.compiled, nil, s.New)
 	}
 
-	return s.New.MatchOpts(c.New) && s.Old.MatchOpts(c.Old)
+	return s.New.MatchOpts(c.New) && s.Old.MatchOpts(c.Old) &&
+		matchExpression(c.Expression, c.compiled, s.Old, s.New)
+}
+
+type CriteriaUser struct {
+	Expression string             `json:"expression,omitempty"`
+	New        *user.QueryOptions `json:"new"`
+	Old        *user.QueryOptions `json:"old"`
+
+	compiled *expr.Expr
+}
+
+func (c *CriteriaUser) Match(i interface{}) bool {
+	s, ok := i.(*user.StateChange)
+	if !ok {
+		return false
+	}
+
+	if s.New == nil && s.Old == nil {
+		return false
+	}
+
+	if s.Old == nil {
+		return s.New.MatchOpts(c.New) && matchExpression(c.Expression, c.compiled, nil, s.New)
+	}
+
+	return s.New.MatchOpts(c.New) && s.Old.MatchOpts(c.Old) &&
+		matchExpression(c.Expression, c.compiled, s.Old, s.New)
+}
+
+// empty reports if the criteria match every user.
+func (c *CriteriaUser) empty() bool {
+	return c.Expression == "" &&
+		(c.New == nil || reflect.DeepEqual(c.New, &user.QueryOptions{}))
+}
+
+// EmailTemplates map languages to the subject and body templates used for the
+// email channel. If no body is given for a language the push template is used.
+type EmailTemplates struct {
+	Body    Templates `json:"body,omitempty"`
+	Subject Templates `json:"subject,omitempty"`
 }
 
 // List is a Rule collection.
@@ -116,29 +215,117 @@ type Matcher interface {
 	Match(c interface{}) bool
 }
 
+// PushTemplates enrich the push notifications of a Recipient. Titles map
+// languages to title templates. Badge is either BadgeUnread or a template
+// rendering an integer. CollapseKey, ThreadID and the Data values are templates
+// as well, all of them are rendered with the context of the message.
+type PushTemplates struct {
+	Badge       string            `json:"badge,omitempty"`
+	CollapseKey string            `json:"collapse_key,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+	Sound       string            `json:"sound,omitempty"`
+	ThreadID    string            `json:"thread_id,omitempty"`
+	Titles      Templates         `json:"titles,omitempty"`
+}
+
 // Query is a mapping for templated Recipient lookups.
 type Query map[string]string
 
 // QueryOptions to narrow-down Rule queries.
 type QueryOptions struct {
-	Active  *bool
-	Deleted *bool
-	IDs     []uint64
-	Types   []Type
+	Active    *bool
+	Deleted   *bool
+	IDs       []uint64
+	Scheduled *bool
+	Types     []Type
 }
 
 // Recipient is an abstract description of how to lookup users and template the
-// messaging as well as meta-information.
+// messaging as well as meta-information. Without Channels a Recipient is only
+// notified via push. With Variants every user is assigned one of them, whose
+// Templates and Titles replace those of the Recipient.
 type Recipient struct {
-	Query     Query     `json:"query"`
-	Templates Templates `json:"templates"`
-	URN       string    `json:"urn"`
+	Aggregate *Aggregate     `json:"aggregate,omitempty"`
+	Channels  Channels       `json:"channels,omitempty"`
+	Email     EmailTemplates `json:"email"`
+	Push      PushTemplates  `json:"push"`
+	Query     Query          `json:"query"`
+	Templates Templates      `json:"templates"`
+	URN       string         `json:"urn"`
+	Variants  Variants       `json:"variants,omitempty"`
+}
+
+// Validate checks that all Channels are supported and that the URN and all
+// templates are syntactically correct.
+func (r Recipient) Validate() error {
+	for _, c := range r.Channels {
+		if c != ChannelEmail && c != ChannelPush {
+			return fmt.Errorf("unsupported channel '%s'", c)
+		}
+	}
+
+	if len(r.Templates) == 0 && len(r.Variants) == 0 {
+		return fmt.Errorf("templates missing")
+	}
+
+	if _, err := template.New("urn").Parse(r.URN); err != nil {
+		return fmt.Errorf("urn: %s", err)
+	}
+
+	ts := map[string]Templates{
+		"templates":     r.Templates,
+		"email.body":    r.Email.Body,
+		"email.subject": r.Email.Subject,
+		"push.data":     Templates(r.Push.Data),
+		"push.titles":   r.Push.Titles,
+		"push": {
+			"collapse_key": r.Push.CollapseKey,
+			"thread_id":    r.Push.ThreadID,
+		},
+	}
+
+	if r.Push.Badge != BadgeUnread {
+		ts["push"]["badge"] = r.Push.Badge
+	}
+
+	if r.Aggregate != nil {
+		if r.Aggregate.Window < 0 {
+			return fmt.Errorf("aggregate window can't be negative")
+		}
+
+		if len(r.Aggregate.Templates) == 0 {
+			return fmt.Errorf("aggregate templates missing")
+		}
+
+		ts["aggregate.templates"] = r.Aggregate.Templates
+	}
+
+	if err := r.Variants.validate(); err != nil {
+		return err
+	}
+
+	for _, v := range r.Variants {
+		ts[fmt.Sprintf("variants.%s.templates", v.ID)] = v.Templates
+		ts[fmt.Sprintf("variants.%s.titles", v.ID)] = v.Titles
+	}
+
+	for name, templates := range ts {
+		for lang, t := range templates {
+			if _, err := template.New(name).Parse(t); err != nil {
+				return fmt.Errorf("%s (%s): %s", name, lang, err)
+			}
+		}
+	}
+
+	return nil
 }
 
 // Recipients is a Recipient collection.
 type Recipients []Recipient
 
-// Rule is a data container to parametrise Pipelines.
+// Rule is a data container to parametrise Pipelines. Messages of Urgent Rules
+// bypass the quiet hours of their recipients. Rules with a Schedule are not
+// triggered by state changes but evaluated whenever the Schedule fires.
 type Rule struct {
 	Active     bool
 	Criteria   Matcher
@@ -147,13 +334,173 @@ type Rule struct {
 	ID         uint64
 	Name       string
 	Recipients Recipients
+	Schedule   *Schedule
 	Type       Type
+	Urgent     bool
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
 
 // Validate checks for semantic correctness.
 func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return wrapError(ErrInvalidRule, "name missing")
+	}
+
+	var ruleType Type
+
+	switch r.Criteria.(type) {
+	case *CriteriaConnection:
+		ruleType = TypeConnection
+	case *CriteriaEvent:
+		ruleType = TypeEvent
+	case *CriteriaObject:
+		ruleType = TypeObject
+	case *CriteriaReaction:
+		ruleType = TypeReaction
+	case *CriteriaUser:
+		ruleType = TypeUser
+	default:
+		return wrapError(ErrInvalidRule, "criteria missing")
+	}
+
+	if ruleType != r.Type {
+		return wrapError(
+			ErrInvalidRule,
+			"criteria for %s don't match type %s",
+			ruleType,
+			r.Type,
+		)
+	}
+
+	if len(r.Recipients) == 0 {
+		return wrapError(ErrInvalidRule, "recipients missing")
+	}
+
+	for i, recipient := range r.Recipients {
+		if err := recipient.Validate(); err != nil {
+			return wrapError(ErrInvalidRule, "recipient %d: %s", i, err)
+		}
+	}
+
+	if r.Schedule != nil {
+		if err := r.Schedule.Validate(r.Type); err != nil {
+			return wrapError(ErrInvalidRule, "schedule: %s", err)
+		}
+
+		// Without either every user of the app is notified on every run.
+		if c, ok := r.Criteria.(*CriteriaUser); ok && r.Schedule.Inactivity == 0 && c.empty() {
+			return wrapError(
+				ErrInvalidRule,
+				"schedule: inactivity or criteria required for %s rules",
+				r.Type,
+			)
+		}
+	}
+
+	if err := compileCriteria(r.Criteria); err != nil {
+		return wrapError(ErrInvalidRule, "criteria expression: %s", err)
+	}
+
+	return nil
+}
+
+// Schedule describes when a scheduled Rule is evaluated. Cron is a five field
+// cron expression evaluated in UTC. Without Inactivity every user matching the
+// criteria, which user rules then require, or every object created since the
+// previous run, is considered.
+// With Inactivity given in seconds only users whose last read, and objects
+// without comments whose creation, passed that threshold since the previous run
+// are considered, so every period of inactivity is reported once.
+type Schedule struct {
+	Cron       string `json:"cron"`
+	Inactivity int    `json:"inactivity,omitempty"`
+}
+
+// Validate checks that Cron is well-formed and the Schedule is supported for
+// Rules of type t.
+func (s *Schedule) Validate(t Type) error {
+	if t != TypeObject && t != TypeUser {
+		return fmt.Errorf("not supported for %s rules", t)
+	}
+
+	if _, err := cron.Parse(s.Cron); err != nil {
+		return err
+	}
+
+	if s.Inactivity < 0 {
+		return fmt.Errorf("inactivity can't be negative")
+	}
+
+	return nil
+}
+
+// Variant is an alternative wording of the Templates and push Titles of a
+// Recipient. Users are split between the Variants of a Recipient in proportion
+// to their Weight.
+type Variant struct {
+	ID        string    `json:"id"`
+	Templates Templates `json:"templates"`
+	Titles    Templates `json:"titles,omitempty"`
+	Weight    int       `json:"weight"`
+}
+
+// Variants is a Variant collection.
+type Variants []Variant
+
+// Pick returns the Variant the user is assigned to for the Rule. Assignment is
+// deterministic, the hash of rule and user id decides, so a user keeps seeing
+// the same Variant. The hash is well mixed so that different Rules split their
+// users independently.
+func (vs Variants) Pick(ruleID, userID uint64) *Variant {
+	total := 0
+
+	for _, v := range vs {
+		total += v.Weight
+	}
+
+	if total <= 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", ruleID, userID)))
+
+	bucket := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+
+	for i, v := range vs {
+		if bucket < v.Weight {
+			return &vs[i]
+		}
+
+		bucket -= v.Weight
+	}
+
+	return nil
+}
+
+func (vs Variants) validate() error {
+	seen := map[string]struct{}{}
+
+	for i, v := range vs {
+		if v.ID == "" {
+			return fmt.Errorf("variant %d: id missing", i)
+		}
+
+		if _, ok := seen[v.ID]; ok {
+			return fmt.Errorf("variant %d: duplicate id '%s'", i, v.ID)
+		}
+
+		seen[v.ID] = struct{}{}
+
+		if v.Weight <= 0 {
+			return fmt.Errorf("variant %s: weight must be positive", v.ID)
+		}
+
+		if len(v.Templates) == 0 {
+			return fmt.Errorf("variant %s: templates missing", v.ID)
+		}
+	}
+
 	return nil
 }
 
@@ -174,6 +521,87 @@ type Templates map[string]string
 // Type indicates for which entity the criterias are encoded in the rule.
 type Type uint8
 
+// String returns the name of the entity the Type covers, it doubles as the
+// category users can toggle notifications for.
+func (t Type) String() string {
+	switch t {
+	case TypeConnection:
+		return "connection"
+	case TypeEvent:
+		return "event"
+	case TypeObject:
+		return "object"
+	case TypeReaction:
+		return "reaction"
+	case TypeUser:
+		return "user"
+	}
+
+	return fmt.Sprintf("unknown(%d)", t)
+}
+
 func flakeNamespace(ns string) string {
 	return fmt.Sprintf("%s_%s", ns, "rules")
 }
+
+// compileCriteria parses the expression of the criteria and checks it against
+// the entity matched, the result is kept for Match. It happens when a Rule is
+// validated or loaded.
+func compileCriteria(c Matcher) error {
+	var (
+		compiled **expr.Expr
+		entity   interface{}
+		src      string
+	)
+
+	switch c := c.(type) {
+	case *CriteriaConnection:
+		compiled, entity, src = &c.compiled, &connection.Connection{}, c.Expression
+	case *CriteriaEvent:
+		compiled, entity, src = &c.compiled, &event.Event{}, c.Expression
+	case *CriteriaObject:
+		compiled, entity, src = &c.compiled, &object.Object{}, c.Expression
+	case *CriteriaReaction:
+		compiled, entity, src = &c.compiled, &reaction.Reaction{}, c.Expression
+	case *CriteriaUser:
+		compiled, entity, src = &c.compiled, &user.User{}, c.Expression
+	default:
+		return nil
+	}
+
+	*compiled = nil
+
+	if src == "" {
+		return nil
+	}
+
+	e, err := expr.Parse(src)
+	if err != nil {
+		return err
+	}
+
+	if err := e.Check(entity); err != nil {
+		return err
+	}
+
+	*compiled = e
+
+	return nil
+}
+
+// matchExpression reports if the compiled criteria expression holds for the
+// given state change, an empty expression always does. Expressions which were
+// not compiled or fail to evaluate never match.
+func matchExpression(src string, e *expr.Expr, old, new interface{}) bool {
+	if src == "" {
+		return true
+	}
+
+	if e == nil {
+		return false
+	}
+
+	ok, err := e.Eval(old, new)
+
+	return err == nil && ok
+}
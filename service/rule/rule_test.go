@@ -0,0 +1,205 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/user"
+)
+
+func TestCriteriaObjectMatchExpression(t *testing.T) {
+	var (
+		c = &CriteriaObject{
+			Expression: "changed(visibility) && new.visibility == 30 && 'news' in new.tags",
+			New:        &object.QueryOptions{},
+			Old:        &object.QueryOptions{},
+		}
+		old = &object.Object{
+			Tags:       []string{"news"},
+			Visibility: object.VisibilityConnection,
+		}
+		new = &object.Object{
+			Tags:       []string{"news"},
+			Visibility: object.VisibilityPublic,
+		}
+	)
+
+	// Expressions are only evaluated once compiled.
+	if have, want := c.Match(&object.StateChange{New: new, Old: old}), false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if err := compileCriteria(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := c.Match(&object.StateChange{New: new, Old: old}), true; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := c.Match(&object.StateChange{New: new, Old: new}), false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := c.Match(&object.StateChange{New: new}), false; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var (
+		r  = testRule()
+		rs = List{
+			{},             // Missing Name
+			{Name: r.Name}, // Missing Criteria
+			{Name: r.Name, Criteria: r.Criteria, Type: TypeEvent},                                                                                                                        // Mismatching Type
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type},                                                                                                                           // Missing Recipients
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{}}},                                                                                               // Missing Templates
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Templates: Templates{"en": "{{.Owner"}}}},                                                         // Malformed Template
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Templates: r.Recipients[0].Templates, URN: "{{end}}"}}},                                           // Malformed URN
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Channels: Channels{"sms"}, Templates: r.Recipients[0].Templates}}},                                // Unsupported Channel
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Push: PushTemplates{Titles: Templates{"en": "{{.Owner"}}, Templates: r.Recipients[0].Templates}}}, // Malformed Push Title
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Push: PushTemplates{Badge: "{{if}}"}, Templates: r.Recipients[0].Templates}}},                     // Malformed Push Badge
+			{Name: r.Name, Criteria: &CriteriaObject{Expression: "new.visibility =="}, Type: r.Type, Recipients: r.Recipients},                                                           // Malformed Expression
+			{Name: r.Name, Criteria: &CriteriaObject{Expression: "new.visibilty == 30"}, Type: r.Type, Recipients: r.Recipients},                                                         // Unknown Field
+			{Name: r.Name, Criteria: &CriteriaObject{Expression: "changed(tags.x)"}, Type: r.Type, Recipients: r.Recipients},                                                             // Inaccessible Field
+			{Name: r.Name, Criteria: &CriteriaUser{Expression: "new.password == 'x'"}, Type: TypeUser, Recipients: r.Recipients},                                                         // Hidden Password
+			{Name: r.Name, Criteria: &CriteriaUser{Expression: "changed(session_token)"}, Type: TypeUser, Recipients: r.Recipients},                                                      // Hidden Session Token
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: r.Recipients, Schedule: &Schedule{Cron: "0 25 * * *"}},                                                        // Malformed Cron
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: r.Recipients, Schedule: &Schedule{Cron: "@daily", Inactivity: -1}},                                            // Negative Inactivity
+			{Name: r.Name, Criteria: &CriteriaEvent{}, Type: TypeEvent, Recipients: r.Recipients, Schedule: &Schedule{Cron: "@daily"}},                                                   // Unsupported Type
+			{Name: r.Name, Criteria: &CriteriaUser{}, Type: TypeUser, Recipients: r.Recipients, Schedule: &Schedule{Cron: "@daily"}},                                                     // Unbounded User Schedule
+			{Name: r.Name, Criteria: &CriteriaUser{New: &user.QueryOptions{}}, Type: TypeUser, Recipients: r.Recipients, Schedule: &Schedule{Cron: "@daily"}},                            // Empty User Criteria
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Variants: Variants{{Templates: r.Recipients[0].Templates, Weight: 1}}}}},                          // Missing Variant ID
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Variants: Variants{{ID: "a", Weight: 1}}}}},                                                       // Missing Variant Templates
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Variants: Variants{{ID: "a", Templates: r.Recipients[0].Templates}}}}},                            // Missing Variant Weight
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Variants: Variants{{ID: "a", Templates: Templates{"en": "{{.Owner"}, Weight: 1}}}}},               // Malformed Variant Template
+			{Name: r.Name, Criteria: r.Criteria, Type: r.Type, Recipients: Recipients{{Variants: testVariants(r.Recipients[0].Templates, "a", "a")}}},                                    // Duplicate Variant ID
+		}
+	)
+
+	for _, r := range rs {
+		if have, want := r.Validate(), ErrInvalidRule; !IsInvalidRule(have) {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	r.Recipients[0].Variants = testVariants(nil, "a", "b")
+	r.Recipients[0].Templates = nil
+
+	if err := r.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// Scheduled user rules need to be bounded by inactivity or criteria.
+	for _, c := range []*CriteriaUser{
+		{Expression: "new.enabled"},
+		{New: &user.QueryOptions{CustomIDs: []string{"beta"}}},
+	} {
+		s := &Rule{Name: r.Name, Criteria: c, Type: TypeUser, Recipients: r.Recipients, Schedule: &Schedule{Cron: "@daily"}}
+
+		if err := s.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	s := &Rule{Name: r.Name, Criteria: &CriteriaUser{}, Type: TypeUser, Recipients: r.Recipients, Schedule: &Schedule{Cron: "@daily", Inactivity: 3600}}
+
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVariantsPick(t *testing.T) {
+	vs := testVariants(nil, "a", "b")
+	vs[1].Weight = 3
+
+	picks := map[string]int{}
+
+	for userID := uint64(1); userID <= 4000; userID++ {
+		v := vs.Pick(123, userID)
+
+		if have, want := vs.Pick(123, userID), v; have != want {
+			t.Fatalf("have %v, want %v", have, want)
+		}
+
+		picks[v.ID]++
+	}
+
+	// The share of a variant follows its weight.
+	if have := picks["b"]; have < 2800 || have > 3200 {
+		t.Errorf("have %v, want around %v", have, 3000)
+	}
+
+	if have := Variants(nil).Pick(123, 1); have != nil {
+		t.Errorf("have %v, want %v", have, nil)
+	}
+}
+
+func TestVariantsPickIndependent(t *testing.T) {
+	vs := testVariants(nil, "a", "b")
+
+	for _, ruleIDs := range [][2]uint64{
+		{123, 124},
+		{1, 2},
+		{1, 1 << 32},
+	} {
+		pairs := map[string]int{}
+
+		for userID := uint64(1); userID <= 4000; userID++ {
+			pairs[vs.Pick(ruleIDs[0], userID).ID+vs.Pick(ruleIDs[1], userID).ID]++
+		}
+
+		// Every combination of variants is as likely as any other.
+		for _, pair := range []string{"aa", "ab", "ba", "bb"} {
+			if have := pairs[pair]; have < 850 || have > 1150 {
+				t.Errorf("rules %v %s: have %v, want around %v", ruleIDs, pair, have, 1000)
+			}
+		}
+	}
+}
+
+func testRule() *Rule {
+	return &Rule{
+		Criteria: &CriteriaObject{
+			Expression: "new.visibility >= 30 && !changed(visibility)",
+		},
+		Name: "Public post",
+		Recipients: Recipients{
+			{
+				Query: Query{
+					"ownerFriends": "",
+				},
+				Templates: Templates{
+					"en": "{{.Owner.Username}} posted",
+				},
+				URN: "tapglue/posts/{{.Object.ID}}",
+			},
+		},
+		Type: TypeObject,
+	}
+}
+
+func testVariants(ts Templates, ids ...string) Variants {
+	vs := Variants{}
+
+	if ts == nil {
+		ts = Templates{
+			"en": "{{.Owner.Username}} posted",
+		}
+	}
+
+	for _, id := range ids {
+		vs = append(vs, Variant{
+			ID:        id,
+			Templates: ts,
+			Weight:    1,
+		})
+	}
+
+	return vs
+}
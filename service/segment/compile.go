@@ -0,0 +1,227 @@
+package segment
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQL fragments Conditions are compiled from. The users table is aliased as u,
+// %s placeholders take parameter references or the namespace.
+const (
+	pgMatchUser = `(u.json_data->>'enabled')::BOOL = true
+		AND (u.json_data->>'deleted')::BOOL = false`
+	pgUserID = `(u.json_data->>'id')::BIGINT`
+
+	pgFieldCreatedAt = `(u.json_data->>'created_at')::TIMESTAMPTZ`
+	pgFieldText      = `COALESCE(u.json_data->>'%s', '')`
+
+	pgLastRead       = `u.last_read`
+	pgLastReadExists = `u.last_read > '0001-01-01 00:00:00'`
+
+	pgMetadataExists  = `(u.json_data->'metadata'->>%s) IS NOT NULL`
+	pgMetadataNumeric = `(CASE
+		WHEN u.json_data->'metadata'->>%[1]s ~ '^-{0,1}[0-9]+(\.[0-9]+){0,1}$'
+		THEN (u.json_data->'metadata'->>%[1]s)::NUMERIC
+		END)`
+	pgMetadataText = `COALESCE(u.json_data->'metadata'->>%s, '')`
+
+	pgCountConnections = `(SELECT count(*) FROM %s.connections c
+		WHERE (c.json_data->>'type')::TEXT = '%s'
+		AND (c.json_data->>'state')::TEXT = 'confirmed'
+		AND (c.json_data->>'enabled')::BOOL = true
+		AND %s)`
+	pgConnectionFrom = `(c.json_data->>'user_from_id')::BIGINT = (u.json_data->>'id')::BIGINT`
+	pgConnectionTo   = `(c.json_data->>'user_to_id')::BIGINT = (u.json_data->>'id')::BIGINT`
+
+	pgCountEvents = `(SELECT count(*) FROM %s.events e
+		WHERE (e.json_data->>'user_id')::BIGINT = (u.json_data->>'id')::BIGINT
+		AND (e.json_data->>'type')::TEXT = %s
+		AND (e.json_data->>'enabled')::BOOL = true%s)`
+	pgCountEventsAfter = `
+		AND (e.json_data->>'created_at')::TIMESTAMPTZ > %s`
+
+	pgCountObjects = `(SELECT count(*) FROM %s.objects o
+		WHERE (o.json_data->>'owner_id')::BIGINT = (u.json_data->>'id')::BIGINT
+		AND (o.json_data->>'type')::TEXT = %s
+		AND (o.json_data->>'deleted')::BOOL = false%s)`
+	pgCountObjectsAfter = `
+		AND (o.json_data->>'created_at')::TIMESTAMPTZ > %s`
+)
+
+var operators = map[Op]string{
+	OpEq:  "=",
+	OpGt:  ">",
+	OpGte: ">=",
+	OpLt:  "<",
+	OpLte: "<=",
+	OpNeq: "<>",
+}
+
+// compiler turns a Definition into a condition on the users table of the
+// namespace. Parameters are referenced by position, starting after offset, so
+// the condition can be embedded into statements with parameters of their own.
+type compiler struct {
+	now    time.Time
+	ns     string
+	offset int
+	params []interface{}
+}
+
+func compile(
+	ns string,
+	d Definition,
+	now time.Time,
+	offset int,
+) (string, []interface{}, error) {
+	c := &compiler{
+		now:    now,
+		ns:     ns,
+		offset: offset,
+		params: []interface{}{},
+	}
+
+	where := []string{
+		pgMatchUser,
+	}
+
+	for _, cond := range d.All {
+		s, err := c.condition(cond)
+		if err != nil {
+			return "", nil, err
+		}
+
+		where = append(where, s)
+	}
+
+	if len(d.Any) > 0 {
+		any := []string{}
+
+		for _, cond := range d.Any {
+			s, err := c.condition(cond)
+			if err != nil {
+				return "", nil, err
+			}
+
+			any = append(any, s)
+		}
+
+		where = append(where, fmt.Sprintf("(%s)", strings.Join(any, "\n\t\tOR ")))
+	}
+
+	return strings.Join(where, "\n\t\tAND "), c.params, nil
+}
+
+func (c *compiler) condition(cond Condition) (string, error) {
+	if err := cond.Validate(); err != nil {
+		return "", wrapError(ErrInvalidSegment, "%s", err)
+	}
+
+	switch cond.Attribute {
+	case AttributeConnections:
+		var match string
+
+		switch cond.Key {
+		case ConnectionsFollowers:
+			match = fmt.Sprintf(pgCountConnections, c.ns, "follow", pgConnectionTo)
+		case ConnectionsFollowings:
+			match = fmt.Sprintf(pgCountConnections, c.ns, "follow", pgConnectionFrom)
+		case ConnectionsFriends:
+			match = fmt.Sprintf(
+				pgCountConnections,
+				c.ns,
+				"friend",
+				fmt.Sprintf("(%s OR %s)", pgConnectionFrom, pgConnectionTo),
+			)
+		}
+
+		return c.count(match, cond)
+	case AttributeEvents:
+		var (
+			kind  = c.param(cond.Key)
+			after = ""
+		)
+
+		if cond.Window > 0 {
+			after = fmt.Sprintf(pgCountEventsAfter, c.param(c.since(cond)))
+		}
+
+		return c.count(fmt.Sprintf(pgCountEvents, c.ns, kind, after), cond)
+	case AttributeObjects:
+		var (
+			kind  = c.param(cond.Key)
+			after = ""
+		)
+
+		if cond.Window > 0 {
+			after = fmt.Sprintf(pgCountObjectsAfter, c.param(c.since(cond)))
+		}
+
+		return c.count(fmt.Sprintf(pgCountObjects, c.ns, kind, after), cond)
+	case AttributeField:
+		if cond.Key == FieldCreatedAt {
+			if cond.Op == OpExists {
+				return "true", nil
+			}
+
+			return c.compare(pgFieldCreatedAt, cond.Op, c.since(cond)), nil
+		}
+
+		field := fmt.Sprintf(pgFieldText, cond.Key)
+
+		if cond.Op == OpExists {
+			return fmt.Sprintf("%s <> ''", field), nil
+		}
+
+		return c.compare(field, cond.Op, cond.Value), nil
+	case AttributeLastRead:
+		if cond.Op == OpExists {
+			return pgLastReadExists, nil
+		}
+
+		return c.compare(pgLastRead, cond.Op, c.since(cond)), nil
+	case AttributeMetadata:
+		key := fmt.Sprintf("%s::TEXT", c.param(cond.Key))
+
+		switch cond.Op {
+		case OpExists:
+			return fmt.Sprintf(pgMetadataExists, key), nil
+		case OpEq, OpNeq:
+			return c.compare(fmt.Sprintf(pgMetadataText, key), cond.Op, cond.Value), nil
+		}
+
+		v, err := strconv.ParseFloat(cond.Value, 64)
+		if err != nil {
+			return "", err
+		}
+
+		return c.compare(fmt.Sprintf(pgMetadataNumeric, key), cond.Op, v), nil
+	}
+
+	return "", wrapError(ErrInvalidSegment, "unsupported attribute '%s'", cond.Attribute)
+}
+
+func (c *compiler) compare(expr string, op Op, v interface{}) string {
+	return fmt.Sprintf("%s %s %s", expr, operators[op], c.param(v))
+}
+
+func (c *compiler) count(expr string, cond Condition) (string, error) {
+	n, err := strconv.ParseUint(cond.Value, 10, 32)
+	if err != nil {
+		return "", err
+	}
+
+	return c.compare(expr, cond.Op, int64(n)), nil
+}
+
+func (c *compiler) param(v interface{}) string {
+	c.params = append(c.params, v)
+
+	return fmt.Sprintf("$%d", c.offset+len(c.params))
+}
+
+// since returns the start of the Window.
+func (c *compiler) since(cond Condition) time.Time {
+	return c.now.Add(-time.Duration(cond.Window) * time.Second).UTC()
+}
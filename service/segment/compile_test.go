@@ -0,0 +1,121 @@
+package segment
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompile(t *testing.T) {
+	var (
+		now  = time.Date(2017, 3, 14, 12, 0, 0, 0, time.UTC)
+		week = now.Add(-7 * 24 * time.Hour)
+		d    = Definition{
+			All: []Condition{
+				{
+					Attribute: AttributeEvents,
+					Key:       "tg_post",
+					Op:        OpGte,
+					Value:     "3",
+					Window:    604800,
+				},
+				{
+					Attribute: AttributeField,
+					Key:       "email",
+					Op:        OpExists,
+				},
+			},
+			Any: []Condition{
+				{
+					Attribute: AttributeMetadata,
+					Key:       "level",
+					Op:        OpGt,
+					Value:     "2.5",
+				},
+				{
+					Attribute: AttributeLastRead,
+					Op:        OpLt,
+					Window:    604800,
+				},
+			},
+		}
+	)
+
+	where, params, err := compile("app_1_1", d, now, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fragment := range []string{
+		"FROM app_1_1.events e",
+		"(e.json_data->>'type')::TEXT = $3",
+		"(e.json_data->>'created_at')::TIMESTAMPTZ > $4",
+		") >= $5",
+		"COALESCE(u.json_data->>'email', '') <> ''",
+		"u.json_data->'metadata'->>$6::TEXT",
+		"::NUMERIC\n\t\tEND) > $7",
+		"\n\t\tOR u.last_read < $8)",
+	} {
+		if !strings.Contains(where, fragment) {
+			t.Errorf("expected %q in\n%s", fragment, where)
+		}
+	}
+
+	want := []interface{}{
+		"tg_post",
+		week,
+		int64(3),
+		"level",
+		2.5,
+		week,
+	}
+
+	if have := params; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+}
+
+func TestCompileCreatedAt(t *testing.T) {
+	var (
+		now = time.Date(2017, 3, 14, 12, 0, 0, 0, time.UTC)
+		d   = Definition{
+			All: []Condition{
+				{
+					Attribute: AttributeField,
+					Key:       FieldCreatedAt,
+					Op:        OpLt,
+					Window:    86400,
+				},
+			},
+		}
+	)
+
+	where, params, err := compile("app_1_1", d, now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fragment := "(u.json_data->>'created_at')::TIMESTAMPTZ < $1"; !strings.Contains(where, fragment) {
+		t.Errorf("expected %q in\n%s", fragment, where)
+	}
+
+	if have, want := params, []interface{}{now.Add(-24 * time.Hour)}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %#v, want %#v", have, want)
+	}
+}
+
+func TestCompileEmpty(t *testing.T) {
+	where, params, err := compile("app_1_1", Definition{}, time.Now(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := where, pgMatchUser; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := len(params), 0; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,317 @@
+package segment
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tapglue/snaas/service/event"
+	"github.com/tapglue/snaas/service/user"
+)
+
+type prepareFunc func(
+	t *testing.T,
+	namespace string,
+) (Service, user.Service, event.Service)
+
+func testServiceCount(t *testing.T, p prepareFunc) {
+	var (
+		namespace          = "service_count"
+		service, users, es = p(t, namespace)
+		active, _          = testUsers(t, namespace, users, es)
+	)
+
+	cases := map[*Definition]int{
+		&Definition{}: 4,
+		&Definition{
+			All: []Condition{testActive()},
+		}: len(active),
+		&Definition{
+			All: []Condition{
+				testActive(),
+				{Attribute: AttributeMetadata, Key: "level", Op: OpGte, Value: "2"},
+			},
+		}: 1,
+		&Definition{
+			Any: []Condition{
+				{Attribute: AttributeMetadata, Key: "level", Op: OpExists},
+				{Attribute: AttributeField, Key: "custom_id", Op: OpEq, Value: "vip"},
+			},
+		}: 3,
+	}
+
+	for d, want := range cases {
+		have, err := service.Count(namespace, *d)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+
+	_, err := service.Count(namespace, Definition{
+		All: []Condition{
+			{Attribute: AttributeEvents, Op: OpGte, Value: "3"},
+		},
+	})
+	if have, want := err, ErrInvalidSegment; !IsInvalidSegment(have) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func testServicePut(t *testing.T, p prepareFunc) {
+	var (
+		namespace     = "service_put"
+		service, _, _ = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testSegment())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.ID == 0 {
+		t.Error("expected id to be set")
+	}
+
+	created.Name = "Power posters"
+
+	updated, err := service.Put(namespace, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ss, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ss), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := ss[0], updated; !reflect.DeepEqual(have, want) {
+		t.Errorf("\nhave %#v\nwant %#v", have, want)
+	}
+
+	missing := testSegment()
+	missing.ID = created.ID + 1
+
+	if _, err := service.Put(namespace, missing); !IsNotFound(err) {
+		t.Errorf("have %v, want %v", err, ErrNotFound)
+	}
+
+	if _, err := service.Put(namespace, &Segment{}); !IsInvalidSegment(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidSegment)
+	}
+}
+
+func testServiceQuery(t *testing.T, p prepareFunc) {
+	var (
+		namespace     = "service_query"
+		service, _, _ = p(t, namespace)
+		ids           = []uint64{}
+	)
+
+	for i := 0; i < 3; i++ {
+		s, err := service.Put(namespace, testSegment())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, s.ID)
+	}
+
+	cases := map[*QueryOptions]int{
+		&QueryOptions{}:                          3,
+		&QueryOptions{IDs: ids[:2]}:              2,
+		&QueryOptions{IDs: []uint64{ids[2]}}:     1,
+		&QueryOptions{IDs: []uint64{ids[2] + 1}}: 0,
+	}
+
+	for opts, want := range cases {
+		ss, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := len(ss); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func testServiceRefresh(t *testing.T, p prepareFunc) {
+	var (
+		namespace          = "service_refresh"
+		service, users, es = p(t, namespace)
+		active, inactive   = testUsers(t, namespace, users, es)
+	)
+
+	s, err := service.Put(namespace, testSegment())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = service.Refresh(namespace, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := s.Size, len(active); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if s.RefreshedAt.IsZero() {
+		t.Error("expected refreshed_at to be set")
+	}
+
+	ids, err := service.Members(namespace, s.ID, MemberOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := ids, active; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	ids, err = service.Members(namespace, s.ID, MemberOptions{
+		After: active[0],
+		Limit: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := ids, active[1:]; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	// The user leaving the segment is removed, the one joining added.
+	us, err := users.Query(namespace, user.QueryOptions{
+		IDs: []uint64{
+			active[0],
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	us[0].Enabled = false
+
+	if _, err := users.Put(namespace, us[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	testEvents(t, namespace, es, inactive[0], 3)
+
+	s, err = service.Refresh(namespace, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err = service.Members(namespace, s.ID, MemberOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append([]uint64{}, active[1:]...)
+	want = append(want, inactive[0])
+
+	if have := ids; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := s.Size, len(want); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func testActive() Condition {
+	return Condition{
+		Attribute: AttributeEvents,
+		Key:       "tg_post",
+		Op:        OpGte,
+		Value:     "3",
+		Window:    604800,
+	}
+}
+
+func testEvents(
+	t *testing.T,
+	namespace string,
+	es event.Service,
+	userID uint64,
+	n int,
+) {
+	for i := 0; i < n; i++ {
+		_, err := es.Put(namespace, &event.Event{
+			Enabled:    true,
+			Type:       "tg_post",
+			UserID:     userID,
+			Visibility: event.VisibilityPrivate,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testSegment() *Segment {
+	return &Segment{
+		Definition: Definition{
+			All: []Condition{
+				testActive(),
+			},
+		},
+		Name: "Posters",
+	}
+}
+
+// testUsers creates four users of which the first two posted at least three
+// times, their ids are returned in ascending order.
+func testUsers(
+	t *testing.T,
+	namespace string,
+	users user.Service,
+	es event.Service,
+) (active, inactive []uint64) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for i, m := range []user.Metadata{
+		{"level": 3},
+		{"level": "1"},
+		{"level": "none"},
+		nil,
+	} {
+		u, err := users.Put(namespace, &user.User{
+			Email:    fmt.Sprintf("user%d@tapglue.test", r.Int63()),
+			Enabled:  true,
+			Metadata: m,
+			Password: "secret",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i < 2 {
+			testEvents(t, namespace, es, u.ID, 3)
+			active = append(active, u.ID)
+
+			continue
+		}
+
+		testEvents(t, namespace, es, u.ID, 1)
+		inactive = append(inactive, u.ID)
+	}
+
+	return active, inactive
+}
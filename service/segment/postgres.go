@@ -0,0 +1,510 @@
+package segment
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tapglue/snaas/platform/flake"
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/connection"
+	"github.com/tapglue/snaas/service/event"
+	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/user"
+)
+
+const (
+	pgInsertSegment = `INSERT INTO
+		%s.segments(definition, id, name, refreshed_at, size, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7)`
+	pgUpdateSegment = `
+		UPDATE
+			%s.segments
+		SET
+			definition = $2,
+			name = $3,
+			updated_at = $4
+		WHERE
+			id = $1`
+	pgUpdateSize = `
+		UPDATE
+			%s.segments
+		SET
+			refreshed_at = $2,
+			size = (SELECT count(*) FROM %s.segment_members WHERE segment_id = $1)
+		WHERE
+			id = $1
+		RETURNING
+			size`
+
+	pgCountMatches = `SELECT count(*) FROM %s.users u
+		WHERE %s`
+
+	pgDeleteLeft = `
+		DELETE FROM
+			%s.segment_members m
+		WHERE
+			m.segment_id = $1
+			AND NOT EXISTS (
+				SELECT 1 FROM %s.users u
+				WHERE (u.json_data->>'id')::BIGINT = m.user_id
+				AND %s
+			)`
+	pgInsertJoined = `
+		INSERT INTO
+			%s.segment_members(segment_id, user_id, created_at)
+		SELECT
+			$1::BIGINT, ` + pgUserID + `, $2::TIMESTAMP
+		FROM
+			%s.users u
+		WHERE
+			%s
+		ON CONFLICT (segment_id, user_id) DO NOTHING`
+
+	pgClauseIDs = `id IN (?)`
+
+	pgListMembers = `
+		SELECT
+			user_id
+		FROM
+			%s.segment_members
+		WHERE
+			segment_id = $1
+			AND user_id > $2
+		ORDER BY
+			user_id ASC
+		LIMIT
+			$3`
+	pgListSegments = `
+		SELECT
+			definition, id, name, refreshed_at, size, created_at, updated_at
+		FROM
+			%s.segments
+		%s
+		ORDER BY
+			created_at DESC`
+
+	pgCreateSchema       = `CREATE SCHEMA IF NOT EXISTS %s`
+	pgCreateTableMembers = `CREATE TABLE IF NOT EXISTS %s.segment_members(
+		segment_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (segment_id, user_id)
+	)`
+	pgCreateTableSegments = `CREATE TABLE IF NOT EXISTS %s.segments(
+		definition JSONB NOT NULL,
+		id BIGINT NOT NULL UNIQUE,
+		name TEXT NOT NULL,
+		refreshed_at TIMESTAMP NOT NULL,
+		size INT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`
+	pgDropTableMembers  = `DROP TABLE IF EXISTS %s.segment_members`
+	pgDropTableSegments = `DROP TABLE IF EXISTS %s.segments`
+)
+
+type pgService struct {
+	db *sqlx.DB
+}
+
+// PostgresService returns a Postgres based Service implementation.
+func PostgresService(db *sqlx.DB) Service {
+	return &pgService{
+		db: db,
+	}
+}
+
+func (s *pgService) Count(ns string, d Definition) (int, error) {
+	if err := d.Validate(); err != nil {
+		return 0, wrapError(ErrInvalidSegment, "%s", err)
+	}
+
+	where, params, err := compile(ns, d, time.Now(), 0)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		count int
+		query = fmt.Sprintf(pgCountMatches, ns, where)
+	)
+
+	err = s.db.Get(&count, query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.setupSources(ns); err != nil {
+			return 0, err
+		}
+
+		err = s.db.Get(&count, query, params...)
+	}
+
+	return count, err
+}
+
+func (s *pgService) Members(
+	ns string,
+	id uint64,
+	opts MemberOptions,
+) ([]uint64, error) {
+	ids := []uint64{}
+
+	err := s.db.Select(
+		&ids,
+		fmt.Sprintf(pgListMembers, ns),
+		id,
+		opts.After,
+		opts.Limit,
+	)
+	if err != nil {
+		if pg.IsRelationNotFound(pg.WrapError(err)) {
+			return []uint64{}, nil
+		}
+
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (s *pgService) Put(ns string, seg *Segment) (*Segment, error) {
+	if err := seg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if seg.ID == 0 {
+		return s.insert(ns, seg)
+	}
+
+	return s.update(ns, seg)
+}
+
+func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
+	where, params, err := convertOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ss, err := s.listSegments(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		ss, err = s.listSegments(ns, where, params...)
+	}
+
+	return ss, err
+}
+
+func (s *pgService) Refresh(ns string, seg *Segment) (*Segment, error) {
+	err := s.refresh(ns, seg)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.setupSources(ns); err != nil {
+			return nil, err
+		}
+
+		err = s.refresh(ns, seg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return seg, nil
+}
+
+func (s *pgService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateTableSegments, ns),
+		fmt.Sprintf(pgCreateTableMembers, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) Teardown(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgDropTableMembers, ns),
+		fmt.Sprintf(pgDropTableSegments, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("teardown '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) insert(ns string, seg *Segment) (*Segment, error) {
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	seg.ID = id
+	seg.RefreshedAt = time.Time{}
+	seg.Size = 0
+	seg.CreatedAt = ts
+	seg.UpdatedAt = ts
+
+	definition, err := json.Marshal(seg.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		params = []interface{}{
+			definition,
+			seg.ID,
+			seg.Name,
+			seg.RefreshedAt,
+			seg.Size,
+			seg.CreatedAt,
+			seg.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgInsertSegment, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return seg, nil
+}
+
+// refresh brings the cached members in line with the Definition. Users who no
+// longer match are removed and new matches added, members which still match
+// are left untouched.
+func (s *pgService) refresh(ns string, seg *Segment) error {
+	now, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return err
+	}
+
+	left, leftParams, err := compile(ns, seg.Definition, now, 1)
+	if err != nil {
+		return err
+	}
+
+	joined, joinedParams, err := compile(ns, seg.Definition, now, 2)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		fmt.Sprintf(pgDeleteLeft, ns, ns, left),
+		append([]interface{}{seg.ID}, leftParams...)...,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(
+		fmt.Sprintf(pgInsertJoined, ns, ns, joined),
+		append([]interface{}{seg.ID, now}, joinedParams...)...,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	var size int
+
+	err = tx.Get(&size, fmt.Sprintf(pgUpdateSize, ns, ns), seg.ID, now)
+	if err != nil {
+		_ = tx.Rollback()
+
+		if err == sql.ErrNoRows {
+			return wrapError(ErrNotFound, "%d", seg.ID)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	seg.RefreshedAt = now
+	seg.Size = size
+
+	return nil
+}
+
+// setupSources prepares the tables of the namespace a Definition is evaluated
+// against, they are otherwise only created on first write.
+func (s *pgService) setupSources(ns string) error {
+	ss := []interface {
+		Setup(string) error
+	}{
+		connection.PostgresService(s.db),
+		event.PostgresService(s.db),
+		object.PostgresService(s.db),
+		user.PostgresService(s.db),
+		s,
+	}
+
+	for _, source := range ss {
+		if err := source.Setup(ns); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) update(ns string, seg *Segment) (*Segment, error) {
+	now, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	seg.UpdatedAt = now
+
+	definition, err := json.Marshal(seg.Definition)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.db.Exec(
+		fmt.Sprintf(pgUpdateSegment, ns),
+		seg.ID,
+		definition,
+		seg.Name,
+		seg.UpdatedAt,
+	)
+	if err != nil {
+		if pg.IsRelationNotFound(pg.WrapError(err)) {
+			return nil, wrapError(ErrNotFound, "%d", seg.ID)
+		}
+
+		return nil, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, wrapError(ErrNotFound, "%d", seg.ID)
+	}
+
+	return seg, nil
+}
+
+func (s *pgService) listSegments(
+	ns, where string,
+	params ...interface{},
+) (List, error) {
+	query := fmt.Sprintf(pgListSegments, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ss := List{}
+
+	for rows.Next() {
+		var (
+			seg = &Segment{}
+
+			definition = []byte{}
+		)
+
+		err := rows.Scan(
+			&definition,
+			&seg.ID,
+			&seg.Name,
+			&seg.RefreshedAt,
+			&seg.Size,
+			&seg.CreatedAt,
+			&seg.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(definition, &seg.Definition); err != nil {
+			return nil, err
+		}
+
+		seg.RefreshedAt = seg.RefreshedAt.UTC()
+		seg.CreatedAt = seg.CreatedAt.UTC()
+		seg.UpdatedAt = seg.UpdatedAt.UTC()
+
+		ss = append(ss, seg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ss, nil
+}
+
+func convertOpts(opts QueryOptions) (string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+	)
+
+	if len(opts.IDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.IDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	return where, params, nil
+}
@@ -0,0 +1,80 @@
+//go:build integration
+// +build integration
+
+package segment
+
+import (
+	"flag"
+	"fmt"
+	"os/user"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+
+	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/service/event"
+	serviceUser "github.com/tapglue/snaas/service/user"
+)
+
+var pgTestURL string
+
+func TestPostgresCount(t *testing.T) {
+	testServiceCount(t, preparePostgres)
+}
+
+func TestPostgresPut(t *testing.T) {
+	testServicePut(t, preparePostgres)
+}
+
+func TestPostgresQuery(t *testing.T) {
+	testServiceQuery(t, preparePostgres)
+}
+
+func TestPostgresRefresh(t *testing.T) {
+	testServiceRefresh(t, preparePostgres)
+}
+
+func preparePostgres(
+	t *testing.T,
+	namespace string,
+) (Service, serviceUser.Service, event.Service) {
+	db, err := sqlx.Connect("postgres", pgTestURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		s      = PostgresService(db)
+		users  = serviceUser.PostgresService(db)
+		events = event.PostgresService(db)
+	)
+
+	if err := s.Teardown(namespace); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := users.Teardown(namespace); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := events.Teardown(namespace); err != nil {
+		t.Fatal(err)
+	}
+
+	return s, users, events
+}
+
+func init() {
+	user, err := user.Current()
+	if err != nil {
+		panic(err)
+	}
+
+	d := fmt.Sprintf(pg.URLTest, user.Username)
+
+	url := flag.String("postgres.url", d, "Postgres connection URL")
+	flag.Parse()
+
+	pgTestURL = *url
+}
@@ -0,0 +1,273 @@
+package segment
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tapglue/snaas/platform/service"
+)
+
+// Attribute of a user a Condition looks at.
+type Attribute string
+
+// Supported attributes. Field and Metadata compare values of the user itself,
+// Connections, Events and Objects count related entities and LastRead is the
+// time the user last read their notifications.
+const (
+	AttributeConnections Attribute = "connections"
+	AttributeEvents      Attribute = "events"
+	AttributeField       Attribute = "field"
+	AttributeLastRead    Attribute = "last_read"
+	AttributeMetadata    Attribute = "metadata"
+	AttributeObjects     Attribute = "objects"
+)
+
+// Keys of AttributeConnections.
+const (
+	ConnectionsFollowers  = "followers"
+	ConnectionsFollowings = "followings"
+	ConnectionsFriends    = "friends"
+)
+
+// FieldCreatedAt is the only time field of AttributeField.
+const FieldCreatedAt = "created_at"
+
+// Op is the comparison a Condition applies.
+type Op string
+
+// Supported comparisons.
+const (
+	OpEq     Op = "eq"
+	OpExists Op = "exists"
+	OpGt     Op = "gt"
+	OpGte    Op = "gte"
+	OpLt     Op = "lt"
+	OpLte    Op = "lte"
+	OpNeq    Op = "neq"
+)
+
+var (
+	fields = map[string]struct{}{
+		"custom_id":    {},
+		"email":        {},
+		"first_name":   {},
+		"last_name":    {},
+		"timezone":     {},
+		"url":          {},
+		"user_name":    {},
+		FieldCreatedAt: {},
+	}
+	opsCount = map[Op]struct{}{
+		OpEq:  {},
+		OpGt:  {},
+		OpGte: {},
+		OpLt:  {},
+		OpLte: {},
+		OpNeq: {},
+	}
+	opsText = map[Op]struct{}{
+		OpEq:     {},
+		OpExists: {},
+		OpNeq:    {},
+	}
+	opsTime = map[Op]struct{}{
+		OpExists: {},
+		OpGt:     {},
+		OpGte:    {},
+		OpLt:     {},
+		OpLte:    {},
+	}
+)
+
+// Condition is a single criterion users are matched by. Key names the field,
+// metadata key, connection kind or the type of events and objects counted.
+//
+// Text values are compared as given, metadata is compared numerically for
+// ordering Ops. Counts are compared to the integer Value, with a Window only
+// events and objects of the last Window seconds are counted. Times are compared
+// to the moment Window seconds ago: last_read gte with a Window of 604800
+// matches users who read within the last seven days.
+type Condition struct {
+	Attribute Attribute `json:"attribute"`
+	Key       string    `json:"key,omitempty"`
+	Op        Op        `json:"op"`
+	Value     string    `json:"value,omitempty"`
+	Window    int       `json:"window,omitempty"`
+}
+
+// Validate checks for semantic correctness.
+func (c Condition) Validate() error {
+	if c.Window < 0 {
+		return fmt.Errorf("window can't be negative")
+	}
+
+	switch c.Attribute {
+	case AttributeConnections:
+		switch c.Key {
+		case ConnectionsFollowers, ConnectionsFollowings, ConnectionsFriends:
+		default:
+			return fmt.Errorf("unsupported connections key '%s'", c.Key)
+		}
+
+		if c.Window != 0 {
+			return fmt.Errorf("connections can't have a window")
+		}
+
+		return validateCount(c)
+	case AttributeEvents, AttributeObjects:
+		if c.Key == "" {
+			return fmt.Errorf("%s: missing type", c.Attribute)
+		}
+
+		return validateCount(c)
+	case AttributeField:
+		if _, ok := fields[c.Key]; !ok {
+			return fmt.Errorf("unsupported field '%s'", c.Key)
+		}
+
+		if c.Key == FieldCreatedAt {
+			return validateTime(c)
+		}
+
+		return validateText(c)
+	case AttributeLastRead:
+		return validateTime(c)
+	case AttributeMetadata:
+		if c.Key == "" {
+			return fmt.Errorf("metadata: missing key")
+		}
+
+		if _, ok := opsText[c.Op]; ok {
+			return nil
+		}
+
+		if _, ok := opsCount[c.Op]; !ok {
+			return fmt.Errorf("metadata: unsupported op '%s'", c.Op)
+		}
+
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return fmt.Errorf("metadata: value '%s' is not a number", c.Value)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unsupported attribute '%s'", c.Attribute)
+}
+
+// Definition selects the enabled users which match all Conditions of All and,
+// if given, at least one of Any. An empty Definition selects every user.
+type Definition struct {
+	All []Condition `json:"all,omitempty"`
+	Any []Condition `json:"any,omitempty"`
+}
+
+// Validate checks for semantic correctness.
+func (d Definition) Validate() error {
+	for i, c := range d.All {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("all %d: %s", i, err)
+		}
+	}
+
+	for i, c := range d.Any {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("any %d: %s", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Segment is a named Definition together with the state of its cached
+// members. Size is the number of members as of RefreshedAt.
+type Segment struct {
+	Definition  Definition
+	ID          uint64
+	Name        string
+	RefreshedAt time.Time
+	Size        int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Validate checks for semantic correctness.
+func (s *Segment) Validate() error {
+	if s.Name == "" {
+		return wrapError(ErrInvalidSegment, "missing name")
+	}
+
+	if err := s.Definition.Validate(); err != nil {
+		return wrapError(ErrInvalidSegment, "%s", err)
+	}
+
+	return nil
+}
+
+// List is a Segment collection.
+type List []*Segment
+
+// MemberOptions to page through the cached members of a Segment, they are
+// returned in order of their id after the given one.
+type MemberOptions struct {
+	After uint64
+	Limit int
+}
+
+// QueryOptions to narrow-down Segment queries.
+type QueryOptions struct {
+	IDs []uint64
+}
+
+// Service for Segment interactions. Count evaluates a Definition on the spot,
+// Refresh brings the cached members of a Segment up to date by only adding
+// users who joined and removing those who left since the last refresh.
+type Service interface {
+	service.Lifecycle
+
+	Count(namespace string, d Definition) (int, error)
+	Members(namespace string, id uint64, opts MemberOptions) ([]uint64, error)
+	Put(namespace string, s *Segment) (*Segment, error)
+	Query(namespace string, opts QueryOptions) (List, error)
+	Refresh(namespace string, s *Segment) (*Segment, error)
+}
+
+// ServiceMiddleware is a chainable behaviour modifier for Service.
+type ServiceMiddleware func(Service) Service
+
+func flakeNamespace(ns string) string {
+	return fmt.Sprintf("%s_%s", ns, "segments")
+}
+
+func validateCount(c Condition) error {
+	if _, ok := opsCount[c.Op]; !ok {
+		return fmt.Errorf("%s: unsupported op '%s'", c.Attribute, c.Op)
+	}
+
+	if _, err := strconv.ParseUint(c.Value, 10, 32); err != nil {
+		return fmt.Errorf("%s: value '%s' is not a count", c.Attribute, c.Value)
+	}
+
+	return nil
+}
+
+func validateText(c Condition) error {
+	if _, ok := opsText[c.Op]; !ok {
+		return fmt.Errorf("%s: unsupported op '%s'", c.Key, c.Op)
+	}
+
+	return nil
+}
+
+func validateTime(c Condition) error {
+	if _, ok := opsTime[c.Op]; !ok {
+		return fmt.Errorf("%s: unsupported op '%s'", c.Attribute, c.Op)
+	}
+
+	if c.Op != OpExists && c.Window == 0 {
+		return fmt.Errorf("%s: missing window", c.Attribute)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package segment
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	ss := List{
+		{Definition: Definition{}}, // Name missing
+		{Definition: Definition{All: []Condition{{Attribute: "age", Op: OpEq, Value: "30"}}}, Name: "age"},                                                  // Attribute unsupported
+		{Definition: Definition{All: []Condition{{Attribute: AttributeConnections, Key: "enemies", Op: OpEq, Value: "1"}}}, Name: "c"},                      // Connections key unsupported
+		{Definition: Definition{All: []Condition{{Attribute: AttributeConnections, Key: ConnectionsFriends, Op: OpGt, Value: "1", Window: 60}}}, Name: "c"}, // Connections with window
+		{Definition: Definition{All: []Condition{{Attribute: AttributeEvents, Op: OpGte, Value: "3"}}}, Name: "e"},                                          // Events type missing
+		{Definition: Definition{All: []Condition{{Attribute: AttributeEvents, Key: "tg_like", Op: OpExists}}}, Name: "e"},                                   // Events op unsupported
+		{Definition: Definition{All: []Condition{{Attribute: AttributeObjects, Key: "tg_post", Op: OpGte, Value: "-1"}}}, Name: "o"},                        // Objects count invalid
+		{Definition: Definition{All: []Condition{{Attribute: AttributeField, Key: "password", Op: OpExists}}}, Name: "f"},                                   // Field unsupported
+		{Definition: Definition{All: []Condition{{Attribute: AttributeField, Key: "email", Op: OpGt, Value: "a"}}}, Name: "f"},                              // Field op unsupported
+		{Definition: Definition{Any: []Condition{{Attribute: AttributeField, Key: FieldCreatedAt, Op: OpGte}}}, Name: "f"},                                  // Time window missing
+		{Definition: Definition{Any: []Condition{{Attribute: AttributeLastRead, Op: OpEq, Window: 60}}}, Name: "l"},                                         // Time op unsupported
+		{Definition: Definition{Any: []Condition{{Attribute: AttributeLastRead, Op: OpLt, Window: -60}}}, Name: "l"},                                        // Window negative
+		{Definition: Definition{Any: []Condition{{Attribute: AttributeMetadata, Op: OpEq, Value: "pro"}}}, Name: "m"},                                       // Metadata key missing
+		{Definition: Definition{Any: []Condition{{Attribute: AttributeMetadata, Key: "level", Op: OpGt, Value: "high"}}}, Name: "m"},                        // Metadata value not a number
+	}
+
+	for _, s := range ss {
+		if have, want := s.Validate(), ErrInvalidSegment; !IsInvalidSegment(have) {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
@@ -321,6 +321,92 @@ func testServicePutLastRead(t *testing.T, p prepareFunc) {
 	}
 }
 
+func testServiceQueryLastRead(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_query_last_read"
+		service   = p(t, namespace)
+		now       = time.Now().UTC().Truncate(time.Second)
+		reads     = []time.Time{
+			now.Add(-3 * time.Hour),
+			now.Add(-2 * time.Hour),
+			now.Add(-1 * time.Hour),
+		}
+		ids = []uint64{}
+	)
+
+	for _, ts := range reads {
+		created, err := service.Put(namespace, testUser())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := service.PutLastRead(namespace, created.ID, ts); err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, created.ID)
+	}
+
+	list, err := service.Query(namespace, QueryOptions{
+		LastReadAfter:  reads[0],
+		LastReadBefore: reads[1],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(list), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := list[0].ID, ids[1]; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func testServiceQueryAfter(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_query_after"
+		service   = p(t, namespace)
+		ids       = []uint64{}
+	)
+
+	for i := 0; i < 5; i++ {
+		created, err := service.Put(namespace, testUser())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, created.ID)
+	}
+
+	var (
+		opts  = QueryOptions{Limit: 2}
+		paged = []uint64{}
+	)
+
+	for {
+		us, err := service.Query(namespace, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, u := range us {
+			paged = append(paged, u.ID)
+		}
+
+		if len(us) < opts.Limit {
+			break
+		}
+
+		opts.After = us[len(us)-1].ID
+	}
+
+	if have, want := paged, ids; !reflect.DeepEqual(have, want) {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
 func testServicePutUsernameUnique(t *testing.T, p prepareFunc) {
 	var (
 		namespace = "service_put_email"
@@ -61,6 +61,7 @@ func (s *memService) Put(ns string, input *User) (*User, error) {
 			if u.ID == input.ID {
 				keep = true
 				input.CreatedAt = u.CreatedAt
+				input.LastRead = u.LastRead
 			}
 		}
 
@@ -95,6 +96,12 @@ func (s *memService) Query(ns string, opts QueryOptions) (List, error) {
 
 	us := filterList(s.users[ns].ToList(), opts)
 
+	if opts.Limit > 0 {
+		sort.Slice(us, func(i, j int) bool {
+			return us[i].ID < us[j].ID
+		})
+	}
+
 	if opts.Limit > 0 && len(us) > opts.Limit {
 		us = us[:opts.Limit]
 	}
@@ -177,6 +184,10 @@ func filterList(us List, opts QueryOptions) List {
 	rs := List{}
 
 	for _, u := range us {
+		if opts.After > 0 && u.ID <= opts.After {
+			continue
+		}
+
 		if !inTypes(u.CustomID, opts.CustomIDs) {
 			continue
 		}
@@ -197,6 +208,14 @@ func filterList(us List, opts QueryOptions) List {
 			continue
 		}
 
+		if !opts.LastReadAfter.IsZero() && !u.LastRead.After(opts.LastReadAfter) {
+			continue
+		}
+
+		if !opts.LastReadBefore.IsZero() && u.LastRead.After(opts.LastReadBefore) {
+			continue
+		}
+
 		if opts.SocialIDs != nil {
 			keep := false
 
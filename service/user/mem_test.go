@@ -14,6 +14,14 @@ func TestMemPutLastRead(t *testing.T) {
 	testServicePutLastRead(t, prepareMem)
 }
 
+func TestMemQueryAfter(t *testing.T) {
+	testServiceQueryAfter(t, prepareMem)
+}
+
+func TestMemQueryLastRead(t *testing.T) {
+	testServiceQueryLastRead(t, prepareMem)
+}
+
 func TestMemSearch(t *testing.T) {
 	testServiceSearch(t, prepareMem)
 }
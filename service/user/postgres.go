@@ -11,6 +11,7 @@ import (
 	serr "github.com/tapglue/snaas/error"
 	"github.com/tapglue/snaas/platform/flake"
 	"github.com/tapglue/snaas/platform/pg"
+	"github.com/tapglue/snaas/platform/source"
 )
 
 const limitDefault = 200
@@ -33,14 +34,17 @@ const (
 		WHERE
 			(json_data->>'id')::BIGINT = $2::BIGINT`
 
-	pgClauseBefore    = `(json_data->>'id')::BIGINT > ?`
-	pgClauseCustomIDs = `(json_data->>'custom_id')::TEXT IN (?)`
-	pgClauseDeleted   = `(json_data->>'deleted')::BOOL = ?::BOOL`
-	pgClauseEmail     = `(json_data->>'email')::CITEXT IN (?)`
-	pgClauseEnabled   = `(json_data->>'enabled')::BOOL = ?::BOOL`
-	pgClauseIDs       = `(json_data->>'id')::BIGINT IN (?)`
-	pgClauseSocialIDs = `(json_data->'social_ids'->>'%s')::TEXT IN (?)`
-	pgClauseUsernames = `(json_data->>'user_name')::CITEXT IN (?)`
+	pgClauseAfter          = `(json_data->>'id')::BIGINT > ?`
+	pgClauseBefore         = `(json_data->>'id')::BIGINT > ?`
+	pgClauseCustomIDs      = `(json_data->>'custom_id')::TEXT IN (?)`
+	pgClauseDeleted        = `(json_data->>'deleted')::BOOL = ?::BOOL`
+	pgClauseEmail          = `(json_data->>'email')::CITEXT IN (?)`
+	pgClauseEnabled        = `(json_data->>'enabled')::BOOL = ?::BOOL`
+	pgClauseIDs            = `(json_data->>'id')::BIGINT IN (?)`
+	pgClauseLastReadAfter  = `last_read > ?`
+	pgClauseLastReadBefore = `last_read <= ?`
+	pgClauseSocialIDs      = `(json_data->'social_ids'->>'%s')::TEXT IN (?)`
+	pgClauseUsernames      = `(json_data->>'user_name')::CITEXT IN (?)`
 
 	pgClauseSearchEmail     = `(json_data->>'email')::TEXT ILIKE '%%%s%%'`
 	pgClauseSearchFirstname = `(json_data->>'first_name')::TEXT ILIKE '%%%s%%'`
@@ -50,6 +54,7 @@ const (
 
 	pgOrderCreatedAt = `json_data->>'created_at' DESC`
 	pgOrderFirstname = `json_data->>'first_name' ASC`
+	pgOrderID        = `(json_data->>'id')::BIGINT ASC`
 	pgOrderLastname  = `json_data->>'first_naem' ASC`
 	pgOrderUsername  = `json_data->>'user_name' ASC`
 
@@ -116,7 +121,8 @@ const (
 )
 
 type pgService struct {
-	db *sqlx.DB
+	db     *sqlx.DB
+	outbox *source.Outbox
 }
 
 // PostgresService returns a Postgres based Service implementation.
@@ -124,6 +130,16 @@ func PostgresService(db *sqlx.DB) Service {
 	return &pgService{db: db}
 }
 
+// PostgresOutboxService returns a Postgres based Service implementation which
+// stores the state changes of every Put in the outbox of the Entity as part of
+// the same transaction.
+func PostgresOutboxService(db *sqlx.DB) Service {
+	return &pgService{
+		db:     db,
+		outbox: source.NewOutbox(db, Entity),
+	}
+}
+
 func (s *pgService) Count(ns string, opts QueryOptions) (int, error) {
 	where, params, err := convertOpts(opts)
 	if err != nil {
@@ -145,6 +161,8 @@ func (s *pgService) Put(ns string, user *User) (*User, error) {
 		return nil, err
 	}
 
+	var old *User
+
 	if user.ID != 0 {
 		params = []interface{}{
 			user.ID,
@@ -163,7 +181,8 @@ func (s *pgService) Put(ns string, user *User) (*User, error) {
 			return nil, ErrNotFound
 		}
 
-		user.CreatedAt = us[0].CreatedAt
+		old = us[0]
+		user.CreatedAt = old.CreatedAt
 	} else {
 		id, err := flake.NextID(flakeNamespace(ns))
 		if err != nil {
@@ -188,14 +207,14 @@ func (s *pgService) Put(ns string, user *User) (*User, error) {
 
 	params = append([]interface{}{data}, params...)
 
-	_, err = s.db.Exec(wrapNamespace(query, ns), params...)
+	err = s.exec(ns, old, user, wrapNamespace(query, ns), params...)
 	if err != nil {
 		if pg.IsRelationNotFound(pg.WrapError(err)) {
 			if err := s.Setup(ns); err != nil {
 				return nil, err
 			}
 
-			_, err = s.db.Exec(wrapNamespace(query, ns), params...)
+			err = s.exec(ns, old, user, wrapNamespace(query, ns), params...)
 		}
 
 		if pg.IsNotUnique(pg.WrapError(err)) {
@@ -214,7 +233,7 @@ func (s *pgService) PutLastRead(ns string, userID uint64, ts time.Time) error {
 				return err
 			}
 
-			_, err = s.db.Exec(pgUpdateLastRead, userID, ts.UTC())
+			_, err = s.db.Exec(wrapNamespace(pgUpdateLastRead, ns), userID, ts.UTC())
 		}
 	}
 	return err
@@ -256,6 +275,10 @@ func (s *pgService) Setup(ns string) error {
 		}
 	}
 
+	if s.outbox != nil {
+		return s.outbox.Setup(ns)
+	}
+
 	return nil
 }
 
@@ -271,9 +294,28 @@ func (s *pgService) Teardown(ns string) error {
 		}
 	}
 
+	if s.outbox != nil {
+		return s.outbox.Teardown(ns)
+	}
+
 	return nil
 }
 
+func (s *pgService) exec(
+	ns string,
+	old, new *User,
+	query string,
+	params ...interface{},
+) error {
+	if s.outbox == nil {
+		_, err := s.db.Exec(query, params...)
+		return err
+	}
+
+	// Secrets are stripped as state changes leave the service boundary.
+	return s.outbox.Exec(ns, sanitize(old), sanitize(new), query, params...)
+}
+
 func (s *pgService) countUsers(
 	ns, where string,
 	params ...interface{},
@@ -354,6 +396,11 @@ func convertOpts(opts QueryOptions) (string, []interface{}, error) {
 		params  = []interface{}{}
 	)
 
+	if opts.After > 0 {
+		clauses = append(clauses, pgClauseAfter)
+		params = append(params, opts.After)
+	}
+
 	if opts.Before > 0 {
 		clauses = append(clauses, pgClauseBefore)
 		params = append(params, opts.Before)
@@ -427,6 +474,16 @@ func convertOpts(opts QueryOptions) (string, []interface{}, error) {
 		params = append(params, ps...)
 	}
 
+	if !opts.LastReadAfter.IsZero() {
+		clauses = append(clauses, pgClauseLastReadAfter)
+		params = append(params, opts.LastReadAfter.UTC())
+	}
+
+	if !opts.LastReadBefore.IsZero() {
+		clauses = append(clauses, pgClauseLastReadBefore)
+		params = append(params, opts.LastReadBefore.UTC())
+	}
+
 	if opts.SocialIDs != nil {
 		for platform, ids := range opts.SocialIDs {
 			ps := []interface{}{}
@@ -477,6 +534,8 @@ func convertOpts(opts QueryOptions) (string, []interface{}, error) {
 				pgOrderLastname,
 			}, ",\n"),
 		)
+	} else if opts.Limit > 0 {
+		query = fmt.Sprintf("%s\nORDER BY %s\n", query, pgOrderID)
 	}
 
 	if opts.Limit > 0 {
@@ -38,6 +38,14 @@ func TestPostgresQuery(t *testing.T) {
 	testServiceQuery(t, preparePostgres)
 }
 
+func TestPostgresQueryAfter(t *testing.T) {
+	testServiceQueryAfter(t, preparePostgres)
+}
+
+func TestPostgresQueryLastRead(t *testing.T) {
+	testServiceQueryLastRead(t, preparePostgres)
+}
+
 func TestPostgresSearch(t *testing.T) {
 	testServiceSearch(t, preparePostgres)
 }
@@ -0,0 +1,35 @@
+package user
+
+import "github.com/tapglue/snaas/platform/source"
+
+// Entity declares User state changes to the source framework. Only ids of
+// users are logged as they carry personal data.
+var Entity = source.Entity{
+	Name:    serviceName,
+	Outbox:  "user_outbox",
+	Payload: func() interface{} { return &User{} },
+	Queue:   "user-state-change",
+	Redact: func(payload interface{}) interface{} {
+		u, ok := payload.(*User)
+		if !ok || u == nil {
+			return nil
+		}
+
+		return u.ID
+	},
+}
+
+// ChangeFrom returns the User view on a decoded state change.
+func ChangeFrom(c *source.Change) *StateChange {
+	new, _ := c.New.(*User)
+	old, _ := c.Old.(*User)
+
+	return &StateChange{
+		AckID:     c.AckID,
+		ID:        c.ID,
+		Namespace: c.Namespace,
+		New:       new,
+		Old:       old,
+		SentAt:    c.SentAt,
+	}
+}
@@ -0,0 +1,21 @@
+package user
+
+import "testing"
+
+func TestEntityRedact(t *testing.T) {
+	var u *User
+
+	if have := Entity.Redact(u); have != nil {
+		t.Errorf("have %v, want %v", have, nil)
+	}
+
+	have := Entity.Redact(&User{
+		Email:    "alice@tapglue.test",
+		ID:       123,
+		Password: "hash",
+	})
+
+	if want := uint64(123); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
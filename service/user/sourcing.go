@@ -0,0 +1,91 @@
+package user
+
+import (
+	"time"
+
+	"github.com/tapglue/snaas/platform/source"
+)
+
+type sourcingService struct {
+	producer source.Producer
+	service  Service
+}
+
+// SourcingServiceMiddleware propagates state changes for the Service via the
+// given Producer. A failed propagation is reported as error of the operation.
+func SourcingServiceMiddleware(producer source.Producer) ServiceMiddleware {
+	return func(service Service) Service {
+		return &sourcingService{
+			service:  service,
+			producer: producer,
+		}
+	}
+}
+
+func (s *sourcingService) Count(ns string, opts QueryOptions) (int, error) {
+	return s.service.Count(ns, opts)
+}
+
+func (s *sourcingService) Put(ns string, input *User) (new *User, err error) {
+	var old *User
+
+	// Secrets are stripped as state changes leave the service boundary.
+	defer func() {
+		if err == nil {
+			_, err = s.producer.Propagate(ns, sanitize(old), sanitize(new))
+		}
+	}()
+
+	if input.ID != 0 {
+		us, err := s.service.Query(ns, QueryOptions{
+			IDs: []uint64{
+				input.ID,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(us) == 1 {
+			old = us[0]
+		}
+	}
+
+	return s.service.Put(ns, input)
+}
+
+func (s *sourcingService) PutLastRead(
+	ns string,
+	userID uint64,
+	lastRead time.Time,
+) error {
+	return s.service.PutLastRead(ns, userID, lastRead)
+}
+
+func (s *sourcingService) Query(ns string, opts QueryOptions) (List, error) {
+	return s.service.Query(ns, opts)
+}
+
+func (s *sourcingService) Search(ns string, opts QueryOptions) (List, error) {
+	return s.service.Search(ns, opts)
+}
+
+func (s *sourcingService) Setup(ns string) error {
+	return s.service.Setup(ns)
+}
+
+func (s *sourcingService) Teardown(ns string) error {
+	return s.service.Teardown(ns)
+}
+
+func sanitize(u *User) *User {
+	if u == nil {
+		return nil
+	}
+
+	c := *u
+	c.Password = ""
+	c.SessionToken = ""
+
+	return &c
+}
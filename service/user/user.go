@@ -7,6 +7,7 @@ import (
 
 	"github.com/asaskevich/govalidator"
 
+	"github.com/tapglue/snaas/platform/quiet"
 	"github.com/tapglue/snaas/platform/service"
 )
 
@@ -83,21 +84,26 @@ type Private struct {
 	Verified bool   `json:"verified"`
 }
 
-// QueryOptions is used to narrow-down user queries.
+// QueryOptions is used to narrow-down user queries. Options only meaningful to
+// queries and searches are not exposed as criteria. Queries with a Limit but
+// without Before are ordered by id, After continues them past the given id.
 type QueryOptions struct {
-	Before     uint64
-	CustomIDs  []string
-	Deleted    *bool
-	Emails     []string
-	Firstnames []string
-	Enabled    *bool
-	IDs        []uint64
-	Lastnames  []string
-	Limit      int
-	Offset     uint
-	Query      string
-	SocialIDs  map[string][]string
-	Usernames  []string
+	After          uint64              `json:"-"`
+	Before         uint64              `json:"-"`
+	CustomIDs      []string            `json:"custom_ids,omitempty"`
+	Deleted        *bool               `json:"deleted,omitempty"`
+	Emails         []string            `json:"emails,omitempty"`
+	Firstnames     []string            `json:"-"`
+	Enabled        *bool               `json:"enabled,omitempty"`
+	IDs            []uint64            `json:"ids,omitempty"`
+	LastReadAfter  time.Time           `json:"-"`
+	LastReadBefore time.Time           `json:"-"`
+	Lastnames      []string            `json:"-"`
+	Limit          int                 `json:"-"`
+	Offset         uint                `json:"-"`
+	Query          string              `json:"-"`
+	SocialIDs      map[string][]string `json:"social_ids,omitempty"`
+	Usernames      []string            `json:"usernames,omitempty"`
 }
 
 // Service for user interactions.
@@ -114,6 +120,16 @@ type Service interface {
 // ServiceMiddleware is a chainable behaviour modifier for Service.
 type ServiceMiddleware func(Service) Service
 
+// StateChange transports all information necessary to observe state changes.
+type StateChange struct {
+	AckID     string
+	ID        string
+	Namespace string
+	New       *User
+	Old       *User
+	SentAt    time.Time
+}
+
 // User is the representation of a customer of an app.
 type User struct {
 	About          string            `json:"about"`
@@ -133,16 +149,57 @@ type User struct {
 	Lastname       string            `json:"last_name"`
 	LastRead       time.Time         `json:"-"`
 	Metadata       Metadata          `json:"metadata"`
-	Password       string            `json:"password"`
+	Password       string            `json:"password" expr:"-"`
 	Private        *Private          `json:"private,omitempty"`
+	QuietHours     *quiet.Hours      `json:"quiet_hours,omitempty"`
 	SessionToken   string            `json:"-"`
 	SocialIDs      map[string]string `json:"social_ids,omitempty"`
+	Timezone       string            `json:"timezone,omitempty"`
 	URL            string            `json:"url,omitempty"`
 	Username       string            `json:"user_name,omitempty"`
 	CreatedAt      time.Time         `json:"created_at"`
 	UpdatedAt      time.Time         `json:"updated_at"`
 }
 
+// MatchOpts indicates if the User matches the given QueryOptions.
+func (u *User) MatchOpts(opts *QueryOptions) bool {
+	if opts == nil {
+		return true
+	}
+
+	if !inTypes(u.CustomID, opts.CustomIDs) {
+		return false
+	}
+
+	if opts.Deleted != nil && u.Deleted != *opts.Deleted {
+		return false
+	}
+
+	if !inTypes(u.Email, opts.Emails) {
+		return false
+	}
+
+	if opts.Enabled != nil && u.Enabled != *opts.Enabled {
+		return false
+	}
+
+	if !inIDs(u.ID, opts.IDs) {
+		return false
+	}
+
+	for platform, ids := range opts.SocialIDs {
+		if !inTypes(u.SocialIDs[platform], ids) {
+			return false
+		}
+	}
+
+	if !inTypes(u.Username, opts.Usernames) {
+		return false
+	}
+
+	return true
+}
+
 // Validate performs semantic checks on the passed User values for correctness.
 func (u *User) Validate() error {
 	if u.Email == "" && u.Username == "" {
@@ -171,6 +228,16 @@ func (u *User) Validate() error {
 		}
 	}
 
+	if u.QuietHours != nil {
+		if err := u.QuietHours.Validate(); err != nil {
+			return wrapError(ErrInvalidUser, "%s", err)
+		}
+	}
+
+	if _, err := quiet.Location(u.Timezone); err != nil {
+		return wrapError(ErrInvalidUser, "%s", err)
+	}
+
 	if ok := govalidator.IsURL(u.URL); u.URL != "" && !ok {
 		return wrapError(ErrInvalidUser, "invalid url")
 	}
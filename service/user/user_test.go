@@ -31,3 +31,36 @@ func TestValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchOpts(t *testing.T) {
+	var (
+		enabled = true
+		u       = &User{
+			Email:     validEmail,
+			Enabled:   enabled,
+			ID:        42,
+			SocialIDs: map[string]string{"facebook": "fb42"},
+			Username:  "alice",
+		}
+	)
+
+	cases := []struct {
+		opts *QueryOptions
+		want bool
+	}{
+		{nil, true},
+		{&QueryOptions{Enabled: &enabled, IDs: []uint64{42}}, true},
+		{&QueryOptions{IDs: []uint64{43}}, false},
+		{&QueryOptions{Emails: []string{validEmail}}, true},
+		{&QueryOptions{Emails: []string{"other@tp.gl"}}, false},
+		{&QueryOptions{SocialIDs: map[string][]string{"facebook": {"fb42"}}}, true},
+		{&QueryOptions{SocialIDs: map[string][]string{"twitter": {"fb42"}}}, false},
+		{&QueryOptions{Usernames: []string{"bob"}}, false},
+	}
+
+	for _, c := range cases {
+		if have, want := u.MatchOpts(c.opts), c.want; have != want {
+			t.Errorf("have %v, want %v for %#v", have, want, c.opts)
+		}
+	}
+}
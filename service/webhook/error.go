@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+)
+
+const errFmt = "%s: %s"
+
+// Common errors for Webhook service implementations and validations.
+var (
+	ErrInvalidDelivery = errors.New("invalid delivery")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+)
+
+// Error wrapper.
+type Error struct {
+	err error
+	msg string
+}
+
+func (e Error) Error() string {
+	return e.msg
+}
+
+// IsInvalidDelivery indicates if err is ErrInvalidDelivery.
+func IsInvalidDelivery(err error) bool {
+	return unwrapError(err) == ErrInvalidDelivery
+}
+
+// IsInvalidWebhook indicates if err is ErrInvalidWebhook.
+func IsInvalidWebhook(err error) bool {
+	return unwrapError(err) == ErrInvalidWebhook
+}
+
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case *Error:
+		return e.err
+	}
+
+	return err
+}
+
+func wrapError(err error, format string, args ...interface{}) error {
+	return &Error{
+		err: err,
+		msg: fmt.Sprintf(
+			errFmt,
+			err.Error(),
+			fmt.Sprintf(format, args...),
+		),
+	}
+}
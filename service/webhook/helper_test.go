@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/rule"
+)
+
+type prepareFunc func(t *testing.T, namespace string) Service
+
+type prepareDeliveryFunc func(t *testing.T, namespace string) DeliveryService
+
+func testServicePut(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_put"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testWebhook())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := service.Query(namespace, QueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ws), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	w := ws[0]
+
+	if have, want := w.URL, created.URL; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if have, want := len(w.Subscriptions), 2; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if _, ok := w.Subscriptions[0].Criteria.(*rule.CriteriaObject); !ok {
+		t.Errorf("have %T, want %T", w.Subscriptions[0].Criteria, &rule.CriteriaObject{})
+	}
+
+	if w.Subscriptions[1].Criteria != nil {
+		t.Errorf("have %v, want %v", w.Subscriptions[1].Criteria, nil)
+	}
+
+	w.Active = true
+
+	updated, err := service.Put(namespace, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := true
+
+	ws, err = service.Query(namespace, QueryOptions{
+		Active: &active,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ws), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := ws[0].ID, updated.ID; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if _, err := service.Put(namespace, &Webhook{}); !IsInvalidWebhook(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidWebhook)
+	}
+}
+
+func testServiceQuery(t *testing.T, p prepareFunc) {
+	var (
+		namespace = "service_query"
+		service   = p(t, namespace)
+		deleted   = true
+	)
+
+	for i := 0; i < 3; i++ {
+		w := testWebhook()
+		w.Deleted = i == 0
+
+		_, err := service.Put(namespace, w)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[*QueryOptions]int{
+		&QueryOptions{}:                  3,
+		&QueryOptions{Deleted: &deleted}: 1,
+	}
+
+	for opts, want := range cases {
+		ws, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := len(ws); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func testDeliveryServicePut(t *testing.T, p prepareDeliveryFunc) {
+	var (
+		namespace = "service_put"
+		service   = p(t, namespace)
+	)
+
+	created, err := service.Put(namespace, testDelivery(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.NextAttemptAt.IsZero() {
+		t.Error("expected next attempt to be set")
+	}
+
+	created.Attempts = 1
+	created.State = StateSucceeded
+	created.StatusCode = 204
+
+	_, err = service.Put(namespace, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := service.Query(namespace, DeliveryQueryOptions{
+		IDs: []uint64{
+			created.ID,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := len(ds), 1; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+
+	if have, want := ds[0].State, StateSucceeded; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := ds[0].StatusCode, 204; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if _, err := service.Put(namespace, &Delivery{}); !IsInvalidDelivery(err) {
+		t.Errorf("have %v, want %v", err, ErrInvalidDelivery)
+	}
+}
+
+func testDeliveryServiceQuery(t *testing.T, p prepareDeliveryFunc) {
+	var (
+		namespace = "service_query"
+		service   = p(t, namespace)
+	)
+
+	for i := 0; i < 4; i++ {
+		d := testDelivery(uint64(i%2 + 1))
+
+		if i == 3 {
+			d.NextAttemptAt = time.Now().Add(time.Hour)
+		}
+
+		_, err := service.Put(namespace, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[*DeliveryQueryOptions]int{
+		&DeliveryQueryOptions{}:                                 4,
+		&DeliveryQueryOptions{Due: time.Now().Add(time.Minute)}: 3,
+		&DeliveryQueryOptions{Limit: 1}:                         1,
+		&DeliveryQueryOptions{States: []State{StateFailed}}:     0,
+		&DeliveryQueryOptions{WebhookIDs: []uint64{2}}:          2,
+	}
+
+	for opts, want := range cases {
+		ds, err := service.Query(namespace, *opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have := len(ds); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func testDelivery(webhookID uint64) *Delivery {
+	return &Delivery{
+		AppID:     1,
+		Event:     "object",
+		Payload:   []byte(`{"type":"object"}`),
+		State:     StatePending,
+		WebhookID: webhookID,
+	}
+}
+
+func testWebhook() *Webhook {
+	return &Webhook{
+		Name:   "backend",
+		Secret: "s3cr3t",
+		Subscriptions: Subscriptions{
+			{
+				Criteria: &rule.CriteriaObject{
+					New: &object.QueryOptions{
+						Types: []string{
+							"tg_post",
+						},
+					},
+				},
+				Type: rule.TypeObject,
+			},
+			{
+				Type: rule.TypeConnection,
+			},
+		},
+		URL: "https://example.com/hooks",
+	}
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Headers sent along with every delivery.
+const (
+	HeaderDelivery  = "X-Tapglue-Delivery"
+	HeaderEvent     = "X-Tapglue-Event"
+	HeaderSignature = "X-Tapglue-Signature"
+)
+
+// Client returns an http.Client for deliveries. Webhook URLs are controlled by
+// apps, so connections to loopback, private, link-local and unspecified
+// addresses are refused after the host is resolved and redirects are not
+// followed.
+func Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Control:   dialControl,
+		KeepAlive: 30 * time.Second,
+		Timeout:   timeout,
+	}
+
+	return &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+}
+
+// PublicIP reports if ip can be addressed by deliveries.
+func PublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsUnspecified()
+}
+
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+		return fmt.Errorf("address %s not allowed", address)
+	}
+
+	return nil
+}
+
+// PostFunc hands the payload of the Delivery to the Webhook and returns the
+// status code of the response. Non-2xx responses are reported as error.
+type PostFunc func(w *Webhook, d *Delivery) (int, error)
+
+// Post returns a PostFunc which sends the payload as JSON signed with the
+// secret of the Webhook.
+func Post(client *http.Client) PostFunc {
+	return func(w *Webhook, d *Delivery) (int, error) {
+		req, err := http.NewRequest("POST", w.URL, bytes.NewReader(d.Payload))
+		if err != nil {
+			return 0, err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(HeaderDelivery, strconv.FormatUint(d.ID, 10))
+		req.Header.Set(HeaderEvent, d.Event)
+		req.Header.Set(HeaderSignature, Sign(w.Secret, time.Now(), d.Payload))
+
+		res, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer res.Body.Close()
+
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+		}
+
+		return res.StatusCode, nil
+	}
+}
+
+// Sign returns the signature header for the body sent at the given time, e.g.
+// t=1492000000,sha256=5d5d13... The HMAC-SHA256 keyed with the secret covers
+// the unix timestamp and the body joined by a dot, receivers recompute it and
+// reject deliveries whose timestamp is too old to prevent replays.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(t + "."))
+	_, _ = mac.Write(body)
+
+	return fmt.Sprintf("t=%s,sha256=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPost(t *testing.T) {
+	var (
+		d = testDelivery(1)
+		w = testWebhook()
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sig := r.Header.Get(HeaderSignature)
+
+		ts, err := strconv.ParseInt(strings.TrimPrefix(strings.SplitN(sig, ",", 2)[0], "t="), 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := sig, Sign(w.Secret, time.Unix(ts, 0), body); have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+		if have, want := time.Since(time.Unix(ts, 0)) < time.Minute, true; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+		if have, want := r.Header.Get(HeaderEvent), d.Event; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w.URL = srv.URL
+
+	code, err := Post(http.DefaultClient)(w, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := code, http.StatusNoContent; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestPostFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	w := testWebhook()
+	w.URL = srv.URL
+
+	code, err := Post(http.DefaultClient)(w, testDelivery(1))
+	if err == nil {
+		t.Error("expected error")
+	}
+
+	if have, want := code, http.StatusInternalServerError; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestClient(t *testing.T) {
+	called := false
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	w := testWebhook()
+	w.URL = srv.URL
+
+	_, err := Post(Client(time.Second))(w, testDelivery(1))
+	if err == nil {
+		t.Error("expected error")
+	}
+
+	if called {
+		t.Error("expected loopback not to be dialed")
+	}
+
+	req, err := http.NewRequest("GET", "https://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Client(time.Second).CheckRedirect(req, []*http.Request{req})
+	if have, want := err, http.ErrUseLastResponse; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for ip, public := range map[string]bool{
+		"0.0.0.0":         false,
+		"10.1.2.3":        false,
+		"127.0.0.1":       false,
+		"169.254.169.254": false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"::":              false,
+		"::1":             false,
+		"::ffff:10.0.0.1": false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"8.8.8.8":         true,
+		"2001:4860::8888": true,
+	} {
+		if have, want := PublicIP(net.ParseIP(ip)), public; have != want {
+			t.Errorf("%s: have %v, want %v", ip, have, want)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Reference computed with:
+	// echo -n '1492000000.{}' | openssl dgst -sha256 -hmac secret
+	want := "t=1492000000,sha256=11654719291dc14fa129e5274c83127d3298ece4c5679771054b975b2fee54e1"
+
+	if have := Sign("secret", time.Unix(1492000000, 0), []byte("{}")); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
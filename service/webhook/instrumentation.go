@@ -0,0 +1,214 @@
+package webhook
+
+import (
+	"time"
+
+	kitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/tapglue/snaas/platform/metrics"
+)
+
+const (
+	serviceName         = "webhook"
+	serviceNameDelivery = "webhook_delivery"
+)
+
+type instrumentService struct {
+	component string
+	errCount  kitmetrics.Counter
+	opCount   kitmetrics.Counter
+	opLatency *prometheus.HistogramVec
+	next      Service
+	store     string
+}
+
+// InstrumentServiceMiddleware observes key aspects of Service operations and
+// exposes Prometheus metrics.
+func InstrumentServiceMiddleware(
+	component, store string,
+	errCount kitmetrics.Counter,
+	opCount kitmetrics.Counter,
+	opLatency *prometheus.HistogramVec,
+) ServiceMiddleware {
+	return func(next Service) Service {
+		return &instrumentService{
+			component: component,
+			errCount:  errCount,
+			opCount:   opCount,
+			opLatency: opLatency,
+			next:      next,
+			store:     store,
+		}
+	}
+}
+
+func (s *instrumentService) Put(
+	ns string,
+	input *Webhook,
+) (output *Webhook, err error) {
+	defer func(begin time.Time) {
+		s.track("Put", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Put(ns, input)
+}
+
+func (s *instrumentService) Query(
+	ns string,
+	opts QueryOptions,
+) (list List, err error) {
+	defer func(begin time.Time) {
+		s.track("Query", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Query(ns, opts)
+}
+
+func (s *instrumentService) Setup(ns string) (err error) {
+	defer func(begin time.Time) {
+		s.track("Setup", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Setup(ns)
+}
+
+func (s *instrumentService) Teardown(ns string) (err error) {
+	defer func(begin time.Time) {
+		s.track("Teardown", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Teardown(ns)
+}
+
+func (s *instrumentService) track(
+	method, namespace string,
+	begin time.Time,
+	err error,
+) {
+	track(
+		s.component, serviceName, s.store,
+		s.errCount, s.opCount, s.opLatency,
+		method, namespace,
+		begin,
+		err,
+	)
+}
+
+type instrumentDeliveryService struct {
+	component string
+	errCount  kitmetrics.Counter
+	opCount   kitmetrics.Counter
+	opLatency *prometheus.HistogramVec
+	next      DeliveryService
+	store     string
+}
+
+// InstrumentDeliveryServiceMiddleware observes key aspects of DeliveryService
+// operations and exposes Prometheus metrics.
+func InstrumentDeliveryServiceMiddleware(
+	component, store string,
+	errCount kitmetrics.Counter,
+	opCount kitmetrics.Counter,
+	opLatency *prometheus.HistogramVec,
+) DeliveryServiceMiddleware {
+	return func(next DeliveryService) DeliveryService {
+		return &instrumentDeliveryService{
+			component: component,
+			errCount:  errCount,
+			opCount:   opCount,
+			opLatency: opLatency,
+			next:      next,
+			store:     store,
+		}
+	}
+}
+
+func (s *instrumentDeliveryService) Put(
+	ns string,
+	input *Delivery,
+) (output *Delivery, err error) {
+	defer func(begin time.Time) {
+		s.track("Put", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Put(ns, input)
+}
+
+func (s *instrumentDeliveryService) Query(
+	ns string,
+	opts DeliveryQueryOptions,
+) (list Deliveries, err error) {
+	defer func(begin time.Time) {
+		s.track("Query", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Query(ns, opts)
+}
+
+func (s *instrumentDeliveryService) Setup(ns string) (err error) {
+	defer func(begin time.Time) {
+		s.track("Setup", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Setup(ns)
+}
+
+func (s *instrumentDeliveryService) Teardown(ns string) (err error) {
+	defer func(begin time.Time) {
+		s.track("Teardown", ns, begin, err)
+	}(time.Now())
+
+	return s.next.Teardown(ns)
+}
+
+func (s *instrumentDeliveryService) track(
+	method, namespace string,
+	begin time.Time,
+	err error,
+) {
+	track(
+		s.component, serviceNameDelivery, s.store,
+		s.errCount, s.opCount, s.opLatency,
+		method, namespace,
+		begin,
+		err,
+	)
+}
+
+func track(
+	component, service, store string,
+	errCount, opCount kitmetrics.Counter,
+	opLatency *prometheus.HistogramVec,
+	method, namespace string,
+	begin time.Time,
+	err error,
+) {
+	if err != nil {
+		errCount.With(
+			metrics.FieldComponent, component,
+			metrics.FieldMethod, method,
+			metrics.FieldNamespace, namespace,
+			metrics.FieldService, service,
+			metrics.FieldStore, store,
+		).Add(1)
+
+		return
+	}
+
+	opCount.With(
+		metrics.FieldComponent, component,
+		metrics.FieldMethod, method,
+		metrics.FieldNamespace, namespace,
+		metrics.FieldService, service,
+		metrics.FieldStore, store,
+	).Add(1)
+
+	opLatency.With(prometheus.Labels{
+		metrics.FieldComponent: component,
+		metrics.FieldMethod:    method,
+		metrics.FieldNamespace: namespace,
+		metrics.FieldService:   service,
+		metrics.FieldStore:     store,
+	}).Observe(time.Since(begin).Seconds())
+}
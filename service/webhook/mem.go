@@ -0,0 +1,268 @@
+package webhook
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tapglue/snaas/platform/flake"
+)
+
+type memService struct {
+	sync.Mutex
+
+	webhooks map[string]map[uint64]*Webhook
+}
+
+// MemService returns a memory backed implementation of Service.
+func MemService() Service {
+	return &memService{
+		webhooks: map[string]map[uint64]*Webhook{},
+	}
+}
+
+func (s *memService) Put(ns string, w *Webhook) (*Webhook, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.webhooks[ns]; !ok {
+		s.webhooks[ns] = map[uint64]*Webhook{}
+	}
+
+	now := time.Now().UTC()
+
+	if w.ID == 0 {
+		id, err := flake.NextID(flakeNamespace(ns))
+		if err != nil {
+			return nil, err
+		}
+
+		w.ID = id
+		w.CreatedAt = now
+	} else {
+		old, ok := s.webhooks[ns][w.ID]
+		if !ok {
+			return nil, wrapError(ErrInvalidWebhook, "%d not found", w.ID)
+		}
+
+		w.CreatedAt = old.CreatedAt
+	}
+
+	w.UpdatedAt = now
+
+	s.webhooks[ns][w.ID] = copyWebhook(w)
+
+	return copyWebhook(w), nil
+}
+
+func (s *memService) Query(ns string, opts QueryOptions) (List, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	ws := List{}
+
+	for _, w := range s.webhooks[ns] {
+		if opts.Active != nil && w.Active != *opts.Active {
+			continue
+		}
+
+		if opts.Deleted != nil && w.Deleted != *opts.Deleted {
+			continue
+		}
+
+		if !inIDs(w.ID, opts.IDs) {
+			continue
+		}
+
+		ws = append(ws, copyWebhook(w))
+	}
+
+	sort.Sort(sort.Reverse(ws))
+
+	return ws, nil
+}
+
+func (s *memService) Setup(ns string) error {
+	return nil
+}
+
+func (s *memService) Teardown(ns string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.webhooks, ns)
+
+	return nil
+}
+
+type memDeliveryService struct {
+	sync.Mutex
+
+	deliveries map[string]map[uint64]*Delivery
+}
+
+// MemDeliveryService returns a memory backed implementation of
+// DeliveryService.
+func MemDeliveryService() DeliveryService {
+	return &memDeliveryService{
+		deliveries: map[string]map[uint64]*Delivery{},
+	}
+}
+
+func (s *memDeliveryService) Put(ns string, d *Delivery) (*Delivery, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.deliveries[ns]; !ok {
+		s.deliveries[ns] = map[uint64]*Delivery{}
+	}
+
+	now := time.Now().UTC()
+
+	if d.ID == 0 {
+		id, err := flake.NextID(flakeNamespaceDeliveries(ns))
+		if err != nil {
+			return nil, err
+		}
+
+		d.ID = id
+		d.CreatedAt = now
+
+		if d.NextAttemptAt.IsZero() {
+			d.NextAttemptAt = now
+		}
+	} else {
+		old, ok := s.deliveries[ns][d.ID]
+		if !ok {
+			return nil, wrapError(ErrInvalidDelivery, "%d not found", d.ID)
+		}
+
+		d.CreatedAt = old.CreatedAt
+	}
+
+	d.UpdatedAt = now
+
+	s.deliveries[ns][d.ID] = copyDelivery(d)
+
+	return copyDelivery(d), nil
+}
+
+func (s *memDeliveryService) Query(
+	ns string,
+	opts DeliveryQueryOptions,
+) (Deliveries, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	ds := Deliveries{}
+
+	for _, d := range s.deliveries[ns] {
+		if !inIDs(d.AppID, opts.AppIDs) {
+			continue
+		}
+
+		if !opts.Due.IsZero() && d.NextAttemptAt.After(opts.Due) {
+			continue
+		}
+
+		if !inIDs(d.ID, opts.IDs) {
+			continue
+		}
+
+		if !inStates(d.State, opts.States) {
+			continue
+		}
+
+		if !inIDs(d.WebhookID, opts.WebhookIDs) {
+			continue
+		}
+
+		ds = append(ds, copyDelivery(d))
+	}
+
+	if opts.Due.IsZero() {
+		sort.Sort(sort.Reverse(ds))
+	} else {
+		sort.Sort(deliveriesByNextAttempt(ds))
+	}
+
+	if opts.Limit > 0 && len(ds) > opts.Limit {
+		ds = ds[:opts.Limit]
+	}
+
+	return ds, nil
+}
+
+func (s *memDeliveryService) Setup(ns string) error {
+	return nil
+}
+
+func (s *memDeliveryService) Teardown(ns string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.deliveries, ns)
+
+	return nil
+}
+
+type deliveriesByNextAttempt Deliveries
+
+func (ds deliveriesByNextAttempt) Len() int {
+	return len(ds)
+}
+
+func (ds deliveriesByNextAttempt) Less(i, j int) bool {
+	return ds[i].NextAttemptAt.Before(ds[j].NextAttemptAt)
+}
+
+func (ds deliveriesByNextAttempt) Swap(i, j int) {
+	ds[i], ds[j] = ds[j], ds[i]
+}
+
+func copyDelivery(d *Delivery) *Delivery {
+	old := *d
+	return &old
+}
+
+func copyWebhook(w *Webhook) *Webhook {
+	old := *w
+	old.Subscriptions = append(Subscriptions{}, w.Subscriptions...)
+	return &old
+}
+
+func inIDs(id uint64, ids []uint64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inStates(s State, ss []State) bool {
+	if len(ss) == 0 {
+		return true
+	}
+
+	for _, state := range ss {
+		if state == s {
+			return true
+		}
+	}
+
+	return false
+}
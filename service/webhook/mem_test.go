@@ -0,0 +1,27 @@
+package webhook
+
+import "testing"
+
+func TestMemPut(t *testing.T) {
+	testServicePut(t, prepareMem)
+}
+
+func TestMemQuery(t *testing.T) {
+	testServiceQuery(t, prepareMem)
+}
+
+func TestMemDeliveryPut(t *testing.T) {
+	testDeliveryServicePut(t, prepareMemDelivery)
+}
+
+func TestMemDeliveryQuery(t *testing.T) {
+	testDeliveryServiceQuery(t, prepareMemDelivery)
+}
+
+func prepareMem(t *testing.T, namespace string) Service {
+	return MemService()
+}
+
+func prepareMemDelivery(t *testing.T, namespace string) DeliveryService {
+	return MemDeliveryService()
+}
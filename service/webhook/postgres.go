@@ -0,0 +1,706 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/tapglue/snaas/platform/flake"
+	"github.com/tapglue/snaas/platform/pg"
+)
+
+const (
+	pgInsertWebhook = `INSERT INTO
+		%s.webhooks(active, deleted, id, name, secret, subscriptions, url, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	pgUpdateWebhook = `
+		UPDATE
+			%s.webhooks
+		SET
+			active = $2,
+			deleted = $3,
+			name = $4,
+			secret = $5,
+			subscriptions = $6,
+			url = $7,
+			updated_at = $8
+		WHERE
+			id = $1`
+
+	pgClauseActive  = `active = ?`
+	pgClauseDeleted = `deleted = ?`
+	pgClauseIDs     = `id IN (?)`
+
+	pgListWebhooks = `
+		SELECT
+			active, deleted, id, name, secret, subscriptions, url, created_at, updated_at
+		FROM
+			%s.webhooks
+		%s`
+	pgOrderCreatedAt = `ORDER BY created_at DESC`
+
+	pgCreateSchema = `CREATE SCHEMA IF NOT EXISTS %s`
+	pgCreateTable  = `CREATE TABLE IF NOT EXISTS %s.webhooks(
+		active BOOL DEFAULT false,
+		deleted BOOL DEFAULT false,
+		id BIGINT NOT NULL UNIQUE,
+		name TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		subscriptions JSONB NOT NULL,
+		url TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`
+	pgDropTable = `DROP TABLE IF EXISTS %s.webhooks`
+)
+
+const (
+	pgInsertDelivery = `INSERT INTO
+		%s.webhook_deliveries(app_id, attempts, error, event, id, payload, state, status_code, webhook_id, next_attempt_at, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	pgUpdateDelivery = `
+		UPDATE
+			%s.webhook_deliveries
+		SET
+			attempts = $2,
+			error = $3,
+			state = $4,
+			status_code = $5,
+			next_attempt_at = $6,
+			updated_at = $7
+		WHERE
+			id = $1`
+
+	pgClauseAppIDs     = `app_id IN (?)`
+	pgClauseDue        = `next_attempt_at <= ?`
+	pgClauseStates     = `state IN (?)`
+	pgClauseWebhookIDs = `webhook_id IN (?)`
+
+	pgListDeliveries = `
+		SELECT
+			app_id, attempts, error, event, id, payload, state, status_code, webhook_id, next_attempt_at, created_at, updated_at
+		FROM
+			%s.webhook_deliveries
+		%s`
+
+	pgCreateDeliveryTable = `CREATE TABLE IF NOT EXISTS %s.webhook_deliveries(
+		app_id BIGINT NOT NULL,
+		attempts INT NOT NULL,
+		error TEXT NOT NULL,
+		event TEXT NOT NULL,
+		id BIGINT NOT NULL UNIQUE,
+		payload JSONB NOT NULL,
+		state TEXT NOT NULL,
+		status_code INT NOT NULL,
+		webhook_id BIGINT NOT NULL,
+		next_attempt_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`
+	pgDropDeliveryTable = `DROP TABLE IF EXISTS %s.webhook_deliveries`
+
+	pgIndexDeliveryDue = `
+		CREATE INDEX
+			%s
+		ON
+			%s.webhook_deliveries(state, next_attempt_at)`
+	pgIndexDeliveryWebhook = `
+		CREATE INDEX
+			%s
+		ON
+			%s.webhook_deliveries(app_id, webhook_id, created_at)`
+)
+
+type pgService struct {
+	db *sqlx.DB
+}
+
+// PostgresService returns a Postgres based Service implementation.
+func PostgresService(db *sqlx.DB) Service {
+	return &pgService{
+		db: db,
+	}
+}
+
+func (s *pgService) Put(ns string, w *Webhook) (*Webhook, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	if w.ID == 0 {
+		return s.insert(ns, w)
+	}
+
+	return s.update(ns, w)
+}
+
+func (s *pgService) Query(ns string, opts QueryOptions) (List, error) {
+	where, params, err := convertOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := s.listWebhooks(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		ws, err = s.listWebhooks(ns, where, params...)
+	}
+
+	return ws, err
+}
+
+func (s *pgService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateTable, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) Teardown(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgDropTable, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("teardown '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgService) insert(ns string, w *Webhook) (*Webhook, error) {
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now().UTC()
+	}
+
+	ts, err := time.Parse(pg.TimeFormat, w.CreatedAt.UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	w.CreatedAt = ts
+	w.UpdatedAt = ts
+
+	id, err := flake.NextID(flakeNamespace(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	w.ID = id
+
+	subscriptions, err := json.Marshal(w.Subscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		params = []interface{}{
+			w.Active,
+			w.Deleted,
+			w.ID,
+			w.Name,
+			w.Secret,
+			subscriptions,
+			w.URL,
+			w.CreatedAt,
+			w.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgInsertWebhook, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (s *pgService) listWebhooks(
+	ns, where string,
+	params ...interface{},
+) (List, error) {
+	query := fmt.Sprintf(pgListWebhooks, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ws := List{}
+
+	for rows.Next() {
+		var (
+			subscriptions = []byte{}
+			w             = &Webhook{}
+		)
+
+		err := rows.Scan(
+			&w.Active,
+			&w.Deleted,
+			&w.ID,
+			&w.Name,
+			&w.Secret,
+			&subscriptions,
+			&w.URL,
+			&w.CreatedAt,
+			&w.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(subscriptions, &w.Subscriptions); err != nil {
+			return nil, err
+		}
+
+		w.CreatedAt = w.CreatedAt.UTC()
+		w.UpdatedAt = w.UpdatedAt.UTC()
+
+		ws = append(ws, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+func (s *pgService) update(ns string, w *Webhook) (*Webhook, error) {
+	now, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	w.UpdatedAt = now
+
+	subscriptions, err := json.Marshal(w.Subscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		params = []interface{}{
+			w.ID,
+			w.Active,
+			w.Deleted,
+			w.Name,
+			w.Secret,
+			subscriptions,
+			w.URL,
+			w.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgUpdateWebhook, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+type pgDeliveryService struct {
+	db *sqlx.DB
+}
+
+// PostgresDeliveryService returns a Postgres based DeliveryService
+// implementation.
+func PostgresDeliveryService(db *sqlx.DB) DeliveryService {
+	return &pgDeliveryService{
+		db: db,
+	}
+}
+
+func (s *pgDeliveryService) Put(ns string, d *Delivery) (*Delivery, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	if d.ID == 0 {
+		return s.insert(ns, d)
+	}
+
+	return s.update(ns, d)
+}
+
+func (s *pgDeliveryService) Query(
+	ns string,
+	opts DeliveryQueryOptions,
+) (Deliveries, error) {
+	where, params, err := convertDeliveryOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := s.listDeliveries(ns, where, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		ds, err = s.listDeliveries(ns, where, params...)
+	}
+
+	return ds, err
+}
+
+func (s *pgDeliveryService) Setup(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgCreateSchema, ns),
+		fmt.Sprintf(pgCreateDeliveryTable, ns),
+		pg.GuardIndex(ns, "webhook_delivery_due", pgIndexDeliveryDue),
+		pg.GuardIndex(ns, "webhook_delivery_webhook", pgIndexDeliveryWebhook),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("setup '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgDeliveryService) Teardown(ns string) error {
+	qs := []string{
+		fmt.Sprintf(pgDropDeliveryTable, ns),
+	}
+
+	for _, q := range qs {
+		_, err := s.db.Exec(q)
+		if err != nil {
+			return fmt.Errorf("teardown '%s': %s", q, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *pgDeliveryService) insert(ns string, d *Delivery) (*Delivery, error) {
+	ts, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := flake.NextID(flakeNamespaceDeliveries(ns))
+	if err != nil {
+		return nil, err
+	}
+
+	d.ID = id
+	d.CreatedAt = ts
+	d.UpdatedAt = ts
+
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = ts
+	}
+
+	var (
+		params = []interface{}{
+			d.AppID,
+			d.Attempts,
+			d.Error,
+			d.Event,
+			d.ID,
+			[]byte(d.Payload),
+			string(d.State),
+			d.StatusCode,
+			d.WebhookID,
+			d.NextAttemptAt.UTC(),
+			d.CreatedAt,
+			d.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgInsertDelivery, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (s *pgDeliveryService) listDeliveries(
+	ns, where string,
+	params ...interface{},
+) (Deliveries, error) {
+	query := fmt.Sprintf(pgListDeliveries, ns, where)
+
+	rows, err := s.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ds := Deliveries{}
+
+	for rows.Next() {
+		var (
+			d = &Delivery{}
+
+			payload []byte
+			state   string
+		)
+
+		err := rows.Scan(
+			&d.AppID,
+			&d.Attempts,
+			&d.Error,
+			&d.Event,
+			&d.ID,
+			&payload,
+			&state,
+			&d.StatusCode,
+			&d.WebhookID,
+			&d.NextAttemptAt,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		d.Payload = payload
+		d.State = State(state)
+		d.NextAttemptAt = d.NextAttemptAt.UTC()
+		d.CreatedAt = d.CreatedAt.UTC()
+		d.UpdatedAt = d.UpdatedAt.UTC()
+
+		ds = append(ds, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+func (s *pgDeliveryService) update(ns string, d *Delivery) (*Delivery, error) {
+	now, err := time.Parse(pg.TimeFormat, time.Now().UTC().Format(pg.TimeFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	d.UpdatedAt = now
+
+	var (
+		params = []interface{}{
+			d.ID,
+			d.Attempts,
+			d.Error,
+			string(d.State),
+			d.StatusCode,
+			d.NextAttemptAt.UTC(),
+			d.UpdatedAt,
+		}
+		query = fmt.Sprintf(pgUpdateDelivery, ns)
+	)
+
+	_, err = s.db.Exec(query, params...)
+	if err != nil && pg.IsRelationNotFound(pg.WrapError(err)) {
+		if err := s.Setup(ns); err != nil {
+			return nil, err
+		}
+
+		_, err = s.db.Exec(query, params...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func convertOpts(opts QueryOptions) (string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+	)
+
+	if opts.Active != nil {
+		clause, _, err := sqlx.In(pgClauseActive, []interface{}{*opts.Active})
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, *opts.Active)
+	}
+
+	if opts.Deleted != nil {
+		clause, _, err := sqlx.In(pgClauseDeleted, []interface{}{*opts.Deleted})
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, *opts.Deleted)
+	}
+
+	if len(opts.IDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.IDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	where = fmt.Sprintf("%s\n%s", where, pgOrderCreatedAt)
+
+	return where, params, nil
+}
+
+func convertDeliveryOpts(
+	opts DeliveryQueryOptions,
+) (string, []interface{}, error) {
+	var (
+		clauses = []string{}
+		params  = []interface{}{}
+		order   = pgOrderCreatedAt
+	)
+
+	if len(opts.AppIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.AppIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseAppIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if !opts.Due.IsZero() {
+		clauses = append(clauses, pgClauseDue)
+		params = append(params, opts.Due.UTC().Format(pg.TimeFormat))
+
+		// Due deliveries are worked off oldest first.
+		order = `ORDER BY next_attempt_at ASC`
+	}
+
+	if len(opts.IDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.IDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.States) > 0 {
+		ps := []interface{}{}
+
+		for _, s := range opts.States {
+			ps = append(ps, string(s))
+		}
+
+		clause, _, err := sqlx.In(pgClauseStates, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	if len(opts.WebhookIDs) > 0 {
+		ps := []interface{}{}
+
+		for _, id := range opts.WebhookIDs {
+			ps = append(ps, id)
+		}
+
+		clause, _, err := sqlx.In(pgClauseWebhookIDs, ps)
+		if err != nil {
+			return "", nil, err
+		}
+
+		clauses = append(clauses, clause)
+		params = append(params, ps...)
+	}
+
+	where := ""
+
+	if len(clauses) > 0 {
+		where = sqlx.Rebind(sqlx.DOLLAR, pg.ClausesToWhere(clauses...))
+	}
+
+	where = fmt.Sprintf("%s\n%s", where, order)
+
+	if opts.Limit > 0 {
+		where = fmt.Sprintf("%s\nLIMIT %d", where, opts.Limit)
+	}
+
+	return where, params, nil
+}
@@ -0,0 +1,279 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tapglue/snaas/platform/service"
+	"github.com/tapglue/snaas/service/rule"
+)
+
+// Delivery states.
+const (
+	StateFailed    State = "failed"
+	StatePending   State = "pending"
+	StateSucceeded State = "succeeded"
+)
+
+// Delivery is a single attempt to hand a state change to a Webhook, kept as
+// log of the outcome.
+type Delivery struct {
+	AppID         uint64
+	Attempts      int
+	Error         string
+	Event         string
+	ID            uint64
+	Payload       json.RawMessage
+	State         State
+	StatusCode    int
+	WebhookID     uint64
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Validate checks for semantic correctness.
+func (d *Delivery) Validate() error {
+	if d.AppID == 0 {
+		return wrapError(ErrInvalidDelivery, "missing app id")
+	}
+
+	if d.Event == "" {
+		return wrapError(ErrInvalidDelivery, "missing event")
+	}
+
+	if d.WebhookID == 0 {
+		return wrapError(ErrInvalidDelivery, "missing webhook id")
+	}
+
+	if len(d.Payload) == 0 {
+		return wrapError(ErrInvalidDelivery, "missing payload")
+	}
+
+	switch d.State {
+	case StateFailed, StatePending, StateSucceeded:
+		// valid
+	default:
+		return wrapError(ErrInvalidDelivery, "unsupported state '%s'", d.State)
+	}
+
+	return nil
+}
+
+// Deliveries is a Delivery collection.
+type Deliveries []*Delivery
+
+func (ds Deliveries) Len() int {
+	return len(ds)
+}
+
+func (ds Deliveries) Less(i, j int) bool {
+	return ds[i].CreatedAt.Before(ds[j].CreatedAt)
+}
+
+func (ds Deliveries) Swap(i, j int) {
+	ds[i], ds[j] = ds[j], ds[i]
+}
+
+// DeliveryQueryOptions to narrow-down Delivery queries.
+type DeliveryQueryOptions struct {
+	AppIDs     []uint64
+	Due        time.Time
+	IDs        []uint64
+	Limit      int
+	States     []State
+	WebhookIDs []uint64
+}
+
+// DeliveryService for Delivery interactions.
+type DeliveryService interface {
+	service.Lifecycle
+
+	Put(namespace string, d *Delivery) (*Delivery, error)
+	Query(namespace string, opts DeliveryQueryOptions) (Deliveries, error)
+}
+
+// DeliveryServiceMiddleware is a chainable behaviour modifier for
+// DeliveryService.
+type DeliveryServiceMiddleware func(DeliveryService) DeliveryService
+
+// List is a Webhook collection.
+type List []*Webhook
+
+func (ws List) Len() int {
+	return len(ws)
+}
+
+func (ws List) Less(i, j int) bool {
+	return ws[i].CreatedAt.Before(ws[j].CreatedAt)
+}
+
+func (ws List) Swap(i, j int) {
+	ws[i], ws[j] = ws[j], ws[i]
+}
+
+// QueryOptions to narrow-down Webhook queries.
+type QueryOptions struct {
+	Active  *bool
+	Deleted *bool
+	IDs     []uint64
+}
+
+// Service for Webhook interactions.
+type Service interface {
+	service.Lifecycle
+
+	Put(namespace string, w *Webhook) (*Webhook, error)
+	Query(namespace string, opts QueryOptions) (List, error)
+}
+
+// ServiceMiddleware is a chainable behaviour modifier for Service.
+type ServiceMiddleware func(Service) Service
+
+// State of a Delivery.
+type State string
+
+// Subscription selects the state changes of one entity type. Without Criteria
+// every state change of the type is selected.
+type Subscription struct {
+	Criteria rule.Matcher
+	Type     rule.Type
+}
+
+// MarshalJSON to encode the Subscription with its criteria.
+func (s Subscription) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Criteria rule.Matcher `json:"criteria,omitempty"`
+		Type     rule.Type    `json:"type"`
+	}{
+		Criteria: s.Criteria,
+		Type:     s.Type,
+	})
+}
+
+// UnmarshalJSON to decode the criteria matching the type of the Subscription.
+func (s *Subscription) UnmarshalJSON(raw []byte) error {
+	f := struct {
+		Criteria json.RawMessage `json:"criteria"`
+		Type     rule.Type       `json:"type"`
+	}{}
+
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return err
+	}
+
+	s.Type = f.Type
+	s.Criteria = nil
+
+	if len(f.Criteria) == 0 || string(f.Criteria) == "null" {
+		return nil
+	}
+
+	switch f.Type {
+	case rule.TypeConnection:
+		s.Criteria = &rule.CriteriaConnection{}
+	case rule.TypeEvent:
+		s.Criteria = &rule.CriteriaEvent{}
+	case rule.TypeObject:
+		s.Criteria = &rule.CriteriaObject{}
+	case rule.TypeReaction:
+		s.Criteria = &rule.CriteriaReaction{}
+	case rule.TypeUser:
+		s.Criteria = &rule.CriteriaUser{}
+	default:
+		return wrapError(ErrInvalidWebhook, "unsupported type %d", f.Type)
+	}
+
+	return json.Unmarshal(f.Criteria, s.Criteria)
+}
+
+// Subscriptions is a Subscription collection.
+type Subscriptions []Subscription
+
+// Webhook subscribes an external HTTP endpoint to state changes of an App.
+type Webhook struct {
+	Active        bool
+	Deleted       bool
+	ID            uint64
+	Name          string
+	Secret        string
+	Subscriptions Subscriptions
+	URL           string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Match reports if the state change of the given type is selected by one of
+// the Subscriptions.
+func (w *Webhook) Match(t rule.Type, change interface{}) bool {
+	for _, s := range w.Subscriptions {
+		if s.Type != t {
+			continue
+		}
+
+		if s.Criteria == nil || s.Criteria.Match(change) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate checks for semantic correctness.
+func (w *Webhook) Validate() error {
+	if w.Name == "" {
+		return wrapError(ErrInvalidWebhook, "missing name")
+	}
+
+	if w.Secret == "" {
+		return wrapError(ErrInvalidWebhook, "missing secret")
+	}
+
+	if len(w.Subscriptions) == 0 {
+		return wrapError(ErrInvalidWebhook, "missing subscriptions")
+	}
+
+	for _, s := range w.Subscriptions {
+		switch s.Type {
+		case rule.TypeConnection, rule.TypeEvent, rule.TypeObject, rule.TypeReaction,
+			rule.TypeUser:
+			// valid
+		default:
+			return wrapError(ErrInvalidWebhook, "unsupported type %d", s.Type)
+		}
+	}
+
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return wrapError(ErrInvalidWebhook, "invalid url: %s", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return wrapError(ErrInvalidWebhook, "unsupported url scheme '%s'", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return wrapError(ErrInvalidWebhook, "missing url host")
+	}
+
+	// Hostnames are checked once resolved when delivering, see Client.
+	host := strings.ToLower(u.Hostname())
+
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && !PublicIP(ip)) {
+		return wrapError(ErrInvalidWebhook, "url host '%s' not allowed", host)
+	}
+
+	return nil
+}
+
+func flakeNamespace(ns string) string {
+	return fmt.Sprintf("%s_%s", ns, "webhooks")
+}
+
+func flakeNamespaceDeliveries(ns string) string {
+	return fmt.Sprintf("%s_%s", ns, "webhook_deliveries")
+}
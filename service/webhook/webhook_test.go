@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tapglue/snaas/service/connection"
+	"github.com/tapglue/snaas/service/object"
+	"github.com/tapglue/snaas/service/rule"
+)
+
+func TestSubscriptionJSON(t *testing.T) {
+	raw, err := json.Marshal(testWebhook().Subscriptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ss := Subscriptions{}
+
+	if err := json.Unmarshal(raw, &ss); err != nil {
+		t.Fatal(err)
+	}
+
+	c, ok := ss[0].Criteria.(*rule.CriteriaObject)
+	if !ok {
+		t.Fatalf("have %T, want %T", ss[0].Criteria, &rule.CriteriaObject{})
+	}
+
+	if have, want := c.New.Types[0], "tg_post"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	if ss[1].Criteria != nil {
+		t.Errorf("have %v, want %v", ss[1].Criteria, nil)
+	}
+}
+
+func TestWebhookMatch(t *testing.T) {
+	w := testWebhook()
+
+	cases := []struct {
+		change interface{}
+		t      rule.Type
+		want   bool
+	}{
+		{
+			change: &object.StateChange{New: &object.Object{Type: "tg_post"}},
+			t:      rule.TypeObject,
+			want:   true,
+		},
+		{
+			change: &object.StateChange{New: &object.Object{Type: "tg_comment"}},
+			t:      rule.TypeObject,
+			want:   false,
+		},
+		{
+			change: &connection.StateChange{New: &connection.Connection{}},
+			t:      rule.TypeConnection,
+			want:   true,
+		},
+		{
+			change: &object.StateChange{New: &object.Object{Type: "tg_post"}},
+			t:      rule.TypeEvent,
+			want:   false,
+		},
+	}
+
+	for _, c := range cases {
+		if have, want := w.Match(c.t, c.change), c.want; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	ws := List{
+		// Missing name.
+		{
+			Secret:        "s3cr3t",
+			Subscriptions: testWebhook().Subscriptions,
+			URL:           "https://example.com",
+		},
+		// Missing secret.
+		{
+			Name:          "backend",
+			Subscriptions: testWebhook().Subscriptions,
+			URL:           "https://example.com",
+		},
+		// Missing subscriptions.
+		{
+			Name:   "backend",
+			Secret: "s3cr3t",
+			URL:    "https://example.com",
+		},
+		// Unsupported scheme.
+		{
+			Name:          "backend",
+			Secret:        "s3cr3t",
+			Subscriptions: testWebhook().Subscriptions,
+			URL:           "ftp://example.com",
+		},
+		// Loopback host.
+		{
+			Name:          "backend",
+			Secret:        "s3cr3t",
+			Subscriptions: testWebhook().Subscriptions,
+			URL:           "http://127.0.0.1:8080/hooks",
+		},
+		// Link-local host.
+		{
+			Name:          "backend",
+			Secret:        "s3cr3t",
+			Subscriptions: testWebhook().Subscriptions,
+			URL:           "http://169.254.169.254/latest/meta-data",
+		},
+		// Private host.
+		{
+			Name:          "backend",
+			Secret:        "s3cr3t",
+			Subscriptions: testWebhook().Subscriptions,
+			URL:           "http://[fd00::1]/hooks",
+		},
+	}
+
+	for _, w := range ws {
+		if have, want := w.Validate(), ErrInvalidWebhook; !IsInvalidWebhook(have) {
+			t.Errorf("have %v, want %v", have, want)
+		}
+	}
+}